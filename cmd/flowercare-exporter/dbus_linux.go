@@ -0,0 +1,18 @@
+//go:build linux
+
+package main
+
+import (
+	"github.com/xperimental/flowercare-exporter/internal/bluezdbus"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
+)
+
+func newDBusReader(cfg config.Config) updater.Reader {
+	return &bluezdbus.Reader{
+		Adapter:          cfg.Device,
+		SkipRealtimeMode: cfg.SkipRealtimeMode,
+		Lenient:          cfg.LenientParsing,
+		Layouts:          loadLayouts(cfg),
+	}
+}
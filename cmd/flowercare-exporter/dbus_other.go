@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"runtime"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
+)
+
+// newDBusReader fails, as BlueZ and its D-Bus API only exist on Linux.
+func newDBusReader(cfg config.Config) updater.Reader {
+	log.Fatalf("The D-Bus backend is not available on %s.", runtime.GOOS)
+	return nil
+}
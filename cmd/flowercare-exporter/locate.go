@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+const locateCommand = "locate"
+
+// runLocate asks a running exporter to make the LED of a sensor blink. The exporter is used, because it holds the
+// Bluetooth adapter.
+func runLocate(args []string) error {
+	flags := pflag.NewFlagSet(locateCommand, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] <mac>\n\n", os.Args[0], locateCommand)
+		flags.PrintDefaults()
+	}
+	baseURL := flags.String("url", "http://localhost:9294", "URL of the running exporter. Needs to be the admin address, if it is set.")
+	timeout := flags.Duration("timeout", time.Minute, "Timeout for waiting for the sensor.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("need exactly one MAC address")
+	}
+	macAddress := flags.Arg(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	target := strings.TrimSuffix(*baseURL, "/") + "/api/v1/sensors/" + url.PathEscape(macAddress) + "/locate"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("can not reach exporter: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil || body.Error == "" {
+			return fmt.Errorf("exporter returned status %d", res.StatusCode)
+		}
+
+		return errors.New(body.Error)
+	}
+
+	fmt.Printf("The LED of %s is blinking.\n", macAddress)
+	return nil
+}
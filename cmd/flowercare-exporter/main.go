@@ -0,0 +1,1002 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/xperimental/flowercare-exporter/internal/adapterlock"
+	"github.com/xperimental/flowercare-exporter/internal/alert"
+	"github.com/xperimental/flowercare-exporter/internal/api"
+	"github.com/xperimental/flowercare-exporter/internal/bluetooth"
+	"github.com/xperimental/flowercare-exporter/internal/bluezcli"
+	"github.com/xperimental/flowercare-exporter/internal/collector"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/grafana"
+	"github.com/xperimental/flowercare-exporter/internal/hcistats"
+	"github.com/xperimental/flowercare-exporter/internal/history"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/internal/loki"
+	"github.com/xperimental/flowercare-exporter/internal/modbus"
+	"github.com/xperimental/flowercare-exporter/internal/recording"
+	"github.com/xperimental/flowercare-exporter/internal/registry"
+	"github.com/xperimental/flowercare-exporter/internal/simulator"
+	"github.com/xperimental/flowercare-exporter/internal/sink"
+	"github.com/xperimental/flowercare-exporter/internal/species"
+	"github.com/xperimental/flowercare-exporter/internal/tracing"
+	"github.com/xperimental/flowercare-exporter/internal/web"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
+)
+
+var (
+	logLevel = &slog.LevelVar{}
+	log      = newLogger(logging.FormatText)
+
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+
+	// Registries for the metric groups, which can be selected using the collect[] parameter of the metrics endpoint.
+	updaterRegistry = prometheus.NewRegistry()
+	hciRegistry     = prometheus.NewRegistry()
+	plantsRegistry  = prometheus.NewRegistry()
+	sinkRegistry    = prometheus.NewRegistry()
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == locateCommand {
+		if err := runLocate(os.Args[2:]); err != nil {
+			log.Fatalf("Error locating sensor: %s", err)
+		}
+		return
+	}
+
+	config, err := config.Parse(log)
+	if err != nil {
+		log.Fatalf("Error in configuration: %s", err)
+	}
+
+	logLevel.Set(slog.Level(config.LogLevel))
+	log = newLogger(logging.Format(config.LogFormat))
+	if config.PowerProfile != "" {
+		log.Infof("Using power profile %q.", config.PowerProfile)
+	}
+
+	if config.Benchmark.Sensors > 0 {
+		runBenchmark(config)
+		return
+	}
+
+	if config.Discover {
+		runDiscover(config)
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), config.Tracing, version)
+	if err != nil {
+		log.Fatalf("Error setting up tracing: %s", err)
+	}
+	if config.Tracing.Enabled() {
+		log.Infof("Exporting traces to %s", config.Tracing.Endpoint)
+	}
+
+	adapterName, source, reader, sensors := createReader(config)
+	scanner, _ := reader.(updater.Scanner)
+	diagnoser, _ := reader.(updater.Diagnoser)
+	namer, _ := reader.(updater.Namer)
+	historyReader, _ := reader.(updater.HistoryReader)
+	blinker, _ := reader.(updater.Blinker)
+	if config.RecordFile != "" {
+		log.Infof("Recording readings to %q", config.RecordFile)
+		recorder, err := recording.NewRecorder(log, config.RecordFile, reader, sensors)
+		if err != nil {
+			log.Fatalf("Error opening recording file: %s", err)
+		}
+
+		reader = recorder
+	}
+
+	var reg *registry.Registry
+	if config.RegistryFile != "" {
+		reg, err = registry.Open(config.RegistryFile)
+		if err != nil {
+			log.Fatalf("Error opening registry: %s", err)
+		}
+
+		if config.AutoRegister.Enabled {
+			sensors = mergeSensors(sensors, autoRegisteredSensors(reg))
+		}
+	}
+	sensors = replaceSensors(sensors, config.Replacements, reg)
+
+	lokiClient := createLokiClient(config)
+	dispatcher, err := sink.NewDispatcher(log, sink.Options{
+		JournalDir:     config.SinkJournalDir,
+		DeadLetterFile: config.SinkDeadLetter,
+		Deadband:       config.SinkDeadband,
+	}, createSinks(config, lokiClient)...)
+	if err != nil {
+		log.Fatalf("Error creating sinks: %s", err)
+	}
+	sinkRegistry.MustRegister(dispatcher)
+	plants := loadPlants(config)
+	alerts, silences := createAlertEngine(config, plants, lokiClient)
+	historyStore, err := history.Open(log, config.HistoryFile, config.HistoryDays, config.HistoryHourly, config.Location)
+	if err != nil {
+		log.Fatalf("Error opening history: %s", err)
+	}
+
+	provider := updater.New(log.Slog(), updater.Options{
+		AdapterName:     adapterName,
+		Source:          source,
+		Reader:          reader,
+		RefreshTimeout:  config.RefreshTimeout,
+		WatchdogTimeout: config.WatchdogTimeout,
+		Retry:           config.Retry,
+		ReadBudget:      config.ReadBudget,
+		Health:          config.Health,
+		Cooldown:        config.ReadCooldown,
+		QuietHours:      config.QuietHours,
+		SharedLock:      sharedLock(config.AdapterLockFile),
+		Scanner:         scanner,
+		Diagnoser:       diagnoser,
+		Blinker:         blinker,
+		Namer:           namer,
+		NameInterval:    config.NameInterval,
+		ScanInterval:    config.ScanInterval,
+		ScanDuration:    config.ScanDuration,
+		Beacons:         config.UseBeacons(),
+		Passive:         config.AllPassive(),
+		PassiveSensors:  config.PassiveSensors(),
+		AutoRegister:    config.AutoRegister,
+		Registry:        sensorRegistry(reg),
+		OnData:          onData(dispatcher, alerts, historyStore),
+		History:         historyReader,
+		Backfill:        config.Backfill,
+		OnHistory:       dispatcher.PublishHistory,
+		RemovalGrace:    config.RemovalGrace,
+		StaleDuration:   config.StaleDuration,
+	})
+
+	for _, s := range sensors {
+		log.Infof("Sensor: %s", s)
+		provider.AddSensor(s)
+	}
+	updaterRegistry.MustRegister(provider)
+
+	c := &collector.Flowercare{
+		Log:            log,
+		Source:         provider.GetData,
+		Sensors:        provider.Sensors,
+		StaleDuration:  config.StaleDuration,
+		ForgetDuration: config.ForgetDuration,
+		ReadInfo:       provider.GetReadInfo,
+		Unconfigured:   provider.Unconfigured,
+		Removed:        provider.Removed,
+	}
+
+	versionMetric := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: collector.MetricPrefix + "build_info",
+		Help: "Contains build information as labels. Value set to 1.",
+		ConstLabels: prometheus.Labels{
+			"version": version,
+			"commit":  commit,
+			"date":    date,
+		},
+	})
+	versionMetric.Set(1)
+	prometheus.MustRegister(versionMetric)
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: collector.MetricPrefix + "adapter_suspect",
+		Help: "Set to 1 if the last read on the Bluetooth adapter had to be abandoned by the watchdog.",
+		ConstLabels: prometheus.Labels{
+			"adapter": adapterName,
+		},
+	}, func() float64 {
+		if provider.AdapterSuspect() {
+			return 1
+		}
+		return 0
+	}))
+
+	groups := map[string]prometheus.Gatherer{
+		"exporter": prometheus.DefaultGatherer,
+		"updater":  updaterRegistry,
+		"hci":      hciRegistry,
+		"plants":   plantsRegistry,
+		"sinks":    sinkRegistry,
+	}
+	if len(config.Federation.Gateways) > 0 {
+		log.Infof("Federating gateways: %s", &config.Federation.Gateways)
+		client, err := config.AuthProfile(config.Federation.Auth).HTTPClient()
+		if err != nil {
+			log.Fatalf("Error creating federation client: %s", err)
+		}
+
+		groups["federation"] = &collector.Federation{
+			Log:      log,
+			Gateways: config.Federation.Gateways,
+			Timeout:  config.Federation.Timeout,
+			Client:   client,
+		}
+	}
+
+	mainMux := http.NewServeMux()
+	mainMux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		collector.Handler(c, groups, collector.HandlerConfig{
+			ScrapeOffset: config.ScrapeOffset,
+			Filter: collector.Filter{
+				Keep: config.KeepMetrics,
+				Drop: config.DropMetrics,
+			},
+			Labels: config.Labels,
+			Opts: promhttp.HandlerOpts{
+				// OpenMetrics is needed for exposing exemplars.
+				EnableOpenMetrics: true,
+			},
+		})))
+	mainMux.Handle("/", http.RedirectHandler("/metrics", http.StatusFound))
+
+	adminMux := mainMux
+	if config.AdminAddr != "" {
+		adminMux = http.NewServeMux()
+	}
+	adminMux.Handle("/grafana/dashboard.json", grafana.Handler(collector.MetricPrefix, provider.Sensors))
+	adminMux.Handle("/api/", api.New(log, provider, api.Options{
+		LogLevel: logLevel,
+		Location: config.Location,
+		History:  historyStore,
+		Silences: silences,
+		Admin:    config.AdminAddr != "" || config.EnableAdminAPI,
+	}))
+
+	startListener("metrics", config.ListenAddr, mainMux, config)
+	if config.AdminAddr != "" {
+		startListener("admin", config.AdminAddr, adminMux, config)
+	}
+	modbusServer := createModbusServer(config, provider)
+
+	wg := &sync.WaitGroup{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	startSignalHandler(ctx, wg, cancel)
+	startScheduleLoop(ctx, wg, config, provider)
+	dispatcher.Start(ctx, wg)
+	historyStore.Start(ctx, wg, config.HistoryFlush)
+	if alerts != nil {
+		alerts.Start(ctx, wg)
+	}
+	if modbusServer != nil {
+		modbusServer.Start(ctx, wg)
+	}
+	provider.Start(ctx, wg)
+
+	log.Info("Exporter is started.")
+	wg.Wait()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err := provider.Close(shutdownCtx); err != nil {
+		log.Errorf("Error closing adapter: %s", err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Errorf("Error flushing traces: %s", err)
+	}
+	log.Info("Shutdown complete.")
+}
+
+func newLogger(format logging.Format) *logging.Logger {
+	handler, err := logging.NewHandler(format, os.Stderr, logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating logger: %s\n", err)
+		os.Exit(1)
+	}
+
+	return logging.New(slog.New(handler))
+}
+
+func createLokiClient(cfg config.Config) *loki.Client {
+	if cfg.Loki.URL == "" {
+		return nil
+	}
+
+	client, err := loki.New(cfg.Loki, cfg.Labels, cfg.AuthProfile(cfg.Loki.Auth))
+	if err != nil {
+		log.Fatalf("Error creating Loki client: %s", err)
+	}
+	return client
+}
+
+func createSinks(cfg config.Config, lokiClient *loki.Client) []sink.Target {
+	var sinks []sink.Target
+
+	if cfg.Postgres.URL != "" {
+		log.Infof("Writing readings to PostgreSQL table %q", cfg.Postgres.Table)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		postgres, err := sink.NewPostgres(ctx, cfg.Postgres, cfg.AuthProfile(cfg.Postgres.Auth))
+		if err != nil {
+			log.Fatalf("Error creating PostgreSQL sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  postgres,
+			Batch: cfg.Postgres.Batch,
+			Retry: cfg.Postgres.Retry,
+		})
+	}
+
+	if cfg.Redis.URL != "" {
+		log.Info("Publishing readings to Redis")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		redis, err := sink.NewRedis(ctx, cfg.Redis, cfg.AuthProfile(cfg.Redis.Auth))
+		if err != nil {
+			log.Fatalf("Error creating Redis sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  redis,
+			Batch: cfg.Redis.Batch,
+			Retry: cfg.Redis.Retry,
+		})
+	}
+
+	if cfg.HomeAssistant.URL != "" {
+		log.Infof("Setting entity states in Home Assistant at %s", cfg.HomeAssistant.URL)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		homeAssistant, err := sink.NewHomeAssistant(ctx, cfg.HomeAssistant, cfg.AuthProfile(cfg.HomeAssistant.Auth))
+		if err != nil {
+			log.Fatalf("Error creating Home Assistant sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  homeAssistant,
+			Batch: cfg.HomeAssistant.Batch,
+			Retry: cfg.HomeAssistant.Retry,
+		})
+	}
+
+	if cfg.OpenHAB.URL != "" {
+		log.Infof("Updating items in openHAB at %s", cfg.OpenHAB.URL)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		openHAB, err := sink.NewOpenHAB(ctx, cfg.OpenHAB, cfg.AuthProfile(cfg.OpenHAB.Auth))
+		if err != nil {
+			log.Fatalf("Error creating openHAB sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  openHAB,
+			Batch: cfg.OpenHAB.Batch,
+			Retry: cfg.OpenHAB.Retry,
+		})
+	}
+
+	if cfg.Domoticz.URL != "" {
+		log.Infof("Updating devices in Domoticz at %s", cfg.Domoticz.URL)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		domoticz, err := sink.NewDomoticz(ctx, cfg.Domoticz, cfg.AuthProfile(cfg.Domoticz.Auth))
+		if err != nil {
+			log.Fatalf("Error creating Domoticz sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  domoticz,
+			Batch: cfg.Domoticz.Batch,
+			Retry: cfg.Domoticz.Retry,
+		})
+	}
+
+	if cfg.ThingSpeak.ChannelsFile != "" {
+		log.Info("Pushing readings to ThingSpeak")
+		thingSpeak, err := sink.NewThingSpeak(cfg.ThingSpeak, cfg.AuthProfile(cfg.ThingSpeak.Auth))
+		if err != nil {
+			log.Fatalf("Error creating ThingSpeak sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  thingSpeak,
+			Batch: cfg.ThingSpeak.Batch,
+			Retry: cfg.ThingSpeak.Retry,
+		})
+	}
+
+	if cfg.MQTT.Broker != "" {
+		log.Infof("Publishing readings to MQTT broker at %s", cfg.MQTT.Broker)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		mqtt, err := sink.NewMQTT(ctx, cfg.MQTT, cfg.AuthProfile(cfg.MQTT.Auth))
+		if err != nil {
+			log.Fatalf("Error creating MQTT sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  mqtt,
+			Batch: cfg.MQTT.Batch,
+			Retry: cfg.MQTT.Retry,
+		})
+	}
+
+	if cfg.AWSIoT.Endpoint != "" {
+		log.Infof("Publishing readings to AWS IoT Core at %s", cfg.AWSIoT.Endpoint)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		awsIoT, err := sink.NewAWSIoT(ctx, cfg.AWSIoT, cfg.AuthProfile(cfg.AWSIoT.Auth))
+		if err != nil {
+			log.Fatalf("Error creating AWS IoT Core sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  awsIoT,
+			Batch: cfg.AWSIoT.Batch,
+			Retry: cfg.AWSIoT.Retry,
+		})
+	}
+
+	if cfg.AzureIoT.ConnectionFile != "" {
+		log.Info("Publishing readings to Azure IoT Hub")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		azureIoT, err := sink.NewAzureIoT(ctx, cfg.AzureIoT, cfg.AuthProfile(cfg.AzureIoT.Auth))
+		if err != nil {
+			log.Fatalf("Error creating Azure IoT Hub sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  azureIoT,
+			Batch: cfg.AzureIoT.Batch,
+			Retry: cfg.AzureIoT.Retry,
+		})
+	}
+
+	if cfg.Webhook.URL != "" {
+		log.Infof("Posting readings to webhook at %s", cfg.Webhook.URL)
+		webhook, err := sink.NewWebhook(cfg.Webhook, cfg.AuthProfile(cfg.Webhook.Auth))
+		if err != nil {
+			log.Fatalf("Error creating webhook sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:  webhook,
+			Batch: cfg.Webhook.Batch,
+			Retry: cfg.Webhook.Retry,
+		})
+	}
+
+	if lokiClient != nil && cfg.Loki.Readings {
+		log.Infof("Sending readings to Loki at %s", cfg.Loki.URL)
+		sinks = append(sinks, sink.Target{
+			Sink:  sink.NewLoki(lokiClient),
+			Batch: cfg.Loki.Batch,
+			Retry: cfg.Loki.Retry,
+		})
+	}
+
+	if cfg.RemoteWrite.URL != "" {
+		log.Infof("Pushing history records to remote-write endpoint at %s", cfg.RemoteWrite.URL)
+		remoteWrite, err := sink.NewRemoteWrite(cfg.RemoteWrite, cfg.Labels, cfg.AuthProfile(cfg.RemoteWrite.Auth))
+		if err != nil {
+			log.Fatalf("Error creating remote-write sink: %s", err)
+		}
+		sinks = append(sinks, sink.Target{
+			Sink:        remoteWrite,
+			Batch:       cfg.RemoteWrite.Batch,
+			Retry:       cfg.RemoteWrite.Retry,
+			HistoryOnly: true,
+		})
+	}
+
+	return sinks
+}
+
+// onData passes successful readings to the sinks, the history and the alert engine, if enabled.
+func onData(dispatcher *sink.Dispatcher, alerts *alert.Engine, historyStore *history.Store) func(config.Sensor, miflora.Data) {
+	return func(sensor config.Sensor, data miflora.Data) {
+		dispatcher.Publish(sensor, data)
+		historyStore.Observe(sensor, data)
+		if alerts != nil {
+			alerts.Observe(sensor, data)
+		}
+	}
+}
+
+func sharedLock(fileName string) updater.SharedLock {
+	if fileName == "" {
+		return nil
+	}
+
+	return adapterlock.New(fileName)
+}
+
+// sensorRegistry returns the registry, if it is enabled, for use by the updater.
+func sensorRegistry(reg *registry.Registry) updater.Registry {
+	if reg == nil {
+		return nil
+	}
+
+	return reg
+}
+
+func loadPlants(cfg config.Config) []alert.Plant {
+	if cfg.PlantsFile == "" {
+		return nil
+	}
+
+	db, err := species.Builtin()
+	if err != nil {
+		log.Fatalf("Error loading species database: %s", err)
+	}
+
+	for _, f := range cfg.SpeciesFiles {
+		imported, err := species.LoadFile(f)
+		if err != nil {
+			log.Fatalf("Error loading species: %s", err)
+		}
+
+		log.Infof("Loaded %d species from %q", len(imported), f)
+		db.Add(imported...)
+	}
+
+	plants, err := alert.LoadPlants(cfg.PlantsFile, db)
+	if err != nil {
+		log.Fatalf("Error loading plants: %s", err)
+	}
+
+	plantsRegistry.MustRegister(&collector.PlantInfo{
+		Plants:   plants,
+		Location: cfg.Location,
+	})
+	return plants
+}
+
+func createAlertEngine(cfg config.Config, plants []alert.Plant, lokiClient *loki.Client) (*alert.Engine, *alert.Silences) {
+	if cfg.PlantsFile == "" {
+		return nil, nil
+	}
+
+	silences, err := alert.NewSilences(cfg.SilencesFile)
+	if err != nil {
+		log.Fatalf("Error loading silences: %s", err)
+	}
+	plantsRegistry.MustRegister(&collector.Silences{
+		Silences: silences,
+	})
+
+	var notifiers []alert.Notifier
+	if cfg.Email.SMTPAddr != "" {
+		email, err := alert.NewEmail(cfg.Email)
+		if err != nil {
+			log.Fatalf("Error creating email notifier: %s", err)
+		}
+		notifiers = append(notifiers, email)
+	}
+
+	if cfg.Slack.WebhookURL != "" {
+		slack, err := alert.NewSlack(cfg.Slack.WebhookURL, cfg.Slack.MessageTemplate)
+		if err != nil {
+			log.Fatalf("Error creating Slack notifier: %s", err)
+		}
+		notifiers = append(notifiers, slack)
+	}
+
+	if cfg.Discord.WebhookURL != "" {
+		discord, err := alert.NewDiscord(cfg.Discord.WebhookURL, cfg.Discord.MessageTemplate)
+		if err != nil {
+			log.Fatalf("Error creating Discord notifier: %s", err)
+		}
+		notifiers = append(notifiers, discord)
+	}
+
+	if lokiClient != nil && cfg.Loki.Alerts {
+		notifiers = append(notifiers, alert.NewLoki(lokiClient))
+	}
+
+	if len(notifiers) == 0 {
+		log.Warn("No notifiers configured, alerts will only be logged.")
+	}
+
+	log.Infof("Evaluating alerts for %d plants.", len(plants))
+	return alert.NewEngine(log, plants, cfg.StaleDuration, cfg.Location, silences, notifiers...), silences
+}
+
+func createModbusServer(cfg config.Config, provider *updater.Updater) *modbus.Server {
+	if cfg.ModbusAddr == "" {
+		return nil
+	}
+
+	mappings, err := modbus.LoadMappings(cfg.ModbusMapFile)
+	if err != nil {
+		log.Fatalf("Error loading Modbus register map: %s", err)
+	}
+
+	server := &modbus.Server{
+		Log:      log,
+		Mappings: mappings,
+		Sensors:  provider.Sensors,
+		Source:   provider.GetData,
+	}
+	if err := server.Listen(cfg.ModbusAddr); err != nil {
+		log.Fatalf("Error listening for Modbus: %s", err)
+	}
+
+	log.Infof("Serving %d sensors using Modbus TCP on %s...", len(mappings), cfg.ModbusAddr)
+	return server
+}
+
+func startListener(name, addr string, handler http.Handler, cfg config.Config) {
+	go func() {
+		log.Infof("Listen for %s on %s...", name, addr)
+		log.Fatal(web.ListenAndServe(addr, cfg.AllowList.Handler(handler), cfg.TLS))
+	}()
+}
+
+func createReader(cfg config.Config) (string, string, updater.Reader, []config.Sensor) {
+	switch {
+	case cfg.FederationOnly():
+		log.Info("No local sensors configured, only federating other exporters.")
+		return "none", "none", noReader{}, nil
+	case cfg.Simulate > 0:
+		log.Infof("Simulating %d sensors.", cfg.Simulate)
+		return simulator.AdapterName, "simulated", simulator.New(), append(cfg.Sensors, simulatedSensors(cfg.Simulate)...)
+	case cfg.ReplayFile != "":
+		log.Infof("Replaying %q with speed %v.", cfg.ReplayFile, cfg.ReplaySpeed)
+		replayer, err := recording.Load(cfg.ReplayFile, cfg.ReplaySpeed)
+		if err != nil {
+			log.Fatalf("Error loading recording: %s", err)
+		}
+
+		return recording.AdapterName, "replay", replayer, mergeSensors(cfg.Sensors, replayer.Sensors())
+	case cfg.Backend == config.BackendExec:
+		log.Infof("Bluetooth Device: %s (using BlueZ tools)", cfg.Device)
+		return cfg.Device, "active", &bluezcli.Reader{
+			Adapter:          cfg.Device,
+			SkipRealtimeMode: cfg.SkipRealtimeMode,
+			Lenient:          cfg.LenientParsing,
+			Layouts:          loadLayouts(cfg),
+		}, cfg.Sensors
+	case cfg.Backend == config.BackendDBus:
+		log.Infof("Bluetooth Device: %s (using BlueZ D-Bus API)", cfg.Device)
+		return cfg.Device, "active", newDBusReader(cfg), cfg.Sensors
+	}
+
+	log.Infof("Bluetooth Device: %s", cfg.Device)
+	hciStats := hcistats.New(cfg.Device)
+	device, err := bluetooth.Open(cfg.Device, hciStats.Options()...)
+	if err != nil {
+		log.Fatalf("Error creating device: %s", err)
+	}
+	hciRegistry.MustRegister(hciStats)
+
+	return cfg.Device, "active", bluetoothReader{&updater.DeviceReader{
+		Log:              log.Slog(),
+		Device:           hciStats.Wrap(device),
+		FirmwareInterval: cfg.FirmwareInterval,
+		SkipRealtimeMode: cfg.SkipRealtimeMode,
+		ScanDuration:     cfg.ScanBeforeRead,
+		MinRSSI:          cfg.MinRSSI,
+		Lenient:          cfg.LenientParsing,
+		Layouts:          loadLayouts(cfg),
+	}}, cfg.Sensors
+}
+
+// bluetoothReader releases the Bluetooth device of the reader when it is closed.
+type bluetoothReader struct {
+	*updater.DeviceReader
+}
+
+// Close stops scanning and releases the Bluetooth device.
+func (r bluetoothReader) Close() error {
+	return bluetooth.Close(r.Device)
+}
+
+// loadLayouts returns the layouts of the sensor data by the MAC address of the sensors with a device model.
+func loadLayouts(cfg config.Config) map[string]miflora.Layout {
+	if cfg.LayoutsFile == "" {
+		return nil
+	}
+
+	raw, err := os.ReadFile(cfg.LayoutsFile)
+	if err != nil {
+		log.Fatalf("Error reading layouts file: %s", err)
+	}
+
+	var models map[string]miflora.Layout
+	if err := json.Unmarshal(raw, &models); err != nil {
+		log.Fatalf("Error parsing layouts file %q: %s", cfg.LayoutsFile, err)
+	}
+
+	for model, layout := range models {
+		if err := layout.Validate(); err != nil {
+			log.Fatalf("Invalid layout for model %q: %s", model, err)
+		}
+	}
+
+	result := map[string]miflora.Layout{}
+	for mac, model := range cfg.SensorModels {
+		layout, ok := models[model]
+		if !ok {
+			log.Fatalf("Unknown model %q for sensor %s", model, mac)
+		}
+
+		log.Infof("Using layout of model %q for sensor %s", model, mac)
+		result[mac] = layout
+	}
+	return result
+}
+
+// noReader is used when there are no local sensors.
+type noReader struct{}
+
+func (noReader) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	return miflora.Data{}, errors.New("no local Bluetooth adapter")
+}
+
+// autoRegisteredSensors returns the sensors which have been registered automatically before.
+func autoRegisteredSensors(reg *registry.Registry) []config.Sensor {
+	result := []config.Sensor{}
+	for _, e := range reg.Entries() {
+		if !e.AutoRegistered {
+			continue
+		}
+
+		result = append(result, config.Sensor{
+			Name:       e.Name,
+			MacAddress: e.MacAddress,
+		})
+	}
+	return result
+}
+
+// simulatedSensors returns the configuration for count simulated sensors.
+func simulatedSensors(count int) []config.Sensor {
+	result := make([]config.Sensor, 0, count)
+	for i := 1; i <= count; i++ {
+		name, macAddress := simulator.Sensor(i)
+		result = append(result, config.Sensor{
+			Name:       name,
+			MacAddress: macAddress,
+		})
+	}
+	return result
+}
+
+// mergeSensors adds all additional sensors which are not already configured.
+func mergeSensors(configured, additional []config.Sensor) []config.Sensor {
+	known := map[string]bool{}
+	for _, s := range configured {
+		known[strings.ToUpper(s.MacAddress)] = true
+		known[strings.ToUpper(s.Address())] = true
+	}
+
+	result := append([]config.Sensor{}, configured...)
+	for _, s := range additional {
+		if known[strings.ToUpper(s.MacAddress)] {
+			continue
+		}
+
+		result = append(result, s)
+	}
+	return result
+}
+
+// runBenchmark runs the scheduler with simulated sensors and prints the results.
+func runBenchmark(cfg config.Config) {
+	log.Infof("Benchmarking scheduler with %d sensors for %s.", cfg.Benchmark.Sensors, cfg.Benchmark.Duration)
+	start := time.Now()
+	result := updater.Benchmark(context.Background(), updater.BenchmarkOptions{
+		Options: updater.Options{
+			AdapterName:     simulator.AdapterName,
+			Source:          "benchmark",
+			Reader:          simulator.New(),
+			RefreshTimeout:  cfg.RefreshTimeout,
+			WatchdogTimeout: cfg.WatchdogTimeout,
+			Retry:           cfg.Retry,
+			ReadBudget:      cfg.ReadBudget,
+			Health:          cfg.Health,
+			QuietHours:      cfg.QuietHours,
+			StaleDuration:   cfg.StaleDuration,
+		},
+		Sensors:         simulatedSensors(cfg.Benchmark.Sensors),
+		Duration:        cfg.Benchmark.Duration,
+		RefreshDuration: cfg.RefreshDuration,
+		ReadDuration:    cfg.Benchmark.ReadDuration,
+		FailureRate:     cfg.Benchmark.FailureRate,
+	})
+	log.Infof("Benchmark finished after %s.", time.Since(start).Round(time.Millisecond))
+
+	fmt.Printf("Reads:            %d (%d failed)\n", result.Reads, result.Failures)
+	fmt.Printf("Reads per sensor: %.1f\n", float64(result.Reads)/float64(cfg.Benchmark.Sensors))
+	fmt.Printf("Never read:       %d sensors\n", result.Unread)
+	fmt.Printf("Latency:          p50 %s, p95 %s, p99 %s, max %s\n", result.LatencyP50, result.LatencyP95, result.LatencyP99, result.LatencyMax)
+	fmt.Printf("Queue length:     avg %.1f, max %d\n", result.AvgQueueLength, result.MaxQueueLength)
+	fmt.Printf("Saturation:       %.1f%% of ticks with overdue sensors\n", 100*result.Saturation)
+	fmt.Printf("Stale sensors:    avg %.1f, max %d\n", result.AvgStale, result.MaxStale)
+}
+
+// runDiscover scans for Flower Care devices once and logs the devices found. With auto-registration enabled, the
+// devices are added to the registry, so that they are read after the next start.
+func runDiscover(cfg config.Config) {
+	_, _, reader, sensors := createReader(cfg)
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	scanner, ok := reader.(updater.Scanner)
+	if !ok {
+		log.Fatalf("Backend %q does not support scanning.", cfg.Backend)
+	}
+
+	var reg *registry.Registry
+	if cfg.AutoRegister.Enabled {
+		var err error
+		reg, err = registry.Open(cfg.RegistryFile)
+		if err != nil {
+			log.Fatalf("Error opening registry: %s", err)
+		}
+
+		sensors = mergeSensors(sensors, autoRegisteredSensors(reg))
+	}
+
+	configured := map[string]string{}
+	for _, s := range sensors {
+		configured[strings.ToUpper(s.MacAddress)] = s.Name
+		configured[strings.ToUpper(s.Address())] = s.Name
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	log.Infof("Scanning for Flower Care devices for %s.", cfg.ScanDuration)
+	var lock sync.Mutex
+	found := map[string]int{}
+	err := scanner.Scan(ctx, cfg.ScanDuration, func(a miflora.Advertisement) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		mac := a.MacAddress
+		rssi, seen := found[mac]
+		if !seen || a.RSSI > rssi {
+			found[mac] = a.RSSI
+		}
+		if seen {
+			return
+		}
+
+		if name, ok := configured[strings.ToUpper(mac)]; ok {
+			log.Infof("Found device %s (RSSI %d dBm), configured as %q.", mac, a.RSSI, name)
+			return
+		}
+		log.Infof("Found device %s (RSSI %d dBm, name %q).", mac, a.RSSI, a.Name)
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Fatalf("Error scanning for devices: %s", err)
+	}
+
+	macs := make([]string, 0, len(found))
+	for mac := range found {
+		if _, ok := configured[strings.ToUpper(mac)]; !ok {
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	log.Infof("Found %d devices, %d of them not configured.", len(found), len(macs))
+
+	now := time.Now()
+	for _, mac := range macs {
+		name := cfg.AutoRegister.DefaultName(mac)
+		fmt.Printf("--sensor %s=%s\t# RSSI %d dBm\n", name, mac, found[mac])
+
+		if reg == nil || !cfg.AutoRegister.Matches(mac) {
+			continue
+		}
+
+		if err := reg.Registered(mac, name, now); err != nil {
+			log.Fatalf("Error saving registry: %s", err)
+		}
+		log.Infof("Registered sensor %q (%s).", name, mac)
+	}
+}
+
+// replaceSensors sets the devices of replaced sensors and records the replacements in the registry, if it is enabled.
+// Sensors using the MAC address of a new device are removed, because the device is read as the replaced sensor.
+func replaceSensors(sensors []config.Sensor, replacements config.Replacements, reg *registry.Registry) []config.Sensor {
+	for _, r := range replacements {
+		found := false
+		result := []config.Sensor{}
+		for _, s := range sensors {
+			switch {
+			case strings.EqualFold(s.MacAddress, r.Old):
+				log.Infof("Sensor %q has been replaced by %s", s, r.New)
+				s.Device = r.New
+				found = true
+			case strings.EqualFold(s.MacAddress, r.New):
+				log.Infof("Removing sensor %q, which is used as replacement of %s", s, r.Old)
+				continue
+			}
+
+			result = append(result, s)
+		}
+		sensors = result
+
+		if !found {
+			log.Fatalf("Replaced sensor is not configured: %s", r.Old)
+		}
+
+		if reg != nil {
+			if err := reg.Replaced(r.New, r.Old, time.Now()); err != nil {
+				log.Errorf("Error saving registry: %s", err)
+			}
+		}
+	}
+
+	return sensors
+}
+
+func startSignalHandler(ctx context.Context, wg *sync.WaitGroup, cancel func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, notifySignals()...)
+
+		log.Debug("Signal handler ready.")
+		for sig := range sigCh {
+			if levelSignal != nil && sig == levelSignal {
+				level := logging.NextLevel(logLevel.Level())
+				logLevel.Set(level)
+				log.Warnf("Log level changed to %s.", logging.LevelName(level))
+				continue
+			}
+
+			log.Debug("Got shutdown signal.")
+			signal.Reset()
+			cancel()
+			return
+		}
+	}()
+}
+
+func startScheduleLoop(ctx context.Context, wg *sync.WaitGroup, cfg config.Config, provider *updater.Updater) {
+	wg.Add(1)
+
+	refresher := time.NewTicker(cfg.RefreshDuration)
+	if cfg.WarmUpDuration > 0 {
+		log.Infof("Spreading first reads over %s.", cfg.WarmUpDuration)
+		provider.WarmUp(time.Now(), cfg.WarmUpDuration)
+	} else {
+		provider.UpdateAll(time.Now())
+	}
+
+	go func() {
+		defer wg.Done()
+
+		log.Debug("Schedule loop ready.")
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug("Shutting down refresh loop")
+				return
+			case now := <-refresher.C:
+				log.Debugf("Updating all at %s", now)
+				provider.UpdateAll(now)
+			}
+		}
+	}()
+}
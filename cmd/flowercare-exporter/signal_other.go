@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+)
+
+// levelSignal is nil, as there is no signal for changing the log level on this platform.
+var levelSignal os.Signal
+
+func notifySignals() []os.Signal {
+	return []os.Signal{os.Interrupt}
+}
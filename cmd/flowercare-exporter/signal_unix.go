@@ -0,0 +1,15 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// levelSignal cycles through the log levels.
+var levelSignal os.Signal = syscall.SIGUSR1
+
+func notifySignals() []os.Signal {
+	return []os.Signal{syscall.SIGINT, syscall.SIGTERM, levelSignal}
+}
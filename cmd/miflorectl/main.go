@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/xperimental/flowercare-exporter/internal/cli"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+)
+
+var (
+	logLevel = &slog.LevelVar{}
+	log      = logging.New(slog.New(logging.NewTextHandler(os.Stderr, logLevel)))
+
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+func main() {
+	env := &cli.Env{
+		Log:     log,
+		Out:     os.Stdout,
+		Adapter: "hci0",
+		Timeout: time.Minute,
+	}
+	level := config.LogLevel(slog.LevelWarn)
+
+	pflag.CommandLine.SetInterspersed(false)
+	pflag.Var(&level, "log-level", "Minimum log level to show.")
+	pflag.StringVarP(&env.Adapter, "adapter", "i", env.Adapter, "Bluetooth device to use for communication.")
+	pflag.DurationVar(&env.Timeout, "timeout", env.Timeout, "Timeout for a single operation on a sensor.")
+	timezone := pflag.String("timezone", "", "Time zone used for showing times, for example Europe/Berlin. Uses the local time zone if empty.")
+	showVersion := pflag.Bool("version", false, "Show version information and exit.")
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [args]\n\n", os.Args[0])
+		cli.PrintUsage(os.Stderr, cli.SensorCommands)
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		pflag.PrintDefaults()
+	}
+	pflag.Parse()
+
+	if *showVersion {
+		fmt.Printf("miflorectl %s (commit %s, built %s)\n", version, commit, date)
+		return
+	}
+
+	logLevel.Set(slog.Level(level))
+
+	location, err := config.LoadLocation(*timezone)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid time zone: %s\n", err)
+		os.Exit(2)
+	}
+	env.Location = location
+
+	args := pflag.Args()
+	if len(args) == 0 {
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := cli.Find(cli.SensorCommands, args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", args[0])
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	err = cmd.Run(ctx, env, args[1:])
+	if closeErr := env.Close(); closeErr != nil {
+		log.Debugf("Error closing device: %s", closeErr)
+	}
+	if err != nil {
+		log.Fatalf("Error running %s: %s", cmd.Name, err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package adapterlock coordinates the use of a Bluetooth adapter between processes using an advisory file lock.
+package adapterlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const retryInterval = 100 * time.Millisecond
+
+var errLocked = errors.New("file is locked")
+
+// File is an advisory lock on a file. Other applications can coordinate with the exporter by locking the same file
+// using flock(2), for example using the flock command-line tool.
+type File struct {
+	path string
+	file *os.File
+}
+
+// New creates a lock using the file at path. The file is created if it does not exist.
+func New(path string) *File {
+	return &File{
+		path: path,
+	}
+}
+
+// Lock acquires the lock, waiting until it is released by other processes or the context is done.
+func (f *File) Lock(ctx context.Context) error {
+	file, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("can not open lock file: %s", err)
+	}
+
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		err := tryLock(file)
+		switch {
+		case err == nil:
+			f.file = file
+			return nil
+		case !errors.Is(err, errLocked):
+			file.Close()
+			return fmt.Errorf("can not lock file: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return fmt.Errorf("lock is held by another process: %s", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (f *File) Unlock() error {
+	if f.file == nil {
+		return errors.New("not locked")
+	}
+
+	err := unlock(f.file)
+	f.file.Close()
+	f.file = nil
+	return err
+}
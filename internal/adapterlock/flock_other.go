@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package adapterlock
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func tryLock(file *os.File) error {
+	return fmt.Errorf("file locking is not supported on %s", runtime.GOOS)
+}
+
+func unlock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package alert evaluates the readings of the sensors and sends notifications about plants needing attention.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Condition describes why an alert is firing.
+type Condition string
+
+const (
+	// Below is used when the value is lower than the minimum.
+	Below Condition = "below"
+	// Above is used when the value is higher than the maximum.
+	Above Condition = "above"
+	// Stale is used when no data has been received from the sensor for too long.
+	Stale Condition = "stale"
+)
+
+// Alert is a notification about a plant entering or leaving an alerting state.
+type Alert struct {
+	Plant     Plant
+	Rule      string
+	Condition Condition
+	Value     float64
+	Threshold float64
+	Unit      string
+	Resolved  bool
+	// Since is the time the condition was first met.
+	Since time.Time
+	Time  time.Time
+}
+
+// Notifier delivers alerts to the user.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Summary returns a short human-readable description of the alert.
+func (a Alert) Summary() string {
+	if a.Condition == Stale {
+		if a.Resolved {
+			return "Receiving data again"
+		}
+
+		return fmt.Sprintf("No data received for %s", time.Duration(a.Value*float64(time.Second)).Round(time.Second))
+	}
+
+	if a.Resolved {
+		return fmt.Sprintf("%s back to normal (currently %s)", a.Rule, a.formatValue(a.Value))
+	}
+
+	return fmt.Sprintf("%s %s %s (currently %s)", a.Rule, a.Condition, a.formatValue(a.Threshold), a.formatValue(a.Value))
+}
+
+func (a Alert) formatValue(v float64) string {
+	if a.Unit == "" {
+		return fmt.Sprintf("%g", v)
+	}
+
+	return fmt.Sprintf("%g %s", v, a.Unit)
+}
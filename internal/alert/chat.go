@@ -0,0 +1,84 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+)
+
+// Chat posts alerts to an incoming webhook of a chat service like Slack or Discord.
+type Chat struct {
+	name    string
+	url     string
+	field   string
+	message *template.Template
+	client  *http.Client
+}
+
+// NewSlack creates a notifier posting to a Slack incoming webhook.
+func NewSlack(url, messageTemplate string) (*Chat, error) {
+	return newChat("slack", url, "text", messageTemplate)
+}
+
+// NewDiscord creates a notifier posting to a Discord webhook.
+func NewDiscord(url, messageTemplate string) (*Chat, error) {
+	return newChat("discord", url, "content", messageTemplate)
+}
+
+func newChat(name, url, field, messageTemplate string) (*Chat, error) {
+	message, err := template.New(name).Parse(messageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse %s message template: %s", name, err)
+	}
+
+	return &Chat{
+		name:    name,
+		url:     url,
+		field:   field,
+		message: message,
+		client:  &http.Client{},
+	}, nil
+}
+
+// Name implements Notifier
+func (c *Chat) Name() string {
+	return c.name
+}
+
+// Notify implements Notifier
+func (c *Chat) Notify(ctx context.Context, alert Alert) error {
+	message := &bytes.Buffer{}
+	if err := c.message.Execute(message, alert); err != nil {
+		return fmt.Errorf("can not render message: %s", err)
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		c.field: message.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("can not encode message: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can not post message: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
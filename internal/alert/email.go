@@ -0,0 +1,135 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+const defaultEmailBody = `{{ .Summary }}
+
+Plant:  {{ .Plant.Name }} ({{ .Plant.MacAddress }})
+Time:   {{ .Time.Format "2006-01-02 15:04:05 MST" }}
+{{- if ne .Condition "stale" }}
+Value:  {{ .Value }} {{ .Unit }}
+{{- if not .Resolved }}
+Limit:  {{ .Threshold }} {{ .Unit }}
+{{- end }}
+{{- end }}
+`
+
+// Email sends alerts using SMTP. Alerts are sent to the recipients of the plant or the default recipients.
+type Email struct {
+	addr    string
+	auth    smtp.Auth
+	from    string
+	to      []string
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewEmail creates an email notifier. The templates are validated on creation.
+func NewEmail(cfg config.EmailConfig) (*Email, error) {
+	host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse SMTP address: %s", err)
+	}
+
+	subject, err := template.New("subject").Parse(cfg.SubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse subject template: %s", err)
+	}
+
+	bodyText := defaultEmailBody
+	if cfg.BodyFile != "" {
+		raw, err := os.ReadFile(cfg.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can not read body template: %s", err)
+		}
+		bodyText = string(raw)
+	}
+
+	body, err := template.New("body").Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse body template: %s", err)
+	}
+
+	e := &Email{
+		addr:    cfg.SMTPAddr,
+		from:    cfg.From,
+		to:      cfg.To,
+		subject: subject,
+		body:    body,
+	}
+
+	if cfg.Username != "" {
+		password, err := clientauth.ReadSecret(cfg.PasswordFile, cfg.PasswordEnv, "SMTP password")
+		if err != nil {
+			return nil, err
+		}
+
+		e.auth = smtp.PlainAuth("", cfg.Username, password, host)
+	}
+
+	return e, nil
+}
+
+// Name implements Notifier
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Notify implements Notifier
+func (e *Email) Notify(ctx context.Context, alert Alert) error {
+	to := alert.Plant.Email
+	if len(to) == 0 {
+		to = e.to
+	}
+
+	if len(to) == 0 {
+		return errors.New("no recipients for alert")
+	}
+
+	subject := &bytes.Buffer{}
+	if err := e.subject.Execute(subject, alert); err != nil {
+		return fmt.Errorf("can not render subject: %s", err)
+	}
+
+	body := &bytes.Buffer{}
+	if err := e.body.Execute(body, alert); err != nil {
+		return fmt.Errorf("can not render body: %s", err)
+	}
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(msg, "\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(e.addr, e.auth, e.from, to, msg.Bytes())
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,294 @@
+package alert
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	queueSize     = 100
+	checkInterval = time.Minute
+	notifyTimeout = 30 * time.Second
+)
+
+type reading struct {
+	Sensor config.Sensor
+	Data   miflora.Data
+}
+
+type check struct {
+	Rule  string
+	Unit  string
+	Value func(miflora.Data) float64
+	Range func(Thresholds) Range
+	// Known is optional. If set, the check is skipped for readings which do not contain the value.
+	Known func(miflora.Data) bool
+}
+
+var checks = []check{
+	{
+		Rule:  "moisture",
+		Unit:  "%",
+		Value: func(d miflora.Data) float64 { return float64(d.Sensors.Moisture) },
+		Range: func(t Thresholds) Range { return t.Moisture },
+	},
+	{
+		Rule:  "temperature",
+		Unit:  "°C",
+		Value: func(d miflora.Data) float64 { return d.Sensors.Temperature },
+		Range: func(t Thresholds) Range { return t.Temperature },
+	},
+	{
+		Rule:  "light",
+		Unit:  "lx",
+		Value: func(d miflora.Data) float64 { return float64(d.Sensors.Light) },
+		Range: func(t Thresholds) Range { return t.Light },
+	},
+	{
+		Rule:  "conductivity",
+		Unit:  "µS/cm",
+		Value: func(d miflora.Data) float64 { return float64(d.Sensors.Conductivity) },
+		Range: func(t Thresholds) Range { return t.Conductivity },
+	},
+	{
+		Rule:  "battery",
+		Unit:  "%",
+		Value: func(d miflora.Data) float64 { return float64(d.Firmware.Battery) },
+		Range: func(t Thresholds) Range { return t.Battery },
+		Known: func(d miflora.Data) bool { return d.Firmware.Known() },
+	},
+}
+
+type sensorState struct {
+	Name     string
+	LastSeen time.Time
+	Rules    map[string]*ruleState
+}
+
+// ruleState tracks a rule whose condition is met. The rule is pending until it has been met for the configured duration.
+type ruleState struct {
+	Alert        Alert
+	Firing       bool
+	LastNotified time.Time
+}
+
+// Engine evaluates the readings of the plants and notifies about changes of their alerting state.
+type Engine struct {
+	log        *logging.Logger
+	plants     map[string]Plant
+	staleAfter time.Duration
+	location   *time.Location
+	notifiers  []Notifier
+	silences   *Silences
+	readings   chan reading
+
+	state map[string]*sensorState
+}
+
+// NewEngine creates an engine for the plants. staleAfter is used for plants which do not set their own stale duration.
+// Schedules are evaluated in the time zone of the location. No notifications are sent for alerts matching one of the
+// silences, which can be nil.
+func NewEngine(log *logging.Logger, plants []Plant, staleAfter time.Duration, location *time.Location, silences *Silences, notifiers ...Notifier) *Engine {
+	e := &Engine{
+		log:        log,
+		plants:     map[string]Plant{},
+		staleAfter: staleAfter,
+		location:   location,
+		notifiers:  notifiers,
+		silences:   silences,
+		readings:   make(chan reading, queueSize),
+		state:      map[string]*sensorState{},
+	}
+	for _, p := range plants {
+		e.plants[strings.ToUpper(p.MacAddress)] = p
+	}
+
+	return e
+}
+
+// Observe queues a reading for evaluation. Readings are dropped if the queue is full.
+func (e *Engine) Observe(sensor config.Sensor, data miflora.Data) {
+	select {
+	case e.readings <- reading{Sensor: sensor, Data: data}:
+	default:
+		e.log.Warnf("Alert queue full, dropping reading of %q", sensor)
+	}
+}
+
+// Start starts evaluating readings and periodically checks for plants which have not been updated.
+func (e *Engine) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		e.reset(time.Now())
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				e.log.Debug("Shutting down alert engine.")
+				return
+			case r := <-e.readings:
+				e.evaluate(ctx, r)
+			case now := <-ticker.C:
+				e.checkStale(ctx, now)
+			}
+		}
+	}()
+}
+
+// reset clears the state of all plants. Plants are considered seen at the time, so that they do not become stale
+// immediately.
+func (e *Engine) reset(now time.Time) {
+	for mac, p := range e.plants {
+		e.state[mac] = &sensorState{
+			Name:     p.Name,
+			LastSeen: now,
+			Rules:    map[string]*ruleState{},
+		}
+	}
+}
+
+func (e *Engine) evaluate(ctx context.Context, r reading) {
+	mac := strings.ToUpper(r.Sensor.MacAddress)
+	plant, ok := e.plants[mac]
+	if !ok {
+		return
+	}
+
+	state := e.state[mac]
+	if plant.Name == "" {
+		state.Name = r.Sensor.Name
+	}
+	state.LastSeen = r.Data.Time
+	e.update(ctx, plant, state, Alert{
+		Rule:      "stale",
+		Condition: Stale,
+		Time:      r.Data.Time,
+	}, plant.Alerting, false)
+
+	thresholds := plant.ThresholdsAt(r.Data.Time.In(e.location))
+	for _, c := range checks {
+		if c.Known != nil && !c.Known(r.Data) {
+			continue
+		}
+
+		rng := c.Range(thresholds)
+		alert := Alert{
+			Rule:  c.Rule,
+			Value: c.Value(r.Data),
+			Unit:  c.Unit,
+			Time:  r.Data.Time,
+		}
+
+		var active *Alert
+		if rs, ok := state.Rules[c.Rule]; ok {
+			active = &rs.Alert
+		}
+
+		matched := rng.match(&alert, active)
+		e.update(ctx, plant, state, alert, rng.RuleOptions.merge(plant.Alerting), matched)
+	}
+}
+
+func (e *Engine) checkStale(ctx context.Context, now time.Time) {
+	for mac, plant := range e.plants {
+		staleAfter := time.Duration(plant.StaleAfter)
+		if staleAfter == 0 {
+			staleAfter = e.staleAfter
+		}
+
+		state := e.state[mac]
+		age := now.Sub(state.LastSeen)
+		if age < staleAfter {
+			continue
+		}
+
+		e.update(ctx, plant, state, Alert{
+			Rule:      "stale",
+			Condition: Stale,
+			Value:     age.Seconds(),
+			Threshold: staleAfter.Seconds(),
+			Unit:      "s",
+			Time:      now,
+		}, plant.Alerting, true)
+	}
+}
+
+// update advances the state of the alert's rule and sends notifications when it fires, repeats or resolves.
+func (e *Engine) update(ctx context.Context, plant Plant, state *sensorState, alert Alert, opts RuleOptions, matched bool) {
+	switch {
+	case state.Name != "":
+		plant.Name = state.Name
+	case plant.Name == "":
+		plant.Name = plant.MacAddress
+	}
+	alert.Plant = plant
+
+	rs, ok := state.Rules[alert.Rule]
+	if !matched {
+		if !ok {
+			return
+		}
+		delete(state.Rules, alert.Rule)
+
+		if rs.Firing && opts.sendResolved() {
+			resolved := rs.Alert
+			resolved.Plant = plant
+			resolved.Value = alert.Value
+			resolved.Time = alert.Time
+			resolved.Resolved = true
+			e.notify(ctx, resolved)
+		}
+		return
+	}
+
+	if !ok {
+		rs = &ruleState{}
+		state.Rules[alert.Rule] = rs
+		alert.Since = alert.Time
+	} else {
+		alert.Since = rs.Alert.Since
+	}
+	rs.Alert = alert
+
+	notify := false
+	switch {
+	case !rs.Firing && alert.Time.Sub(alert.Since) >= time.Duration(opts.For):
+		rs.Firing = true
+		notify = true
+	case rs.Firing && opts.Repeat > 0 && alert.Time.Sub(rs.LastNotified) >= time.Duration(opts.Repeat):
+		notify = true
+	}
+
+	if notify {
+		rs.LastNotified = alert.Time
+		e.notify(ctx, alert)
+	}
+}
+
+func (e *Engine) notify(ctx context.Context, alert Alert) {
+	if e.silences.Silenced(alert.Plant.MacAddress, alert.Rule, alert.Time) {
+		e.log.Infof("Silenced alert for %q: %s", alert.Plant.Name, alert.Summary())
+		return
+	}
+
+	e.log.Infof("Alert for %q: %s", alert.Plant.Name, alert.Summary())
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	for _, n := range e.notifiers {
+		if err := n.Notify(ctx, alert); err != nil {
+			e.log.Errorf("Error sending alert using %s: %s", n.Name(), err)
+		}
+	}
+}
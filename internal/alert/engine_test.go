@@ -0,0 +1,269 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const testMacAddress = "C4:7C:8D:00:00:01"
+
+var testStart = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeNotifier records the alerts it receives.
+type fakeNotifier struct {
+	alerts []Alert
+}
+
+func (n *fakeNotifier) Name() string {
+	return "fake"
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+// fakeClock provides the time of the readings and stale checks passed to the engine.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) set(offset time.Duration) time.Time {
+	c.now = testStart.Add(offset)
+	return c.now
+}
+
+// step is either a reading of the moisture at the time or a check for stale plants, if check is set.
+type step struct {
+	at       time.Duration
+	moisture byte
+	check    bool
+	want     []string
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+// describe returns the rule, condition and time of the alert relative to the start of the test.
+func describe(a Alert) string {
+	result := fmt.Sprintf("%s %s at %s", a.Rule, a.Condition, a.Time.Sub(testStart))
+	if a.Resolved {
+		result += " resolved"
+	}
+	return result
+}
+
+func runSteps(t *testing.T, e *Engine, n *fakeNotifier, steps []step) {
+	t.Helper()
+
+	clock := &fakeClock{}
+	e.reset(clock.set(0))
+	sensor := config.Sensor{Name: "basil", MacAddress: testMacAddress}
+	for i, s := range steps {
+		n.alerts = nil
+		now := clock.set(s.at)
+		if s.check {
+			e.checkStale(context.Background(), now)
+		} else {
+			e.evaluate(context.Background(), reading{
+				Sensor: sensor,
+				Data: miflora.Data{
+					Time: now,
+					Sensors: miflora.Sensors{
+						Temperature:  20,
+						Moisture:     s.moisture,
+						Light:        500,
+						Conductivity: 300,
+					},
+				},
+			})
+		}
+
+		var got []string
+		for _, a := range n.alerts {
+			got = append(got, describe(a))
+		}
+		if !reflect.DeepEqual(got, s.want) {
+			t.Errorf("got alerts %q in step %d, want %q", got, i, s.want)
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	tests := []struct {
+		desc     string
+		moisture Range
+		alerting RuleOptions
+		steps    []step
+	}{
+		{
+			desc:     "fires and resolves",
+			moisture: Range{Min: float(20), Max: float(60)},
+			steps: []step{
+				{at: 0, moisture: 30},
+				{at: time.Minute, moisture: 15, want: []string{"moisture below at 1m0s"}},
+				{at: 2 * time.Minute, moisture: 10},
+				{at: 3 * time.Minute, moisture: 30, want: []string{"moisture below at 3m0s resolved"}},
+				{at: 4 * time.Minute, moisture: 70, want: []string{"moisture above at 4m0s"}},
+			},
+		},
+		{
+			desc:     "for duration",
+			moisture: Range{Min: float(20)},
+			alerting: RuleOptions{For: Duration(10 * time.Minute)},
+			steps: []step{
+				{at: 0, moisture: 15},
+				{at: 5 * time.Minute, moisture: 15},
+				{at: 10 * time.Minute, moisture: 15, want: []string{"moisture below at 10m0s"}},
+				{at: 15 * time.Minute, moisture: 15},
+			},
+		},
+		{
+			desc:     "condition ends before for duration",
+			moisture: Range{Min: float(20)},
+			alerting: RuleOptions{For: Duration(10 * time.Minute)},
+			steps: []step{
+				{at: 0, moisture: 15},
+				{at: 5 * time.Minute, moisture: 25},
+				{at: 10 * time.Minute, moisture: 15},
+				{at: 15 * time.Minute, moisture: 15},
+				{at: 20 * time.Minute, moisture: 15, want: []string{"moisture below at 20m0s"}},
+			},
+		},
+		{
+			desc:     "rule options override plant",
+			moisture: Range{Min: float(20), RuleOptions: RuleOptions{For: Duration(5 * time.Minute)}},
+			alerting: RuleOptions{For: Duration(time.Hour)},
+			steps: []step{
+				{at: 0, moisture: 15},
+				{at: 5 * time.Minute, moisture: 15, want: []string{"moisture below at 5m0s"}},
+			},
+		},
+		{
+			desc:     "hysteresis",
+			moisture: Range{Min: float(20), Max: float(60), Hysteresis: 5},
+			steps: []step{
+				{at: 0, moisture: 22},
+				{at: time.Minute, moisture: 15, want: []string{"moisture below at 1m0s"}},
+				{at: 2 * time.Minute, moisture: 22},
+				{at: 3 * time.Minute, moisture: 24},
+				{at: 4 * time.Minute, moisture: 25, want: []string{"moisture below at 4m0s resolved"}},
+				{at: 5 * time.Minute, moisture: 58},
+			},
+		},
+		{
+			desc:     "hysteresis of maximum",
+			moisture: Range{Max: float(60), Hysteresis: 5},
+			steps: []step{
+				{at: 0, moisture: 61, want: []string{"moisture above at 0s"}},
+				{at: time.Minute, moisture: 56},
+				{at: 2 * time.Minute, moisture: 55, want: []string{"moisture above at 2m0s resolved"}},
+			},
+		},
+		{
+			desc:     "repeat interval",
+			moisture: Range{Min: float(20)},
+			alerting: RuleOptions{Repeat: Duration(30 * time.Minute)},
+			steps: []step{
+				{at: 0, moisture: 15, want: []string{"moisture below at 0s"}},
+				{at: 10 * time.Minute, moisture: 15},
+				{at: 30 * time.Minute, moisture: 15, want: []string{"moisture below at 30m0s"}},
+				{at: 45 * time.Minute, moisture: 15},
+				{at: 65 * time.Minute, moisture: 15, want: []string{"moisture below at 1h5m0s"}},
+				{at: 70 * time.Minute, moisture: 30, want: []string{"moisture below at 1h10m0s resolved"}},
+			},
+		},
+		{
+			desc:     "resolve without notification",
+			moisture: Range{Min: float(20)},
+			alerting: RuleOptions{SendResolved: boolPtr(false)},
+			steps: []step{
+				{at: 0, moisture: 15, want: []string{"moisture below at 0s"}},
+				{at: time.Minute, moisture: 30},
+				{at: 2 * time.Minute, moisture: 15, want: []string{"moisture below at 2m0s"}},
+			},
+		},
+		{
+			desc:     "stale sensor",
+			moisture: Range{Min: float(20)},
+			steps: []step{
+				{at: 30 * time.Minute, check: true},
+				{at: time.Hour, check: true, want: []string{"stale stale at 1h0m0s"}},
+				{at: 90 * time.Minute, check: true},
+				{at: 100 * time.Minute, moisture: 30, want: []string{"stale stale at 1h40m0s resolved"}},
+				{at: 130 * time.Minute, check: true},
+			},
+		},
+		{
+			desc:     "stale sensor repeats",
+			moisture: Range{Min: float(20)},
+			alerting: RuleOptions{Repeat: Duration(time.Hour)},
+			steps: []step{
+				{at: time.Hour, check: true, want: []string{"stale stale at 1h0m0s"}},
+				{at: 90 * time.Minute, check: true},
+				{at: 2 * time.Hour, check: true, want: []string{"stale stale at 2h0m0s"}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			n := &fakeNotifier{}
+			plants := []Plant{
+				{
+					MacAddress: testMacAddress,
+					Thresholds: Thresholds{Moisture: tc.moisture},
+					Alerting:   tc.alerting,
+				},
+			}
+			e := NewEngine(logging.Discard(), plants, time.Hour, time.UTC, nil, n)
+
+			runSteps(t, e, n, tc.steps)
+		})
+	}
+}
+
+func TestEngineAlert(t *testing.T) {
+	n := &fakeNotifier{}
+	plants := []Plant{
+		{
+			MacAddress: "c4:7c:8d:00:00:01",
+			Thresholds: Thresholds{Moisture: Range{Min: float(20)}},
+			Alerting:   RuleOptions{For: Duration(10 * time.Minute)},
+		},
+	}
+	e := NewEngine(logging.Discard(), plants, time.Hour, time.UTC, nil, n)
+
+	runSteps(t, e, n, []step{
+		{at: 0, moisture: 15},
+		{at: 10 * time.Minute, moisture: 12, want: []string{"moisture below at 10m0s"}},
+	})
+
+	want := Alert{
+		Plant:     plants[0],
+		Rule:      "moisture",
+		Condition: Below,
+		Value:     12,
+		Threshold: 20,
+		Unit:      "%",
+		Since:     testStart,
+		Time:      testStart.Add(10 * time.Minute),
+	}
+	want.Plant.Name = "basil"
+
+	if got := n.alerts[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got alert %+v, want %+v", got, want)
+	}
+}
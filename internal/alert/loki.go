@@ -0,0 +1,67 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/xperimental/flowercare-exporter/internal/loki"
+)
+
+type lokiLine struct {
+	Plant      string    `json:"plant"`
+	MacAddress string    `json:"macAddress"`
+	Rule       string    `json:"rule"`
+	Condition  Condition `json:"condition"`
+	Value      float64   `json:"value"`
+	Threshold  float64   `json:"threshold,omitempty"`
+	Unit       string    `json:"unit,omitempty"`
+	Resolved   bool      `json:"resolved"`
+	Summary    string    `json:"summary"`
+}
+
+// Loki sends a log line per notification to Loki.
+type Loki struct {
+	client *loki.Client
+}
+
+// NewLoki creates a notifier using the Loki client.
+func NewLoki(client *loki.Client) *Loki {
+	return &Loki{
+		client: client,
+	}
+}
+
+// Name implements Notifier
+func (l *Loki) Name() string {
+	return "loki"
+}
+
+// Notify implements Notifier
+func (l *Loki) Notify(ctx context.Context, alert Alert) error {
+	raw, err := json.Marshal(lokiLine{
+		Plant:      alert.Plant.Name,
+		MacAddress: alert.Plant.MacAddress,
+		Rule:       alert.Rule,
+		Condition:  alert.Condition,
+		Value:      alert.Value,
+		Threshold:  alert.Threshold,
+		Unit:       alert.Unit,
+		Resolved:   alert.Resolved,
+		Summary:    alert.Summary(),
+	})
+	if err != nil {
+		return fmt.Errorf("can not encode alert: %s", err)
+	}
+
+	return l.client.Push(ctx, []loki.Entry{
+		{
+			Time: alert.Time,
+			Labels: map[string]string{
+				"type":  "alert",
+				"plant": alert.Plant.Name,
+			},
+			Line: string(raw),
+		},
+	})
+}
@@ -0,0 +1,242 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/species"
+)
+
+// Plant contains the alerting settings for the plant monitored by one sensor.
+type Plant struct {
+	MacAddress string `json:"macAddress"`
+	Name       string `json:"name,omitempty"`
+	// Species is optional. If set, the recommended ranges of the species are used for thresholds which are not set.
+	Species string `json:"species,omitempty"`
+	// Location and PotSize are only used as metadata in the flowercare_plant_info metric.
+	Location   string     `json:"location,omitempty"`
+	PotSize    string     `json:"potSize,omitempty"`
+	Thresholds Thresholds `json:"thresholds"`
+	// Schedules change the thresholds depending on the date or time of day.
+	Schedules  []Schedule `json:"schedules,omitempty"`
+	StaleAfter Duration   `json:"staleAfter,omitempty"`
+	Email      []string   `json:"email,omitempty"`
+	// Alerting contains the defaults for all rules of the plant.
+	Alerting RuleOptions `json:"alerting"`
+}
+
+// Thresholds contains the accepted ranges of the values read from a sensor.
+type Thresholds struct {
+	Moisture     Range `json:"moisture"`
+	Temperature  Range `json:"temperature"`
+	Light        Range `json:"light"`
+	Conductivity Range `json:"conductivity"`
+	Battery      Range `json:"battery"`
+}
+
+// Range limits a value. Limits which are not set are not checked.
+type Range struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Hysteresis is the distance the value needs to move back into the range before an alert is resolved.
+	Hysteresis float64 `json:"hysteresis,omitempty"`
+	RuleOptions
+}
+
+// RuleOptions controls when notifications are sent for a rule. Options which are not set are taken from the plant.
+type RuleOptions struct {
+	// For is the duration the condition needs to be met before the alert fires.
+	For Duration `json:"for,omitempty"`
+	// Repeat is the interval for repeating the notification while the alert is firing. Not repeated if zero.
+	Repeat Duration `json:"repeat,omitempty"`
+	// SendResolved controls if a notification is sent when the alert is resolved. Defaults to true.
+	SendResolved *bool `json:"sendResolved,omitempty"`
+}
+
+func (o RuleOptions) merge(defaults RuleOptions) RuleOptions {
+	if o.For == 0 {
+		o.For = defaults.For
+	}
+
+	if o.Repeat == 0 {
+		o.Repeat = defaults.Repeat
+	}
+
+	if o.SendResolved == nil {
+		o.SendResolved = defaults.SendResolved
+	}
+
+	return o
+}
+
+func (o RuleOptions) sendResolved() bool {
+	return o.SendResolved == nil || *o.SendResolved
+}
+
+func (o RuleOptions) validate() error {
+	if o.For < 0 {
+		return errors.New("for duration can not be negative")
+	}
+
+	if o.Repeat < 0 {
+		return errors.New("repeat interval can not be negative")
+	}
+
+	return nil
+}
+
+// match checks if the value is outside the range and fills the condition and threshold of the alert.
+// A rule which is already active keeps matching until the value is back inside the range by more than the hysteresis.
+func (r Range) match(alert *Alert, active *Alert) bool {
+	switch {
+	case r.Min != nil && alert.Value < *r.Min:
+		alert.Condition = Below
+		alert.Threshold = *r.Min
+	case r.Max != nil && alert.Value > *r.Max:
+		alert.Condition = Above
+		alert.Threshold = *r.Max
+	case active == nil:
+		return false
+	case active.Condition == Below && r.Min != nil && alert.Value < *r.Min+r.Hysteresis:
+		alert.Condition = Below
+		alert.Threshold = *r.Min
+	case active.Condition == Above && r.Max != nil && alert.Value > *r.Max-r.Hysteresis:
+		alert.Condition = Above
+		alert.Threshold = *r.Max
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (t *Thresholds) applySpecies(s species.Species) {
+	t.Moisture.applyDefault(s.Moisture)
+	t.Temperature.applyDefault(s.Temperature)
+	t.Light.applyDefault(s.Light)
+	t.Conductivity.applyDefault(s.Conductivity)
+}
+
+func (r *Range) applyDefault(d species.Range) {
+	if r.Min == nil {
+		min := d.Min
+		r.Min = &min
+	}
+
+	if r.Max == nil {
+		max := d.Max
+		r.Max = &max
+	}
+}
+
+func (t Thresholds) validate() error {
+	for name, r := range map[string]Range{
+		"moisture":     t.Moisture,
+		"temperature":  t.Temperature,
+		"light":        t.Light,
+		"conductivity": t.Conductivity,
+		"battery":      t.Battery,
+	} {
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return fmt.Errorf("%s: minimum is larger than maximum", name)
+		}
+
+		if r.Hysteresis < 0 {
+			return fmt.Errorf("%s: hysteresis can not be negative", name)
+		}
+
+		if err := r.RuleOptions.validate(); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Duration is a time.Duration which is represented as a string like "1h30m" in JSON.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// LoadPlants reads the plant settings from a JSON file. The thresholds of plants with a species are completed using the database.
+func LoadPlants(fileName string, db species.Database) ([]Plant, error) {
+	raw, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var plants []Plant
+	if err := json.Unmarshal(raw, &plants); err != nil {
+		return nil, fmt.Errorf("can not parse plants file %q: %s", fileName, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range plants {
+		p := &plants[i]
+		if p.MacAddress == "" {
+			return nil, fmt.Errorf("plant %d has no MAC address", i)
+		}
+
+		mac := strings.ToUpper(p.MacAddress)
+		if seen[mac] {
+			return nil, fmt.Errorf("duplicate plant for %s", p.MacAddress)
+		}
+		seen[mac] = true
+
+		if p.StaleAfter < 0 {
+			return nil, fmt.Errorf("plant %s: stale duration can not be negative", p.MacAddress)
+		}
+
+		if err := p.Alerting.validate(); err != nil {
+			return nil, fmt.Errorf("plant %s: %s", p.MacAddress, err)
+		}
+
+		if p.Species != "" {
+			s, ok := db.Lookup(p.Species)
+			if !ok {
+				return nil, fmt.Errorf("plant %s: unknown species %q", p.MacAddress, p.Species)
+			}
+			p.Thresholds.applySpecies(s)
+		}
+
+		if err := p.Thresholds.validate(); err != nil {
+			return nil, fmt.Errorf("plant %s: %s", p.MacAddress, err)
+		}
+
+		for j := range p.Schedules {
+			s := &p.Schedules[j]
+			if err := s.parse(); err != nil {
+				return nil, fmt.Errorf("plant %s: %s", p.MacAddress, err)
+			}
+
+			if err := p.Thresholds.override(s.Thresholds).validate(); err != nil {
+				return nil, fmt.Errorf("plant %s: schedule %q: %s", p.MacAddress, s.Name, err)
+			}
+		}
+	}
+
+	return plants, nil
+}
@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule replaces the thresholds of a plant during a range of dates and/or a time of day. Both ranges can wrap
+// around, for example from "11-01" to "02-28" or from "20:00" to "06:00". A range which is not set always matches.
+type Schedule struct {
+	Name string `json:"name,omitempty"`
+	// From and To are the first and last day of the date range as "MM-DD".
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Start and End limit the schedule to a time of day as "HH:MM". End is not included.
+	Start      string     `json:"start,omitempty"`
+	End        string     `json:"end,omitempty"`
+	Thresholds Thresholds `json:"thresholds"`
+
+	fromDay, toDay         int
+	startMinute, endMinute int
+}
+
+func (s *Schedule) parse() error {
+	if (s.From == "") != (s.To == "") {
+		return fmt.Errorf("schedule %q: from and to need to be set together", s.Name)
+	}
+
+	if (s.Start == "") != (s.End == "") {
+		return fmt.Errorf("schedule %q: start and end need to be set together", s.Name)
+	}
+
+	if s.From != "" {
+		from, err := time.Parse("01-02", s.From)
+		if err != nil {
+			return fmt.Errorf("schedule %q: invalid from date: %s", s.Name, err)
+		}
+
+		to, err := time.Parse("01-02", s.To)
+		if err != nil {
+			return fmt.Errorf("schedule %q: invalid to date: %s", s.Name, err)
+		}
+
+		s.fromDay = dayOfYear(from)
+		s.toDay = dayOfYear(to)
+	}
+
+	if s.Start != "" {
+		start, err := time.Parse("15:04", s.Start)
+		if err != nil {
+			return fmt.Errorf("schedule %q: invalid start time: %s", s.Name, err)
+		}
+
+		end, err := time.Parse("15:04", s.End)
+		if err != nil {
+			return fmt.Errorf("schedule %q: invalid end time: %s", s.Name, err)
+		}
+
+		s.startMinute = minuteOfDay(start)
+		s.endMinute = minuteOfDay(end)
+	}
+
+	return nil
+}
+
+// active checks if the schedule applies at the time, which needs to be in the time zone of the schedule.
+func (s Schedule) active(t time.Time) bool {
+	if s.From != "" {
+		day := dayOfYear(t)
+		if s.fromDay <= s.toDay {
+			if day < s.fromDay || day > s.toDay {
+				return false
+			}
+		} else if day < s.fromDay && day > s.toDay {
+			return false
+		}
+	}
+
+	if s.Start != "" {
+		minute := minuteOfDay(t)
+		if s.startMinute <= s.endMinute {
+			if minute < s.startMinute || minute >= s.endMinute {
+				return false
+			}
+		} else if minute < s.startMinute && minute >= s.endMinute {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dayOfYear returns the month and day as a number, which is comparable regardless of leap years.
+func dayOfYear(t time.Time) int {
+	return int(t.Month())*100 + t.Day()
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// ThresholdsAt returns the thresholds of the plant at the time. The limits set in active schedules replace the ones of
+// the plant, with later schedules taking precedence.
+func (p Plant) ThresholdsAt(t time.Time) Thresholds {
+	result := p.Thresholds
+	for _, s := range p.Schedules {
+		if s.active(t) {
+			result = result.override(s.Thresholds)
+		}
+	}
+
+	return result
+}
+
+func (t Thresholds) override(o Thresholds) Thresholds {
+	return Thresholds{
+		Moisture:     t.Moisture.override(o.Moisture),
+		Temperature:  t.Temperature.override(o.Temperature),
+		Light:        t.Light.override(o.Light),
+		Conductivity: t.Conductivity.override(o.Conductivity),
+		Battery:      t.Battery.override(o.Battery),
+	}
+}
+
+func (r Range) override(o Range) Range {
+	if o.Min != nil {
+		r.Min = o.Min
+	}
+
+	if o.Max != nil {
+		r.Max = o.Max
+	}
+
+	if o.Hysteresis != 0 {
+		r.Hysteresis = o.Hysteresis
+	}
+
+	r.RuleOptions = o.RuleOptions.merge(r.RuleOptions)
+	return r
+}
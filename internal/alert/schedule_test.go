@@ -0,0 +1,257 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+)
+
+func TestScheduleActive(t *testing.T) {
+	tests := []struct {
+		desc     string
+		schedule Schedule
+		time     time.Time
+		want     bool
+	}{
+		{
+			desc:     "no ranges",
+			schedule: Schedule{},
+			time:     time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "inside date range",
+			schedule: Schedule{From: "05-01", To: "09-30"},
+			time:     time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "last day of date range",
+			schedule: Schedule{From: "05-01", To: "09-30"},
+			time:     time.Date(2026, 9, 30, 23, 59, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "outside date range",
+			schedule: Schedule{From: "05-01", To: "09-30"},
+			time:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			desc:     "date range wrapping around end of year",
+			schedule: Schedule{From: "11-01", To: "02-28"},
+			time:     time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "outside date range wrapping around end of year",
+			schedule: Schedule{From: "11-01", To: "02-28"},
+			time:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			desc:     "leap day",
+			schedule: Schedule{From: "02-29", To: "02-29"},
+			time:     time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "inside time of day",
+			schedule: Schedule{Start: "08:00", End: "20:00"},
+			time:     time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "end of time of day is not included",
+			schedule: Schedule{Start: "08:00", End: "20:00"},
+			time:     time.Date(2026, 6, 1, 20, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			desc:     "time of day wrapping around midnight",
+			schedule: Schedule{Start: "20:00", End: "06:00"},
+			time:     time.Date(2026, 6, 1, 2, 0, 0, 0, time.UTC),
+			want:     true,
+		},
+		{
+			desc:     "outside time of day wrapping around midnight",
+			schedule: Schedule{Start: "20:00", End: "06:00"},
+			time:     time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			desc:     "date and time of day",
+			schedule: Schedule{From: "11-01", To: "02-28", Start: "20:00", End: "06:00"},
+			time:     time.Date(2026, 6, 1, 22, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := tc.schedule
+			if err := s.parse(); err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got := s.active(tc.time); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScheduleParseError(t *testing.T) {
+	tests := []struct {
+		desc     string
+		schedule Schedule
+	}{
+		{
+			desc:     "from without to",
+			schedule: Schedule{From: "05-01"},
+		},
+		{
+			desc:     "start without end",
+			schedule: Schedule{Start: "08:00"},
+		},
+		{
+			desc:     "invalid date",
+			schedule: Schedule{From: "13-01", To: "09-30"},
+		},
+		{
+			desc:     "invalid time",
+			schedule: Schedule{Start: "08:00", End: "25:00"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := tc.schedule
+			if err := s.parse(); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestThresholdsAt(t *testing.T) {
+	plant := Plant{
+		Thresholds: Thresholds{
+			Moisture:    Range{Min: float(20), Max: float(60)},
+			Temperature: Range{Min: float(10)},
+		},
+		Schedules: []Schedule{
+			{
+				Name: "summer",
+				From: "05-01",
+				To:   "09-30",
+				Thresholds: Thresholds{
+					Moisture: Range{Min: float(30)},
+				},
+			},
+			{
+				Name:  "night",
+				Start: "20:00",
+				End:   "06:00",
+				Thresholds: Thresholds{
+					Moisture:    Range{Min: float(25), RuleOptions: RuleOptions{For: Duration(time.Hour)}},
+					Temperature: Range{Min: float(5)},
+				},
+			},
+		},
+	}
+	for i := range plant.Schedules {
+		if err := plant.Schedules[i].parse(); err != nil {
+			t.Fatalf("got error %q", err)
+		}
+	}
+
+	tests := []struct {
+		desc            string
+		time            time.Time
+		wantMoistureMin float64
+		wantMoistureMax float64
+		wantTempMin     float64
+		wantFor         time.Duration
+	}{
+		{
+			desc:            "no schedule",
+			time:            time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+			wantMoistureMin: 20,
+			wantMoistureMax: 60,
+			wantTempMin:     10,
+		},
+		{
+			desc:            "date schedule",
+			time:            time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC),
+			wantMoistureMin: 30,
+			wantMoistureMax: 60,
+			wantTempMin:     10,
+		},
+		{
+			desc:            "time of day schedule",
+			time:            time.Date(2026, 1, 15, 22, 0, 0, 0, time.UTC),
+			wantMoistureMin: 25,
+			wantMoistureMax: 60,
+			wantTempMin:     5,
+			wantFor:         time.Hour,
+		},
+		{
+			desc:            "later schedule takes precedence",
+			time:            time.Date(2026, 6, 15, 22, 0, 0, 0, time.UTC),
+			wantMoistureMin: 25,
+			wantMoistureMax: 60,
+			wantTempMin:     5,
+			wantFor:         time.Hour,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := plant.ThresholdsAt(tc.time)
+			if *got.Moisture.Min != tc.wantMoistureMin || *got.Moisture.Max != tc.wantMoistureMax {
+				t.Errorf("got moisture %g-%g, want %g-%g", *got.Moisture.Min, *got.Moisture.Max, tc.wantMoistureMin, tc.wantMoistureMax)
+			}
+
+			if *got.Temperature.Min != tc.wantTempMin {
+				t.Errorf("got minimum temperature %g, want %g", *got.Temperature.Min, tc.wantTempMin)
+			}
+
+			if time.Duration(got.Moisture.For) != tc.wantFor {
+				t.Errorf("got for duration %s, want %s", time.Duration(got.Moisture.For), tc.wantFor)
+			}
+		})
+	}
+}
+
+func TestEngineSchedule(t *testing.T) {
+	location := time.FixedZone("UTC+2", 2*60*60)
+	n := &fakeNotifier{}
+	plants := []Plant{
+		{
+			MacAddress: testMacAddress,
+			Thresholds: Thresholds{Moisture: Range{Min: float(20)}},
+			Schedules: []Schedule{
+				{
+					Name:       "afternoon",
+					Start:      "14:00",
+					End:        "18:00",
+					Thresholds: Thresholds{Moisture: Range{Min: float(10)}},
+				},
+			},
+		},
+	}
+	if err := plants[0].Schedules[0].parse(); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	e := NewEngine(logging.Discard(), plants, 24*time.Hour, location, nil, n)
+
+	// The test starts at 12:00 UTC, which is 14:00 in the time zone of the engine.
+	runSteps(t, e, n, []step{
+		{at: 0, moisture: 15},
+		{at: time.Hour, moisture: 15},
+		{at: 4 * time.Hour, moisture: 15, want: []string{"moisture below at 4h0m0s"}},
+		{at: 5 * time.Hour, moisture: 25, want: []string{"moisture below at 5h0m0s resolved"}},
+	})
+}
@@ -0,0 +1,190 @@
+package alert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Silence suppresses the notifications of a plant or a rule until it ends. An empty MAC address or rule matches all
+// plants or rules.
+type Silence struct {
+	ID         string    `json:"id"`
+	MacAddress string    `json:"macAddress,omitempty"`
+	Rule       string    `json:"rule,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	StartsAt   time.Time `json:"startsAt"`
+	EndsAt     time.Time `json:"endsAt"`
+}
+
+func (s Silence) matches(macAddress, rule string, now time.Time) bool {
+	if now.Before(s.StartsAt) || !now.Before(s.EndsAt) {
+		return false
+	}
+
+	if s.MacAddress != "" && !strings.EqualFold(s.MacAddress, macAddress) {
+		return false
+	}
+
+	return s.Rule == "" || s.Rule == rule
+}
+
+// Rules returns the names of the rules, which can be silenced.
+func Rules() []string {
+	result := []string{"stale"}
+	for _, c := range checks {
+		result = append(result, c.Rule)
+	}
+
+	return result
+}
+
+// Silences contains the silences. If a file name is set, they are saved to the file on every change, so that they are
+// kept across restarts.
+type Silences struct {
+	fileName string
+
+	lock     sync.RWMutex
+	silences []Silence
+}
+
+// NewSilences creates the silences and loads the ones saved in the file, if it is set.
+func NewSilences(fileName string) (*Silences, error) {
+	s := &Silences{
+		fileName: fileName,
+	}
+
+	if fileName == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &s.silences); err != nil {
+		return nil, fmt.Errorf("can not parse silences file %q: %s", fileName, err)
+	}
+
+	return s, nil
+}
+
+// Add adds the silence with a new ID and returns it.
+func (s *Silences) Add(silence Silence) (Silence, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Silence{}, err
+	}
+	silence.ID = hex.EncodeToString(id)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.expire(silence.StartsAt)
+	s.silences = append(s.silences, silence)
+	return silence, s.save()
+}
+
+// Delete removes the silence with the ID. It returns false if there is no such silence.
+func (s *Silences) Delete(id string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, silence := range s.silences {
+		if silence.ID != id {
+			continue
+		}
+
+		s.silences = append(s.silences[:i], s.silences[i+1:]...)
+		return true, s.save()
+	}
+
+	return false, nil
+}
+
+// Active returns the silences, which have not ended yet, ordered by their end.
+func (s *Silences) Active(now time.Time) []Silence {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := []Silence{}
+	for _, silence := range s.silences {
+		if now.Before(silence.EndsAt) {
+			result = append(result, silence)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].EndsAt.Before(result[j].EndsAt)
+	})
+	return result
+}
+
+// Silenced returns true if a silence matches the rule of the plant.
+func (s *Silences) Silenced(macAddress, rule string, now time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, silence := range s.silences {
+		if silence.matches(macAddress, rule, now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expire removes the silences, which have ended.
+func (s *Silences) expire(now time.Time) {
+	active := s.silences[:0]
+	for _, silence := range s.silences {
+		if now.Before(silence.EndsAt) {
+			active = append(active, silence)
+		}
+	}
+	s.silences = active
+}
+
+func (s *Silences) save() error {
+	if s.fileName == "" {
+		return nil
+	}
+
+	raw, err := json.Marshal(s.silences)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.fileName), filepath.Base(s.fileName)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.fileName)
+}
@@ -0,0 +1,188 @@
+package alert
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+)
+
+func TestSilenceMatches(t *testing.T) {
+	silence := func(macAddress, rule string) Silence {
+		return Silence{
+			MacAddress: macAddress,
+			Rule:       rule,
+			StartsAt:   testStart,
+			EndsAt:     testStart.Add(time.Hour),
+		}
+	}
+
+	tests := []struct {
+		desc    string
+		silence Silence
+		rule    string
+		offset  time.Duration
+		want    bool
+	}{
+		{
+			desc:    "plant and rule",
+			silence: silence(testMacAddress, "moisture"),
+			rule:    "moisture",
+			want:    true,
+		},
+		{
+			desc:    "mac address is not case-sensitive",
+			silence: silence("c4:7c:8d:00:00:01", "moisture"),
+			rule:    "moisture",
+			want:    true,
+		},
+		{
+			desc:    "other rule",
+			silence: silence(testMacAddress, "moisture"),
+			rule:    "light",
+			want:    false,
+		},
+		{
+			desc:    "other plant",
+			silence: silence("C4:7C:8D:00:00:02", "moisture"),
+			rule:    "moisture",
+			want:    false,
+		},
+		{
+			desc:    "all rules",
+			silence: silence(testMacAddress, ""),
+			rule:    "stale",
+			want:    true,
+		},
+		{
+			desc:    "all plants",
+			silence: silence("", "moisture"),
+			rule:    "moisture",
+			want:    true,
+		},
+		{
+			desc:    "not started",
+			silence: silence(testMacAddress, ""),
+			rule:    "moisture",
+			offset:  -time.Second,
+			want:    false,
+		},
+		{
+			desc:    "ended",
+			silence: silence(testMacAddress, ""),
+			rule:    "moisture",
+			offset:  time.Hour,
+			want:    false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.silence.matches(testMacAddress, tc.rule, testStart.Add(tc.offset))
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSilences(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "silences.json")
+	s, err := NewSilences(fileName)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	ended, err := s.Add(Silence{Rule: "light", StartsAt: testStart, EndsAt: testStart.Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	long, err := s.Add(Silence{MacAddress: testMacAddress, StartsAt: testStart, EndsAt: testStart.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	short, err := s.Add(Silence{Rule: "moisture", StartsAt: testStart.Add(time.Hour), EndsAt: testStart.Add(90 * time.Minute)})
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if long.ID == "" || long.ID == short.ID {
+		t.Errorf("got IDs %q and %q, want unique IDs", long.ID, short.ID)
+	}
+
+	// Adding a silence removes the ones which have ended at its start.
+	s, err = NewSilences(fileName)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	active := s.Active(testStart)
+	if len(active) != 2 || active[0].ID != short.ID || active[1].ID != long.ID {
+		t.Errorf("got active silences %v, want %q and %q", active, short.ID, long.ID)
+	}
+
+	ok, err := s.Delete(ended.ID)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	if ok {
+		t.Errorf("got deleted %v for ended silence, want false", ok)
+	}
+
+	ok, err = s.Delete(long.ID)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	if !ok {
+		t.Errorf("got deleted %v, want true", ok)
+	}
+
+	s, err = NewSilences(fileName)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if got := s.Active(testStart); len(got) != 1 || got[0].ID != short.ID {
+		t.Errorf("got active silences %v after delete, want %q", got, short.ID)
+	}
+
+	if s.Silenced(testMacAddress, "light", testStart.Add(time.Hour)) {
+		t.Error("got silenced rule after delete")
+	}
+
+	if !s.Silenced(testMacAddress, "moisture", testStart.Add(time.Hour)) {
+		t.Error("got rule not silenced")
+	}
+}
+
+func TestEngineSilence(t *testing.T) {
+	s, err := NewSilences("")
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if _, err := s.Add(Silence{Rule: "moisture", StartsAt: testStart, EndsAt: testStart.Add(time.Hour)}); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	n := &fakeNotifier{}
+	plants := []Plant{
+		{
+			MacAddress: testMacAddress,
+			Thresholds: Thresholds{Moisture: Range{Min: float(20)}},
+			Alerting:   RuleOptions{Repeat: Duration(30 * time.Minute)},
+		},
+	}
+	e := NewEngine(logging.Discard(), plants, 30*time.Minute, time.UTC, s, n)
+
+	// The notifications of the moisture rule are only sent again after the silence has ended.
+	runSteps(t, e, n, []step{
+		{at: 0, moisture: 15},
+		{at: 30 * time.Minute, check: true, want: []string{"stale stale at 30m0s"}},
+		{at: 40 * time.Minute, moisture: 15, want: []string{"stale stale at 40m0s resolved"}},
+		{at: 70 * time.Minute, moisture: 15, want: []string{"moisture below at 1h10m0s"}},
+	})
+}
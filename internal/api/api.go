@@ -0,0 +1,559 @@
+// Package api contains the JSON API of the exporter.
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/alert"
+	"github.com/xperimental/flowercare-exporter/internal/history"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
+)
+
+const (
+	defaultScanDuration = 10 * time.Second
+	maxScanDuration     = time.Minute
+	// maxBodySize limits the size of request bodies, which only contain small JSON objects.
+	maxBodySize = 64 * 1024
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// API serves the JSON endpoints.
+type API struct {
+	log     *logging.Logger
+	updater *updater.Updater
+	opts    Options
+	mux     *http.ServeMux
+}
+
+// Options contains the optional parts of the API.
+type Options struct {
+	// LogLevel can be changed using the API.
+	LogLevel *slog.LevelVar
+	// Location is the time zone of the returned times.
+	Location *time.Location
+	// History contains the daily aggregates of the readings.
+	History *history.Store
+	// Silences can be managed using the API. Nil if alerting is disabled.
+	Silences *alert.Silences
+	// Admin enables the requests changing the state of the exporter or using the adapter, like managing sensors and
+	// silences, changing the log level, connectivity checks and locating sensors. Otherwise only GET requests are
+	// served.
+	Admin bool
+}
+
+// New creates the API for the updater.
+func New(log *logging.Logger, u *updater.Updater, opts Options) *API {
+	a := &API{
+		log:     log,
+		updater: u,
+		opts:    opts,
+		mux:     http.NewServeMux(),
+	}
+	a.mux.HandleFunc("/api/openapi.json", a.handleOpenAPI)
+	a.mux.HandleFunc("/api/v1/status", a.handleStatus)
+	a.mux.HandleFunc("/api/v1/payloads", a.handlePayloads)
+	a.mux.HandleFunc("/api/v1/sensors", a.handleSensors)
+	a.mux.HandleFunc("/api/v1/sensors/", a.handleSensor)
+	a.mux.HandleFunc("/api/v1/diagnose/", a.handleDiagnose)
+	a.mux.HandleFunc("/api/v1/loglevel", a.handleLogLevel)
+	a.mux.HandleFunc("/api/v1/history/", a.handleHistory)
+	a.mux.HandleFunc("/api/v1/silences", a.handleSilences)
+	a.mux.HandleFunc("/api/v1/silences/", a.handleSilence)
+
+	return a
+}
+
+// ServeHTTP implements http.Handler
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.opts.Admin && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		a.sendError(w, http.StatusForbidden, "admin API is disabled, it needs --admin-addr or --enable-admin-api")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	a.mux.ServeHTTP(w, r)
+}
+
+func (a *API) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+func (a *API) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a.sendJSON(w, http.StatusOK, a.updater.Status().In(a.opts.Location))
+}
+
+func (a *API) handlePayloads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a.sendJSON(w, http.StatusOK, a.updater.Payloads(a.opts.Location))
+}
+
+type sensorRequest struct {
+	MacAddress string `json:"macAddress"`
+	Name       string `json:"name"`
+}
+
+type sensorUpdate struct {
+	Disabled *bool `json:"disabled"`
+}
+
+type sensorResponse struct {
+	MacAddress string `json:"macAddress"`
+	Name       string `json:"name"`
+	Device     string `json:"device,omitempty"`
+	Disabled   bool   `json:"disabled"`
+}
+
+func (a *API) handleSensors(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		result := []sensorResponse{}
+		for _, s := range a.updater.Status().Sensors {
+			if s.Removed == nil {
+				result = append(result, newSensorResponse(s))
+			}
+		}
+		a.sendJSON(w, http.StatusOK, result)
+	case http.MethodPost:
+		var request sensorRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("can not parse request: %s", err))
+			return
+		}
+
+		if _, err := net.ParseMAC(request.MacAddress); err != nil {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid MAC address: %s", request.MacAddress))
+			return
+		}
+
+		sensor := updater.Sensor{
+			Name:       request.Name,
+			MacAddress: strings.ToUpper(request.MacAddress),
+		}
+		if !a.updater.Register(sensor, time.Now()) {
+			a.sendError(w, http.StatusConflict, fmt.Sprintf("sensor already registered: %s", sensor.MacAddress))
+			return
+		}
+
+		a.sendSensor(w, http.StatusCreated, sensor.MacAddress)
+	default:
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (a *API) handleSensor(w http.ResponseWriter, r *http.Request) {
+	path, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/sensors/"), "/")
+	macAddress, ok := a.findSensor(path)
+	if action == "locate" {
+		a.handleLocate(w, r, macAddress)
+		return
+	}
+
+	if action != "" {
+		a.sendError(w, http.StatusNotFound, fmt.Sprintf("not found: %s", r.URL.Path))
+		return
+	}
+
+	if !ok {
+		a.sendError(w, http.StatusNotFound, fmt.Sprintf("sensor not found: %s", macAddress))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.sendSensor(w, http.StatusOK, macAddress)
+	case http.MethodPut:
+		var request sensorUpdate
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("can not parse request: %s", err))
+			return
+		}
+
+		if request.Disabled != nil {
+			a.updater.SetDisabled(macAddress, *request.Disabled, time.Now())
+		}
+		a.sendSensor(w, http.StatusOK, macAddress)
+	case http.MethodDelete:
+		if !a.updater.RemoveSensor(macAddress, time.Now()) {
+			a.sendError(w, http.StatusNotFound, fmt.Sprintf("sensor not found: %s", macAddress))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleLocate makes the LED of a sensor blink. Sensors which are not registered can be located as well, so that
+// devices found while scanning can be identified.
+func (a *API) handleLocate(w http.ResponseWriter, r *http.Request, macAddress string) {
+	if r.Method != http.MethodPost {
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if _, err := net.ParseMAC(macAddress); err != nil {
+		a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid MAC address: %s", macAddress))
+		return
+	}
+
+	err := a.updater.Locate(r.Context(), macAddress)
+	switch {
+	case errors.Is(err, updater.ErrLocateUnsupported):
+		a.sendError(w, http.StatusNotImplemented, err.Error())
+		return
+	case updater.IsRejected(err):
+		a.sendError(w, http.StatusTooManyRequests, err.Error())
+		return
+	case err != nil:
+		a.sendError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findSensor returns the MAC address of the registered sensor matching the MAC address ignoring the case.
+func (a *API) findSensor(macAddress string) (string, bool) {
+	for _, s := range a.updater.Sensors() {
+		if strings.EqualFold(s.MacAddress, macAddress) {
+			return s.MacAddress, true
+		}
+	}
+
+	return macAddress, false
+}
+
+func (a *API) sendSensor(w http.ResponseWriter, status int, macAddress string) {
+	for _, s := range a.updater.Status().Sensors {
+		if s.MacAddress == macAddress && s.Removed == nil {
+			a.sendJSON(w, status, newSensorResponse(s))
+			return
+		}
+	}
+
+	a.sendError(w, http.StatusNotFound, fmt.Sprintf("sensor not found: %s", macAddress))
+}
+
+func newSensorResponse(s updater.SensorStatus) sensorResponse {
+	return sensorResponse{
+		MacAddress: s.MacAddress,
+		Name:       s.Name,
+		Device:     s.Device,
+		Disabled:   s.Disabled,
+	}
+}
+
+func (a *API) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	macAddress := strings.TrimPrefix(r.URL.Path, "/api/v1/diagnose/")
+	if _, err := net.ParseMAC(macAddress); err != nil {
+		a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid MAC address: %s", macAddress))
+		return
+	}
+
+	scanDuration := defaultScanDuration
+	if value := r.URL.Query().Get("scanDuration"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 || d > maxScanDuration {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid scan duration: %s", value))
+			return
+		}
+		scanDuration = d
+	}
+
+	report, err := a.updater.Diagnose(r.Context(), macAddress, scanDuration)
+	switch {
+	case errors.Is(err, updater.ErrDiagnoseUnsupported):
+		a.sendError(w, http.StatusNotImplemented, err.Error())
+		return
+	case updater.IsRejected(err):
+		a.sendError(w, http.StatusTooManyRequests, err.Error())
+		return
+	case err != nil:
+		a.sendError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	a.sendJSON(w, http.StatusOK, report)
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+func (a *API) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var request logLevel
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("can not parse request: %s", err))
+			return
+		}
+
+		level, err := logging.ParseLevel(request.Level)
+		if err != nil {
+			a.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		a.opts.LogLevel.Set(level)
+		a.log.Warnf("Log level changed to %s.", logging.LevelName(level))
+	default:
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a.sendJSON(w, http.StatusOK, logLevel{
+		Level: logging.LevelName(a.opts.LogLevel.Level()),
+	})
+}
+
+type aggregate struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Count int     `json:"count"`
+}
+
+type dailyAggregates struct {
+	Date         string               `json:"date"`
+	Measurements map[string]aggregate `json:"measurements"`
+}
+
+type hourlyAggregates struct {
+	Start        time.Time            `json:"start"`
+	Measurements map[string]aggregate `json:"measurements"`
+}
+
+func newAggregates(measurements map[string]history.Aggregate) map[string]aggregate {
+	result := make(map[string]aggregate, len(measurements))
+	for key, m := range measurements {
+		result[key] = aggregate{
+			Min:   m.Min,
+			Max:   m.Max,
+			Avg:   m.Avg(),
+			Count: m.Count,
+		}
+	}
+	return result
+}
+
+func (a *API) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	macAddress, period, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/history/"), "/")
+	if !ok || (period != "daily" && period != "hourly") {
+		a.sendError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if _, err := net.ParseMAC(macAddress); err != nil {
+		a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid MAC address: %s", macAddress))
+		return
+	}
+
+	if period == "hourly" {
+		hours := 0
+		if value := r.URL.Query().Get("hours"); value != "" {
+			h, err := strconv.Atoi(value)
+			if err != nil || h < 1 {
+				a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid number of hours: %s", value))
+				return
+			}
+			hours = h
+		}
+
+		result := []hourlyAggregates{}
+		for _, hour := range a.opts.History.Hourly(macAddress, hours) {
+			result = append(result, hourlyAggregates{
+				Start:        hour.Start,
+				Measurements: newAggregates(hour.Measurements),
+			})
+		}
+
+		a.sendJSON(w, http.StatusOK, result)
+		return
+	}
+
+	days := 0
+	if value := r.URL.Query().Get("days"); value != "" {
+		d, err := strconv.Atoi(value)
+		if err != nil || d < 1 {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid number of days: %s", value))
+			return
+		}
+		days = d
+	}
+
+	result := []dailyAggregates{}
+	for _, day := range a.opts.History.Daily(macAddress, days) {
+		result = append(result, dailyAggregates{
+			Date:         day.Date,
+			Measurements: newAggregates(day.Measurements),
+		})
+	}
+
+	a.sendJSON(w, http.StatusOK, result)
+}
+
+type silenceRequest struct {
+	MacAddress string `json:"macAddress"`
+	Rule       string `json:"rule"`
+	Duration   string `json:"duration"`
+	Comment    string `json:"comment"`
+}
+
+func (a *API) handleSilences(w http.ResponseWriter, r *http.Request) {
+	if a.opts.Silences == nil {
+		a.sendError(w, http.StatusNotImplemented, "alerting is not enabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		silences := a.opts.Silences.Active(time.Now())
+		for i, silence := range silences {
+			silences[i] = silenceIn(silence, a.opts.Location)
+		}
+		a.sendJSON(w, http.StatusOK, silences)
+	case http.MethodPost:
+		var request silenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			a.sendError(w, http.StatusBadRequest, fmt.Sprintf("can not parse request: %s", err))
+			return
+		}
+
+		silence, err := newSilence(request, time.Now())
+		if err != nil {
+			a.sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		silence, err = a.opts.Silences.Add(silence)
+		if err != nil {
+			a.sendError(w, http.StatusInternalServerError, fmt.Sprintf("can not save silence: %s", err))
+			return
+		}
+
+		a.log.Infof("Added silence %s until %s: %s", silence.ID, silence.EndsAt.Format(time.RFC3339), silence.Comment)
+		a.sendJSON(w, http.StatusCreated, silenceIn(silence, a.opts.Location))
+	default:
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func newSilence(request silenceRequest, now time.Time) (alert.Silence, error) {
+	if request.MacAddress != "" {
+		if _, err := net.ParseMAC(request.MacAddress); err != nil {
+			return alert.Silence{}, fmt.Errorf("invalid MAC address: %s", request.MacAddress)
+		}
+	}
+
+	if request.Rule != "" {
+		known := false
+		for _, rule := range alert.Rules() {
+			known = known || rule == request.Rule
+		}
+
+		if !known {
+			return alert.Silence{}, fmt.Errorf("unknown rule %q, expected one of %s", request.Rule, alert.Rules())
+		}
+	}
+
+	duration, err := time.ParseDuration(request.Duration)
+	if err != nil || duration <= 0 {
+		return alert.Silence{}, fmt.Errorf("invalid duration: %s", request.Duration)
+	}
+
+	return alert.Silence{
+		MacAddress: strings.ToUpper(request.MacAddress),
+		Rule:       request.Rule,
+		Comment:    request.Comment,
+		StartsAt:   now,
+		EndsAt:     now.Add(duration),
+	}, nil
+}
+
+func silenceIn(silence alert.Silence, location *time.Location) alert.Silence {
+	silence.StartsAt = silence.StartsAt.In(location)
+	silence.EndsAt = silence.EndsAt.In(location)
+	return silence
+}
+
+func (a *API) handleSilence(w http.ResponseWriter, r *http.Request) {
+	if a.opts.Silences == nil {
+		a.sendError(w, http.StatusNotImplemented, "alerting is not enabled")
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		a.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/silences/")
+	found, err := a.opts.Silences.Delete(id)
+	switch {
+	case err != nil:
+		a.sendError(w, http.StatusInternalServerError, fmt.Sprintf("can not save silences: %s", err))
+		return
+	case !found:
+		a.sendError(w, http.StatusNotFound, fmt.Sprintf("silence not found: %s", id))
+		return
+	}
+
+	a.log.Infof("Deleted silence %s.", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (a *API) sendError(w http.ResponseWriter, status int, message string) {
+	a.sendJSON(w, status, errorResponse{
+		Error: message,
+	})
+}
+
+func (a *API) sendJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		a.log.Errorf("Error encoding response: %s", err)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+)
+
+func TestAdminRequests(t *testing.T) {
+	largeBody := `{"macAddress": "C4:7C:8D:00:00:01"` + strings.Repeat(" ", maxBodySize) + `}`
+
+	tests := []struct {
+		desc       string
+		admin      bool
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:       "add sensor without admin",
+			method:     http.MethodPost,
+			path:       "/api/v1/sensors",
+			body:       `{"macAddress": "C4:7C:8D:00:00:01"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			desc:       "remove sensor without admin",
+			method:     http.MethodDelete,
+			path:       "/api/v1/sensors/C4:7C:8D:00:00:01",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			desc:       "set log level without admin",
+			method:     http.MethodPut,
+			path:       "/api/v1/loglevel",
+			body:       `{"level": "debug"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			desc:       "diagnose without admin",
+			method:     http.MethodPost,
+			path:       "/api/v1/diagnose/C4:7C:8D:00:00:01",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			desc:       "add silence without admin",
+			method:     http.MethodPost,
+			path:       "/api/v1/silences",
+			body:       `{"macAddress": "C4:7C:8D:00:00:01", "rule": "moisture", "duration": "1h"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			desc:       "body too large",
+			admin:      true,
+			method:     http.MethodPost,
+			path:       "/api/v1/sensors",
+			body:       largeBody,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "request body too large",
+		},
+		{
+			desc:       "openapi without admin",
+			method:     http.MethodGet,
+			path:       "/api/openapi.json",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			a := New(logging.Discard(), nil, Options{Admin: tc.admin})
+
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("got status %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+			}
+
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("got body %q, want it to contain %q", w.Body, tc.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package bluetooth opens Bluetooth adapters using the backend available on the current platform.
+package bluetooth
+
+import (
+	"github.com/go-ble/ble"
+)
+
+// Open opens the named Bluetooth adapter. The options are passed to the backend, which might ignore options it does
+// not support. Before opening the adapter, it checks that the process has the needed permissions, so that missing
+// permissions result in an actionable error instead of a failing system call.
+func Open(name string, opts ...ble.Option) (ble.Device, error) {
+	if err := checkPermissions(); err != nil {
+		return nil, err
+	}
+
+	return openDevice(name, opts...)
+}
+
+// Close releases an adapter opened using Open. Scanning is stopped before the HCI socket is closed, so that the adapter
+// is not left busy for the next process. Connections need to be closed before. Wrapped devices are unwrapped using
+// their Unwrap method.
+func Close(device ble.Device) error {
+	for {
+		w, ok := device.(interface{ Unwrap() ble.Device })
+		if !ok {
+			break
+		}
+
+		device = w.Unwrap()
+	}
+
+	return closeDevice(device)
+}
@@ -0,0 +1,76 @@
+//go:build linux
+
+package bluetooth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	capNetAdmin = 12
+	capNetRaw   = 13
+)
+
+// checkPermissions checks that the process has the capabilities needed for using a raw HCI socket.
+func checkPermissions() error {
+	caps, err := effectiveCapabilities()
+	if err != nil {
+		// Do not prevent opening the adapter if the capabilities can not be determined.
+		return nil
+	}
+
+	var missing []string
+	if caps&(1<<capNetAdmin) == 0 {
+		missing = append(missing, "CAP_NET_ADMIN")
+	}
+
+	if caps&(1<<capNetRaw) == 0 {
+		missing = append(missing, "CAP_NET_RAW")
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		binary = os.Args[0]
+	}
+
+	return fmt.Errorf("missing capabilities %s for using the Bluetooth adapter: "+
+		"run \"setcap cap_net_raw,cap_net_admin+eip %s\", "+
+		"set \"AmbientCapabilities=CAP_NET_RAW CAP_NET_ADMIN\" in the systemd unit, "+
+		"use \"--cap-add NET_RAW --cap-add NET_ADMIN\" with Docker "+
+		"or use --backend dbus, which does not need these capabilities",
+		strings.Join(missing, " and "), binary)
+}
+
+// effectiveCapabilities returns the effective capability set of the process as listed in /proc/self/status.
+func effectiveCapabilities() (uint64, error) {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+
+		return strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, errors.New("no effective capabilities in status")
+}
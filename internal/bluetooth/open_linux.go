@@ -0,0 +1,21 @@
+//go:build linux
+
+package bluetooth
+
+import (
+	"github.com/go-ble/ble"
+	"github.com/go-ble/ble/linux"
+)
+
+func openDevice(name string, opts ...ble.Option) (ble.Device, error) {
+	return linux.NewDeviceWithName(name, opts...)
+}
+
+func closeDevice(device ble.Device) error {
+	if d, ok := device.(*linux.Device); ok {
+		// Fails if the adapter is not scanning, which is fine.
+		_ = d.HCI.StopScanning()
+	}
+
+	return device.Stop()
+}
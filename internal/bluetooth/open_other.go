@@ -0,0 +1,22 @@
+//go:build !linux && !windows
+
+package bluetooth
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/go-ble/ble"
+)
+
+func openDevice(name string, opts ...ble.Option) (ble.Device, error) {
+	return nil, fmt.Errorf("no Bluetooth backend available on %s", runtime.GOOS)
+}
+
+func closeDevice(device ble.Device) error {
+	return device.Stop()
+}
+
+func checkPermissions() error {
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package bluetooth
+
+import (
+	"fmt"
+
+	"github.com/go-ble/ble"
+	tinyble "tinygo.org/x/bluetooth"
+)
+
+// openDevice opens the default adapter using WinRT. Windows does not name adapters, so the name is ignored, as are the
+// options, which only apply to the HCI backend.
+func openDevice(name string, opts ...ble.Option) (ble.Device, error) {
+	if err := tinyble.DefaultAdapter.Enable(); err != nil {
+		return nil, fmt.Errorf("can not enable Bluetooth adapter: %s", err)
+	}
+
+	return newWinRTDevice(tinyble.DefaultAdapter), nil
+}
+
+func closeDevice(device ble.Device) error {
+	return device.Stop()
+}
+
+func checkPermissions() error {
+	return nil
+}
@@ -0,0 +1,470 @@
+//go:build windows
+
+package bluetooth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+	tinyble "tinygo.org/x/bluetooth"
+)
+
+const (
+	// stopScanInterval is the interval in which stopping a scan is retried until the scan returns, because the
+	// watcher might not be started yet when the context is done.
+	stopScanInterval = 50 * time.Millisecond
+
+	// maxValueSize is the maximum size of an attribute value.
+	maxValueSize = 512
+
+	// defaultMTU is the ATT MTU reported for connections, as WinRT negotiates the MTU on its own.
+	defaultMTU = 23
+)
+
+var (
+	errNotSupported = errors.New("not supported by the WinRT backend")
+	errDisconnected = errors.New("disconnected")
+)
+
+// winrtDevice implements the central role of ble.Device using the WinRT Bluetooth API. Windows does not address
+// attributes by their handle, so characteristics are looked up by their UUID instead.
+type winrtDevice struct {
+	adapter *tinyble.Adapter
+
+	scanLock sync.Mutex
+}
+
+func newWinRTDevice(adapter *tinyble.Adapter) *winrtDevice {
+	return &winrtDevice{
+		adapter: adapter,
+	}
+}
+
+func (d *winrtDevice) AddService(svc *ble.Service) error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) RemoveAllServices() error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) SetServices(svcs []*ble.Service) error {
+	return errNotSupported
+}
+
+// Stop stops a running scan. WinRT has no adapter handle which needs to be released.
+func (d *winrtDevice) Stop() error {
+	// Fails if the adapter is not scanning, which is fine.
+	_ = d.adapter.StopScan()
+	return nil
+}
+
+func (d *winrtDevice) Advertise(ctx context.Context, adv ble.Advertisement) error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) AdvertiseNameAndServices(ctx context.Context, name string, uuids ...ble.UUID) error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) AdvertiseMfgData(ctx context.Context, id uint16, b []byte) error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) AdvertiseServiceData16(ctx context.Context, id uint16, b []byte) error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) AdvertiseIBeaconData(ctx context.Context, b []byte) error {
+	return errNotSupported
+}
+
+func (d *winrtDevice) AdvertiseIBeacon(ctx context.Context, u ble.UUID, major, minor uint16, pwr int8) error {
+	return errNotSupported
+}
+
+// Scan scans until the context is done. Like the HCI backend it returns the error of the context in that case.
+func (d *winrtDevice) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d.scanLock.Lock()
+	defer d.scanLock.Unlock()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		for {
+			// Fails if the watcher has not been started yet, so it is retried until the scan returns.
+			_ = d.adapter.StopScan()
+
+			select {
+			case <-done:
+				return
+			case <-time.After(stopScanInterval):
+			}
+		}
+	}()
+
+	seen := map[string]bool{}
+	err := d.adapter.Scan(func(_ *tinyble.Adapter, result tinyble.ScanResult) {
+		a := newAdvertisement(result)
+		if !allowDup {
+			addr := a.Addr().String()
+			if seen[addr] {
+				return
+			}
+			seen[addr] = true
+		}
+
+		h(a)
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// Dial connects to the device and discovers its characteristics, so that they can be found by their UUID.
+func (d *winrtDevice) Dial(ctx context.Context, addr ble.Addr) (ble.Client, error) {
+	mac, err := tinyble.ParseMAC(addr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %s", addr, err)
+	}
+
+	device, err := d.adapter.Connect(tinyble.Address{
+		MACAddress: tinyble.MACAddress{
+			MAC: mac,
+		},
+	}, tinyble.ConnectionParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	characteristics, err := discoverCharacteristics(device)
+	if err != nil {
+		_ = device.Disconnect()
+		return nil, fmt.Errorf("error discovering characteristics: %s", err)
+	}
+
+	conn := &winrtConn{
+		ctx:          ctx,
+		device:       device,
+		addr:         addr,
+		disconnected: make(chan struct{}),
+	}
+
+	return &winrtClient{
+		conn:            conn,
+		characteristics: characteristics,
+	}, nil
+}
+
+// discoverCharacteristics returns the characteristics of all services of the device by their UUID.
+func discoverCharacteristics(device tinyble.Device) (map[string]tinyble.DeviceCharacteristic, error) {
+	services, err := device.DiscoverServices(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]tinyble.DeviceCharacteristic{}
+	for _, service := range services {
+		characteristics, err := service.DiscoverCharacteristics(nil)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %s", service.UUID(), err)
+		}
+
+		for _, c := range characteristics {
+			result[toBLEUUID(c.UUID()).String()] = c
+		}
+	}
+
+	return result, nil
+}
+
+// toBLEUUID converts the UUID, using the short form for 16-bit UUIDs like the HCI backend does.
+func toBLEUUID(u tinyble.UUID) ble.UUID {
+	if u.Is16Bit() {
+		return ble.UUID16(u.Get16Bit())
+	}
+
+	// Both libraries store the bytes of the UUID in little-endian order.
+	b := u.Bytes()
+	return ble.UUID(b[:])
+}
+
+// winrtClient implements the parts of ble.Client needed for reading and writing characteristics.
+type winrtClient struct {
+	conn            *winrtConn
+	characteristics map[string]tinyble.DeviceCharacteristic
+}
+
+func (c *winrtClient) Addr() ble.Addr {
+	return c.conn.addr
+}
+
+func (c *winrtClient) Name() string {
+	return ""
+}
+
+func (c *winrtClient) Profile() *ble.Profile {
+	return nil
+}
+
+func (c *winrtClient) DiscoverProfile(force bool) (*ble.Profile, error) {
+	return nil, errNotSupported
+}
+
+func (c *winrtClient) DiscoverServices(filter []ble.UUID) ([]*ble.Service, error) {
+	return nil, errNotSupported
+}
+
+func (c *winrtClient) DiscoverIncludedServices(filter []ble.UUID, s *ble.Service) ([]*ble.Service, error) {
+	return nil, errNotSupported
+}
+
+func (c *winrtClient) DiscoverCharacteristics(filter []ble.UUID, s *ble.Service) ([]*ble.Characteristic, error) {
+	return nil, errNotSupported
+}
+
+func (c *winrtClient) DiscoverDescriptors(filter []ble.UUID, char *ble.Characteristic) ([]*ble.Descriptor, error) {
+	return nil, errNotSupported
+}
+
+func (c *winrtClient) ReadCharacteristic(char *ble.Characteristic) ([]byte, error) {
+	characteristic, err := c.find(char)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, maxValueSize)
+	n, err := characteristic.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func (c *winrtClient) ReadLongCharacteristic(char *ble.Characteristic) ([]byte, error) {
+	return c.ReadCharacteristic(char)
+}
+
+func (c *winrtClient) WriteCharacteristic(char *ble.Characteristic, value []byte, noRsp bool) error {
+	characteristic, err := c.find(char)
+	if err != nil {
+		return err
+	}
+
+	if noRsp {
+		_, err = characteristic.WriteWithoutResponse(value)
+	} else {
+		_, err = characteristic.Write(value)
+	}
+
+	return err
+}
+
+func (c *winrtClient) ReadDescriptor(d *ble.Descriptor) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+func (c *winrtClient) WriteDescriptor(d *ble.Descriptor, v []byte) error {
+	return errNotSupported
+}
+
+func (c *winrtClient) ReadRSSI() int {
+	return 0
+}
+
+func (c *winrtClient) ExchangeMTU(rxMTU int) (int, error) {
+	return 0, errNotSupported
+}
+
+func (c *winrtClient) Subscribe(char *ble.Characteristic, ind bool, h ble.NotificationHandler) error {
+	return errNotSupported
+}
+
+func (c *winrtClient) Unsubscribe(char *ble.Characteristic, ind bool) error {
+	return errNotSupported
+}
+
+func (c *winrtClient) ClearSubscriptions() error {
+	return nil
+}
+
+func (c *winrtClient) CancelConnection() error {
+	return c.conn.Close()
+}
+
+func (c *winrtClient) Disconnected() <-chan struct{} {
+	return c.conn.disconnected
+}
+
+func (c *winrtClient) Conn() ble.Conn {
+	return c.conn
+}
+
+// find returns the discovered characteristic with the UUID of the characteristic.
+func (c *winrtClient) find(char *ble.Characteristic) (tinyble.DeviceCharacteristic, error) {
+	select {
+	case <-c.conn.disconnected:
+		return tinyble.DeviceCharacteristic{}, errDisconnected
+	default:
+	}
+
+	if char.UUID == nil {
+		return tinyble.DeviceCharacteristic{}, fmt.Errorf("characteristic 0x%02x has no UUID", char.ValueHandle)
+	}
+
+	characteristic, ok := c.characteristics[char.UUID.String()]
+	if !ok {
+		return tinyble.DeviceCharacteristic{}, fmt.Errorf("characteristic %s not found", char.UUID)
+	}
+
+	return characteristic, nil
+}
+
+// winrtConn represents the GATT session of a connected device. Data can not be sent or received directly.
+type winrtConn struct {
+	ctx          context.Context
+	device       tinyble.Device
+	addr         ble.Addr
+	closeOnce    sync.Once
+	closeErr     error
+	disconnected chan struct{}
+}
+
+func (c *winrtConn) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (c *winrtConn) Write(p []byte) (int, error) {
+	return 0, errNotSupported
+}
+
+// Close closes the GATT session. It can be called more than once.
+func (c *winrtConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.device.Disconnect()
+		close(c.disconnected)
+	})
+
+	return c.closeErr
+}
+
+func (c *winrtConn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *winrtConn) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+func (c *winrtConn) LocalAddr() ble.Addr {
+	return ble.NewAddr("")
+}
+
+func (c *winrtConn) RemoteAddr() ble.Addr {
+	return c.addr
+}
+
+func (c *winrtConn) RxMTU() int {
+	return defaultMTU
+}
+
+func (c *winrtConn) SetRxMTU(mtu int) {}
+
+func (c *winrtConn) TxMTU() int {
+	return defaultMTU
+}
+
+func (c *winrtConn) SetTxMTU(mtu int) {}
+
+func (c *winrtConn) ReadRSSI() int {
+	return 0
+}
+
+func (c *winrtConn) Disconnected() <-chan struct{} {
+	return c.disconnected
+}
+
+// winrtAdvertisement contains the parts of an advertisement which are provided by WinRT: the address, signal
+// strength, name and manufacturer data.
+type winrtAdvertisement struct {
+	addr             ble.Addr
+	rssi             int
+	localName        string
+	manufacturerData []byte
+}
+
+func newAdvertisement(result tinyble.ScanResult) *winrtAdvertisement {
+	a := &winrtAdvertisement{
+		addr:      ble.NewAddr(result.Address.String()),
+		rssi:      int(result.RSSI),
+		localName: result.LocalName(),
+	}
+
+	// Like the HCI backend, the manufacturer data starts with the company ID in little-endian order.
+	if data := result.ManufacturerData(); len(data) > 0 {
+		a.manufacturerData = append([]byte{byte(data[0].CompanyID), byte(data[0].CompanyID >> 8)}, data[0].Data...)
+	}
+
+	return a
+}
+
+func (a *winrtAdvertisement) LocalName() string {
+	return a.localName
+}
+
+func (a *winrtAdvertisement) ManufacturerData() []byte {
+	return a.manufacturerData
+}
+
+// ServiceData returns nil, as WinRT does not pass the service data of advertisements, which contain the MiBeacon
+// frames used in passive mode.
+func (a *winrtAdvertisement) ServiceData() []ble.ServiceData {
+	return nil
+}
+
+func (a *winrtAdvertisement) Services() []ble.UUID {
+	return nil
+}
+
+func (a *winrtAdvertisement) OverflowService() []ble.UUID {
+	return nil
+}
+
+func (a *winrtAdvertisement) TxPowerLevel() int {
+	return 0
+}
+
+func (a *winrtAdvertisement) Connectable() bool {
+	return true
+}
+
+func (a *winrtAdvertisement) SolicitedService() []ble.UUID {
+	return nil
+}
+
+func (a *winrtAdvertisement) RSSI() int {
+	return a.rssi
+}
+
+func (a *winrtAdvertisement) Addr() ble.Addr {
+	return a.addr
+}
@@ -0,0 +1,237 @@
+// Package bluezcli reads sensors by running the command-line tools of BlueZ instead of using the HCI socket directly.
+// It is slower than the native backend, because every operation uses a new connection, but works on systems where
+// the raw HCI socket is not usable.
+package bluezcli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	gatttoolBinary     = "gatttool"
+	bluetoothctlBinary = "bluetoothctl"
+
+	firmwareHandle = "0x0038"
+	modeHandle     = "0x0033"
+	modeValue      = "a01f"
+	sensorsHandle  = "0x0035"
+
+	valuePrefix = "Characteristic value/descriptor:"
+)
+
+var deviceLine = regexp.MustCompile(`Device ([0-9A-Fa-f:]{17}) (.*)$`)
+
+// Reader reads sensors using gatttool and scans for sensors using bluetoothctl.
+type Reader struct {
+	// Adapter is the name of the Bluetooth adapter used by gatttool, for example "hci0".
+	Adapter string
+	// SkipRealtimeMode disables enabling the realtime measurement before reading the sensor values.
+	SkipRealtimeMode bool
+	// Lenient accepts sensor data of unexpected lengths sent by clone devices.
+	Lenient bool
+	// Layouts contains the layouts of the sensor data of clone devices by their MAC address.
+	Layouts map[string]miflora.Layout
+}
+
+// ReadData implements updater.Reader
+func (r *Reader) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	firmwareRaw, err := r.readHandle(ctx, macAddress, firmwareHandle)
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: stage(err),
+			Err:   fmt.Errorf("error reading firmware info: %s", err),
+		}
+	}
+
+	if !r.SkipRealtimeMode {
+		if _, err := r.gatttool(ctx, macAddress, "--char-write-req", "-a", modeHandle, "-n", modeValue); err != nil {
+			return miflora.Data{}, &miflora.ReadError{
+				Stage: stage(err),
+				Err:   fmt.Errorf("can not enable realtime reading: %s", err),
+			}
+		}
+	}
+
+	sensorsRaw, err := r.readHandle(ctx, macAddress, sensorsHandle)
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: stage(err),
+			Err:   fmt.Errorf("error reading sensor data: %s", err),
+		}
+	}
+
+	opts := miflora.ReadOptions{
+		Lenient: r.Lenient,
+	}
+	if layout, ok := r.Layouts[strings.ToUpper(macAddress)]; ok {
+		opts.Layout = &layout
+	}
+
+	return miflora.ParseRawData(miflora.RawData{
+		Firmware: firmwareRaw,
+		Sensors:  sensorsRaw,
+	}, opts)
+}
+
+// stage returns the stage in which a gatttool command failed. gatttool connects to the sensor for every command, so
+// every step can fail while connecting.
+func stage(err error) string {
+	if strings.Contains(strings.ToLower(err.Error()), "connect") {
+		return miflora.StageDial
+	}
+
+	return miflora.StageRead
+}
+
+func (r *Reader) readHandle(ctx context.Context, macAddress, handle string) ([]byte, error) {
+	output, err := r.gatttool(ctx, macAddress, "--char-read", "-a", handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValue(output)
+}
+
+func (r *Reader) gatttool(ctx context.Context, macAddress string, args ...string) (string, error) {
+	args = append([]string{"-i", r.Adapter, "-b", macAddress}, args...)
+	output, err := exec.CommandContext(ctx, gatttoolBinary, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %s: %s", gatttoolBinary, err, strings.TrimSpace(string(output)))
+	}
+
+	// gatttool does not always set the exit code when it fails to connect.
+	if bytes.Contains(output, []byte("error")) {
+		return "", fmt.Errorf("%s failed: %s", gatttoolBinary, strings.TrimSpace(string(output)))
+	}
+
+	return string(output), nil
+}
+
+// parseValue extracts the value from the output of "gatttool --char-read", for example
+// "Characteristic value/descriptor: 64 27 33 2e 32 2e 31".
+func parseValue(output string) ([]byte, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, valuePrefix) {
+			continue
+		}
+
+		value := strings.ReplaceAll(strings.TrimPrefix(line, valuePrefix), " ", "")
+		return hex.DecodeString(value)
+	}
+
+	return nil, fmt.Errorf("no value in output: %s", strings.TrimSpace(output))
+}
+
+// Scan implements updater.Scanner
+func (r *Reader) Scan(ctx context.Context, duration time.Duration, handler func(miflora.Advertisement)) error {
+	names, err := r.knownDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	timeout := int(math.Ceil(duration.Seconds()))
+	if timeout < 1 {
+		timeout = 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration+5*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, bluetoothctlBinary, "--timeout", strconv.Itoa(timeout), "scan", "on")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("can not create pipe: %s", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("can not start %s: %s", bluetoothctlBinary, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		a, ok := parseScanLine(scanner.Text(), names)
+		if ok {
+			handler(a)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s failed: %s", bluetoothctlBinary, err)
+	}
+
+	return nil
+}
+
+// knownDevices returns the names of the devices already known to BlueZ, because these are not repeated while scanning.
+func (r *Reader) knownDevices(ctx context.Context) (map[string]string, error) {
+	output, err := exec.CommandContext(ctx, bluetoothctlBinary, "devices").Output()
+	if err != nil {
+		return nil, fmt.Errorf("can not list devices: %s", err)
+	}
+
+	names := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if m := deviceLine.FindStringSubmatch(line); m != nil {
+			names[strings.ToUpper(m[1])] = m[2]
+		}
+	}
+
+	return names, nil
+}
+
+// parseScanLine parses lines like "[NEW] Device C4:7C:8D:00:00:01 Flower care" or
+// "[CHG] Device C4:7C:8D:00:00:01 RSSI: -72". Only lines of Flower Care devices are returned.
+func parseScanLine(line string, names map[string]string) (miflora.Advertisement, bool) {
+	m := deviceLine.FindStringSubmatch(line)
+	if m == nil {
+		return miflora.Advertisement{}, false
+	}
+
+	macAddress := strings.ToUpper(m[1])
+	rssi := 0
+	switch {
+	case strings.HasPrefix(m[2], "RSSI: "):
+		rssi = parseRSSI(strings.TrimPrefix(m[2], "RSSI: "))
+	case strings.HasPrefix(m[2], "Name: "):
+		names[macAddress] = strings.TrimPrefix(m[2], "Name: ")
+	case strings.Contains(m[2], ": "):
+		return miflora.Advertisement{}, false
+	default:
+		names[macAddress] = m[2]
+	}
+
+	name := names[macAddress]
+	switch strings.ToLower(name) {
+	case "flower care", "flower mate":
+	default:
+		return miflora.Advertisement{}, false
+	}
+
+	return miflora.Advertisement{
+		MacAddress: macAddress,
+		Name:       name,
+		RSSI:       rssi,
+	}, true
+}
+
+// parseRSSI parses the RSSI as printed by bluetoothctl. Newer versions print it like "0xffffffb8 (-72)".
+func parseRSSI(value string) int {
+	if start := strings.Index(value, "("); start >= 0 {
+		value = strings.TrimSuffix(value[start+1:], ")")
+	}
+
+	rssi, _ := strconv.Atoi(strings.TrimSpace(value))
+	return rssi
+}
@@ -0,0 +1,188 @@
+package bluezcli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		desc    string
+		output  string
+		want    []byte
+		wantErr bool
+	}{
+		{
+			desc:   "firmware",
+			output: "Characteristic value/descriptor: 64 15 33 2e 31 2e 38 \n",
+			want:   []byte{0x64, 0x15, 0x33, 0x2e, 0x31, 0x2e, 0x38},
+		},
+		{
+			desc:   "sensor data",
+			output: "Characteristic value/descriptor: f2 00 00 ee 00 00 00 24 16 01 02 3c 00 fb 34 9b \n",
+			want:   []byte{0xf2, 0x00, 0x00, 0xee, 0x00, 0x00, 0x00, 0x24, 0x16, 0x01, 0x02, 0x3c, 0x00, 0xfb, 0x34, 0x9b},
+		},
+		{
+			desc:   "value after other output",
+			output: "Characteristic value was written successfully\nCharacteristic value/descriptor: 64 15 33 2e 31 2e 38 \n",
+			want:   []byte{0x64, 0x15, 0x33, 0x2e, 0x31, 0x2e, 0x38},
+		},
+		{
+			desc:    "no value",
+			output:  "Characteristic value was written successfully\n",
+			wantErr: true,
+		},
+		{
+			desc:    "invalid value",
+			output:  "Characteristic value/descriptor: 6 15\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseValue(tc.output)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got value %x, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got value %x, want %x", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseScanLine(t *testing.T) {
+	tests := []struct {
+		desc      string
+		line      string
+		names     map[string]string
+		want      miflora.Advertisement
+		wantOK    bool
+		wantNames map[string]string
+	}{
+		{
+			desc:   "new device",
+			line:   "[NEW] Device C4:7C:8D:6A:3E:1F Flower care",
+			names:  map[string]string{},
+			want:   miflora.Advertisement{MacAddress: "C4:7C:8D:6A:3E:1F", Name: "Flower care"},
+			wantOK: true,
+			wantNames: map[string]string{
+				"C4:7C:8D:6A:3E:1F": "Flower care",
+			},
+		},
+		{
+			desc:   "changed RSSI",
+			line:   "[CHG] Device c4:7c:8d:6a:3e:1f RSSI: -72",
+			names:  map[string]string{"C4:7C:8D:6A:3E:1F": "Flower care"},
+			want:   miflora.Advertisement{MacAddress: "C4:7C:8D:6A:3E:1F", Name: "Flower care", RSSI: -72},
+			wantOK: true,
+		},
+		{
+			desc:   "changed RSSI in newer format",
+			line:   "[CHG] Device C4:7C:8D:6A:3E:1F RSSI: 0xffffffb8 (-72)",
+			names:  map[string]string{"C4:7C:8D:6A:3E:1F": "Flower mate"},
+			want:   miflora.Advertisement{MacAddress: "C4:7C:8D:6A:3E:1F", Name: "Flower mate", RSSI: -72},
+			wantOK: true,
+		},
+		{
+			desc:   "changed name",
+			line:   "[CHG] Device C4:7C:8D:6A:3E:1F Name: Flower care",
+			names:  map[string]string{},
+			want:   miflora.Advertisement{MacAddress: "C4:7C:8D:6A:3E:1F", Name: "Flower care"},
+			wantOK: true,
+			wantNames: map[string]string{
+				"C4:7C:8D:6A:3E:1F": "Flower care",
+			},
+		},
+		{
+			desc:  "RSSI of unknown device",
+			line:  "[CHG] Device C4:7C:8D:6A:3E:1F RSSI: -72",
+			names: map[string]string{},
+		},
+		{
+			desc:  "other device",
+			line:  "[NEW] Device 4C:65:A8:DC:0A:1B MJ_HT_V1",
+			names: map[string]string{},
+			wantNames: map[string]string{
+				"4C:65:A8:DC:0A:1B": "MJ_HT_V1",
+			},
+		},
+		{
+			desc:  "other property",
+			line:  "[CHG] Device C4:7C:8D:6A:3E:1F ManufacturerData Key: 0x0001",
+			names: map[string]string{"C4:7C:8D:6A:3E:1F": "Flower care"},
+		},
+		{
+			desc:  "no device",
+			line:  "Discovery started",
+			names: map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, ok := parseScanLine(tc.line, tc.names)
+			if ok != tc.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tc.wantOK)
+			}
+
+			if got != tc.want {
+				t.Errorf("got advertisement %#v, want %#v", got, tc.want)
+			}
+
+			for mac, want := range tc.wantNames {
+				if name := tc.names[mac]; name != want {
+					t.Errorf("got name %q for %s, want %q", name, mac, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStage(t *testing.T) {
+	tests := []struct {
+		desc string
+		err  error
+		want string
+	}{
+		{
+			desc: "connection failed",
+			err:  errors.New("gatttool failed: connect error: Connection refused (111)"),
+			want: miflora.StageDial,
+		},
+		{
+			desc: "connection timed out",
+			err:  errors.New("gatttool failed: exit status 1: connect: Function not implemented (38)"),
+			want: miflora.StageDial,
+		},
+		{
+			desc: "read failed",
+			err:  errors.New("gatttool failed: Characteristic value/descriptor read failed: Attribute can't be read"),
+			want: miflora.StageRead,
+		},
+		{
+			desc: "no value",
+			err:  errors.New("no value in output: "),
+			want: miflora.StageRead,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := stage(tc.err); got != tc.want {
+				t.Errorf("got stage %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+//go:build linux
+
+// Package bluezdbus reads sensors through the BlueZ daemon using its D-Bus API. Unlike the native backend it does not
+// open raw HCI sockets, so the exporter needs no capabilities, only access to the system bus.
+package bluezdbus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	bluezService  = "org.bluez"
+	adapterIface  = "org.bluez.Adapter1"
+	deviceIface   = "org.bluez.Device1"
+	gattCharIface = "org.bluez.GattCharacteristic1"
+
+	objectManagerIface = "org.freedesktop.DBus.ObjectManager"
+	propertiesIface    = "org.freedesktop.DBus.Properties"
+
+	modeUUID     = "00001a00-0000-1000-8000-00805f9b34fb"
+	sensorsUUID  = "00001a01-0000-1000-8000-00805f9b34fb"
+	firmwareUUID = "00001a02-0000-1000-8000-00805f9b34fb"
+
+	pollInterval = 200 * time.Millisecond
+)
+
+var modeValue = []byte{0xA0, 0x1F}
+
+// managedObjects is the result of GetManagedObjects: the properties of the interfaces of every object.
+type managedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// Reader reads sensors using the GATT characteristics exported by BlueZ.
+type Reader struct {
+	// Adapter is the name of the Bluetooth adapter, for example "hci0".
+	Adapter string
+	// SkipRealtimeMode disables enabling the realtime measurement before reading the sensor values.
+	SkipRealtimeMode bool
+	// Lenient accepts sensor data of unexpected lengths sent by clone devices.
+	Lenient bool
+	// Layouts contains the layouts of the sensor data of clone devices by their MAC address.
+	Layouts map[string]miflora.Layout
+}
+
+// ReadData implements updater.Reader
+func (r *Reader) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: miflora.StageDial,
+			Err:   fmt.Errorf("can not connect to system bus: %s", err),
+		}
+	}
+	defer conn.Close()
+
+	devicePath, err := r.findDevice(ctx, conn, macAddress)
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: miflora.StageDial,
+			Err:   err,
+		}
+	}
+
+	device := conn.Object(bluezService, devicePath)
+	if err := device.CallWithContext(ctx, deviceIface+".Connect", 0).Err; err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: miflora.StageDial,
+			Err:   fmt.Errorf("can not connect: %s", err),
+		}
+	}
+	defer device.Call(deviceIface+".Disconnect", 0)
+
+	chars, err := characteristics(ctx, conn, devicePath)
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: miflora.StageDial,
+			Err:   err,
+		}
+	}
+
+	firmwareRaw, err := readValue(ctx, conn, chars, firmwareUUID)
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: miflora.StageRead,
+			Err:   fmt.Errorf("error reading firmware info: %s", err),
+		}
+	}
+
+	if !r.SkipRealtimeMode {
+		if err := writeValue(ctx, conn, chars, modeUUID, modeValue); err != nil {
+			return miflora.Data{}, &miflora.ReadError{
+				Stage: miflora.StageRead,
+				Err:   fmt.Errorf("can not enable realtime reading: %s", err),
+			}
+		}
+	}
+
+	sensorsRaw, err := readValue(ctx, conn, chars, sensorsUUID)
+	if err != nil {
+		return miflora.Data{}, &miflora.ReadError{
+			Stage: miflora.StageRead,
+			Err:   fmt.Errorf("error reading sensor data: %s", err),
+		}
+	}
+
+	opts := miflora.ReadOptions{
+		Lenient: r.Lenient,
+	}
+	if layout, ok := r.Layouts[strings.ToUpper(macAddress)]; ok {
+		opts.Layout = &layout
+	}
+
+	return miflora.ParseRawData(miflora.RawData{
+		Firmware: firmwareRaw,
+		Sensors:  sensorsRaw,
+	}, opts)
+}
+
+func (r *Reader) adapterPath() dbus.ObjectPath {
+	return dbus.ObjectPath("/org/bluez/" + r.Adapter)
+}
+
+// findDevice returns the object path of the device. BlueZ only knows devices, which it has seen while discovering,
+// so discovery is started if the device is not known yet.
+func (r *Reader) findDevice(ctx context.Context, conn *dbus.Conn, macAddress string) (dbus.ObjectPath, error) {
+	path := dbus.ObjectPath(fmt.Sprintf("%s/dev_%s", r.adapterPath(), strings.ReplaceAll(strings.ToUpper(macAddress), ":", "_")))
+
+	known := func() (bool, error) {
+		objects, err := getManagedObjects(ctx, conn)
+		if err != nil {
+			return false, err
+		}
+
+		_, ok := objects[path][deviceIface]
+		return ok, nil
+	}
+
+	ok, err := known()
+	if err != nil || ok {
+		return path, err
+	}
+
+	adapter := conn.Object(bluezService, r.adapterPath())
+	stop, err := startDiscovery(ctx, adapter, false)
+	if err != nil {
+		return "", err
+	}
+	defer stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("device not found: %s", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+
+		ok, err := known()
+		if err != nil || ok {
+			return path, err
+		}
+	}
+}
+
+// characteristics waits until the services of the connected device are resolved and returns the object paths of its
+// characteristics by their UUID.
+func characteristics(ctx context.Context, conn *dbus.Conn, devicePath dbus.ObjectPath) (map[string]dbus.ObjectPath, error) {
+	device := conn.Object(bluezService, devicePath)
+	for {
+		resolved, err := device.GetProperty(deviceIface + ".ServicesResolved")
+		if err != nil {
+			return nil, fmt.Errorf("can not get device state: %s", err)
+		}
+
+		if done, ok := resolved.Value().(bool); ok && done {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("services not resolved: %s", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+
+	objects, err := getManagedObjects(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]dbus.ObjectPath{}
+	prefix := string(devicePath) + "/"
+	for path, ifaces := range objects {
+		props, ok := ifaces[gattCharIface]
+		if !ok || !strings.HasPrefix(string(path), prefix) {
+			continue
+		}
+
+		if uuid, ok := props["UUID"].Value().(string); ok {
+			result[strings.ToLower(uuid)] = path
+		}
+	}
+
+	return result, nil
+}
+
+func readValue(ctx context.Context, conn *dbus.Conn, chars map[string]dbus.ObjectPath, uuid string) ([]byte, error) {
+	path, ok := chars[uuid]
+	if !ok {
+		return nil, fmt.Errorf("characteristic not found: %s", uuid)
+	}
+
+	var value []byte
+	err := conn.Object(bluezService, path).
+		CallWithContext(ctx, gattCharIface+".ReadValue", 0, map[string]dbus.Variant{}).
+		Store(&value)
+	return value, err
+}
+
+func writeValue(ctx context.Context, conn *dbus.Conn, chars map[string]dbus.ObjectPath, uuid string, value []byte) error {
+	path, ok := chars[uuid]
+	if !ok {
+		return fmt.Errorf("characteristic not found: %s", uuid)
+	}
+
+	return conn.Object(bluezService, path).
+		CallWithContext(ctx, gattCharIface+".WriteValue", 0, value, map[string]dbus.Variant{
+			"type": dbus.MakeVariant("request"),
+		}).Err
+}
+
+func getManagedObjects(ctx context.Context, conn *dbus.Conn) (managedObjects, error) {
+	var objects managedObjects
+	if err := conn.Object(bluezService, "/").CallWithContext(ctx, objectManagerIface+".GetManagedObjects", 0).Store(&objects); err != nil {
+		return nil, fmt.Errorf("can not list BlueZ objects: %s", err)
+	}
+
+	return objects, nil
+}
+
+// startDiscovery starts discovering LE devices. The returned function stops the discovery.
+func startDiscovery(ctx context.Context, adapter dbus.BusObject, duplicates bool) (func(), error) {
+	filter := map[string]dbus.Variant{
+		"Transport":     dbus.MakeVariant("le"),
+		"DuplicateData": dbus.MakeVariant(duplicates),
+	}
+	if err := adapter.CallWithContext(ctx, adapterIface+".SetDiscoveryFilter", 0, filter).Err; err != nil {
+		return nil, fmt.Errorf("can not set discovery filter: %s", err)
+	}
+
+	if err := adapter.CallWithContext(ctx, adapterIface+".StartDiscovery", 0).Err; err != nil {
+		return nil, fmt.Errorf("can not start discovery: %s", err)
+	}
+
+	return func() {
+		adapter.Call(adapterIface+".StopDiscovery", 0)
+	}, nil
+}
+
+// Scan implements updater.Scanner
+func (r *Reader) Scan(ctx context.Context, duration time.Duration, handler func(miflora.Advertisement)) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("can not connect to system bus: %s", err)
+	}
+	defer conn.Close()
+
+	adapterPath := r.adapterPath()
+	for _, member := range []string{"InterfacesAdded", "PropertiesChanged"} {
+		if err := conn.AddMatchSignalContext(ctx,
+			dbus.WithMatchSender(bluezService),
+			dbus.WithMatchMember(member),
+			dbus.WithMatchPathNamespace("/"),
+		); err != nil {
+			return fmt.Errorf("can not watch devices: %s", err)
+		}
+	}
+	signals := make(chan *dbus.Signal, 100)
+	conn.Signal(signals)
+
+	objects, err := getManagedObjects(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	devices := map[dbus.ObjectPath]miflora.Advertisement{}
+	for path, ifaces := range objects {
+		if props, ok := ifaces[deviceIface]; ok {
+			devices[path] = updateDevice(miflora.Advertisement{}, props)
+		}
+	}
+
+	stop, err := startDiscovery(ctx, conn.Object(bluezService, adapterPath), true)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			return nil
+		case s := <-signals:
+			path, props, ok := deviceChange(s)
+			if !ok || !strings.HasPrefix(string(path), string(adapterPath)+"/") {
+				continue
+			}
+
+			a := updateDevice(devices[path], props)
+			devices[path] = a
+			if _, changed := props["RSSI"]; changed && isFlowerCare(a) {
+				handler(a)
+			}
+		}
+	}
+}
+
+// deviceChange returns the changed properties of a device contained in the signal.
+func deviceChange(s *dbus.Signal) (dbus.ObjectPath, map[string]dbus.Variant, bool) {
+	switch s.Name {
+	case objectManagerIface + ".InterfacesAdded":
+		if len(s.Body) < 2 {
+			return "", nil, false
+		}
+
+		path, _ := s.Body[0].(dbus.ObjectPath)
+		ifaces, _ := s.Body[1].(map[string]map[string]dbus.Variant)
+		props, ok := ifaces[deviceIface]
+		return path, props, ok
+	case propertiesIface + ".PropertiesChanged":
+		if len(s.Body) < 2 {
+			return "", nil, false
+		}
+
+		if iface, _ := s.Body[0].(string); iface != deviceIface {
+			return "", nil, false
+		}
+
+		props, ok := s.Body[1].(map[string]dbus.Variant)
+		return s.Path, props, ok
+	}
+
+	return "", nil, false
+}
+
+// updateDevice updates the advertisement using the properties of the device.
+func updateDevice(a miflora.Advertisement, props map[string]dbus.Variant) miflora.Advertisement {
+	if address, ok := props["Address"].Value().(string); ok {
+		a.MacAddress = strings.ToUpper(address)
+	}
+
+	if name, ok := props["Name"].Value().(string); ok {
+		a.Name = name
+	}
+
+	if rssi, ok := props["RSSI"].Value().(int16); ok {
+		a.RSSI = int(rssi)
+	}
+
+	return a
+}
+
+func isFlowerCare(a miflora.Advertisement) bool {
+	switch strings.ToLower(a.Name) {
+	case "flower care", "flower mate":
+		return a.MacAddress != ""
+	}
+
+	return false
+}
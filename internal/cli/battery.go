@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
+)
+
+type batteryEntry struct {
+	Sensor  config.Sensor
+	Battery *int
+	Error   string
+}
+
+func runBatteryReport(ctx context.Context, env *Env, args []string) error {
+	flags := newFlagSet("battery-report")
+	threshold := flags.Int("threshold", 20, "Battery level in percent below which a sensor is flagged.")
+	exporter := flags.String("exporter", "", "URL of a running exporter to take the battery levels from, instead of reading the sensors.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var (
+		entries []batteryEntry
+		err     error
+	)
+	switch {
+	case *exporter != "":
+		if flags.NArg() > 0 {
+			return errors.New("sensors can not be specified when using --exporter")
+		}
+
+		entries, err = batteryFromExporter(ctx, *exporter)
+	case flags.NArg() > 0:
+		entries, err = batteryFromSensors(ctx, env, flags.Args())
+	default:
+		return errors.New("need to provide sensors or --exporter")
+	}
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Battery, entries[j].Battery
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		default:
+			return *a < *b
+		}
+	})
+
+	w := tabwriter.NewWriter(env.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tMAC\tBATTERY\tSTATUS")
+	for _, e := range entries {
+		battery := "-"
+		status := "OK"
+		switch {
+		case e.Battery == nil && e.Error != "":
+			status = "ERROR: " + e.Error
+		case e.Battery == nil:
+			status = "UNKNOWN"
+		case *e.Battery < *threshold:
+			battery = fmt.Sprintf("%d %%", *e.Battery)
+			status = "LOW"
+		default:
+			battery = fmt.Sprintf("%d %%", *e.Battery)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Sensor.Name, e.Sensor.MacAddress, battery, status)
+	}
+	return w.Flush()
+}
+
+func batteryFromSensors(ctx context.Context, env *Env, args []string) ([]batteryEntry, error) {
+	var sensors config.SensorList
+	for _, arg := range args {
+		if err := sensors.Set(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	device, err := env.Device()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]batteryEntry, 0, len(sensors))
+	for _, s := range sensors {
+		entry := batteryEntry{
+			Sensor: s,
+		}
+
+		readCtx, cancel := env.withTimeout(ctx)
+		data, err := miflora.ReadData(readCtx, env.Log.Slog(), device, s.MacAddress)
+		cancel()
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			battery := int(data.Firmware.Battery)
+			entry.Battery = &battery
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func batteryFromExporter(ctx context.Context, baseURL string) ([]batteryEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/v1/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not create request: %s", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can not get status from exporter: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exporter returned status %d", res.StatusCode)
+	}
+
+	var status updater.Status
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("can not parse status: %s", err)
+	}
+
+	entries := make([]batteryEntry, 0, len(status.Sensors))
+	for _, s := range status.Sensors {
+		entries = append(entries, batteryEntry{
+			Sensor: config.Sensor{
+				Name:       s.Name,
+				MacAddress: s.MacAddress,
+			},
+			Battery: s.Battery,
+			Error:   s.LastError,
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,112 @@
+// Package cli contains commands for interacting with sensors from the command line.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/spf13/pflag"
+	"github.com/xperimental/flowercare-exporter/internal/bluetooth"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+)
+
+// Command is a subcommand which can be run from the command line.
+type Command struct {
+	Name        string
+	Args        string
+	Description string
+	Run         func(ctx context.Context, env *Env, args []string) error
+}
+
+// Env contains the state shared by all commands.
+type Env struct {
+	Log     *logging.Logger
+	Out     io.Writer
+	Adapter string
+	Timeout time.Duration
+	// Location is used for showing and parsing times. Uses the local time zone if nil.
+	Location *time.Location
+
+	device ble.Device
+}
+
+// Device returns the Bluetooth device, opening it on first use.
+func (e *Env) Device() (ble.Device, error) {
+	if e.device != nil {
+		return e.device, nil
+	}
+
+	device, err := bluetooth.Open(e.Adapter)
+	if err != nil {
+		return nil, fmt.Errorf("can not open Bluetooth device %q: %s", e.Adapter, err)
+	}
+
+	e.device = device
+	return device, nil
+}
+
+// location returns the time zone used for showing and parsing times.
+func (e *Env) location() *time.Location {
+	if e.Location == nil {
+		return time.Local
+	}
+
+	return e.Location
+}
+
+// Close releases the Bluetooth device, if it has been opened.
+func (e *Env) Close() error {
+	if e.device == nil {
+		return nil
+	}
+
+	return bluetooth.Close(e.device)
+}
+
+// withTimeout returns a context limited by the configured timeout for a single operation.
+func (e *Env) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, e.Timeout)
+}
+
+// Find returns the command with the given name from the list.
+func Find(commands []Command, name string) (Command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return Command{}, false
+}
+
+// PrintUsage prints a list of the commands.
+func PrintUsage(out io.Writer, commands []Command) {
+	sorted := append([]Command{}, commands...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	fmt.Fprintln(out, "Commands:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, c := range sorted {
+		fmt.Fprintf(w, "  %s %s\t%s\n", c.Name, c.Args, c.Description)
+	}
+	w.Flush()
+}
+
+func newFlagSet(name string) *pflag.FlagSet {
+	return pflag.NewFlagSet(name, pflag.ContinueOnError)
+}
+
+func requireMacAddress(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("need exactly one MAC address, got %d arguments", len(args))
+	}
+
+	return args[0], nil
+}
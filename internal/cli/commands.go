@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// SensorCommands contains the commands for directly interacting with sensors.
+var SensorCommands = []Command{
+	{
+		Name:        "scan",
+		Args:        "[--duration d]",
+		Description: "Scan for Flower Care devices.",
+		Run:         runScan,
+	},
+	{
+		Name:        "read",
+		Args:        "[--json] [--lenient] <mac>",
+		Description: "Read the current data from a sensor.",
+		Run:         runRead,
+	},
+	{
+		Name:        "blink",
+		Args:        "<mac>",
+		Description: "Blink the LED of a sensor for identification.",
+		Run:         runBlink,
+	},
+	{
+		Name:        "diagnose",
+		Args:        "[--scan-duration d] [--lenient] <mac>",
+		Description: "Check the connectivity of a sensor step by step.",
+		Run:         runDiagnose,
+	},
+	{
+		Name:        "history",
+		Args:        "[show|export|clear] <mac>",
+		Description: "Read or clear the history records stored on a sensor.",
+		Run:         runHistory,
+	},
+	{
+		Name:        "battery-report",
+		Args:        "[flags] [[name=]mac...]",
+		Description: "Print the battery levels of sensors, flagging those which are low.",
+		Run:         runBatteryReport,
+	},
+	{
+		Name:        "set-time",
+		Args:        "<mac>",
+		Description: "Set the clock of a sensor to the current time.",
+		Run:         runSetTime,
+	},
+}
+
+func runScan(ctx context.Context, env *Env, args []string) error {
+	flags := newFlagSet("scan")
+	duration := flags.Duration("duration", 10*time.Second, "Duration of the scan.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	device, err := env.Device()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	w := tabwriter.NewWriter(env.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MAC\tRSSI\tNAME")
+
+	seen := map[string]bool{}
+	err = miflora.Scan(ctx, device, func(a miflora.Advertisement) {
+		if seen[a.MacAddress] {
+			return
+		}
+		seen[a.MacAddress] = true
+
+		fmt.Fprintf(w, "%s\t%d\t%s\n", a.MacAddress, a.RSSI, a.Name)
+	})
+	if err != nil {
+		return fmt.Errorf("error scanning: %s", err)
+	}
+
+	return w.Flush()
+}
+
+func runRead(ctx context.Context, env *Env, args []string) error {
+	flags := newFlagSet("read")
+	asJSON := flags.Bool("json", false, "Print the data as JSON.")
+	lenient := flags.Bool("lenient", false, "Accept sensor data of unexpected lengths as sent by some clone devices.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	macAddress, err := requireMacAddress(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	device, err := env.Device()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := env.withTimeout(ctx)
+	defer cancel()
+
+	data, err := miflora.ReadDataWithOptions(ctx, env.Log.Slog(), device, macAddress, miflora.ReadOptions{
+		Lenient: *lenient,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(env.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	}
+
+	fmt.Fprintln(env.Out, data)
+	if err := data.Validate(); err != nil {
+		fmt.Fprintf(env.Out, "Warning: implausible data: %s\n", err)
+	}
+	return nil
+}
+
+func runBlink(ctx context.Context, env *Env, args []string) error {
+	macAddress, err := requireMacAddress(args)
+	if err != nil {
+		return err
+	}
+
+	device, err := env.Device()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := env.withTimeout(ctx)
+	defer cancel()
+
+	if err := miflora.Blink(ctx, device, macAddress); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "Sent blink command to %s.\n", macAddress)
+	return nil
+}
+
+func runSetTime(ctx context.Context, env *Env, args []string) error {
+	macAddress, err := requireMacAddress(args)
+	if err != nil {
+		return err
+	}
+
+	device, err := env.Device()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := env.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	if err := miflora.SetTime(ctx, device, macAddress, now); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "Set time of %s to %s.\n", macAddress, now.In(env.location()).Format(time.RFC3339))
+	return nil
+}
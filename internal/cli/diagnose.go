@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/diagnose"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+func runDiagnose(ctx context.Context, env *Env, args []string) error {
+	flags := newFlagSet("diagnose")
+	scanDuration := flags.Duration("scan-duration", 10*time.Second, "Maximum duration for waiting for an advertisement of the sensor. Scanning is skipped if zero.")
+	lenient := flags.Bool("lenient", false, "Accept sensor data of unexpected lengths as sent by some clone devices.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	macAddress, err := requireMacAddress(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := env.withTimeout(ctx)
+	defer cancel()
+
+	report := diagnose.Run(ctx, env.Adapter, env.Device, macAddress, *scanDuration, miflora.ReadOptions{
+		Lenient: *lenient,
+	})
+
+	w := tabwriter.NewWriter(env.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tRESULT\tTIME\tDETAIL")
+	for _, s := range report.Steps {
+		result := "OK"
+		detail := s.Detail
+		if !s.OK {
+			result = "FAILED"
+			detail = s.Error
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, result, time.Duration(s.DurationSeconds*float64(time.Second)).Round(time.Millisecond), detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range report.Steps {
+		if s.Hint != "" {
+			fmt.Fprintf(env.Out, "\nHint for %q: %s\n", s.Name, s.Hint)
+		}
+	}
+
+	if !report.OK {
+		return errors.New("diagnosis found problems")
+	}
+	return nil
+}
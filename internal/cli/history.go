@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+var historyCommands = []Command{
+	{
+		Name:        "show",
+		Args:        "<mac>",
+		Description: "Print the history records stored on a sensor.",
+		Run:         runHistoryShow,
+	},
+	{
+		Name:        "export",
+		Args:        "[--from t] [--to t] [--format csv|json] [--output file] <mac>",
+		Description: "Write the history records stored on a sensor to a file.",
+		Run:         runHistoryExport,
+	},
+	{
+		Name:        "clear",
+		Args:        "--yes <mac>",
+		Description: "Delete all history records stored on a sensor.",
+		Run:         runHistoryClear,
+	},
+}
+
+type exportRecord struct {
+	Time         time.Time `json:"time"`
+	Temperature  float64   `json:"temperature"`
+	Moisture     byte      `json:"moisture"`
+	Light        uint32    `json:"light"`
+	Conductivity uint16    `json:"conductivity"`
+}
+
+// runHistory dispatches to the history subcommands. Without a subcommand the records are shown.
+func runHistory(ctx context.Context, env *Env, args []string) error {
+	if len(args) > 0 {
+		if cmd, ok := Find(historyCommands, args[0]); ok {
+			return cmd.Run(ctx, env, args[1:])
+		}
+	}
+
+	return runHistoryShow(ctx, env, args)
+}
+
+func runHistoryShow(ctx context.Context, env *Env, args []string) error {
+	macAddress, err := requireMacAddress(args)
+	if err != nil {
+		return err
+	}
+
+	records, err := readHistory(ctx, env, macAddress)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(env.Out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "TIME\tTEMPERATURE\tMOISTURE\tLIGHT\tCONDUCTIVITY\t")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%.1f\t%d\t%d\t%d\t\n", r.Time.In(env.location()).Format(time.RFC3339), r.Temperature, r.Moisture, r.Light, r.Conductivity)
+	}
+	return w.Flush()
+}
+
+func runHistoryExport(ctx context.Context, env *Env, args []string) error {
+	flags := newFlagSet("history export")
+	from := flags.String("from", "", "Only export records at or after this time (RFC 3339 or YYYY-MM-DD).")
+	to := flags.String("to", "", "Only export records before this time (RFC 3339 or YYYY-MM-DD).")
+	format := flags.String("format", "csv", "Output format, csv or json.")
+	output := flags.StringP("output", "o", "", "File to write to. Uses standard output if empty.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	macAddress, err := requireMacAddress(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	fromTime, err := parseTimeFlag(*from, env.location())
+	if err != nil {
+		return fmt.Errorf("can not parse --from: %s", err)
+	}
+
+	toTime, err := parseTimeFlag(*to, env.location())
+	if err != nil {
+		return fmt.Errorf("can not parse --to: %s", err)
+	}
+
+	var write func(io.Writer, []exportRecord) error
+	switch *format {
+	case "csv":
+		write = writeCSV
+	case "json":
+		write = writeJSON
+	default:
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	records, err := readHistory(ctx, env, macAddress)
+	if err != nil {
+		return err
+	}
+
+	result := []exportRecord{}
+	for _, r := range records {
+		if !fromTime.IsZero() && r.Time.Before(fromTime) {
+			continue
+		}
+
+		if !toTime.IsZero() && !r.Time.Before(toTime) {
+			continue
+		}
+
+		r.Time = r.Time.In(env.location())
+		result = append(result, exportRecord(r))
+	}
+
+	out := env.Out
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("can not create output file: %s", err)
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	if err := write(out, result); err != nil {
+		return fmt.Errorf("can not write records: %s", err)
+	}
+
+	if *output != "" {
+		fmt.Fprintf(env.Out, "Exported %d records to %s.\n", len(result), *output)
+	}
+	return nil
+}
+
+func runHistoryClear(ctx context.Context, env *Env, args []string) error {
+	flags := newFlagSet("history clear")
+	confirm := flags.Bool("yes", false, "Confirm that the history records should be deleted.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	macAddress, err := requireMacAddress(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	if !*confirm {
+		return errors.New("clearing the history can not be undone, confirm using --yes")
+	}
+
+	device, err := env.Device()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := env.withTimeout(ctx)
+	defer cancel()
+
+	if err := miflora.ClearHistory(ctx, device, macAddress); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.Out, "Cleared history of %s.\n", macAddress)
+	return nil
+}
+
+func readHistory(ctx context.Context, env *Env, macAddress string) ([]miflora.HistoryRecord, error) {
+	device, err := env.Device()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := env.withTimeout(ctx)
+	defer cancel()
+
+	return miflora.ReadHistory(ctx, device, macAddress)
+}
+
+func parseTimeFlag(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC 3339 time or YYYY-MM-DD date")
+	}
+	return t, nil
+}
+
+func writeCSV(w io.Writer, records []exportRecord) error {
+	c := csv.NewWriter(w)
+	if err := c.Write([]string{"time", "temperature", "moisture", "light", "conductivity"}); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		err := c.Write([]string{
+			r.Time.Format(time.RFC3339),
+			strconv.FormatFloat(r.Temperature, 'f', 1, 64),
+			strconv.Itoa(int(r.Moisture)),
+			strconv.FormatUint(uint64(r.Light), 10),
+			strconv.Itoa(int(r.Conductivity)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+func writeJSON(w io.Writer, records []exportRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
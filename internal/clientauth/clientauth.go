@@ -0,0 +1,208 @@
+// Package clientauth contains TLS and authentication settings for connecting to other systems, which can be shared
+// between integrations.
+package clientauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Profile contains the TLS and authentication settings for connecting to another system. Secrets are read either from
+// a file or from an environment variable.
+type Profile struct {
+	CAFile             string `json:"caFile,omitempty"`
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+	BearerTokenFile    string `json:"bearerTokenFile,omitempty"`
+	BearerTokenEnv     string `json:"bearerTokenEnv,omitempty"`
+	Username           string `json:"username,omitempty"`
+	PasswordFile       string `json:"passwordFile,omitempty"`
+	PasswordEnv        string `json:"passwordEnv,omitempty"`
+}
+
+// Profiles contains named profiles.
+type Profiles map[string]Profile
+
+// LoadFile reads the profiles from a JSON file containing an object with the profile names as keys.
+func LoadFile(fileName string) (Profiles, error) {
+	raw, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles Profiles
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return nil, fmt.Errorf("can not parse auth file %q: %s", fileName, err)
+	}
+
+	for name, p := range profiles {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("auth profile %q: %s", name, err)
+		}
+	}
+
+	return profiles, nil
+}
+
+// Validate checks the settings for consistency.
+func (p Profile) Validate() error {
+	if (p.CertFile == "") != (p.KeyFile == "") {
+		return errors.New("need to provide both certificate and key")
+	}
+
+	if p.BearerTokenFile != "" && p.BearerTokenEnv != "" {
+		return errors.New("bearer token file and environment variable can not be used at the same time")
+	}
+
+	if p.PasswordFile != "" && p.PasswordEnv != "" {
+		return errors.New("password file and environment variable can not be used at the same time")
+	}
+
+	if p.HasBearerToken() && p.Username != "" {
+		return errors.New("bearer token and username can not be used at the same time")
+	}
+
+	if (p.PasswordFile != "" || p.PasswordEnv != "") && p.Username == "" {
+		return errors.New("password needs a username")
+	}
+
+	return nil
+}
+
+// HasBearerToken returns true if a bearer token file or environment variable is set.
+func (p Profile) HasBearerToken() bool {
+	return p.BearerTokenFile != "" || p.BearerTokenEnv != ""
+}
+
+// TLSEnabled returns true if any TLS setting is set.
+func (p Profile) TLSEnabled() bool {
+	return p.CAFile != "" || p.CertFile != "" || p.InsecureSkipVerify
+}
+
+// TLSConfig returns the TLS configuration of the profile or nil if no TLS setting is set.
+func (p Profile) TLSConfig() (*tls.Config, error) {
+	if !p.TLSEnabled() {
+		return nil, nil
+	}
+
+	result := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: p.InsecureSkipVerify,
+	}
+
+	if p.CAFile != "" {
+		caPEM, err := os.ReadFile(p.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("can not read CA: %s", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %q", p.CAFile)
+		}
+		result.RootCAs = pool
+	}
+
+	if p.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can not load client certificate: %s", err)
+		}
+		result.Certificates = []tls.Certificate{cert}
+	}
+
+	return result, nil
+}
+
+// Password returns the password from the file or environment variable or an empty string if neither is set.
+func (p Profile) Password() (string, error) {
+	return ReadSecret(p.PasswordFile, p.PasswordEnv, "password")
+}
+
+// BearerToken returns the bearer token from the file or environment variable or an empty string if neither is set.
+func (p Profile) BearerToken() (string, error) {
+	return ReadSecret(p.BearerTokenFile, p.BearerTokenEnv, "bearer token")
+}
+
+// HTTPClient returns a HTTP client, which uses the TLS settings and adds the credentials to every request.
+func (p Profile) HTTPClient() (*http.Client, error) {
+	tlsConfig, err := p.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	password, err := p.Password()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.BearerToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &authTransport{
+			next:     transport,
+			username: p.Username,
+			password: password,
+			token:    token,
+		},
+	}, nil
+}
+
+// ReadSecret returns the secret from the environment variable, if it is set, or from the file. It returns an empty
+// string if neither is set. The name of the secret is used in errors.
+func ReadSecret(fileName, envName, name string) (string, error) {
+	if envName != "" {
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			return "", fmt.Errorf("environment variable for %s not set: %s", name, envName)
+		}
+
+		return strings.TrimSpace(value), nil
+	}
+
+	if fileName == "" {
+		return "", nil
+	}
+
+	raw, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", fmt.Errorf("can not read %s: %s", name, err)
+	}
+
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// authTransport adds basic authentication or a bearer token to the requests.
+type authTransport struct {
+	next     http.RoundTripper
+	username string
+	password string
+	token    string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case t.token != "":
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	case t.username != "":
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -1,12 +1,15 @@
 package collector
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/sirupsen/logrus"
 	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
 	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
 )
 
 const (
@@ -23,6 +26,18 @@ var (
 		"name",
 	}
 
+	sensorConfiguredDesc = prometheus.NewDesc(
+		MetricPrefix+"sensor_configured",
+		"Contains one entry for every configured sensor. Value set to 1.",
+		varLabelNames, nil)
+	sensorUnconfiguredSeenDesc = prometheus.NewDesc(
+		MetricPrefix+"sensor_unconfigured_seen",
+		"Contains one entry for every Flower Care device seen while scanning, which is not configured. Value set to 1.",
+		[]string{"macaddress"}, nil)
+	sensorRemovedDesc = prometheus.NewDesc(
+		MetricPrefix+"sensor_removed",
+		"Contains one entry for every removed sensor, whose last data is still exported. Value set to 1.",
+		varLabelNames, nil)
 	upDesc = prometheus.NewDesc(
 		MetricPrefix+"up",
 		"Shows if data could be successfully retrieved by the collector.",
@@ -31,10 +46,18 @@ var (
 		MetricPrefix+"updated_timestamp",
 		"Contains the timestamp when the last communication with the Bluetooth device happened.",
 		varLabelNames, nil)
+	staleDesc = prometheus.NewDesc(
+		MetricPrefix+"stale",
+		"Set to 1 if the last data of the sensor is older than the stale duration, 0 otherwise.",
+		varLabelNames, nil)
 	infoDesc = prometheus.NewDesc(
 		MetricPrefix+"info",
 		"Contains information about the Flower Care device.",
 		append(varLabelNames, "version"), nil)
+	readInfoDesc = prometheus.NewDesc(
+		MetricPrefix+"read_info",
+		"Contains metadata about the last successful read of the sensor. Value set to 1.",
+		append(varLabelNames, "adapter", "source", "retries"), nil)
 	batteryDesc = prometheus.NewDesc(
 		MetricPrefix+"battery_percent",
 		"Battery level in percent.",
@@ -59,17 +82,29 @@ var (
 
 // Flowercare implements a Prometheus collector that emits metrics of a Miflora sensor.
 type Flowercare struct {
-	Log           logrus.FieldLogger
-	Source        func(macAddress string) (miflora.Data, error)
-	Sensors       []config.Sensor
+	Log     *logging.Logger
+	Source  func(macAddress string) (miflora.Data, error)
+	Sensors func() []config.Sensor
+	// StaleDuration is the age after which data is marked as stale.
 	StaleDuration time.Duration
+	// ForgetDuration is the age after which data is not exported anymore and the sensor is reported as down.
+	ForgetDuration time.Duration
+	ReadInfo       func(macAddress string) (updater.ReadInfo, bool)
+	Unconfigured   func() []updater.Sighting
+	// Removed is optional. If set, the sensors it returns are exported flagged as removed.
+	Removed func() []config.Sensor
 }
 
 // Describe implements prometheus.Collector
 func (c *Flowercare) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sensorConfiguredDesc
+	ch <- sensorUnconfiguredSeenDesc
+	ch <- sensorRemovedDesc
 	ch <- upDesc
 	ch <- updatedTimestampDesc
+	ch <- staleDesc
 	ch <- infoDesc
+	ch <- readInfoDesc
 	ch <- batteryDesc
 	ch <- conductivityDesc
 	ch <- lightDesc
@@ -79,37 +114,107 @@ func (c *Flowercare) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements prometheus.Collector
 func (c *Flowercare) Collect(ch chan<- prometheus.Metric) {
-	for _, s := range c.Sensors {
-		c.collectSensor(ch, s)
+	c.collect(ch, time.Time{})
+}
+
+// collect emits the metrics of the sensors. If the deadline is not zero, sensors whose data is not available before
+// the deadline are skipped.
+func (c *Flowercare) collect(ch chan<- prometheus.Metric, deadline time.Time) {
+	sensors := c.Sensors()
+	for i, s := range sensors {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			c.Log.Warnf("Scrape time budget exceeded, skipping %d sensors.", len(sensors)-i)
+			break
+		}
+
+		c.sendMetric(ch, sensorConfiguredDesc, 1, []string{s.MacAddress, s.Name})
+		c.collectSensor(ch, s, deadline)
+	}
+
+	if c.Removed != nil {
+		for _, s := range c.Removed() {
+			c.sendMetric(ch, sensorRemovedDesc, 1, []string{s.MacAddress, s.Name})
+			c.collectSensor(ch, s, deadline)
+		}
+	}
+
+	if c.Unconfigured != nil {
+		for _, s := range c.Unconfigured() {
+			c.sendMetric(ch, sensorUnconfiguredSeenDesc, 1, []string{s.MacAddress})
+		}
 	}
 }
 
-func (c *Flowercare) collectSensor(ch chan<- prometheus.Metric, s config.Sensor) {
+func (c *Flowercare) collectSensor(ch chan<- prometheus.Metric, s config.Sensor, deadline time.Time) {
 	labels := []string{
 		s.MacAddress,
 		s.Name,
 	}
 
-	data, err := c.Source(s.MacAddress)
+	data, err := c.getData(s.MacAddress, deadline)
 	if err != nil {
 		c.Log.Errorf("Error getting data for %q: %s", s, err)
 		c.sendMetric(ch, upDesc, 0, labels)
 
 		return
 	}
-	c.sendMetric(ch, upDesc, 1, labels)
-	c.sendMetric(ch, updatedTimestampDesc, float64(data.Time.Unix()), labels)
-	c.sendMetric(ch, infoDesc, 1, append(labels, data.Firmware.Version))
 
 	age := time.Since(data.Time)
+	if age >= c.ForgetDuration {
+		c.Log.Debugf("Data for %q is too old: %s > %s", s, age, c.ForgetDuration)
+		c.sendMetric(ch, upDesc, 0, labels)
+
+		return
+	}
+
+	stale := 0.0
 	if age >= c.StaleDuration {
 		c.Log.Debugf("Data for %q is stale: %s > %s", s, age, c.StaleDuration)
-		return
+		stale = 1
+	}
+
+	c.sendMetric(ch, upDesc, 1, labels)
+	c.sendMetric(ch, updatedTimestampDesc, float64(data.Time.Unix()), labels)
+	c.sendMetric(ch, staleDesc, stale, labels)
+	c.sendMetric(ch, infoDesc, 1, append(labels, data.Firmware.Version))
+	if c.ReadInfo != nil {
+		if info, ok := c.ReadInfo(s.MacAddress); ok {
+			c.sendMetric(ch, readInfoDesc, 1, append(labels, info.Adapter, info.Source, strconv.Itoa(info.Retries)))
+		}
 	}
 
 	c.collectData(ch, data, labels)
 }
 
+type sourceResult struct {
+	data miflora.Data
+	err  error
+}
+
+// getData gets the data of the sensor from the source. If the deadline is not zero, it stops waiting for the source
+// once the deadline has passed.
+func (c *Flowercare) getData(macAddress string, deadline time.Time) (miflora.Data, error) {
+	if deadline.IsZero() {
+		return c.Source(macAddress)
+	}
+
+	resultCh := make(chan sourceResult, 1)
+	go func() {
+		data, err := c.Source(macAddress)
+		resultCh <- sourceResult{data, err}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case r := <-resultCh:
+		return r.data, r.err
+	case <-timer.C:
+		return miflora.Data{}, errors.New("data not available within scrape time budget")
+	}
+}
+
 func (c *Flowercare) collectData(ch chan<- prometheus.Metric, data miflora.Data, labels []string) {
 	for _, metric := range []struct {
 		Desc  *prometheus.Desc
@@ -136,6 +241,10 @@ func (c *Flowercare) collectData(ch chan<- prometheus.Metric, data miflora.Data,
 			Value: data.Sensors.Temperature,
 		},
 	} {
+		if metric.Desc == batteryDesc && !data.Firmware.Known() {
+			continue
+		}
+
 		c.sendMetric(ch, metric.Desc, metric.Value, labels)
 	}
 }
@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// GatewayLabel is the label added to the metrics of federated exporters.
+	GatewayLabel = "gateway"
+
+	federationAccept = "text/plain;version=0.0.4"
+)
+
+// Federation implements a Prometheus gatherer, which scrapes the metrics of other exporters and returns them with an
+// added gateway label. Only metrics of this exporter are used. Exporters which can not be scraped are reported using
+// the flowercare_federation_up metric.
+type Federation struct {
+	Log      *logging.Logger
+	Gateways []config.Gateway
+	Timeout  time.Duration
+	Client   *http.Client
+}
+
+type gatewayResult struct {
+	families map[string]*dto.MetricFamily
+	err      error
+}
+
+// Gather implements prometheus.Gatherer
+func (f *Federation) Gather() ([]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.Timeout)
+	defer cancel()
+
+	results := make([]gatewayResult, len(f.Gateways))
+	wg := &sync.WaitGroup{}
+	for i, g := range f.Gateways {
+		wg.Add(1)
+		go func(i int, g config.Gateway) {
+			defer wg.Done()
+
+			families, err := f.scrape(ctx, g)
+			results[i] = gatewayResult{families, err}
+		}(i, g)
+	}
+	wg.Wait()
+
+	byName := map[string]*dto.MetricFamily{}
+	up := &dto.MetricFamily{
+		Name: proto.String(MetricPrefix + "federation_up"),
+		Help: proto.String("Shows if the metrics of the federated exporter could be retrieved."),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	for i, g := range f.Gateways {
+		r := results[i]
+
+		value := 1.0
+		if r.err != nil {
+			f.Log.Errorf("Error scraping gateway %q: %s", g.Name, r.err)
+			value = 0
+		}
+		up.Metric = append(up.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{
+					Name:  proto.String(GatewayLabel),
+					Value: proto.String(g.Name),
+				},
+			},
+			Gauge: &dto.Gauge{
+				Value: proto.Float64(value),
+			},
+		})
+
+		for name, family := range r.families {
+			existing, ok := byName[name]
+			if !ok {
+				byName[name] = family
+				continue
+			}
+
+			if existing.GetType() != family.GetType() {
+				f.Log.Warnf("Metric %q of gateway %q has type %s instead of %s, skipping.", name, g.Name, family.GetType(), existing.GetType())
+				continue
+			}
+			existing.Metric = append(existing.Metric, family.Metric...)
+		}
+	}
+	byName[up.GetName()] = up
+
+	result := make([]*dto.MetricFamily, 0, len(byName))
+	for _, family := range byName {
+		result = append(result, family)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+
+	return result, nil
+}
+
+func (f *Federation) scrape(ctx context.Context, g config.Gateway) (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not create request: %s", err)
+	}
+	req.Header.Set("Accept", federationAccept)
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse metrics: %s", err)
+	}
+
+	gatewayLabels := map[string]string{
+		GatewayLabel: g.Name,
+	}
+	gatewayNames := []string{GatewayLabel}
+	for name, family := range families {
+		if !strings.HasPrefix(name, MetricPrefix) || name == MetricPrefix+"federation_up" {
+			delete(families, name)
+			continue
+		}
+
+		for _, m := range family.Metric {
+			m.Label = mergeLabels(m.Label, gatewayNames, gatewayLabels)
+			m.TimestampMs = nil
+		}
+	}
+
+	return families, nil
+}
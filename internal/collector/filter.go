@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+// Filter removes metrics using keep and drop rules. If keep rules are set, only metrics matching at least one of them
+// are kept. Afterwards, metrics matching any drop rule are removed.
+type Filter struct {
+	Keep config.MetricRules
+	Drop config.MetricRules
+}
+
+// Enabled returns true if the filter has any rules.
+func (f Filter) Enabled() bool {
+	return len(f.Keep) > 0 || len(f.Drop) > 0
+}
+
+// Gatherer wraps the gatherer, so that the returned metrics are filtered.
+func (f Filter) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	if !f.Enabled() {
+		return g
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+
+		result := make([]*dto.MetricFamily, 0, len(families))
+		for _, family := range families {
+			metrics := make([]*dto.Metric, 0, len(family.Metric))
+			for _, m := range family.Metric {
+				if f.keep(family.GetName(), m) {
+					metrics = append(metrics, m)
+				}
+			}
+
+			if len(metrics) == 0 {
+				continue
+			}
+			family.Metric = metrics
+			result = append(result, family)
+		}
+
+		return result, err
+	})
+}
+
+func (f Filter) keep(name string, m *dto.Metric) bool {
+	if len(f.Keep) > 0 && !matchesAny(f.Keep, name, m) {
+		return false
+	}
+
+	return !matchesAny(f.Drop, name, m)
+}
+
+func matchesAny(rules config.MetricRules, name string, m *dto.Metric) bool {
+	for _, rule := range rules {
+		if matches(rule, name, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matches(rule config.MetricRule, name string, m *dto.Metric) bool {
+	if !rule.Metric.MatchString(name) {
+		return false
+	}
+
+	if rule.Sensor == nil {
+		return true
+	}
+
+	for _, label := range m.GetLabel() {
+		switch label.GetName() {
+		case "macaddress", "name":
+			if rule.Sensor.MatchString(label.GetValue()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
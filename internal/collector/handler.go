@@ -0,0 +1,164 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	scrapeTimeoutHeader  = "X-Prometheus-Scrape-Timeout-Seconds"
+	defaultScrapeTimeout = 10 * time.Second
+)
+
+// GroupSensors is the name of the metric group containing the metrics of the Flowercare collector.
+const GroupSensors = "sensors"
+
+// HandlerConfig contains the settings of the metrics handler.
+type HandlerConfig struct {
+	// ScrapeOffset is subtracted from the scrape timeout sent by Prometheus for the time budget of the collector.
+	ScrapeOffset time.Duration
+	// Filter is applied to the metrics of all groups.
+	Filter Filter
+	// Labels are added to all metrics, which do not already have a label with the same name.
+	Labels map[string]string
+	Opts   promhttp.HandlerOpts
+}
+
+// Handler serves the metrics of the collector together with the metrics of the named groups. Using the collect[] URL
+// parameter, the response can be limited to some of the groups. All groups are returned if it is not set.
+//
+// The collector is limited to the scrape timeout sent by Prometheus, so that a slow collection results in incomplete
+// metrics instead of a failed scrape.
+func Handler(c *Flowercare, groups map[string]prometheus.Gatherer, cfg HandlerConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		selected := r.URL.Query()["collect[]"]
+		if len(selected) == 0 {
+			selected = append([]string{GroupSensors}, groupNames(groups)...)
+		}
+
+		var gatherers prometheus.Gatherers
+		seen := map[string]bool{}
+		for _, name := range selected {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if name == GroupSensors {
+				registry := prometheus.NewRegistry()
+				if err := registry.Register(&budgetCollector{c, time.Now().Add(scrapeTimeout(r) - cfg.ScrapeOffset)}); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				gatherers = append(gatherers, registry)
+				continue
+			}
+
+			gatherer, ok := groups[name]
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown metric group: %s", name), http.StatusBadRequest)
+				return
+			}
+			gatherers = append(gatherers, gatherer)
+		}
+
+		gatherer := cfg.Filter.Gatherer(addLabels(gatherers, cfg.Labels))
+		promhttp.HandlerFor(gatherer, cfg.Opts).ServeHTTP(w, r)
+	})
+}
+
+func groupNames(groups map[string]prometheus.Gatherer) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func scrapeTimeout(r *http.Request) time.Duration {
+	header := r.Header.Get(scrapeTimeoutHeader)
+	if header == "" {
+		return defaultScrapeTimeout
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return defaultScrapeTimeout
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// budgetCollector collects the metrics of the Flowercare collector until the deadline.
+type budgetCollector struct {
+	collector *Flowercare
+	deadline  time.Time
+}
+
+// Describe implements prometheus.Collector
+func (b *budgetCollector) Describe(ch chan<- *prometheus.Desc) {
+	b.collector.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (b *budgetCollector) Collect(ch chan<- prometheus.Metric) {
+	b.collector.collect(ch, b.deadline)
+}
+
+// addLabels wraps the gatherer, so that the labels are added to all metrics.
+func addLabels(g prometheus.Gatherer, labels map[string]string) prometheus.Gatherer {
+	if len(labels) == 0 {
+		return g
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, family := range families {
+			for _, m := range family.Metric {
+				m.Label = mergeLabels(m.Label, names, labels)
+			}
+		}
+
+		return families, err
+	})
+}
+
+func mergeLabels(existing []*dto.LabelPair, names []string, labels map[string]string) []*dto.LabelPair {
+	present := map[string]bool{}
+	for _, l := range existing {
+		present[l.GetName()] = true
+	}
+
+	for _, name := range names {
+		if present[name] {
+			continue
+		}
+
+		existing = append(existing, &dto.LabelPair{
+			Name:  proto.String(name),
+			Value: proto.String(labels[name]),
+		})
+	}
+
+	sort.Slice(existing, func(i, j int) bool {
+		return existing[i].GetName() < existing[j].GetName()
+	})
+	return existing
+}
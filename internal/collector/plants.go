@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/xperimental/flowercare-exporter/internal/alert"
+)
+
+var plantInfoDesc = prometheus.NewDesc(
+	MetricPrefix+"plant_info",
+	"Contains the metadata and thresholds of the plants from the plants file. Value set to 1.",
+	[]string{
+		"macaddress",
+		"name",
+		"species",
+		"location",
+		"pot_size",
+		"moisture_min",
+		"moisture_max",
+		"temperature_min",
+		"temperature_max",
+		"light_min",
+		"light_max",
+		"conductivity_min",
+		"conductivity_max",
+	}, nil)
+
+// PlantInfo implements a Prometheus collector that emits the configured metadata of the plants. The thresholds are the
+// ones currently active in the time zone of the location.
+type PlantInfo struct {
+	Plants   []alert.Plant
+	Location *time.Location
+}
+
+// Describe implements prometheus.Collector
+func (c *PlantInfo) Describe(ch chan<- *prometheus.Desc) {
+	ch <- plantInfoDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *PlantInfo) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now().In(c.Location)
+	for _, p := range c.Plants {
+		t := p.ThresholdsAt(now)
+		ch <- prometheus.MustNewConstMetric(plantInfoDesc, prometheus.GaugeValue, 1,
+			p.MacAddress,
+			p.Name,
+			p.Species,
+			p.Location,
+			p.PotSize,
+			formatLimit(t.Moisture.Min),
+			formatLimit(t.Moisture.Max),
+			formatLimit(t.Temperature.Min),
+			formatLimit(t.Temperature.Max),
+			formatLimit(t.Light.Min),
+			formatLimit(t.Light.Max),
+			formatLimit(t.Conductivity.Min),
+			formatLimit(t.Conductivity.Max),
+		)
+	}
+}
+
+var silencedDesc = prometheus.NewDesc(
+	MetricPrefix+"alert_silenced",
+	"Contains the active alert silences. The labels are empty if the silence matches all plants or rules. Value set to 1.",
+	[]string{
+		"macaddress",
+		"rule",
+		"id",
+	}, nil)
+
+// Silences implements a Prometheus collector that emits the active alert silences.
+type Silences struct {
+	Silences *alert.Silences
+}
+
+// Describe implements prometheus.Collector
+func (c *Silences) Describe(ch chan<- *prometheus.Desc) {
+	ch <- silencedDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Silences) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, s := range c.Silences.Active(now) {
+		if now.Before(s.StartsAt) {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(silencedDesc, prometheus.GaugeValue, 1, s.MacAddress, s.Rule, s.ID)
+	}
+}
+
+func formatLimit(limit *float64) string {
+	if limit == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*limit, 'f', -1, 64)
+}
@@ -3,11 +3,22 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata"
 
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/internal/tracing"
+	"github.com/xperimental/flowercare-exporter/internal/web"
+	"github.com/xperimental/flowercare-exporter/pkg/updater"
 )
 
 type SensorList []Sensor
@@ -38,19 +49,276 @@ func (s *SensorList) Set(value string) error {
 	return nil
 }
 
-type Sensor struct {
-	Name       string
-	MacAddress string
+// Replacement replaces the device of a sensor with a new one.
+type Replacement struct {
+	Old string
+	New string
 }
 
-func (s Sensor) String() string {
-	if s.Name == "" {
-		return s.MacAddress
+func (r Replacement) String() string {
+	return fmt.Sprintf("%s=%s", r.Old, r.New)
+}
+
+// Replacements is a list of replacements, which can be set using the syntax "old=new".
+type Replacements []Replacement
+
+func (r *Replacements) String() string {
+	if len(*r) == 0 {
+		return ""
 	}
 
-	return fmt.Sprintf("%s (%s)", s.Name, s.MacAddress)
+	replacements := []string{}
+	for _, replacement := range *r {
+		replacements = append(replacements, replacement.String())
+	}
+	return fmt.Sprintf("%s", replacements)
+}
+
+func (r *Replacements) Type() string {
+	return "old=new"
+}
+
+func (r *Replacements) Set(value string) error {
+	tokens := strings.SplitN(value, "=", 2)
+	if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+		return fmt.Errorf("replacement needs to have the format old=new: %s", value)
+	}
+
+	if strings.EqualFold(tokens[0], tokens[1]) {
+		return fmt.Errorf("sensor can not replace itself: %s", value)
+	}
+
+	*r = append(*r, Replacement{
+		Old: strings.ToUpper(tokens[0]),
+		New: strings.ToUpper(tokens[1]),
+	})
+	return nil
 }
 
+// MetricRule selects metrics by their name and optionally by the sensor. Both are anchored regular expressions and the
+// sensor expression is matched against the MAC address and the name of the sensor.
+type MetricRule struct {
+	Metric *regexp.Regexp
+	Sensor *regexp.Regexp
+	raw    string
+}
+
+func (r MetricRule) String() string {
+	return r.raw
+}
+
+// MetricRules is a list of rules, which can be set using the syntax "metric[@sensor]".
+type MetricRules []MetricRule
+
+func (r *MetricRules) String() string {
+	if len(*r) == 0 {
+		return ""
+	}
+
+	rules := []string{}
+	for _, rule := range *r {
+		rules = append(rules, rule.String())
+	}
+	return fmt.Sprintf("%s", rules)
+}
+
+func (r *MetricRules) Type() string {
+	return "rule"
+}
+
+func (r *MetricRules) Set(value string) error {
+	metric, sensor, hasSensor := strings.Cut(value, "@")
+
+	rule := MetricRule{
+		raw: value,
+	}
+
+	var err error
+	rule.Metric, err = regexp.Compile("^(?:" + metric + ")$")
+	if err != nil {
+		return fmt.Errorf("can not parse metric expression: %s", err)
+	}
+
+	if hasSensor {
+		rule.Sensor, err = regexp.Compile("^(?:" + sensor + ")$")
+		if err != nil {
+			return fmt.Errorf("can not parse sensor expression: %s", err)
+		}
+	}
+
+	*r = append(*r, rule)
+	return nil
+}
+
+// ModelMap assigns device models to sensors, which can be set using the syntax "mac=model".
+type ModelMap map[string]string
+
+func (m *ModelMap) String() string {
+	if len(*m) == 0 {
+		return ""
+	}
+
+	models := []string{}
+	for mac, model := range *m {
+		models = append(models, mac+"="+model)
+	}
+	sort.Strings(models)
+	return fmt.Sprintf("%s", models)
+}
+
+func (m *ModelMap) Type() string {
+	return "mac=model"
+}
+
+func (m *ModelMap) Set(value string) error {
+	mac, model, ok := strings.Cut(value, "=")
+	if !ok || mac == "" || model == "" {
+		return fmt.Errorf("expected mac=model: %s", value)
+	}
+
+	if *m == nil {
+		*m = ModelMap{}
+	}
+	(*m)[strings.ToUpper(mac)] = model
+	return nil
+}
+
+// ModeMap assigns operating modes to single sensors, which can be set using the syntax "mac=mode".
+type ModeMap map[string]string
+
+func (m *ModeMap) String() string {
+	if len(*m) == 0 {
+		return ""
+	}
+
+	modes := []string{}
+	for mac, mode := range *m {
+		modes = append(modes, mac+"="+mode)
+	}
+	sort.Strings(modes)
+	return fmt.Sprintf("%s", modes)
+}
+
+func (m *ModeMap) Type() string {
+	return "mac=mode"
+}
+
+func (m *ModeMap) Set(value string) error {
+	mac, mode, ok := strings.Cut(value, "=")
+	if !ok || mac == "" || mode == "" {
+		return fmt.Errorf("expected mac=mode: %s", value)
+	}
+
+	switch mode {
+	case ModeActive, ModePassive:
+	default:
+		return fmt.Errorf("sensor mode needs to be %s or %s: %s", ModeActive, ModePassive, mode)
+	}
+
+	if *m == nil {
+		*m = ModeMap{}
+	}
+	(*m)[strings.ToUpper(mac)] = mode
+	return nil
+}
+
+// DeadbandMap contains the deadbands of the measurements, which can be set using the syntax "measurement=value".
+type DeadbandMap map[string]float64
+
+func (m *DeadbandMap) String() string {
+	if len(*m) == 0 {
+		return ""
+	}
+
+	deadbands := []string{}
+	for name, value := range *m {
+		deadbands = append(deadbands, name+"="+strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	sort.Strings(deadbands)
+	return fmt.Sprintf("%s", deadbands)
+}
+
+func (m *DeadbandMap) Type() string {
+	return "measurement=value"
+}
+
+func (m *DeadbandMap) Set(value string) error {
+	name, rawValue, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected measurement=value: %s", value)
+	}
+
+	deadband, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil {
+		return fmt.Errorf("can not parse deadband %q: %s", rawValue, err)
+	}
+
+	if deadband < 0 {
+		return fmt.Errorf("deadband can not be negative: %s", value)
+	}
+
+	if *m == nil {
+		*m = DeadbandMap{}
+	}
+	(*m)[name] = deadband
+	return nil
+}
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LabelMap contains labels, which can be set using the syntax "name=value". References to environment variables like
+// ${SITE} in the value are replaced when the label is set.
+type LabelMap map[string]string
+
+func (l *LabelMap) String() string {
+	if len(*l) == 0 {
+		return ""
+	}
+
+	labels := []string{}
+	for name, value := range *l {
+		labels = append(labels, name+"="+value)
+	}
+	sort.Strings(labels)
+	return fmt.Sprintf("%s", labels)
+}
+
+func (l *LabelMap) Type() string {
+	return "label"
+}
+
+func (l *LabelMap) Set(value string) error {
+	name, rawValue, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected name=value: %s", value)
+	}
+
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid label name: %s", name)
+	}
+
+	var missing []string
+	expanded := os.Expand(rawValue, func(key string) string {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			missing = append(missing, key)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+
+	if *l == nil {
+		*l = LabelMap{}
+	}
+	(*l)[name] = expanded
+	return nil
+}
+
+// Sensor identifies a sensor by its MAC address.
+type Sensor = updater.Sensor
+
 func parseSensor(value string) (Sensor, error) {
 	if len(value) == 0 {
 		return Sensor{}, errors.New("empty string")
@@ -69,18 +337,79 @@ func parseSensor(value string) (Sensor, error) {
 	}, nil
 }
 
-type LogLevel logrus.Level
+// Gateway is another exporter, whose metrics are federated.
+type Gateway struct {
+	Name string
+	URL  string
+}
+
+func (g Gateway) String() string {
+	return fmt.Sprintf("%s=%s", g.Name, g.URL)
+}
+
+// GatewayList is a list of gateways, which can be set using the syntax "name=url". The path defaults to /metrics.
+type GatewayList []Gateway
+
+func (g *GatewayList) String() string {
+	if len(*g) == 0 {
+		return ""
+	}
+
+	gateways := []string{}
+	for _, gateway := range *g {
+		gateways = append(gateways, gateway.String())
+	}
+	return fmt.Sprintf("%s", gateways)
+}
+
+func (g *GatewayList) Type() string {
+	return "gateway"
+}
+
+func (g *GatewayList) Set(value string) error {
+	name, rawURL, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=url: %s", value)
+	}
+
+	for _, gateway := range *g {
+		if gateway.Name == name {
+			return fmt.Errorf("duplicate gateway name: %s", name)
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("can not parse gateway URL: %s", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("gateway URL needs to use http or https: %s", rawURL)
+	}
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/metrics"
+	}
+
+	*g = append(*g, Gateway{
+		Name: name,
+		URL:  u.String(),
+	})
+	return nil
+}
+
+type LogLevel slog.Level
 
 func (l *LogLevel) Type() string {
 	return "level"
 }
 
 func (l *LogLevel) String() string {
-	return fmt.Sprintf("%s", logrus.Level(*l))
+	return logging.LevelName(slog.Level(*l))
 }
 
 func (l *LogLevel) Set(val string) error {
-	level, err := logrus.ParseLevel(val)
+	level, err := logging.ParseLevel(val)
 	if err != nil {
 		return err
 	}
@@ -89,77 +418,1259 @@ func (l *LogLevel) Set(val string) error {
 	return nil
 }
 
+// LoadLocation returns the time zone with the name or the local time zone if the name is empty. The time zone database
+// is embedded, so that time zones can be used on systems without one.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %s", err)
+	}
+
+	return location, nil
+}
+
+// QuietHours is a daily time range during which the sensors are not read.
+type QuietHours = updater.QuietHours
+
+// Supported Bluetooth backends.
+const (
+	BackendHCI  = "hci"
+	BackendExec = "exec"
+	BackendDBus = "dbus"
+)
+
+// Supported operating modes.
+const (
+	// ModeActive connects to the sensors for reading all values.
+	ModeActive = "active"
+	// ModeCombined additionally uses the values broadcast by the sensors while scanning.
+	ModeCombined = "combined"
+	// ModePassive only uses the values broadcast by the sensors without connecting to them.
+	ModePassive = "passive"
+)
+
+// PowerProfileBatterySaver is the power profile which reduces the usage of the sensor batteries.
+const PowerProfileBatterySaver = "battery-saver"
+
 type Config struct {
-	LogLevel        LogLevel
-	ListenAddr      string
-	Sensors         SensorList
-	Device          string
-	RefreshDuration time.Duration
-	RefreshTimeout  time.Duration
-	StaleDuration   time.Duration
-	Retry           RetryConfig
+	ConfigFile       string
+	LogLevel         LogLevel
+	LogFormat        string
+	Timezone         string
+	Location         *time.Location
+	ListenAddr       string
+	AdminAddr        string
+	EnableAdminAPI   bool
+	TLS              web.TLSConfig
+	AllowList        web.AllowList
+	Sensors          SensorList
+	Replacements     Replacements
+	Simulate         int
+	Benchmark        BenchmarkConfig
+	ReplayFile       string
+	ReplaySpeed      float64
+	RecordFile       string
+	Device           string
+	Backend          string
+	Mode             string
+	AdapterLockFile  string
+	RefreshDuration  time.Duration
+	RefreshTimeout   time.Duration
+	WarmUpDuration   time.Duration
+	ReadCooldown     time.Duration
+	PowerProfile     string
+	FirmwareInterval time.Duration
+	NameInterval     time.Duration
+	LenientParsing   bool
+	LayoutsFile      string
+	SensorModels     ModelMap
+	SensorModes      ModeMap
+	SkipRealtimeMode bool
+	ScanBeforeRead   time.Duration
+	MinRSSI          int
+	QuietHours       QuietHours
+	WatchdogTimeout  time.Duration
+	ScanInterval     time.Duration
+	ScanDuration     time.Duration
+	Discover         bool
+	AutoRegister     AutoRegisterConfig
+	RegistryFile     string
+	StaleDuration    time.Duration
+	ScrapeOffset     time.Duration
+	KeepMetrics      MetricRules
+	Labels           LabelMap
+	Federation       FederationConfig
+	DropMetrics      MetricRules
+	ForgetDuration   time.Duration
+	RemovalGrace     time.Duration
+	Retry            RetryConfig
+	ReadBudget       ReadBudgetConfig
+	Backfill         BackfillConfig
+	Health           HealthConfig
+	Tracing          tracing.Config
+	AuthFile         string
+	AuthProfiles     clientauth.Profiles
+	Postgres         PostgresConfig
+	Redis            RedisConfig
+	HomeAssistant    HomeAssistantConfig
+	OpenHAB          OpenHABConfig
+	Domoticz         DomoticzConfig
+	ThingSpeak       ThingSpeakConfig
+	MQTT             MQTTConfig
+	AWSIoT           AWSIoTConfig
+	AzureIoT         AzureIoTConfig
+	Webhook          WebhookConfig
+	Loki             LokiConfig
+	RemoteWrite      RemoteWriteConfig
+	HistoryFile      string
+	HistoryDays      int
+	HistoryHourly    int
+	HistoryFlush     time.Duration
+	ModbusAddr       string
+	ModbusMapFile    string
+	SinkJournalDir   string
+	SinkDeadLetter   string
+	SinkDeadband     SinkDeadbandConfig
+	PlantsFile       string
+	SilencesFile     string
+	SpeciesFiles     []string
+	Email            EmailConfig
+	Slack            ChatConfig
+	Discord          ChatConfig
+}
+
+// BenchmarkConfig contains the settings of the scheduler benchmark. The benchmark is run instead of the exporter if
+// the number of sensors is set.
+type BenchmarkConfig struct {
+	Sensors      int
+	Duration     time.Duration
+	ReadDuration time.Duration
+	FailureRate  float64
+}
+
+// FederationConfig contains the other exporters, whose metrics are returned together with the local metrics.
+type FederationConfig struct {
+	Gateways GatewayList
+	Timeout  time.Duration
+	Auth     string
+}
+
+// FederationOnly returns true if no local sensors are configured and only the metrics of other exporters are returned.
+func (c Config) FederationOnly() bool {
+	return len(c.Federation.Gateways) > 0 && len(c.Sensors) == 0 && c.Simulate == 0 && c.ReplayFile == "" && !c.AutoRegister.Enabled
+}
+
+// UseBeacons returns true if the values broadcast by the sensors are used in addition to reading them.
+func (c Config) UseBeacons() bool {
+	return c.Mode == ModeCombined
+}
+
+// PassiveSensors returns the addresses of the sensors with their own mode. The value is true if the sensor is only
+// read from its advertisements.
+func (c Config) PassiveSensors() map[string]bool {
+	result := map[string]bool{}
+	for mac, mode := range c.SensorModes {
+		result[mac] = mode == ModePassive
+	}
+	return result
+}
+
+// AllPassive returns true if all sensors are only read from their advertisements.
+func (c Config) AllPassive() bool {
+	return c.Mode == ModePassive
+}
+
+// broadcastMode returns the mode, which uses the values broadcast by the sensors, or an empty string if none is used.
+func (c Config) broadcastMode() string {
+	if c.Mode != ModeActive {
+		return c.Mode
+	}
+
+	for _, passive := range c.PassiveSensors() {
+		if passive {
+			return ModePassive
+		}
+	}
+	return ""
 }
 
-type RetryConfig struct {
-	MinDuration time.Duration
-	MaxDuration time.Duration
-	Factor      float64
+// PostgresConfig contains the settings for writing readings to PostgreSQL.
+type PostgresConfig struct {
+	URL string
+	// URLFile and URLEnv are alternatives to URL, which keep a password contained in the URL out of the flags.
+	URLFile   string
+	URLEnv    string
+	Table     string
+	Timescale bool
+	Auth      string
+	Batch     SinkBatchConfig
+	Retry     SinkRetryConfig
 }
 
-func Parse(log logrus.FieldLogger) (Config, error) {
+// RedisConfig contains the settings for publishing readings to Redis.
+type RedisConfig struct {
+	URL string
+	// URLFile and URLEnv are alternatives to URL, which keep a password contained in the URL out of the flags.
+	URLFile   string
+	URLEnv    string
+	KeyPrefix string
+	TTL       time.Duration
+	Channel   string
+	Auth      string
+	Batch     SinkBatchConfig
+	Retry     SinkRetryConfig
+}
+
+// HomeAssistantConfig contains the settings for setting the states of entities in Home Assistant.
+type HomeAssistantConfig struct {
+	URL       string
+	TokenFile string
+	TokenEnv  string
+	Auth      string
+	Batch     SinkBatchConfig
+	Retry     SinkRetryConfig
+}
+
+// OpenHABConfig contains the settings for updating items in openHAB.
+type OpenHABConfig struct {
+	URL        string
+	ItemPrefix string
+	Auth       string
+	Batch      SinkBatchConfig
+	Retry      SinkRetryConfig
+}
+
+// DomoticzConfig contains the settings for updating devices in Domoticz.
+type DomoticzConfig struct {
+	URL   string
+	Auth  string
+	Batch SinkBatchConfig
+	Retry SinkRetryConfig
+}
+
+// ThingSpeakConfig contains the settings for pushing readings to ThingSpeak channels.
+type ThingSpeakConfig struct {
+	URL          string
+	ChannelsFile string
+	Auth         string
+	Batch        SinkBatchConfig
+	Retry        SinkRetryConfig
+}
+
+// MQTTConfig contains the settings for publishing readings to an MQTT broker.
+type MQTTConfig struct {
+	Broker   string
+	ClientID string
+	// Topic is a template for the topic of the readings of a sensor.
+	Topic string
+	// Payload is optional. If set, it is a template for the payload of the messages.
+	Payload string
+	// PerMeasurement publishes every measurement of a reading in a separate message.
+	PerMeasurement bool
+	Retain         bool
+	// Availability is the topic the online state of the exporter is published to.
+	Availability string
+	// Discovery enables publishing the Home Assistant discovery messages of the sensors.
+	Discovery       bool
+	DiscoveryPrefix string
+	Auth            string
+	Batch           SinkBatchConfig
+	Retry           SinkRetryConfig
+}
+
+// AWSIoTConfig contains the settings for publishing readings to AWS IoT Core.
+type AWSIoTConfig struct {
+	Endpoint    string
+	ClientID    string
+	ThingPrefix string
+	Shadow      bool
+	Auth        string
+	Batch       SinkBatchConfig
+	Retry       SinkRetryConfig
+}
+
+// AzureIoTConfig contains the settings for publishing readings to Azure IoT Hub.
+type AzureIoTConfig struct {
+	ConnectionFile string
+	Twin           bool
+	Auth           string
+	Batch          SinkBatchConfig
+	Retry          SinkRetryConfig
+}
+
+// WebhookConfig contains the settings for posting readings to a webhook.
+type WebhookConfig struct {
+	URL        string
+	BodyFile   string
+	Every      int
+	SecretFile string
+	SecretEnv  string
+	Auth       string
+	Batch      SinkBatchConfig
+	Retry      SinkRetryConfig
+}
+
+// LokiConfig contains the settings for sending log lines about readings and alerts to Loki.
+type LokiConfig struct {
+	URL      string
+	Tenant   string
+	Readings bool
+	Alerts   bool
+	Auth     string
+	Batch    SinkBatchConfig
+	Retry    SinkRetryConfig
+}
+
+// RemoteWriteConfig contains the settings for pushing the history records read for filling gaps to a Prometheus
+// remote-write endpoint.
+type RemoteWriteConfig struct {
+	URL string
+	// Job and Instance are added as labels, so that the samples belong to the same series as the scraped metrics.
+	Job      string
+	Instance string
+	Auth     string
+	Batch    SinkBatchConfig
+	Retry    SinkRetryConfig
+}
+
+// SinkBatchConfig controls how readings are combined into batches before being written to a sink. A batch is written
+// once it reaches the size or the flush interval has passed.
+type SinkBatchConfig struct {
+	Size          int
+	FlushInterval time.Duration
+}
+
+func (c SinkBatchConfig) validate(name string) error {
+	if c.Size < 1 {
+		return fmt.Errorf("batch size of %s needs to be at least one: %d", name, c.Size)
+	}
+
+	if c.FlushInterval <= 0 {
+		return fmt.Errorf("flush interval of %s needs to be positive: %s", name, c.FlushInterval)
+	}
+
+	return nil
+}
+
+// SinkDeadbandConfig contains the settings for skipping readings, which did not change much since the last published
+// reading of the sensor.
+type SinkDeadbandConfig struct {
+	// Deadbands contains the minimum change of a measurement by its key. Measurements without a deadband are published
+	// on any change.
+	Deadbands DeadbandMap
+	// MaxInterval is the maximum time between two published readings of a sensor. Disabled if zero.
+	MaxInterval time.Duration
+}
+
+// Enabled returns true if a deadband is set.
+func (c SinkDeadbandConfig) Enabled() bool {
+	return len(c.Deadbands) > 0
+}
+
+// SinkRetryConfig is the retry policy for readings, which could not be written to a sink.
+type SinkRetryConfig struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAge is the age of a reading after which it is not retried anymore, but moved to the dead-letter file.
+	MaxAge time.Duration
+}
+
+// Backoff returns the wait time before the next attempt after the given number of consecutive failures.
+func (c SinkRetryConfig) Backoff(failures int) time.Duration {
+	backoff := c.MinBackoff
+	for i := 1; i < failures && backoff < c.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > c.MaxBackoff {
+		return c.MaxBackoff
+	}
+	return backoff
+}
+
+func (c SinkRetryConfig) validate(name string) error {
+	if c.MinBackoff <= 0 {
+		return fmt.Errorf("minimum retry backoff of %s needs to be positive: %s", name, c.MinBackoff)
+	}
+
+	if c.MaxBackoff < c.MinBackoff {
+		return fmt.Errorf("maximum retry backoff of %s needs to be larger or equal to minimum backoff: %s < %s", name, c.MaxBackoff, c.MinBackoff)
+	}
+
+	if c.MaxAge < 0 {
+		return fmt.Errorf("maximum retry age of %s can not be negative: %s", name, c.MaxAge)
+	}
+
+	return nil
+}
+
+// EmailConfig contains the settings for sending alerts by email.
+type EmailConfig struct {
+	SMTPAddr        string
+	Username        string
+	PasswordFile    string
+	PasswordEnv     string
+	From            string
+	To              []string
+	SubjectTemplate string
+	BodyFile        string
+}
+
+// ChatConfig contains the settings for posting alerts to the webhook of a chat service.
+type ChatConfig struct {
+	WebhookURL      string
+	MessageTemplate string
+}
+
+// The settings of the updater are defined in its package, so that they can be used outside of the exporter.
+type (
+	AutoRegisterConfig = updater.AutoRegisterConfig
+	ReadBudgetConfig   = updater.ReadBudgetConfig
+	BackfillConfig     = updater.BackfillConfig
+	HealthConfig       = updater.HealthConfig
+	RetryConfig        = updater.RetryConfig
+)
+
+var (
+	defaultSinkBatch = SinkBatchConfig{
+		Size:          100,
+		FlushInterval: 10 * time.Second,
+	}
+	defaultSinkRetry = SinkRetryConfig{
+		MinBackoff: 10 * time.Second,
+		MaxBackoff: 10 * time.Minute,
+		MaxAge:     24 * time.Hour,
+	}
+)
+
+func Parse(log *logging.Logger) (Config, error) {
 	result := Config{
-		LogLevel:        LogLevel(logrus.InfoLevel),
+		LogLevel:        LogLevel(slog.LevelInfo),
+		LogFormat:       string(logging.FormatText),
+		HistoryDays:     90,
+		HistoryHourly:   7,
+		HistoryFlush:    5 * time.Minute,
 		ListenAddr:      ":9294",
 		Device:          "hci0",
+		Backend:         BackendHCI,
+		Mode:            ModeActive,
+		ReplaySpeed:     1,
 		RefreshDuration: 2 * time.Minute,
-		RefreshTimeout:  time.Minute,
+		RefreshTimeout:  updater.DefaultRefreshTimeout,
 		StaleDuration:   5 * time.Minute,
+		RemovalGrace:    15 * time.Minute,
+		NameInterval:    24 * time.Hour,
+		ScrapeOffset:    500 * time.Millisecond,
+		ScanDuration:    updater.DefaultScanDuration,
+		Backfill: BackfillConfig{
+			MaxAge:  24 * time.Hour,
+			Timeout: 2 * time.Minute,
+		},
+		Benchmark: BenchmarkConfig{
+			Duration:     24 * time.Hour,
+			ReadDuration: 5 * time.Second,
+			FailureRate:  0.05,
+		},
+		Federation: FederationConfig{
+			Timeout: 10 * time.Second,
+		},
+		Postgres: PostgresConfig{
+			Table: "flowercare_readings",
+			Batch: defaultSinkBatch,
+			Retry: defaultSinkRetry,
+		},
+		Redis: RedisConfig{
+			KeyPrefix: "flowercare:",
+			TTL:       10 * time.Minute,
+			Batch:     defaultSinkBatch,
+			Retry:     defaultSinkRetry,
+		},
+		HomeAssistant: HomeAssistantConfig{
+			Batch: defaultSinkBatch,
+			Retry: defaultSinkRetry,
+		},
+		OpenHAB: OpenHABConfig{
+			ItemPrefix: "Flowercare_",
+			Batch:      defaultSinkBatch,
+			Retry:      defaultSinkRetry,
+		},
+		Domoticz: DomoticzConfig{
+			Batch: defaultSinkBatch,
+			Retry: defaultSinkRetry,
+		},
+		ThingSpeak: ThingSpeakConfig{
+			URL: "https://api.thingspeak.com",
+			// The free plan allows one update every 15 seconds per channel.
+			Batch: SinkBatchConfig{
+				Size:          100,
+				FlushInterval: 5 * time.Minute,
+			},
+			Retry: defaultSinkRetry,
+		},
+		MQTT: MQTTConfig{
+			ClientID:        "flowercare-exporter",
+			Topic:           "flowercare/{{ .Name }}/state",
+			Availability:    "flowercare/status",
+			DiscoveryPrefix: "homeassistant",
+			Batch:           defaultSinkBatch,
+			Retry:           defaultSinkRetry,
+		},
+		AWSIoT: AWSIoTConfig{
+			ClientID:    "flowercare-exporter",
+			ThingPrefix: "flowercare-",
+			Shadow:      true,
+			Batch:       defaultSinkBatch,
+			Retry:       defaultSinkRetry,
+		},
+		AzureIoT: AzureIoTConfig{
+			Twin:  true,
+			Batch: defaultSinkBatch,
+			Retry: defaultSinkRetry,
+		},
+		Webhook: WebhookConfig{
+			Every: 1,
+			Batch: defaultSinkBatch,
+			Retry: defaultSinkRetry,
+		},
+		Loki: LokiConfig{
+			Readings: true,
+			Alerts:   true,
+			Batch:    defaultSinkBatch,
+			Retry:    defaultSinkRetry,
+		},
+		RemoteWrite: RemoteWriteConfig{
+			Job:   "flowercare",
+			Batch: defaultSinkBatch,
+			Retry: defaultSinkRetry,
+		},
+		Email: EmailConfig{
+			SubjectTemplate: `{{ if .Resolved }}[RESOLVED]{{ else }}[ALERT]{{ end }} {{ .Plant.Name }}: {{ .Summary }}`,
+		},
+		Slack: ChatConfig{
+			MessageTemplate: `{{ if .Resolved }}:white_check_mark:{{ else }}:warning:{{ end }} *{{ .Plant.Name }}*: {{ .Summary }}`,
+		},
+		Discord: ChatConfig{
+			MessageTemplate: `{{ if .Resolved }}:white_check_mark:{{ else }}:warning:{{ end }} **{{ .Plant.Name }}**: {{ .Summary }}`,
+		},
+		ReadBudget: ReadBudgetConfig{
+			OnDemandPerMinute: 6,
+		},
+		Health: HealthConfig{
+			DegradedAfter: updater.DefaultDegradedAfter,
+			RecoverAfter:  updater.DefaultRecoverAfter,
+		},
 		Retry: RetryConfig{
-			MinDuration: 30 * time.Second,
-			MaxDuration: 30 * time.Minute,
-			Factor:      2,
+			MinDuration:  updater.DefaultRetryMinDuration,
+			MaxDuration:  updater.DefaultRetryMaxDuration,
+			Factor:       updater.DefaultRetryFactor,
+			DownInterval: updater.DefaultDownInterval,
 		},
 	}
 
+	pflag.StringVar(&result.ConfigFile, "config", result.ConfigFile, "YAML file containing options using the flag names as keys. Flags set on the command line take precedence.")
 	pflag.Var(&result.LogLevel, "log-level", "Minimum log level to show.")
-	pflag.StringVarP(&result.ListenAddr, "addr", "a", result.ListenAddr, "Address to listen on for connections.")
-	pflag.VarP(&result.Sensors, "sensor", "s", "MAC-address of sensor to collect data from. Can be specified multiple times.")
-	pflag.StringVarP(&result.Device, "adapter", "i", result.Device, "Bluetooth device to use for communication.")
-	pflag.DurationVarP(&result.RefreshDuration, "refresh-duration", "r", result.RefreshDuration, "Interval used for refreshing data from bluetooth devices.")
-	pflag.DurationVar(&result.RefreshTimeout, "refresh-timeout", result.RefreshTimeout, "Timeout for reading data from a sensor.")
-	pflag.DurationVar(&result.StaleDuration, "stale-duration", result.StaleDuration, "Duration after which data is considered stale and is not used for metrics anymore.")
-	pflag.DurationVar(&result.Retry.MinDuration, "retry-min-duration", result.Retry.MinDuration, "Minimum wait time between retries on error.")
-	pflag.DurationVar(&result.Retry.MaxDuration, "retry-max-duration", result.Retry.MaxDuration, "Maximum wait time between retries on error.")
-	pflag.Float64Var(&result.Retry.Factor, "retry-factor", result.Retry.Factor, "Factor used to multiply wait time for subsequent retries.")
+	pflag.StringVar(&result.LogFormat, "log-format", result.LogFormat, fmt.Sprintf("Format of the log messages. One of %s.", logging.Formats))
+	pflag.IntVar(&result.Simulate, "simulate", result.Simulate, "Number of simulated sensors to register. Enables simulation mode, which does not use Bluetooth at all.")
+	pflag.IntVar(&result.Benchmark.Sensors, "benchmark", result.Benchmark.Sensors, "Number of simulated sensors to run a benchmark of the scheduler with. The exporter exits after printing the results.")
+	pflag.DurationVar(&result.Benchmark.Duration, "benchmark-duration", result.Benchmark.Duration, "Duration simulated by the benchmark.")
+	pflag.DurationVar(&result.Benchmark.ReadDuration, "benchmark-read-duration", result.Benchmark.ReadDuration, "Duration of a simulated read in the benchmark.")
+	pflag.Float64Var(&result.Benchmark.FailureRate, "benchmark-failure-rate", result.Benchmark.FailureRate, "Share of simulated reads, which fail in the benchmark.")
+	pflag.StringVar(&result.ReplayFile, "replay-file", result.ReplayFile, "Recording file to replay instead of reading data using Bluetooth.")
+	pflag.Float64Var(&result.ReplaySpeed, "replay-speed", result.ReplaySpeed, "Factor used to accelerate the replay of a recording.")
+	pflag.StringVar(&result.RecordFile, "record-file", result.RecordFile, "File to append all readings including raw payloads to, for debugging or later replay.")
+	pflag.StringVar(&result.Tracing.Endpoint, "tracing-endpoint", result.Tracing.Endpoint, "OTLP/HTTP endpoint (host:port) to export traces to. Tracing is disabled if empty.")
+	pflag.BoolVar(&result.Tracing.Insecure, "tracing-insecure", result.Tracing.Insecure, "Use plain HTTP instead of HTTPS for exporting traces.")
+	pflag.StringVar(&result.AuthFile, "auth-file", result.AuthFile, "JSON file containing named TLS and authentication profiles, which can be referenced by the integrations.")
+	pflag.StringVar(&result.HistoryFile, "history-file", result.HistoryFile, "File for keeping the daily aggregates of the readings across restarts. Only kept in memory if empty.")
+	pflag.IntVar(&result.HistoryDays, "history-days", result.HistoryDays, "Number of days the daily aggregates of the readings are kept.")
+	pflag.IntVar(&result.HistoryHourly, "history-hourly-days", result.HistoryHourly, "Number of days the hourly aggregates of the readings are kept before they are compacted into daily aggregates.")
+	pflag.DurationVar(&result.HistoryFlush, "history-flush-interval", result.HistoryFlush, "Interval for saving changed aggregates to the history file. They are saved on shutdown as well.")
+	pflag.StringVar(&result.ModbusAddr, "modbus-addr", result.ModbusAddr, "Address to serve the latest readings on as Modbus TCP registers, for example :502. Disabled if empty.")
+	pflag.StringVar(&result.ModbusMapFile, "modbus-map-file", result.ModbusMapFile, "JSON file mapping sensors to Modbus register addresses.")
+	allowList := result.apiFlags()
+	result.updaterFlags()
+	result.sinkFlags()
+	result.alertFlags()
 	pflag.Parse()
 
-	if len(result.Sensors) == 0 {
-		return result, errors.New("need to provide at least one sensor")
+	if result.ConfigFile != "" {
+		if err := loadFile(pflag.CommandLine, result.ConfigFile); err != nil {
+			return result, err
+		}
+	}
+
+	if err := result.applyPowerProfile(pflag.CommandLine); err != nil {
+		return result, err
+	}
+
+	if err := result.validateAPI(*allowList); err != nil {
+		return result, err
 	}
 
-	if len(result.Device) == 0 {
-		return result, errors.New("need to provide a bluetooth device")
+	if err := result.loadAuthProfiles(); err != nil {
+		return result, err
 	}
 
-	if result.RefreshDuration < time.Minute {
-		log.Warnf("Refresh durations below one minute are discouraged: %s", result.RefreshDuration)
+	if err := result.validateSinks(); err != nil {
+		return result, err
 	}
 
-	if result.StaleDuration < (2 * result.RefreshDuration) {
-		return result, fmt.Errorf("stale duration needs to be at least %d", 2*result.RefreshDuration)
+	if err := result.validateAlerts(); err != nil {
+		return result, err
 	}
 
-	if result.Retry.MinDuration < 30*time.Second {
-		return result, fmt.Errorf("retry time needs to be at least thirty seconds: %s", result.Retry.MinDuration)
+	if result.HistoryDays < 1 {
+		return result, fmt.Errorf("history needs to be kept for at least one day: %d", result.HistoryDays)
 	}
 
-	if result.Retry.MaxDuration < result.Retry.MinDuration {
-		return result, fmt.Errorf("maximum retry time needs to be larger or equal to minimum time: %s > %s", result.Retry.MinDuration, result.Retry.MaxDuration)
+	if result.HistoryHourly < 1 {
+		return result, fmt.Errorf("hourly history needs to be kept for at least one day: %d", result.HistoryHourly)
 	}
 
-	if result.Retry.Factor < 1 {
-		return result, fmt.Errorf("retry factor needs to be equal or larger than one: %v", result.Retry.Factor)
+	if result.HistoryFlush <= 0 {
+		return result, fmt.Errorf("history flush interval needs to be positive: %s", result.HistoryFlush)
+	}
+
+	if result.ModbusAddr != "" && result.ModbusMapFile == "" {
+		return result, errors.New("need to provide a register map for Modbus")
+	}
+
+	if result.Simulate < 0 {
+		return result, fmt.Errorf("number of simulated sensors can not be negative: %d", result.Simulate)
+	}
+
+	if result.Simulate > 0 && result.ReplayFile != "" {
+		return result, errors.New("simulation and replay can not be used at the same time")
+	}
+
+	if result.ReplaySpeed <= 0 {
+		return result, fmt.Errorf("replay speed needs to be positive: %v", result.ReplaySpeed)
+	}
+
+	if result.Benchmark.Sensors < 0 {
+		return result, fmt.Errorf("number of benchmark sensors can not be negative: %d", result.Benchmark.Sensors)
+	}
+
+	if result.Benchmark.Sensors > 0 && (result.Benchmark.Duration <= 0 || result.Benchmark.ReadDuration < 0) {
+		return result, errors.New("benchmark duration needs to be positive and read duration can not be negative")
+	}
+
+	if result.Benchmark.FailureRate < 0 || result.Benchmark.FailureRate > 1 {
+		return result, fmt.Errorf("benchmark failure rate needs to be between 0 and 1: %v", result.Benchmark.FailureRate)
+	}
+
+	if err := result.validateUpdater(log); err != nil {
+		return result, err
 	}
 
 	return result, nil
 }
+
+// apiFlags registers the flags of the HTTP server and returns the allow list, which is parsed by validateAPI.
+func (c *Config) apiFlags() *[]string {
+	pflag.StringVar(&c.Timezone, "timezone", c.Timezone, "Time zone of the times returned by the API, for example Europe/Berlin. Uses the local time zone if empty. Metrics always use Unix timestamps.")
+	pflag.StringVarP(&c.ListenAddr, "addr", "a", c.ListenAddr, "Address to listen on for connections.")
+	pflag.StringVar(&c.AdminAddr, "admin-addr", c.AdminAddr, "Address to listen on for the admin and API endpoints. Uses the main address if empty.")
+	pflag.BoolVar(&c.EnableAdminAPI, "enable-admin-api", c.EnableAdminAPI, "Enable the API requests changing the state of the exporter or using the adapter on the main address. They are always enabled on the admin address.")
+	pflag.StringVar(&c.TLS.CertFile, "tls-cert-file", c.TLS.CertFile, "Certificate file for serving HTTPS.")
+	pflag.StringVar(&c.TLS.KeyFile, "tls-key-file", c.TLS.KeyFile, "Key file for serving HTTPS.")
+	pflag.StringVar(&c.TLS.ClientCAFile, "tls-client-ca-file", c.TLS.ClientCAFile, "CA certificates for verifying client certificates. Enables client certificate authentication.")
+	pflag.StringSliceVar(&c.TLS.AllowedCNs, "tls-client-allowed-cn", c.TLS.AllowedCNs, "Common name of client certificates which are allowed to connect. Can be specified multiple times. Allows all verified certificates if empty.")
+	pflag.DurationVar(&c.ScrapeOffset, "scrape-timeout-offset", c.ScrapeOffset, "Time subtracted from the scrape timeout sent by Prometheus for the time budget of collecting the sensor metrics.")
+	pflag.Var(&c.Labels, "label", "Label added to all metrics, for example site=${SITE_NAME}. Environment variables in the value are replaced on startup. Can be specified multiple times.")
+	pflag.Var(&c.Federation.Gateways, "federate", "Other exporter (name=url) whose sensor metrics are returned with an added gateway label. Can be specified multiple times.")
+	pflag.DurationVar(&c.Federation.Timeout, "federate-timeout", c.Federation.Timeout, "Timeout for retrieving the metrics of the other exporters.")
+	pflag.StringVar(&c.Federation.Auth, "federate-auth", c.Federation.Auth, "Name of the profile from the auth file used for connecting to the other exporters.")
+	pflag.Var(&c.KeepMetrics, "keep-metric", "Only return metrics matching one of these rules. Rules are regular expressions for the metric name, optionally followed by @ and a regular expression matching the name or MAC address of the sensor. Can be specified multiple times.")
+	pflag.Var(&c.DropMetrics, "drop-metric", "Do not return metrics matching this rule. Uses the same syntax as --keep-metric. Can be specified multiple times.")
+	return pflag.StringSlice("allow", nil, "IP address or CIDR network allowed to access the HTTP endpoints. Can be specified multiple times. Allows all clients if empty.")
+}
+
+// validateAPI checks the options of the HTTP server and parses the allow list and time zone.
+func (c *Config) validateAPI(allowList []string) error {
+	if c.AdminAddr != "" && c.AdminAddr == c.ListenAddr {
+		return fmt.Errorf("admin address needs to be different from main address: %s", c.AdminAddr)
+	}
+
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+
+	allowed, err := web.ParseAllowList(allowList)
+	if err != nil {
+		return err
+	}
+	c.AllowList = allowed
+
+	c.Location, err = LoadLocation(c.Timezone)
+	if err != nil {
+		return err
+	}
+
+	if c.ScrapeOffset < 0 {
+		return fmt.Errorf("scrape timeout offset can not be negative: %s", c.ScrapeOffset)
+	}
+
+	if len(c.Federation.Gateways) > 0 && c.Federation.Timeout <= 0 {
+		return fmt.Errorf("federation timeout needs to be positive: %s", c.Federation.Timeout)
+	}
+
+	return nil
+}
+
+// updaterFlags registers the flags for reading the sensors.
+func (c *Config) updaterFlags() {
+	pflag.VarP(&c.Sensors, "sensor", "s", "MAC-address of sensor to collect data from. Can be specified multiple times.")
+	pflag.Var(&c.Replacements, "replace-sensor", "Read a sensor from a new device, keeping the old MAC address in labels, alerts and history. Can be specified multiple times.")
+	pflag.StringVarP(&c.Device, "adapter", "i", c.Device, "Bluetooth device to use for communication.")
+	pflag.StringVar(&c.AdapterLockFile, "adapter-lock-file", c.AdapterLockFile, "File which is locked using flock while the adapter is used, for coordinating with other applications.")
+	pflag.StringVar(&c.Backend, "backend", c.Backend, "Bluetooth backend. Either hci for using the adapter directly, dbus for using the BlueZ daemon or exec for running the BlueZ tools gatttool and bluetoothctl.")
+	pflag.StringVar(&c.Mode, "mode", c.Mode, "Operating mode. Either active for connecting to the sensors, combined for additionally using the values broadcast by the sensors while scanning or passive for only using the broadcast values.")
+	pflag.Var(&c.SensorModes, "sensor-mode", "Operating mode of a single sensor. Either active or passive. Can be specified multiple times.")
+	pflag.DurationVarP(&c.RefreshDuration, "refresh-duration", "r", c.RefreshDuration, "Interval used for refreshing data from bluetooth devices.")
+	pflag.DurationVar(&c.WarmUpDuration, "warm-up-duration", c.WarmUpDuration, "Spread the first reads of the sensors after startup over this duration. All sensors are read right away if zero.")
+	pflag.DurationVar(&c.RefreshTimeout, "refresh-timeout", c.RefreshTimeout, "Timeout for reading data from a sensor.")
+	pflag.DurationVar(&c.ReadCooldown, "read-cooldown", c.ReadCooldown, "Minimum time between two consecutive connections on the adapter. Some adapters fail more often when connecting back-to-back.")
+	pflag.StringVar(&c.PowerProfile, "power-profile", c.PowerProfile, "Preset for reducing the battery usage of the sensors. Supported: battery-saver. Flags which are set explicitly take precedence.")
+	pflag.BoolVar(&c.LenientParsing, "lenient-parsing", c.LenientParsing, "Accept sensor data of unexpected lengths as sent by some clone devices, as long as it contains the known fields.")
+	pflag.StringVar(&c.LayoutsFile, "layouts-file", c.LayoutsFile, "JSON file containing the layouts of the sensor data of clone device models.")
+	pflag.Var(&c.SensorModels, "sensor-model", "Device model of a sensor, whose sensor data is parsed using the layout from the layouts file. Can be specified multiple times.")
+	pflag.DurationVar(&c.NameInterval, "name-interval", c.NameInterval, "Interval for reading the device name of sensors without a configured name, which is then used as their name. Disabled if zero.")
+	pflag.DurationVar(&c.FirmwareInterval, "firmware-interval", c.FirmwareInterval, "Interval for reading firmware version and battery level. Values are read on every refresh if zero.")
+	pflag.BoolVar(&c.SkipRealtimeMode, "skip-realtime-mode", c.SkipRealtimeMode, "Do not enable the realtime measurement of the sensors before reading. Saves battery, but some firmware versions return outdated values.")
+	pflag.DurationVar(&c.ScanBeforeRead, "scan-before-read", c.ScanBeforeRead, "Scan for up to this duration before connecting to a sensor and connect using the advertised address. Improves the connection success for sensors at the edge of the range.")
+	pflag.IntVar(&c.MinRSSI, "min-rssi", c.MinRSSI, "Do not connect to sensors whose advertisement has a lower signal strength (in dBm). Needs --scan-before-read. Disabled if zero.")
+	pflag.Var(&c.QuietHours, "quiet-hours", "Daily time range like 22:00-07:00 (local time) during which the sensors are not read.")
+	pflag.DurationVar(&c.WatchdogTimeout, "watchdog-timeout", c.WatchdogTimeout, "Hard limit for a single read, after which the read is abandoned and the adapter marked as suspect. Defaults to twice the refresh timeout.")
+	pflag.DurationVar(&c.ScanInterval, "scan-interval", c.ScanInterval, "Interval for scanning for Flower Care devices in range. Scanning is disabled if zero.")
+	pflag.DurationVar(&c.ScanDuration, "scan-duration", c.ScanDuration, "Duration of a single scan.")
+	pflag.BoolVar(&c.Discover, "discover", c.Discover, "Scan for Flower Care devices for the scan duration, log their MAC addresses and signal strength and exit. With --auto-register the devices are added to the registry file.")
+	pflag.BoolVar(&c.AutoRegister.Enabled, "auto-register", c.AutoRegister.Enabled, "Automatically register Flower Care devices found while scanning.")
+	pflag.StringSliceVar(&c.AutoRegister.Allow, "auto-register-allow", c.AutoRegister.Allow, "MAC address prefix of devices which can be registered automatically. Can be specified multiple times. Allows all devices if empty.")
+	pflag.StringSliceVar(&c.AutoRegister.Deny, "auto-register-deny", c.AutoRegister.Deny, "MAC address prefix of devices which should never be registered automatically. Can be specified multiple times.")
+	pflag.StringVar(&c.RegistryFile, "registry-file", c.RegistryFile, "State file for keeping track of discovered and auto-registered sensors across restarts.")
+	pflag.DurationVar(&c.StaleDuration, "stale-duration", c.StaleDuration, "Duration after which data is considered stale. Stale data is still exported, but marked using the flowercare_stale metric.")
+	pflag.DurationVar(&c.RemovalGrace, "removal-grace-period", c.RemovalGrace, "Duration the last data of a sensor removed at runtime is still exported, flagged as removed.")
+	pflag.DurationVar(&c.ForgetDuration, "forget-duration", c.ForgetDuration, "Duration after which data is not used for metrics anymore and the sensor is reported as down. Defaults to twice the stale duration.")
+	pflag.DurationVar(&c.Retry.MinDuration, "retry-min-duration", c.Retry.MinDuration, "Minimum wait time between retries on error.")
+	pflag.DurationVar(&c.Retry.MaxDuration, "retry-max-duration", c.Retry.MaxDuration, "Maximum wait time between retries on error.")
+	pflag.Float64Var(&c.Retry.Factor, "retry-factor", c.Retry.Factor, "Factor used to multiply wait time for subsequent retries.")
+	pflag.IntVar(&c.Retry.MaxRetries, "retry-max-count", c.Retry.MaxRetries, "Maximum number of consecutive failed reads before a sensor is considered down. Unlimited if zero.")
+	pflag.DurationVar(&c.Retry.MaxWindow, "retry-max-window", c.Retry.MaxWindow, "Maximum duration of consecutive failed reads before a sensor is considered down. Unlimited if zero.")
+	pflag.IntVar(&c.Health.DegradedAfter, "degraded-after", c.Health.DegradedAfter, "Number of consecutive failed reads after which a sensor is degraded.")
+	pflag.IntVar(&c.Health.RecoverAfter, "recover-after", c.Health.RecoverAfter, "Number of consecutive successful reads after which a degraded or down sensor is ok again.")
+	pflag.DurationVar(&c.Retry.DownInterval, "down-probe-interval", c.Retry.DownInterval, "Interval for reading sensors which are considered down.")
+	pflag.IntVar(&c.ReadBudget.PerCycle, "max-reads-per-cycle", c.ReadBudget.PerCycle, "Maximum number of reads per refresh cycle. Sensors which are not read are carried over to the next cycle. Unlimited if zero.")
+	pflag.IntVar(&c.ReadBudget.PerHour, "max-reads-per-hour", c.ReadBudget.PerHour, "Maximum number of reads per hour. Unlimited if zero.")
+	pflag.IntVar(&c.ReadBudget.OnDemandPerMinute, "max-on-demand-per-minute", c.ReadBudget.OnDemandPerMinute, "Maximum number of operations per minute requested using the API, which use the adapter, like connectivity checks. Unlimited if zero.")
+	pflag.DurationVar(&c.Backfill.Gap, "backfill-gap", c.Backfill.Gap, "Read the history records stored on a sensor if its previous reading is older than this duration and pass the missed records to the sinks. Disabled if zero.")
+	pflag.DurationVar(&c.Backfill.MaxAge, "backfill-max-age", c.Backfill.MaxAge, "Maximum age of the history records used for filling gaps.")
+	pflag.DurationVar(&c.Backfill.Timeout, "backfill-timeout", c.Backfill.Timeout, "Timeout for reading the history records of a sensor.")
+	pflag.StringVar(&c.Backfill.StateFile, "backfill-state-file", c.Backfill.StateFile, "State file for keeping the time of the last reading of every sensor, so that gaps caused by restarts are filled as well.")
+}
+
+// validateUpdater checks the options for reading the sensors and fills in the defaults depending on other options.
+func (c *Config) validateUpdater(log *logging.Logger) error {
+	if c.AutoRegister.Enabled && c.ScanInterval == 0 && !c.Discover {
+		return errors.New("auto-registration needs scanning to be enabled using --scan-interval")
+	}
+
+	if c.Discover {
+		if c.Simulate > 0 || c.ReplayFile != "" {
+			return errors.New("discovery needs a Bluetooth adapter and can not be used with simulation or replay")
+		}
+
+		if c.ScanDuration <= 0 {
+			return fmt.Errorf("scan duration needs to be positive: %s", c.ScanDuration)
+		}
+
+		if c.AutoRegister.Enabled && c.RegistryFile == "" {
+			return errors.New("auto-registration during discovery needs a registry file")
+		}
+	}
+
+	if len(c.Sensors) == 0 && c.Simulate == 0 && c.ReplayFile == "" && !c.AutoRegister.Enabled && len(c.Federation.Gateways) == 0 && c.Benchmark.Sensors == 0 && !c.Discover {
+		return errors.New("need to provide at least one sensor")
+	}
+
+	replaced := map[string]bool{}
+	for _, r := range c.Replacements {
+		if replaced[r.Old] || replaced[r.New] {
+			return fmt.Errorf("sensor can only be part of one replacement: %s", r)
+		}
+		replaced[r.Old] = true
+		replaced[r.New] = true
+	}
+
+	if len(c.Device) == 0 {
+		return errors.New("need to provide a bluetooth device")
+	}
+
+	switch c.Backend {
+	case BackendHCI, BackendExec, BackendDBus:
+	default:
+		return fmt.Errorf("unknown backend: %s", c.Backend)
+	}
+
+	if c.RefreshDuration < time.Minute {
+		log.Warnf("Refresh durations below one minute are discouraged: %s", c.RefreshDuration)
+	}
+
+	if c.WatchdogTimeout == 0 {
+		c.WatchdogTimeout = 2 * c.RefreshTimeout
+	}
+
+	if c.WatchdogTimeout <= c.RefreshTimeout {
+		return fmt.Errorf("watchdog timeout needs to be longer than refresh timeout: %s <= %s", c.WatchdogTimeout, c.RefreshTimeout)
+	}
+
+	if c.ScanInterval > 0 && c.ScanDuration <= 0 {
+		return fmt.Errorf("scan duration needs to be positive: %s", c.ScanDuration)
+	}
+
+	switch c.Mode {
+	case ModeActive, ModeCombined, ModePassive:
+	default:
+		return fmt.Errorf("unknown mode: %s", c.Mode)
+	}
+
+	if mode := c.broadcastMode(); mode != "" {
+		if c.ScanInterval == 0 {
+			return fmt.Errorf("%s mode needs scanning to be enabled using --scan-interval", mode)
+		}
+
+		if c.Backend != BackendHCI {
+			return fmt.Errorf("%s mode is not supported by backend: %s", mode, c.Backend)
+		}
+	}
+
+	if c.StaleDuration < (2 * c.RefreshDuration) {
+		return fmt.Errorf("stale duration needs to be at least %d", 2*c.RefreshDuration)
+	}
+
+	if c.ForgetDuration == 0 {
+		c.ForgetDuration = 2 * c.StaleDuration
+	}
+
+	if c.ForgetDuration < c.StaleDuration {
+		return fmt.Errorf("forget duration can not be shorter than stale duration: %s < %s", c.ForgetDuration, c.StaleDuration)
+	}
+
+	if c.RemovalGrace < 0 {
+		return fmt.Errorf("removal grace period can not be negative: %s", c.RemovalGrace)
+	}
+
+	if c.Retry.MinDuration < 30*time.Second {
+		return fmt.Errorf("retry time needs to be at least thirty seconds: %s", c.Retry.MinDuration)
+	}
+
+	if c.Retry.MaxDuration < c.Retry.MinDuration {
+		return fmt.Errorf("maximum retry time needs to be larger or equal to minimum time: %s > %s", c.Retry.MinDuration, c.Retry.MaxDuration)
+	}
+
+	if c.WarmUpDuration < 0 || c.WarmUpDuration > c.RefreshDuration {
+		return fmt.Errorf("warm-up duration needs to be between zero and the refresh duration: %s", c.WarmUpDuration)
+	}
+
+	if c.ScanBeforeRead < 0 || c.ScanBeforeRead >= c.RefreshTimeout {
+		return fmt.Errorf("scan before read needs to be shorter than the refresh timeout: %s >= %s", c.ScanBeforeRead, c.RefreshTimeout)
+	}
+
+	if c.MinRSSI != 0 && c.ScanBeforeRead == 0 {
+		return errors.New("minimum RSSI needs --scan-before-read")
+	}
+
+	if c.FirmwareInterval < 0 {
+		return fmt.Errorf("firmware interval can not be negative: %s", c.FirmwareInterval)
+	}
+
+	if len(c.SensorModels) > 0 && c.LayoutsFile == "" {
+		return errors.New("sensor models need a layouts file")
+	}
+
+	if c.NameInterval < 0 {
+		return fmt.Errorf("name interval can not be negative: %s", c.NameInterval)
+	}
+
+	if c.ReadCooldown < 0 {
+		return fmt.Errorf("read cooldown can not be negative: %s", c.ReadCooldown)
+	}
+
+	if c.ReadBudget.PerCycle < 0 || c.ReadBudget.PerHour < 0 || c.ReadBudget.OnDemandPerMinute < 0 {
+		return errors.New("read budget can not be negative")
+	}
+
+	if c.Backfill.Gap < 0 {
+		return fmt.Errorf("backfill gap can not be negative: %s", c.Backfill.Gap)
+	}
+
+	if c.Backfill.Gap > 0 && (c.Backfill.MaxAge <= 0 || c.Backfill.Timeout <= 0) {
+		return errors.New("backfill maximum age and timeout need to be positive")
+	}
+
+	if c.Retry.Factor < 1 {
+		return fmt.Errorf("retry factor needs to be equal or larger than one: %v", c.Retry.Factor)
+	}
+
+	if c.Retry.MaxRetries < 0 || c.Retry.MaxWindow < 0 {
+		return errors.New("retry limits can not be negative")
+	}
+
+	if c.Health.DegradedAfter < 1 || c.Health.RecoverAfter < 1 {
+		return errors.New("health thresholds need to be at least one")
+	}
+
+	if c.Retry.DownInterval < c.Retry.MinDuration {
+		return fmt.Errorf("down probe interval needs to be at least the minimum retry time: %s < %s", c.Retry.DownInterval, c.Retry.MinDuration)
+	}
+
+	return nil
+}
+
+// sinkFlags registers the flags of the sinks writing the readings to other systems.
+func (c *Config) sinkFlags() {
+	pflag.StringVar(&c.Postgres.URL, "postgres-url", c.Postgres.URL, "Connection URL of a PostgreSQL database to write readings to. Disabled if empty.")
+	pflag.StringVar(&c.Postgres.URLFile, "postgres-url-file", c.Postgres.URLFile, "File containing the connection URL of the PostgreSQL database. Can be used instead of --postgres-url for URLs containing a password.")
+	pflag.StringVar(&c.Postgres.URLEnv, "postgres-url-env", c.Postgres.URLEnv, "Environment variable containing the connection URL of the PostgreSQL database. Can be used instead of --postgres-url for URLs containing a password.")
+	pflag.StringVar(&c.Postgres.Table, "postgres-table", c.Postgres.Table, "Table to write readings to. It is created if it does not exist.")
+	pflag.BoolVar(&c.Postgres.Timescale, "postgres-timescale", c.Postgres.Timescale, "Convert the readings table to a TimescaleDB hypertable.")
+	pflag.StringVar(&c.Postgres.Auth, "postgres-auth", c.Postgres.Auth, "Name of the profile from the auth file used for connecting to PostgreSQL.")
+	sinkBatchFlags("postgres", &c.Postgres.Batch)
+	sinkRetryFlags("postgres", &c.Postgres.Retry)
+	pflag.StringVar(&c.Redis.URL, "redis-url", c.Redis.URL, "Connection URL of a Redis server to publish readings to, for example redis://localhost:6379/0. Disabled if empty.")
+	pflag.StringVar(&c.Redis.URLFile, "redis-url-file", c.Redis.URLFile, "File containing the connection URL of the Redis server. Can be used instead of --redis-url for URLs containing a password.")
+	pflag.StringVar(&c.Redis.URLEnv, "redis-url-env", c.Redis.URLEnv, "Environment variable containing the connection URL of the Redis server. Can be used instead of --redis-url for URLs containing a password.")
+	pflag.StringVar(&c.Redis.KeyPrefix, "redis-key-prefix", c.Redis.KeyPrefix, "Prefix of the keys holding the latest reading of each sensor. Storing readings is disabled if empty.")
+	pflag.DurationVar(&c.Redis.TTL, "redis-ttl", c.Redis.TTL, "Expiry time of the keys holding the latest readings. Keys do not expire if zero.")
+	pflag.StringVar(&c.Redis.Channel, "redis-channel", c.Redis.Channel, "Channel to publish all readings on. Publishing is disabled if empty.")
+	pflag.StringVar(&c.Redis.Auth, "redis-auth", c.Redis.Auth, "Name of the profile from the auth file used for connecting to Redis.")
+	sinkBatchFlags("redis", &c.Redis.Batch)
+	sinkRetryFlags("redis", &c.Redis.Retry)
+	pflag.StringVar(&c.HomeAssistant.URL, "homeassistant-url", c.HomeAssistant.URL, "URL of a Home Assistant instance to set the states of sensor entities in using the REST API, for example http://homeassistant.local:8123. Disabled if empty.")
+	pflag.StringVar(&c.HomeAssistant.TokenFile, "homeassistant-token-file", c.HomeAssistant.TokenFile, "File containing a long-lived access token for Home Assistant.")
+	pflag.StringVar(&c.HomeAssistant.TokenEnv, "homeassistant-token-env", c.HomeAssistant.TokenEnv, "Environment variable containing a long-lived access token for Home Assistant.")
+	pflag.StringVar(&c.HomeAssistant.Auth, "homeassistant-auth", c.HomeAssistant.Auth, "Name of the profile from the auth file used for connecting to Home Assistant.")
+	sinkBatchFlags("homeassistant", &c.HomeAssistant.Batch)
+	sinkRetryFlags("homeassistant", &c.HomeAssistant.Retry)
+	pflag.StringVar(&c.OpenHAB.URL, "openhab-url", c.OpenHAB.URL, "URL of an openHAB instance to update the states of items in using the REST API, for example http://openhab.local:8080. Disabled if empty.")
+	pflag.StringVar(&c.OpenHAB.ItemPrefix, "openhab-item-prefix", c.OpenHAB.ItemPrefix, "Prefix of the openHAB item names, which are followed by the sensor name and the measurement.")
+	pflag.StringVar(&c.OpenHAB.Auth, "openhab-auth", c.OpenHAB.Auth, "Name of the profile from the auth file used for connecting to openHAB.")
+	sinkBatchFlags("openhab", &c.OpenHAB.Batch)
+	sinkRetryFlags("openhab", &c.OpenHAB.Retry)
+	pflag.StringVar(&c.Domoticz.URL, "domoticz-url", c.Domoticz.URL, "URL of a Domoticz instance to update devices in using the JSON API, for example http://domoticz.local:8080. Disabled if empty.")
+	pflag.StringVar(&c.Domoticz.Auth, "domoticz-auth", c.Domoticz.Auth, "Name of the profile from the auth file used for connecting to Domoticz.")
+	sinkBatchFlags("domoticz", &c.Domoticz.Batch)
+	sinkRetryFlags("domoticz", &c.Domoticz.Retry)
+	pflag.StringVar(&c.ThingSpeak.ChannelsFile, "thingspeak-channels-file", c.ThingSpeak.ChannelsFile, "JSON file mapping sensors to ThingSpeak channels and their write API keys. Disabled if empty.")
+	pflag.StringVar(&c.ThingSpeak.URL, "thingspeak-url", c.ThingSpeak.URL, "URL of the ThingSpeak API.")
+	pflag.StringVar(&c.ThingSpeak.Auth, "thingspeak-auth", c.ThingSpeak.Auth, "Name of the profile from the auth file used for connecting to ThingSpeak.")
+	sinkBatchFlags("thingspeak", &c.ThingSpeak.Batch)
+	sinkRetryFlags("thingspeak", &c.ThingSpeak.Retry)
+	pflag.StringVar(&c.MQTT.Broker, "mqtt-broker", c.MQTT.Broker, "URL of an MQTT broker to publish every reading to, for example tcp://mosquitto:1883 or ssl://mosquitto:8883. Disabled if empty.")
+	pflag.StringVar(&c.MQTT.ClientID, "mqtt-client-id", c.MQTT.ClientID, "Client ID used for connecting to the MQTT broker.")
+	pflag.StringVar(&c.MQTT.Topic, "mqtt-topic", c.MQTT.Topic, "Template of the topic the readings of a sensor are published to. Can use the sensor .Name and .MacAddress and the .Measurement when using --mqtt-per-measurement.")
+	pflag.StringVar(&c.MQTT.Payload, "mqtt-payload-template", c.MQTT.Payload, "Template of the payload of the messages. Can use the sensor .Name, .MacAddress, the reading .Data and the .Measurement, .Value and .Unit when using --mqtt-per-measurement. Uses the JSON format of miflorectl or the plain value if empty.")
+	pflag.BoolVar(&c.MQTT.PerMeasurement, "mqtt-per-measurement", c.MQTT.PerMeasurement, "Publish every measurement of a reading in a separate message. The topic needs to contain the .Measurement.")
+	pflag.BoolVar(&c.MQTT.Retain, "mqtt-retain", c.MQTT.Retain, "Publish the readings as retained messages, so that new subscribers get the latest reading.")
+	pflag.StringVar(&c.MQTT.Availability, "mqtt-availability-topic", c.MQTT.Availability, "Topic the online state of the exporter is published to. Disabled if empty.")
+	pflag.BoolVar(&c.MQTT.Discovery, "mqtt-homeassistant-discovery", c.MQTT.Discovery, "Publish Home Assistant discovery messages, so that the sensors are added to Home Assistant automatically.")
+	pflag.StringVar(&c.MQTT.DiscoveryPrefix, "mqtt-discovery-prefix", c.MQTT.DiscoveryPrefix, "Prefix of the Home Assistant discovery topics.")
+	pflag.StringVar(&c.MQTT.Auth, "mqtt-auth", c.MQTT.Auth, "Name of the profile from the auth file used for connecting to the MQTT broker.")
+	sinkBatchFlags("mqtt", &c.MQTT.Batch)
+	sinkRetryFlags("mqtt", &c.MQTT.Retry)
+	pflag.StringVar(&c.AWSIoT.Endpoint, "aws-iot-endpoint", c.AWSIoT.Endpoint, "Device data endpoint of AWS IoT Core to publish readings to, for example abc123-ats.iot.eu-central-1.amazonaws.com. Disabled if empty.")
+	pflag.StringVar(&c.AWSIoT.ClientID, "aws-iot-client-id", c.AWSIoT.ClientID, "MQTT client ID used for connecting to AWS IoT Core.")
+	pflag.StringVar(&c.AWSIoT.ThingPrefix, "aws-iot-thing-prefix", c.AWSIoT.ThingPrefix, "Prefix of the thing names, which are followed by the sensor name.")
+	pflag.BoolVar(&c.AWSIoT.Shadow, "aws-iot-shadow", c.AWSIoT.Shadow, "Report the latest values of the sensors in the device shadows of the things.")
+	pflag.StringVar(&c.AWSIoT.Auth, "aws-iot-auth", c.AWSIoT.Auth, "Name of the profile from the auth file containing the client certificate for AWS IoT Core.")
+	sinkBatchFlags("aws-iot", &c.AWSIoT.Batch)
+	sinkRetryFlags("aws-iot", &c.AWSIoT.Retry)
+	pflag.StringVar(&c.AzureIoT.ConnectionFile, "azure-iot-connection-string-file", c.AzureIoT.ConnectionFile, "File containing the device connection string for Azure IoT Hub. Disabled if empty.")
+	pflag.BoolVar(&c.AzureIoT.Twin, "azure-iot-twin", c.AzureIoT.Twin, "Report the latest values of the sensors as properties of the device twin.")
+	pflag.StringVar(&c.AzureIoT.Auth, "azure-iot-auth", c.AzureIoT.Auth, "Name of the profile from the auth file containing a client certificate for Azure IoT Hub, if the connection string has no shared access key.")
+	sinkBatchFlags("azure-iot", &c.AzureIoT.Batch)
+	sinkRetryFlags("azure-iot", &c.AzureIoT.Retry)
+	pflag.StringVar(&c.Webhook.URL, "webhook-url", c.Webhook.URL, "URL to post every reading to. Disabled if empty.")
+	pflag.StringVar(&c.Webhook.BodyFile, "webhook-body-file", c.Webhook.BodyFile, "File containing the template for the JSON body of webhook requests. Uses the JSON format of miflorectl if empty.")
+	pflag.IntVar(&c.Webhook.Every, "webhook-every", c.Webhook.Every, "Only post every Nth reading of a sensor to the webhook.")
+	pflag.StringVar(&c.Webhook.SecretFile, "webhook-secret-file", c.Webhook.SecretFile, "File containing a secret used for signing the webhook requests using HMAC-SHA256.")
+	pflag.StringVar(&c.Webhook.SecretEnv, "webhook-secret-env", c.Webhook.SecretEnv, "Environment variable containing a secret used for signing the webhook requests using HMAC-SHA256.")
+	pflag.StringVar(&c.Webhook.Auth, "webhook-auth", c.Webhook.Auth, "Name of the profile from the auth file used for connecting to the webhook.")
+	sinkBatchFlags("webhook", &c.Webhook.Batch)
+	sinkRetryFlags("webhook", &c.Webhook.Retry)
+	pflag.StringVar(&c.Loki.URL, "loki-url", c.Loki.URL, "URL of a Loki instance to send a log line per reading and alert to, for example http://loki:3100. Disabled if empty.")
+	pflag.StringVar(&c.Loki.Tenant, "loki-tenant", c.Loki.Tenant, "Tenant ID sent to Loki in the X-Scope-OrgID header.")
+	pflag.BoolVar(&c.Loki.Readings, "loki-readings", c.Loki.Readings, "Send a log line per reading to Loki.")
+	pflag.BoolVar(&c.Loki.Alerts, "loki-alerts", c.Loki.Alerts, "Send a log line per alert notification to Loki.")
+	pflag.StringVar(&c.Loki.Auth, "loki-auth", c.Loki.Auth, "Name of the profile from the auth file used for connecting to Loki.")
+	sinkBatchFlags("loki", &c.Loki.Batch)
+	sinkRetryFlags("loki", &c.Loki.Retry)
+	pflag.StringVar(&c.RemoteWrite.URL, "remote-write-url", c.RemoteWrite.URL, "Prometheus remote-write endpoint to push the history records read for filling gaps to, for example http://prometheus:9090/api/v1/write. Disabled if empty.")
+	pflag.StringVar(&c.RemoteWrite.Job, "remote-write-job", c.RemoteWrite.Job, "Value of the job label of the samples pushed using remote-write. Should match the job scraping the exporter.")
+	pflag.StringVar(&c.RemoteWrite.Instance, "remote-write-instance", c.RemoteWrite.Instance, "Value of the instance label of the samples pushed using remote-write. Should match the scraped instance. Omitted if empty.")
+	pflag.StringVar(&c.RemoteWrite.Auth, "remote-write-auth", c.RemoteWrite.Auth, "Name of the profile from the auth file used for connecting to the remote-write endpoint.")
+	sinkBatchFlags("remote-write", &c.RemoteWrite.Batch)
+	sinkRetryFlags("remote-write", &c.RemoteWrite.Retry)
+	pflag.StringVar(&c.SinkJournalDir, "sink-journal-dir", c.SinkJournalDir, "Directory for journal files, which keep readings until they have been written to the other systems, so that undelivered readings are kept across restarts.")
+	pflag.Var(&c.SinkDeadband.Deadbands, "sink-deadband", "Only publish a reading to the other systems if a measurement changed by more than the deadband since the last published reading, for example temperature=0.5. Can be specified multiple times.")
+	pflag.DurationVar(&c.SinkDeadband.MaxInterval, "sink-deadband-max-interval", c.SinkDeadband.MaxInterval, "Publish a reading regardless of the deadbands, if the last published reading of the sensor is older than this. Disabled if zero.")
+	pflag.StringVar(&c.SinkDeadLetter, "sink-dead-letter-file", c.SinkDeadLetter, "File to append readings to, which could not be written within the maximum retry age. These readings are dropped if empty.")
+}
+
+// validateSinks checks the options of the sinks and reads the secret URLs. It needs the auth profiles to be loaded.
+func (c *Config) validateSinks() error {
+	for _, secret := range []struct {
+		Name string
+		File string
+		Env  string
+	}{
+		{"PostgreSQL URL", c.Postgres.URLFile, c.Postgres.URLEnv},
+		{"Redis URL", c.Redis.URLFile, c.Redis.URLEnv},
+		{"Home Assistant token", c.HomeAssistant.TokenFile, c.HomeAssistant.TokenEnv},
+		{"webhook secret", c.Webhook.SecretFile, c.Webhook.SecretEnv},
+	} {
+		if secret.File != "" && secret.Env != "" {
+			return fmt.Errorf("%s file and environment variable can not be used at the same time", secret.Name)
+		}
+	}
+
+	var err error
+	if c.Postgres.URL, err = secretURL(c.Postgres.URL, c.Postgres.URLFile, c.Postgres.URLEnv, "PostgreSQL URL"); err != nil {
+		return err
+	}
+
+	if c.Redis.URL, err = secretURL(c.Redis.URL, c.Redis.URLFile, c.Redis.URLEnv, "Redis URL"); err != nil {
+		return err
+	}
+
+	if c.Postgres.URL != "" && c.Postgres.Table == "" {
+		return errors.New("need to provide a table name for PostgreSQL")
+	}
+
+	if c.Redis.URL != "" && c.Redis.KeyPrefix == "" && c.Redis.Channel == "" {
+		return errors.New("need to provide a key prefix or a channel for Redis")
+	}
+
+	for _, sink := range []struct {
+		Name  string
+		Batch SinkBatchConfig
+		Retry SinkRetryConfig
+	}{
+		{"postgres", c.Postgres.Batch, c.Postgres.Retry},
+		{"redis", c.Redis.Batch, c.Redis.Retry},
+		{"homeassistant", c.HomeAssistant.Batch, c.HomeAssistant.Retry},
+		{"openhab", c.OpenHAB.Batch, c.OpenHAB.Retry},
+		{"domoticz", c.Domoticz.Batch, c.Domoticz.Retry},
+		{"thingspeak", c.ThingSpeak.Batch, c.ThingSpeak.Retry},
+		{"mqtt", c.MQTT.Batch, c.MQTT.Retry},
+		{"aws-iot", c.AWSIoT.Batch, c.AWSIoT.Retry},
+		{"azure-iot", c.AzureIoT.Batch, c.AzureIoT.Retry},
+		{"webhook", c.Webhook.Batch, c.Webhook.Retry},
+		{"loki", c.Loki.Batch, c.Loki.Retry},
+		{"remote-write", c.RemoteWrite.Batch, c.RemoteWrite.Retry},
+	} {
+		if err := sink.Batch.validate(sink.Name); err != nil {
+			return err
+		}
+
+		if err := sink.Retry.validate(sink.Name); err != nil {
+			return err
+		}
+	}
+
+	if c.HomeAssistant.URL != "" && c.HomeAssistant.TokenFile == "" && c.HomeAssistant.TokenEnv == "" && !c.AuthProfile(c.HomeAssistant.Auth).HasBearerToken() {
+		return errors.New("need to provide an access token for Home Assistant")
+	}
+
+	if c.AWSIoT.Endpoint != "" && c.AuthProfile(c.AWSIoT.Auth).CertFile == "" {
+		return errors.New("need to provide an auth profile with a client certificate for AWS IoT Core")
+	}
+
+	if c.SinkDeadband.MaxInterval < 0 {
+		return fmt.Errorf("deadband max interval can not be negative: %s", c.SinkDeadband.MaxInterval)
+	}
+
+	if c.SinkDeadband.MaxInterval > 0 && !c.SinkDeadband.Enabled() {
+		return errors.New("deadband max interval needs at least one deadband")
+	}
+
+	if c.Webhook.Every < 1 {
+		return fmt.Errorf("webhook needs to post at least every reading: %d", c.Webhook.Every)
+	}
+
+	if c.Redis.TTL < 0 {
+		return fmt.Errorf("redis TTL can not be negative: %s", c.Redis.TTL)
+	}
+
+	if c.MQTT.Discovery && c.MQTT.Broker == "" {
+		return errors.New("home assistant discovery needs an MQTT broker set using --mqtt-broker")
+	}
+
+	if c.MQTT.Discovery && c.MQTT.Payload != "" {
+		return errors.New("home assistant discovery can not be used with a payload template")
+	}
+
+	if c.MQTT.PerMeasurement && !strings.Contains(c.MQTT.Topic, ".Measurement") {
+		return fmt.Errorf("MQTT topic needs to contain the .Measurement when publishing every measurement separately: %s", c.MQTT.Topic)
+	}
+
+	if c.RemoteWrite.URL != "" && c.Backfill.Gap == 0 {
+		return errors.New("remote-write is only used for filling gaps and needs --backfill-gap")
+	}
+
+	return nil
+}
+
+// alertFlags registers the flags of the alerts and their notifications.
+func (c *Config) alertFlags() {
+	pflag.StringVar(&c.PlantsFile, "plants-file", c.PlantsFile, "JSON file containing the alert thresholds of the plants. Alerting is disabled if empty.")
+	pflag.StringVar(&c.SilencesFile, "silences-file", c.SilencesFile, "File for keeping the alert silences across restarts. Only kept in memory if empty.")
+	pflag.StringSliceVar(&c.SpeciesFiles, "species-file", c.SpeciesFiles, "Plant database file (CSV or JSON) in the format used by the Flower Care app and Home Assistant, adding species for alert thresholds. Can be specified multiple times.")
+	pflag.StringVar(&c.Email.SMTPAddr, "smtp-addr", c.Email.SMTPAddr, "Address (host:port) of the SMTP server used for sending alerts by email. Disabled if empty.")
+	pflag.StringVar(&c.Email.Username, "smtp-username", c.Email.Username, "Username for authenticating with the SMTP server.")
+	pflag.StringVar(&c.Email.PasswordFile, "smtp-password-file", c.Email.PasswordFile, "File containing the password for authenticating with the SMTP server.")
+	pflag.StringVar(&c.Email.PasswordEnv, "smtp-password-env", c.Email.PasswordEnv, "Environment variable containing the password for authenticating with the SMTP server.")
+	pflag.StringVar(&c.Email.From, "email-from", c.Email.From, "Sender address of alert emails.")
+	pflag.StringSliceVar(&c.Email.To, "email-to", c.Email.To, "Recipient of alert emails for plants which do not have their own recipients. Can be specified multiple times.")
+	pflag.StringVar(&c.Email.SubjectTemplate, "email-subject", c.Email.SubjectTemplate, "Template for the subject of alert emails.")
+	pflag.StringVar(&c.Email.BodyFile, "email-body-file", c.Email.BodyFile, "File containing the template for the body of alert emails. Uses a built-in template if empty.")
+	pflag.StringVar(&c.Slack.WebhookURL, "slack-webhook-url", c.Slack.WebhookURL, "URL of a Slack incoming webhook to post alerts to. Disabled if empty.")
+	pflag.StringVar(&c.Slack.MessageTemplate, "slack-message", c.Slack.MessageTemplate, "Template for alert messages posted to Slack.")
+	pflag.StringVar(&c.Discord.WebhookURL, "discord-webhook-url", c.Discord.WebhookURL, "URL of a Discord webhook to post alerts to. Disabled if empty.")
+	pflag.StringVar(&c.Discord.MessageTemplate, "discord-message", c.Discord.MessageTemplate, "Template for alert messages posted to Discord.")
+}
+
+// validateAlerts checks the options of the alert notifications.
+func (c *Config) validateAlerts() error {
+	if c.Email.PasswordFile != "" && c.Email.PasswordEnv != "" {
+		return errors.New("SMTP password file and environment variable can not be used at the same time")
+	}
+
+	if c.Email.SMTPAddr != "" {
+		if c.Email.From == "" {
+			return errors.New("need to provide a sender address for alert emails")
+		}
+
+		if c.Email.Username != "" && c.Email.PasswordFile == "" && c.Email.PasswordEnv == "" {
+			return errors.New("need to provide a password file or environment variable for SMTP authentication")
+		}
+	}
+
+	return nil
+}
+
+// loadAuthProfiles loads the auth file and checks that all referenced profiles exist.
+func (c *Config) loadAuthProfiles() error {
+	if c.AuthFile != "" {
+		profiles, err := clientauth.LoadFile(c.AuthFile)
+		if err != nil {
+			return err
+		}
+		c.AuthProfiles = profiles
+	}
+
+	for _, ref := range []struct {
+		Integration string
+		Name        string
+	}{
+		{"postgres", c.Postgres.Auth},
+		{"redis", c.Redis.Auth},
+		{"federation", c.Federation.Auth},
+		{"homeassistant", c.HomeAssistant.Auth},
+		{"openhab", c.OpenHAB.Auth},
+		{"domoticz", c.Domoticz.Auth},
+		{"thingspeak", c.ThingSpeak.Auth},
+		{"mqtt", c.MQTT.Auth},
+		{"aws-iot", c.AWSIoT.Auth},
+		{"azure-iot", c.AzureIoT.Auth},
+		{"webhook", c.Webhook.Auth},
+		{"loki", c.Loki.Auth},
+		{"remote-write", c.RemoteWrite.Auth},
+	} {
+		if ref.Name == "" {
+			continue
+		}
+
+		if _, ok := c.AuthProfiles[ref.Name]; !ok {
+			return fmt.Errorf("auth profile %q of %s not found in auth file", ref.Name, ref.Integration)
+		}
+	}
+
+	if c.AuthProfiles[c.Postgres.Auth].HasBearerToken() || c.AuthProfiles[c.Redis.Auth].HasBearerToken() {
+		return errors.New("bearer tokens are not supported by PostgreSQL and Redis")
+	}
+
+	return nil
+}
+
+// secretURL returns the connection URL read from the file or environment variable, if one of them is set instead of
+// the URL.
+func secretURL(url, fileName, envName, name string) (string, error) {
+	if fileName == "" && envName == "" {
+		return url, nil
+	}
+
+	if url != "" {
+		return "", fmt.Errorf("%s can not be set together with a file or environment variable", name)
+	}
+
+	return clientauth.ReadSecret(fileName, envName, name)
+}
+
+// AuthProfile returns the named profile from the auth file. An empty name results in an empty profile.
+func (c Config) AuthProfile(name string) clientauth.Profile {
+	return c.AuthProfiles[name]
+}
+
+// sinkBatchFlags adds the flags for the batching of a sink.
+func sinkBatchFlags(name string, batch *SinkBatchConfig) {
+	pflag.IntVar(&batch.Size, name+"-batch-size", batch.Size, "Number of readings after which a batch is written.")
+	pflag.DurationVar(&batch.FlushInterval, name+"-flush-interval", batch.FlushInterval, "Maximum time readings are collected before a batch is written. Longer intervals result in fewer, larger writes.")
+}
+
+// sinkRetryFlags adds the flags for the retry policy of a sink.
+func sinkRetryFlags(name string, retry *SinkRetryConfig) {
+	pflag.DurationVar(&retry.MinBackoff, name+"-retry-min-backoff", retry.MinBackoff, "Wait time before retrying readings which could not be written. Doubled after every failed attempt.")
+	pflag.DurationVar(&retry.MaxBackoff, name+"-retry-max-backoff", retry.MaxBackoff, "Maximum wait time between retries.")
+	pflag.DurationVar(&retry.MaxAge, name+"-retry-max-age", retry.MaxAge, "Age after which readings are not retried anymore, but moved to the dead-letter file. Failed readings are not retried if zero.")
+}
+
+// applyPowerProfile changes the settings of the selected power profile, which have not been set explicitly.
+func (c *Config) applyPowerProfile(flags *pflag.FlagSet) error {
+	switch c.PowerProfile {
+	case "":
+		return nil
+	case PowerProfileBatterySaver:
+	default:
+		return fmt.Errorf("unknown power profile: %s", c.PowerProfile)
+	}
+
+	if !flags.Changed("refresh-duration") {
+		c.RefreshDuration = 30 * time.Minute
+	}
+
+	if !flags.Changed("stale-duration") {
+		c.StaleDuration = 10 * time.Hour
+	}
+
+	if !flags.Changed("firmware-interval") {
+		c.FirmwareInterval = 24 * time.Hour
+	}
+
+	if !flags.Changed("skip-realtime-mode") {
+		c.SkipRealtimeMode = true
+	}
+
+	if !flags.Changed("quiet-hours") {
+		c.QuietHours = QuietHours{
+			Start: 22 * time.Hour,
+			End:   7 * time.Hour,
+		}
+	}
+
+	return nil
+}
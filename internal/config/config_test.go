@@ -0,0 +1,476 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReplacementsSet(t *testing.T) {
+	tests := []struct {
+		desc    string
+		value   string
+		want    Replacements
+		wantErr bool
+	}{
+		{
+			desc:  "replacement",
+			value: "c4:7c:8d:6a:3e:1f=c4:7c:8d:6a:3e:20",
+			want:  Replacements{{Old: "C4:7C:8D:6A:3E:1F", New: "C4:7C:8D:6A:3E:20"}},
+		},
+		{
+			desc:    "missing new device",
+			value:   "C4:7C:8D:6A:3E:1F=",
+			wantErr: true,
+		},
+		{
+			desc:    "replaces itself",
+			value:   "C4:7C:8D:6A:3E:1F=c4:7c:8d:6a:3e:1f",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got Replacements
+			err := got.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got replacements %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got replacements %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMetricRulesSet(t *testing.T) {
+	tests := []struct {
+		desc        string
+		value       string
+		metric      string
+		otherMetric string
+		sensor      string
+		wantMatch   bool
+		wantErr     bool
+	}{
+		{
+			desc:        "metric",
+			value:       "flowercare_battery.*",
+			metric:      "flowercare_battery_percent",
+			wantMatch:   true,
+			otherMetric: "flowercare_temperature_celsius",
+		},
+		{
+			desc:        "metric and sensor",
+			value:       "flowercare_light_lux@basil",
+			metric:      "flowercare_light_lux",
+			sensor:      "basil",
+			wantMatch:   true,
+			otherMetric: "flowercare_light",
+		},
+		{
+			desc:    "invalid metric expression",
+			value:   "flowercare_(",
+			wantErr: true,
+		},
+		{
+			desc:    "invalid sensor expression",
+			value:   "flowercare_light_lux@[",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got MetricRules
+			err := got.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got rules %v, want error", got.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("got %d rules, want 1", len(got))
+			}
+			rule := got[0]
+
+			if rule.String() != tc.value {
+				t.Errorf("got rule %q, want %q", rule.String(), tc.value)
+			}
+
+			if match := rule.Metric.MatchString(tc.metric); match != tc.wantMatch {
+				t.Errorf("got match %v for %q, want %v", match, tc.metric, tc.wantMatch)
+			}
+
+			if rule.Metric.MatchString(tc.otherMetric) {
+				t.Errorf("expression is not anchored, matches %q", tc.otherMetric)
+			}
+
+			if tc.sensor == "" {
+				if rule.Sensor != nil {
+					t.Errorf("got sensor expression %q, want none", rule.Sensor)
+				}
+				return
+			}
+
+			if rule.Sensor == nil || !rule.Sensor.MatchString(tc.sensor) {
+				t.Errorf("got sensor expression %v, want match for %q", rule.Sensor, tc.sensor)
+			}
+		})
+	}
+}
+
+func TestModelMapSet(t *testing.T) {
+	tests := []struct {
+		desc    string
+		value   string
+		want    ModelMap
+		wantErr bool
+	}{
+		{
+			desc:  "model",
+			value: "c4:7c:8d:6a:3e:1f=ropot",
+			want:  ModelMap{"C4:7C:8D:6A:3E:1F": "ropot"},
+		},
+		{
+			desc:    "missing model",
+			value:   "C4:7C:8D:6A:3E:1F=",
+			wantErr: true,
+		},
+		{
+			desc:    "missing separator",
+			value:   "C4:7C:8D:6A:3E:1F",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got ModelMap
+			err := got.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got models %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got models %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestModeMapSet(t *testing.T) {
+	tests := []struct {
+		desc    string
+		value   string
+		want    ModeMap
+		wantErr bool
+	}{
+		{
+			desc:  "passive",
+			value: "c4:7c:8d:6a:3e:1f=" + ModePassive,
+			want:  ModeMap{"C4:7C:8D:6A:3E:1F": ModePassive},
+		},
+		{
+			desc:  "active",
+			value: "C4:7C:8D:6A:3E:1F=" + ModeActive,
+			want:  ModeMap{"C4:7C:8D:6A:3E:1F": ModeActive},
+		},
+		{
+			desc:    "unknown mode",
+			value:   "C4:7C:8D:6A:3E:1F=sleepy",
+			wantErr: true,
+		},
+		{
+			desc:    "missing mode",
+			value:   "C4:7C:8D:6A:3E:1F=",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got ModeMap
+			err := got.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got modes %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got modes %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeadbandMapSet(t *testing.T) {
+	tests := []struct {
+		desc    string
+		value   string
+		want    DeadbandMap
+		wantErr bool
+	}{
+		{
+			desc:  "deadband",
+			value: "temperature=0.5",
+			want:  DeadbandMap{"temperature": 0.5},
+		},
+		{
+			desc:  "zero",
+			value: "moisture=0",
+			want:  DeadbandMap{"moisture": 0},
+		},
+		{
+			desc:    "negative",
+			value:   "temperature=-1",
+			wantErr: true,
+		},
+		{
+			desc:    "not a number",
+			value:   "temperature=half",
+			wantErr: true,
+		},
+		{
+			desc:    "missing measurement",
+			value:   "=0.5",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got DeadbandMap
+			err := got.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got deadbands %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got deadbands %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLabelMapSet(t *testing.T) {
+	t.Setenv("FLOWERCARE_TEST_SITE", "greenhouse")
+
+	tests := []struct {
+		desc    string
+		value   string
+		want    LabelMap
+		wantErr bool
+	}{
+		{
+			desc:  "label",
+			value: "room=kitchen",
+			want:  LabelMap{"room": "kitchen"},
+		},
+		{
+			desc:  "empty value",
+			value: "room=",
+			want:  LabelMap{"room": ""},
+		},
+		{
+			desc:  "environment variable",
+			value: "site=${FLOWERCARE_TEST_SITE}-1",
+			want:  LabelMap{"site": "greenhouse-1"},
+		},
+		{
+			desc:    "missing environment variable",
+			value:   "site=${FLOWERCARE_TEST_MISSING}",
+			wantErr: true,
+		},
+		{
+			desc:    "invalid name",
+			value:   "1room=kitchen",
+			wantErr: true,
+		},
+		{
+			desc:    "missing separator",
+			value:   "room",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got LabelMap
+			err := got.Set(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got labels %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got labels %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGatewayListSet(t *testing.T) {
+	tests := []struct {
+		desc    string
+		values  []string
+		want    GatewayList
+		wantErr bool
+	}{
+		{
+			desc:   "default path",
+			values: []string{"garden=http://garden:9294"},
+			want:   GatewayList{{Name: "garden", URL: "http://garden:9294/metrics"}},
+		},
+		{
+			desc:   "custom path",
+			values: []string{"garden=https://garden.example.com/flowercare/metrics"},
+			want:   GatewayList{{Name: "garden", URL: "https://garden.example.com/flowercare/metrics"}},
+		},
+		{
+			desc:    "unsupported scheme",
+			values:  []string{"garden=ftp://garden:9294"},
+			wantErr: true,
+		},
+		{
+			desc:    "duplicate name",
+			values:  []string{"garden=http://garden:9294", "garden=http://shed:9294"},
+			wantErr: true,
+		},
+		{
+			desc:    "missing name",
+			values:  []string{"=http://garden:9294"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var (
+				got GatewayList
+				err error
+			)
+			for _, value := range tc.values {
+				if err = got.Set(value); err != nil {
+					break
+				}
+			}
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got gateways %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got gateways %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSecretURL(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "url")
+	if err := os.WriteFile(fileName, []byte("postgres://user:secret@db/flowercare\n"), 0o600); err != nil {
+		t.Fatalf("can not write URL file: %s", err)
+	}
+	t.Setenv("FLOWERCARE_TEST_URL", "redis://:secret@redis:6379/0")
+
+	tests := []struct {
+		desc     string
+		url      string
+		fileName string
+		envName  string
+		want     string
+		wantErr  bool
+	}{
+		{
+			desc: "url",
+			url:  "postgres://db/flowercare",
+			want: "postgres://db/flowercare",
+		},
+		{
+			desc: "not set",
+			want: "",
+		},
+		{
+			desc:     "file",
+			fileName: fileName,
+			want:     "postgres://user:secret@db/flowercare",
+		},
+		{
+			desc:    "environment variable",
+			envName: "FLOWERCARE_TEST_URL",
+			want:    "redis://:secret@redis:6379/0",
+		},
+		{
+			desc:    "missing environment variable",
+			envName: "FLOWERCARE_TEST_MISSING",
+			wantErr: true,
+		},
+		{
+			desc:     "url and file",
+			url:      "postgres://db/flowercare",
+			fileName: fileName,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := secretURL(tc.url, tc.fileName, tc.envName, "test URL")
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got URL %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got URL %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
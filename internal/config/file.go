@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSensor is a sensor in the configuration file.
+type fileSensor struct {
+	Name       string `yaml:"name"`
+	MacAddress string `yaml:"mac"`
+}
+
+// loadFile reads the YAML configuration file and sets the flags it contains. The keys of the file are the names of the
+// flags, repeatable flags take a list of values. Sensors can also be listed using the sensors key. Flags set on the
+// command line take precedence over the file.
+func loadFile(flags *pflag.FlagSet, fileName string) error {
+	raw, err := os.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("can not read config file: %s", err)
+	}
+
+	var content map[string]yaml.Node
+	if err := yaml.Unmarshal(raw, &content); err != nil {
+		return fmt.Errorf("can not parse config file %q: %s", fileName, err)
+	}
+
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		node := content[key]
+		if err := setFromFile(flags, key, &node); err != nil {
+			return fmt.Errorf("error in config file %q: %s: %s", fileName, key, err)
+		}
+	}
+
+	return nil
+}
+
+func setFromFile(flags *pflag.FlagSet, key string, node *yaml.Node) error {
+	if key == "sensors" {
+		return setSensorsFromFile(flags, node)
+	}
+
+	if key == "config" {
+		return errors.New("config files can not be nested")
+	}
+
+	if flags.Lookup(key) == nil {
+		return errors.New("unknown option")
+	}
+
+	if flags.Changed(key) {
+		return nil
+	}
+
+	values, err := nodeValues(node)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		if err := flags.Set(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setSensorsFromFile adds the sensors listed in the file, unless sensors are set on the command line.
+func setSensorsFromFile(flags *pflag.FlagSet, node *yaml.Node) error {
+	if flags.Changed("sensor") {
+		return nil
+	}
+
+	var sensors []fileSensor
+	if err := node.Decode(&sensors); err != nil {
+		return err
+	}
+
+	for _, s := range sensors {
+		if s.MacAddress == "" {
+			return errors.New("sensor without MAC address")
+		}
+
+		value := s.MacAddress
+		if s.Name != "" {
+			value = s.Name + "=" + s.MacAddress
+		}
+
+		if err := flags.Set("sensor", value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nodeValues returns the values of a scalar or a list of scalars.
+func nodeValues(node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("list can only contain values, line %d", item.Line)
+			}
+
+			values = append(values, item.Value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("needs to be a value or a list of values, line %d", node.Line)
+	}
+}
@@ -0,0 +1,253 @@
+// Package grafana generates a Grafana dashboard matching the metrics of the exporter.
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+const (
+	datasourceVariable = "datasource"
+	sensorVariable     = "sensor"
+	panelWidth         = 12
+	panelHeight        = 8
+)
+
+// Dashboard is the subset of the Grafana dashboard model used by the generated dashboard.
+type Dashboard struct {
+	Title         string     `json:"title"`
+	UID           string     `json:"uid"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the default time range of the dashboard.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating contains the template variables of the dashboard.
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a template variable.
+type Variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      string      `json:"query"`
+	Multi      bool        `json:"multi,omitempty"`
+	IncludeAll bool        `json:"includeAll,omitempty"`
+	Current    interface{} `json:"current,omitempty"`
+}
+
+// Panel is a single panel on the dashboard.
+type Panel struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Type        string      `json:"type"`
+	Datasource  Datasource  `json:"datasource"`
+	GridPos     GridPos     `json:"gridPos"`
+	FieldConfig FieldConfig `json:"fieldConfig"`
+	Targets     []Target    `json:"targets"`
+}
+
+// Datasource references the datasource used by a panel.
+type Datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// GridPos contains the position of a panel.
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// FieldConfig contains the display settings of a panel.
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+// FieldDefaults contains the unit and display range of a panel.
+type FieldDefaults struct {
+	Unit string   `json:"unit"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// Target is a query of a panel.
+type Target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+type panelSpec struct {
+	Title  string
+	Metric string
+	Unit   string
+	Min    *float64
+	Max    *float64
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+var (
+	panels = []panelSpec{
+		{
+			Title:  "Soil moisture",
+			Metric: "moisture_percent",
+			Unit:   "percent",
+			Min:    float(0),
+			Max:    float(100),
+		},
+		{
+			Title:  "Temperature",
+			Metric: "temperature_celsius",
+			Unit:   "celsius",
+		},
+		{
+			Title:  "Brightness",
+			Metric: "brightness_lux",
+			Unit:   "lux",
+			Min:    float(0),
+		},
+		{
+			Title:  "Soil conductivity",
+			Metric: "conductivity_sm",
+			Unit:   "conS",
+			Min:    float(0),
+		},
+		{
+			Title:  "Battery",
+			Metric: "battery_percent",
+			Unit:   "percent",
+			Min:    float(0),
+			Max:    float(100),
+		},
+		{
+			Title:  "Sensor up",
+			Metric: "up",
+			Unit:   "bool",
+			Min:    float(0),
+			Max:    float(1),
+		},
+	}
+
+	uidSanitizer = regexp.MustCompile("[^a-zA-Z0-9-]+")
+)
+
+// New creates a dashboard for the given metric prefix and sensors.
+func New(metricPrefix string, sensors []config.Sensor) Dashboard {
+	options := []string{}
+	for _, s := range sensors {
+		options = append(options, sensorOption(s))
+	}
+
+	datasource := Datasource{
+		Type: "prometheus",
+		UID:  "${" + datasourceVariable + "}",
+	}
+
+	result := Dashboard{
+		Title:         "Flower Care",
+		UID:           uidSanitizer.ReplaceAllString(strings.TrimSuffix(metricPrefix, "_"), "-"),
+		Tags:          []string{"flowercare"},
+		Timezone:      "browser",
+		SchemaVersion: 36,
+		Refresh:       "1m",
+		Time: TimeRange{
+			From: "now-24h",
+			To:   "now",
+		},
+		Templating: Templating{
+			List: []Variable{
+				{
+					Name:  datasourceVariable,
+					Label: "Datasource",
+					Type:  "datasource",
+					Query: "prometheus",
+				},
+				{
+					Name:       sensorVariable,
+					Label:      "Sensor",
+					Type:       "custom",
+					Query:      strings.Join(options, ","),
+					Multi:      true,
+					IncludeAll: true,
+					Current: map[string]interface{}{
+						"text":  "All",
+						"value": "$__all",
+					},
+				},
+			},
+		},
+	}
+
+	for i, p := range panels {
+		result.Panels = append(result.Panels, Panel{
+			ID:         i + 1,
+			Title:      p.Title,
+			Type:       "timeseries",
+			Datasource: datasource,
+			GridPos: GridPos{
+				X: (i % 2) * panelWidth,
+				Y: (i / 2) * panelHeight,
+				W: panelWidth,
+				H: panelHeight,
+			},
+			FieldConfig: FieldConfig{
+				Defaults: FieldDefaults{
+					Unit: p.Unit,
+					Min:  p.Min,
+					Max:  p.Max,
+				},
+			},
+			Targets: []Target{
+				{
+					RefID:        "A",
+					Expr:         fmt.Sprintf(`%s%s{macaddress=~"${%s:regex}"}`, metricPrefix, p.Metric, sensorVariable),
+					LegendFormat: "{{name}}",
+				},
+			},
+		})
+	}
+
+	return result
+}
+
+// sensorOption returns a "text : value" option for the sensor variable, showing the name and selecting by MAC address.
+func sensorOption(s config.Sensor) string {
+	if s.Name == "" {
+		return s.MacAddress
+	}
+
+	return fmt.Sprintf("%s : %s", s.Name, s.MacAddress)
+}
+
+// Handler returns an HTTP handler serving the dashboard as JSON.
+func Handler(metricPrefix string, sensors func() []config.Sensor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(New(metricPrefix, sensors())); err != nil {
+			http.Error(w, fmt.Sprintf("can not encode dashboard: %s", err), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+// Package hcistats collects low-level statistics about the usage of a Bluetooth adapter.
+package hcistats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-ble/ble"
+	"github.com/go-ble/ble/linux/hci"
+	"github.com/go-ble/ble/linux/hci/evt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricPrefix = "flowercare_hci_"
+
+// Stats implements a Prometheus collector containing statistics about one Bluetooth adapter.
+type Stats struct {
+	dialAttempts       prometheus.Counter
+	dialErrors         prometheus.Counter
+	connectionTimeouts prometheus.Counter
+	disconnects        *prometheus.CounterVec
+	advertisements     prometheus.Counter
+}
+
+// New creates a new set of statistics for the named adapter.
+func New(adapter string) *Stats {
+	labels := prometheus.Labels{
+		"adapter": adapter,
+	}
+
+	return &Stats{
+		dialAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        metricPrefix + "dial_attempts_total",
+			Help:        "Number of connection attempts made using the adapter.",
+			ConstLabels: labels,
+		}),
+		dialErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        metricPrefix + "dial_errors_total",
+			Help:        "Number of connection attempts which failed.",
+			ConstLabels: labels,
+		}),
+		connectionTimeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        metricPrefix + "connection_timeouts_total",
+			Help:        "Number of connection attempts which failed because of a timeout.",
+			ConstLabels: labels,
+		}),
+		disconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        metricPrefix + "disconnects_total",
+			Help:        "Number of disconnections by HCI reason code.",
+			ConstLabels: labels,
+		}, []string{"reason"}),
+		advertisements: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        metricPrefix + "advertisements_total",
+			Help:        "Number of advertisements received while scanning.",
+			ConstLabels: labels,
+		}),
+	}
+}
+
+// Options returns the device options needed to collect statistics from the HCI event stream.
+func (s *Stats) Options() []ble.Option {
+	return []ble.Option{
+		ble.OptDisconnectHandler(func(e evt.DisconnectionComplete) {
+			s.disconnects.WithLabelValues(fmt.Sprintf("0x%02x", e.Reason())).Inc()
+		}),
+	}
+}
+
+// Wrap returns a device which records statistics for operations done using the wrapped device.
+func (s *Stats) Wrap(device ble.Device) ble.Device {
+	return &statsDevice{
+		Device: device,
+		stats:  s,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (s *Stats) Describe(ch chan<- *prometheus.Desc) {
+	s.dialAttempts.Describe(ch)
+	s.dialErrors.Describe(ch)
+	s.connectionTimeouts.Describe(ch)
+	s.disconnects.Describe(ch)
+	s.advertisements.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (s *Stats) Collect(ch chan<- prometheus.Metric) {
+	s.dialAttempts.Collect(ch)
+	s.dialErrors.Collect(ch)
+	s.connectionTimeouts.Collect(ch)
+	s.disconnects.Collect(ch)
+	s.advertisements.Collect(ch)
+}
+
+type statsDevice struct {
+	ble.Device
+	stats *Stats
+}
+
+func (d *statsDevice) Dial(ctx context.Context, addr ble.Addr) (ble.Client, error) {
+	d.stats.dialAttempts.Inc()
+
+	c, err := d.Device.Dial(ctx, addr)
+	if err != nil {
+		d.stats.dialErrors.Inc()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, hci.ErrConnTimeout) {
+			d.stats.connectionTimeouts.Inc()
+		}
+	}
+
+	return c, err
+}
+
+// Unwrap returns the wrapped device.
+func (d *statsDevice) Unwrap() ble.Device {
+	return d.Device
+}
+
+func (d *statsDevice) Scan(ctx context.Context, allowDup bool, h ble.AdvHandler) error {
+	return d.Device.Scan(ctx, allowDup, func(a ble.Advertisement) {
+		d.stats.advertisements.Inc()
+		h(a)
+	})
+}
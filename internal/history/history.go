@@ -0,0 +1,435 @@
+// Package history keeps hourly and daily aggregates of the readings, so that the history of a sensor can be shown
+// without a time-series database.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	dateFormat = "2006-01-02"
+	// compactInterval is the interval for rolling up hourly aggregates, which are older than their retention, into
+	// daily aggregates.
+	compactInterval = time.Hour
+)
+
+var measurementValues = map[string]func(miflora.Data) float64{
+	"moisture":     func(d miflora.Data) float64 { return float64(d.Sensors.Moisture) },
+	"temperature":  func(d miflora.Data) float64 { return d.Sensors.Temperature },
+	"illuminance":  func(d miflora.Data) float64 { return float64(d.Sensors.Light) },
+	"conductivity": func(d miflora.Data) float64 { return float64(d.Sensors.Conductivity) },
+	"battery":      func(d miflora.Data) float64 { return float64(d.Firmware.Battery) },
+}
+
+// Aggregate contains the minimum, maximum and sum of the values of a measurement.
+type Aggregate struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Sum   float64 `json:"sum"`
+	Count int     `json:"count"`
+}
+
+func (a *Aggregate) add(value float64) {
+	if a.Count == 0 || value < a.Min {
+		a.Min = value
+	}
+	if a.Count == 0 || value > a.Max {
+		a.Max = value
+	}
+	a.Sum += value
+	a.Count++
+}
+
+// Avg returns the average of the values.
+func (a Aggregate) Avg() float64 {
+	if a.Count == 0 {
+		return 0
+	}
+
+	return a.Sum / float64(a.Count)
+}
+
+func (a *Aggregate) merge(other Aggregate) {
+	if other.Count == 0 {
+		return
+	}
+	if a.Count == 0 || other.Min < a.Min {
+		a.Min = other.Min
+	}
+	if a.Count == 0 || other.Max > a.Max {
+		a.Max = other.Max
+	}
+	a.Sum += other.Sum
+	a.Count += other.Count
+}
+
+// Day contains the aggregates of the measurements of a sensor on one day.
+type Day struct {
+	Date         string               `json:"date"`
+	Measurements map[string]Aggregate `json:"measurements"`
+}
+
+func (d *Day) merge(measurements map[string]Aggregate) {
+	for key, other := range measurements {
+		a := d.Measurements[key]
+		a.merge(other)
+		d.Measurements[key] = a
+	}
+}
+
+// Hour contains the aggregates of the measurements of a sensor during one hour.
+type Hour struct {
+	Start        time.Time            `json:"start"`
+	Measurements map[string]Aggregate `json:"measurements"`
+}
+
+// historyFile is the format of the history file. Files written by older versions only contain the days.
+type historyFile struct {
+	Days  map[string]map[string]Day  `json:"days"`
+	Hours map[string]map[string]Hour `json:"hours"`
+}
+
+// Store keeps aggregates of the readings of the sensors in two tiers: hourly aggregates for the last hourly retention
+// days and daily aggregates for the retention days. Compaction rolls hourly aggregates, which are older than their
+// retention, up into the daily aggregates and removes days older than the retention, so that the store does not grow
+// without bounds. If a file name is set, the aggregates are loaded on startup and saved to the file periodically and on
+// shutdown, so that storage on SD cards is not worn out by rewriting the file after every reading.
+type Store struct {
+	log             *logging.Logger
+	fileName        string
+	retention       int
+	hourlyRetention int
+	location        *time.Location
+
+	lock sync.RWMutex
+	// days contains the daily aggregates by MAC address and date. Days still having hourly aggregates only contain
+	// the compacted hours.
+	days map[string]map[string]Day
+	// hours contains the hourly aggregates by MAC address and start of the hour.
+	hours map[string]map[string]Hour
+	// dirty is set if the aggregates changed since they have been saved.
+	dirty bool
+}
+
+// Open creates a store, which keeps the daily aggregates for retention days and the hourly aggregates for hourly
+// retention days. Days and hours start in the time zone of the location.
+func Open(log *logging.Logger, fileName string, retention, hourlyRetention int, location *time.Location) (*Store, error) {
+	s := &Store{
+		log:             log,
+		fileName:        fileName,
+		retention:       retention,
+		hourlyRetention: hourlyRetention,
+		location:        location,
+		days:            map[string]map[string]Day{},
+		hours:           map[string]map[string]Hour{},
+	}
+
+	if fileName == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := s.load(raw); err != nil {
+		return nil, fmt.Errorf("can not parse history file %q: %s", fileName, err)
+	}
+	s.compact(time.Now())
+
+	return s, nil
+}
+
+func (s *Store) load(raw []byte) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return err
+	}
+
+	if _, ok := keys["days"]; !ok {
+		return json.Unmarshal(raw, &s.days)
+	}
+
+	var file historyFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return err
+	}
+
+	if file.Days != nil {
+		s.days = file.Days
+	}
+	if file.Hours != nil {
+		s.hours = file.Hours
+	}
+	return nil
+}
+
+// Start saves the aggregates every interval, if they have changed, until the context is done. They are saved a last
+// time on shutdown. The aggregates are compacted every compactInterval.
+func (s *Store) Start(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		compactTicker := time.NewTicker(compactInterval)
+		defer compactTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := s.Flush(); err != nil {
+					s.log.Errorf("Error saving history: %s", err)
+				}
+				return
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					s.log.Errorf("Error saving history: %s", err)
+				}
+			case now := <-compactTicker.C:
+				s.lock.Lock()
+				s.compact(now)
+				s.lock.Unlock()
+			}
+		}
+	}()
+}
+
+// Observe adds the reading to the aggregates of its hour. The aggregates are saved by the next Flush.
+func (s *Store) Observe(sensor config.Sensor, data miflora.Data) {
+	mac := strings.ToUpper(sensor.MacAddress)
+	start := s.hourStart(data.Time)
+	key := start.Format(time.RFC3339)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hours, ok := s.hours[mac]
+	if !ok {
+		hours = map[string]Hour{}
+		s.hours[mac] = hours
+	}
+
+	hour, ok := hours[key]
+	if !ok {
+		hour = Hour{
+			Start:        start,
+			Measurements: map[string]Aggregate{},
+		}
+	}
+
+	for key, value := range measurementValues {
+		if key == "battery" && !data.Firmware.Known() {
+			continue
+		}
+
+		a := hour.Measurements[key]
+		a.add(value(data))
+		hour.Measurements[key] = a
+	}
+	hours[key] = hour
+	s.dirty = true
+}
+
+// hourStart returns the start of the hour of the time in the time zone of the store.
+func (s *Store) hourStart(t time.Time) time.Time {
+	t = t.In(s.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.location)
+}
+
+// compact rolls the hourly aggregates, which are older than the hourly retention, up into the daily aggregates and
+// removes the days, which are older than the retention. The caller needs to hold the lock.
+func (s *Store) compact(now time.Time) {
+	hourlyOldest := now.AddDate(0, 0, -s.hourlyRetention)
+	for mac, hours := range s.hours {
+		for key, hour := range hours {
+			if !hour.Start.Before(hourlyOldest) {
+				continue
+			}
+
+			days, ok := s.days[mac]
+			if !ok {
+				days = map[string]Day{}
+				s.days[mac] = days
+			}
+
+			date := hour.Start.In(s.location).Format(dateFormat)
+			day, ok := days[date]
+			if !ok {
+				day = Day{
+					Date:         date,
+					Measurements: map[string]Aggregate{},
+				}
+			}
+			day.merge(hour.Measurements)
+			days[date] = day
+			delete(hours, key)
+			s.dirty = true
+		}
+
+		if len(hours) == 0 {
+			delete(s.hours, mac)
+		}
+	}
+
+	oldest := now.In(s.location).AddDate(0, 0, -s.retention).Format(dateFormat)
+	for mac, days := range s.days {
+		for date := range days {
+			if date <= oldest {
+				delete(days, date)
+				s.dirty = true
+			}
+		}
+
+		if len(days) == 0 {
+			delete(s.days, mac)
+		}
+	}
+}
+
+// Flush saves the aggregates to the file, if they have changed since they have been saved.
+func (s *Store) Flush() error {
+	if s.fileName == "" {
+		return nil
+	}
+
+	s.lock.Lock()
+	if !s.dirty {
+		s.lock.Unlock()
+		return nil
+	}
+
+	raw, err := json.Marshal(historyFile{
+		Days:  s.days,
+		Hours: s.hours,
+	})
+	s.dirty = false
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := s.save(raw); err != nil {
+		s.lock.Lock()
+		s.dirty = true
+		s.lock.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) save(raw []byte) error {
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.fileName), filepath.Base(s.fileName)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.fileName)
+}
+
+// Daily returns the daily aggregates of the sensor, oldest day first. Days, which still have hourly aggregates, include
+// them. If days is positive, only the last days are returned.
+func (s *Store) Daily(macAddress string, days int) []Day {
+	mac := strings.ToUpper(macAddress)
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	byDate := map[string]Day{}
+	for date, day := range s.days[mac] {
+		d := Day{
+			Date:         day.Date,
+			Measurements: make(map[string]Aggregate, len(day.Measurements)),
+		}
+		d.merge(day.Measurements)
+		byDate[date] = d
+	}
+
+	for _, hour := range s.hours[mac] {
+		date := hour.Start.In(s.location).Format(dateFormat)
+		d, ok := byDate[date]
+		if !ok {
+			d = Day{
+				Date:         date,
+				Measurements: map[string]Aggregate{},
+			}
+		}
+		d.merge(hour.Measurements)
+		byDate[date] = d
+	}
+
+	result := make([]Day, 0, len(byDate))
+	for _, day := range byDate {
+		result = append(result, day)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	if days > 0 && len(result) > days {
+		result = result[len(result)-days:]
+	}
+
+	return result
+}
+
+// Hourly returns the hourly aggregates of the sensor, oldest hour first. If hours is positive, only the last hours are
+// returned.
+func (s *Store) Hourly(macAddress string, hours int) []Hour {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := []Hour{}
+	for _, hour := range s.hours[strings.ToUpper(macAddress)] {
+		h := Hour{
+			Start:        hour.Start.In(s.location),
+			Measurements: make(map[string]Aggregate, len(hour.Measurements)),
+		}
+		for key, a := range hour.Measurements {
+			h.Measurements[key] = a
+		}
+		result = append(result, h)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+
+	if hours > 0 && len(result) > hours {
+		result = result[len(result)-hours:]
+	}
+
+	return result
+}
@@ -0,0 +1,125 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+func TestStoreFlush(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "history.json")
+	sensor := config.Sensor{
+		MacAddress: "c4:7c:8d:00:00:01",
+	}
+
+	s, err := Open(nil, fileName, 7, 2, time.UTC)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	s.Observe(sensor, miflora.Data{
+		Time: time.Now(),
+		Sensors: miflora.Sensors{
+			Temperature: 21.5,
+		},
+	})
+
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Fatalf("history file written before flush: %v", err)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if _, err := os.Stat(fileName); err != nil {
+		t.Fatalf("history file not written: %s", err)
+	}
+
+	// Nothing changed, so the file is not rewritten.
+	if err := os.Chtimes(fileName, time.Time{}, time.Unix(0, 0)); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	if unchanged, _ := os.Stat(fileName); !unchanged.ModTime().Equal(time.Unix(0, 0)) {
+		t.Error("history file rewritten without changes")
+	}
+
+	loaded, err := Open(nil, fileName, 7, 2, time.UTC)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	days := loaded.Daily(sensor.MacAddress, 0)
+	if len(days) != 1 {
+		t.Fatalf("got %d days, want 1", len(days))
+	}
+
+	if got := days[0].Measurements["temperature"]; got.Count != 1 || got.Max != 21.5 {
+		t.Errorf("got temperature %#v, want one value of 21.5", got)
+	}
+}
+
+func TestStoreCompact(t *testing.T) {
+	sensor := config.Sensor{
+		MacAddress: "c4:7c:8d:00:00:01",
+	}
+	now := time.Date(2023, 6, 10, 12, 30, 0, 0, time.UTC)
+
+	s, err := Open(nil, "", 5, 2, time.UTC)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	for _, tc := range []struct {
+		time        time.Time
+		temperature float64
+	}{
+		{time: time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC), temperature: 15},
+		{time: time.Date(2023, 6, 7, 10, 0, 0, 0, time.UTC), temperature: 20},
+		{time: time.Date(2023, 6, 7, 11, 0, 0, 0, time.UTC), temperature: 24},
+		{time: time.Date(2023, 6, 9, 10, 0, 0, 0, time.UTC), temperature: 22},
+		{time: time.Date(2023, 6, 10, 12, 0, 0, 0, time.UTC), temperature: 18},
+		{time: time.Date(2023, 6, 10, 12, 15, 0, 0, time.UTC), temperature: 19},
+	} {
+		s.Observe(sensor, miflora.Data{
+			Time: tc.time,
+			Sensors: miflora.Sensors{
+				Temperature: tc.temperature,
+			},
+		})
+	}
+
+	s.compact(now)
+
+	hours := s.Hourly(sensor.MacAddress, 0)
+	if len(hours) != 2 {
+		t.Fatalf("got %d hours, want 2", len(hours))
+	}
+	if got := hours[1].Measurements["temperature"]; got.Count != 2 || got.Min != 18 || got.Max != 19 {
+		t.Errorf("got temperature %#v for last hour, want 18 to 19", got)
+	}
+	if got, ok := hours[1].Measurements["battery"]; ok {
+		t.Errorf("got battery %#v for readings without firmware information", got)
+	}
+
+	days := s.Daily(sensor.MacAddress, 0)
+	wantDates := []string{"2023-06-07", "2023-06-09", "2023-06-10"}
+	if len(days) != len(wantDates) {
+		t.Fatalf("got %d days, want %d", len(days), len(wantDates))
+	}
+	for i, want := range wantDates {
+		if days[i].Date != want {
+			t.Errorf("got date %q at %d, want %q", days[i].Date, i, want)
+		}
+	}
+	if got := days[0].Measurements["temperature"]; got.Count != 2 || got.Avg() != 22 {
+		t.Errorf("got temperature %#v for compacted day, want average 22 of two readings", got)
+	}
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// journaldHandler sends messages to the systemd journal using its native protocol. The attributes are added as
+// fields with uppercase names.
+type journaldHandler struct {
+	level      slog.Leveler
+	identifier string
+	conn       *net.UnixConn
+	lock       *sync.Mutex
+	attrs      []slog.Attr
+	prefix     string
+}
+
+func newJournaldHandler(level slog.Leveler) (*journaldHandler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to journal: %s", err)
+	}
+
+	return &journaldHandler{
+		level:      level,
+		identifier: filepath.Base(os.Args[0]),
+		conn:       conn,
+		lock:       &sync.Mutex{},
+	}, nil
+}
+
+// Enabled implements slog.Handler
+func (h *journaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *journaldHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := &bytes.Buffer{}
+	writeJournalField(buf, "MESSAGE", r.Message)
+	writeJournalField(buf, "PRIORITY", journalPriority(r.Level))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", h.identifier)
+	for _, a := range h.attrs {
+		writeJournalAttr(buf, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeJournalAttr(buf, h.prefix, a)
+		return true
+	})
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *journaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	result := *h
+	result.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	result.attrs = append(result.attrs, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		result.attrs = append(result.attrs, a)
+	}
+	return &result
+}
+
+// WithGroup implements slog.Handler
+func (h *journaldHandler) WithGroup(name string) slog.Handler {
+	result := *h
+	result.prefix = h.prefix + name + "_"
+	return &result
+}
+
+func journalPriority(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "3"
+	case level >= slog.LevelWarn:
+		return "4"
+	case level >= slog.LevelInfo:
+		return "6"
+	default:
+		return "7"
+	}
+}
+
+func writeJournalAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeJournalAttr(buf, prefix+a.Key+"_", ga)
+		}
+		return
+	}
+
+	if a.Key == "" {
+		return
+	}
+
+	writeJournalField(buf, journalFieldName(prefix+a.Key), a.Value.String())
+}
+
+// journalFieldName converts the key to a valid field name, which only contains uppercase letters, digits and
+// underscores and does not start with an underscore.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	return strings.TrimLeft(name, "_")
+}
+
+// writeJournalField writes a field. Values containing newlines use the binary format with an explicit length.
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if name == "" {
+		return
+	}
+
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
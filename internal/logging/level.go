@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LevelName returns the lowercase name of the level.
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// ParseLevel parses the name of a level. "warning" is accepted as an alias of "warn".
+func ParseLevel(name string) (slog.Level, error) {
+	if strings.EqualFold(name, "warning") {
+		return slog.LevelWarn, nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level: %s", name)
+	}
+
+	return level, nil
+}
+
+// cycle contains the levels in the order they are selected by NextLevel.
+var cycle = []slog.Level{slog.LevelError, slog.LevelWarn, slog.LevelInfo, slog.LevelDebug}
+
+// NextLevel returns the next more verbose level. After debug it starts over at error.
+func NextLevel(level slog.Level) slog.Level {
+	for _, l := range cycle {
+		if l < level {
+			return l
+		}
+	}
+
+	return cycle[0]
+}
@@ -0,0 +1,196 @@
+// Package logging contains the logger used by the packages of the exporter. It formats messages like the functions of
+// the fmt package and passes them to a log/slog handler, so that applications using the packages can provide their
+// own handler.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Logger writes messages to a slog.Logger. A nil Logger discards all messages.
+type Logger struct {
+	logger *slog.Logger
+}
+
+// New creates a logger using the slog.Logger. If it is nil, all messages are discarded.
+func New(logger *slog.Logger) *Logger {
+	if logger == nil {
+		return nil
+	}
+
+	return &Logger{
+		logger: logger,
+	}
+}
+
+// Discard returns a logger, which discards all messages.
+func Discard() *Logger {
+	return nil
+}
+
+// Slog returns the underlying slog.Logger.
+func (l *Logger) Slog() *slog.Logger {
+	if l == nil {
+		return slog.New(discardHandler{})
+	}
+
+	return l.logger
+}
+
+// With returns a logger, which adds the attributes to every message.
+func (l *Logger) With(args ...interface{}) *Logger {
+	if l == nil {
+		return nil
+	}
+
+	return New(l.logger.With(args...))
+}
+
+func (l *Logger) log(level slog.Level, msg string) {
+	if l == nil {
+		return
+	}
+
+	l.logger.Log(context.Background(), level, msg)
+}
+
+func (l *Logger) enabled(level slog.Level) bool {
+	return l != nil && l.logger.Enabled(context.Background(), level)
+}
+
+// Debug logs the arguments formatted like fmt.Sprint at debug level.
+func (l *Logger) Debug(args ...interface{}) {
+	if l.enabled(slog.LevelDebug) {
+		l.log(slog.LevelDebug, fmt.Sprint(args...))
+	}
+}
+
+// Debugf logs the arguments formatted like fmt.Sprintf at debug level.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if l.enabled(slog.LevelDebug) {
+		l.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+	}
+}
+
+// Info logs the arguments formatted like fmt.Sprint at info level.
+func (l *Logger) Info(args ...interface{}) {
+	if l.enabled(slog.LevelInfo) {
+		l.log(slog.LevelInfo, fmt.Sprint(args...))
+	}
+}
+
+// Infof logs the arguments formatted like fmt.Sprintf at info level.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if l.enabled(slog.LevelInfo) {
+		l.log(slog.LevelInfo, fmt.Sprintf(format, args...))
+	}
+}
+
+// Warn logs the arguments formatted like fmt.Sprint at warning level.
+func (l *Logger) Warn(args ...interface{}) {
+	if l.enabled(slog.LevelWarn) {
+		l.log(slog.LevelWarn, fmt.Sprint(args...))
+	}
+}
+
+// Warnf logs the arguments formatted like fmt.Sprintf at warning level.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if l.enabled(slog.LevelWarn) {
+		l.log(slog.LevelWarn, fmt.Sprintf(format, args...))
+	}
+}
+
+// Error logs the arguments formatted like fmt.Sprint at error level.
+func (l *Logger) Error(args ...interface{}) {
+	if l.enabled(slog.LevelError) {
+		l.log(slog.LevelError, fmt.Sprint(args...))
+	}
+}
+
+// Errorf logs the arguments formatted like fmt.Sprintf at error level.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if l.enabled(slog.LevelError) {
+		l.log(slog.LevelError, fmt.Sprintf(format, args...))
+	}
+}
+
+// Fatal logs the arguments formatted like fmt.Sprint at error level and exits the program.
+func (l *Logger) Fatal(args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+// Fatalf logs the arguments formatted like fmt.Sprintf at error level and exits the program.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// Format selects the output format of the handler created by NewHandler.
+type Format string
+
+const (
+	// FormatText writes one line of key=value pairs per message.
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per message.
+	FormatJSON Format = "json"
+	// FormatJournald sends the messages to the systemd journal.
+	FormatJournald Format = "journald"
+)
+
+// Formats contains all supported formats.
+var Formats = []Format{FormatText, FormatJSON, FormatJournald}
+
+// NewHandler creates a handler writing messages in the format to the writer. The journald format does not use the
+// writer, but sends the messages to the journal directly.
+func NewHandler(format Format, w io.Writer, level slog.Leveler) (slog.Handler, error) {
+	switch format {
+	case FormatText:
+		return NewTextHandler(w, level), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+		}), nil
+	case FormatJournald:
+		return newJournaldHandler(level)
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+}
+
+// NewTextHandler creates a handler writing one line of key=value pairs per message, without the time.
+func NewTextHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: replaceTextAttr,
+	})
+}
+
+// replaceTextAttr removes the time, which is added by the service manager, and uses lowercase level names.
+func replaceTextAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+
+	switch a.Key {
+	case slog.TimeKey:
+		return slog.Attr{}
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			return slog.String(slog.LevelKey, LevelName(level))
+		}
+	}
+
+	return a
+}
@@ -0,0 +1,139 @@
+// Package loki contains a client for pushing log lines to Grafana Loki.
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+const pushPath = "/loki/api/v1/push"
+
+// Entry is a single log line.
+type Entry struct {
+	Time time.Time
+	// Labels are added to the labels of the client to select the stream of the entry.
+	Labels map[string]string
+	Line   string
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+// Client pushes entries to Loki using the JSON format of the push API.
+type Client struct {
+	url    string
+	tenant string
+	labels map[string]string
+	client *http.Client
+}
+
+// New creates a client for the Loki instance. The labels are added to all streams.
+func New(cfg config.LokiConfig, labels map[string]string, auth clientauth.Profile) (*Client, error) {
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		url:    strings.TrimSuffix(cfg.URL, "/") + pushPath,
+		tenant: cfg.Tenant,
+		labels: labels,
+		client: client,
+	}, nil
+}
+
+// Push sends the entries to Loki.
+func (c *Client) Push(ctx context.Context, entries []Entry) error {
+	// Loki rejects entries which are older than the newest entry of a stream.
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	streams := map[string]*stream{}
+	keys := []string{}
+	for _, e := range sorted {
+		labels := make(map[string]string, len(c.labels)+len(e.Labels))
+		for name, value := range c.labels {
+			labels[name] = value
+		}
+		for name, value := range e.Labels {
+			labels[name] = value
+		}
+
+		key := streamKey(labels)
+		s, ok := streams[key]
+		if !ok {
+			s = &stream{
+				Stream: labels,
+			}
+			streams[key] = s
+			keys = append(keys, key)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), e.Line})
+	}
+
+	req := pushRequest{}
+	for _, key := range keys {
+		req.Streams = append(req.Streams, *streams[key])
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("can not encode entries: %s", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.tenant != "" {
+		httpReq.Header.Set("X-Scope-OrgID", c.tenant)
+	}
+
+	res, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("can not push entries: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("loki returned status %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+func streamKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := &strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(b, "%s=%q,", name, labels[name])
+	}
+	return b.String()
+}
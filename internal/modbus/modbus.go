@@ -0,0 +1,312 @@
+// Package modbus contains a Modbus TCP server, which provides the latest readings of the sensors as registers, so that
+// they can be used by PLCs and irrigation controllers.
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	functionReadHoldingRegisters = 0x03
+	functionReadInputRegisters   = 0x04
+
+	exceptionIllegalFunction    = 0x01
+	exceptionIllegalDataAddress = 0x02
+	exceptionIllegalDataValue   = 0x03
+
+	maxRegisters = 125
+	idleTimeout  = 5 * time.Minute
+)
+
+// noData is the value of the age register of sensors without a reading. All other registers are zero.
+const noData = 0xFFFF
+
+// unknownBattery is the value of the battery register, if the reading does not contain the battery level.
+const unknownBattery = 0xFFFF
+
+// register converts a reading into the value of a register.
+type register func(data miflora.Data, now time.Time) uint16
+
+var registers = map[string]register{
+	"moisture": func(d miflora.Data, _ time.Time) uint16 {
+		return uint16(d.Sensors.Moisture)
+	},
+	// The temperature is a signed value in tenths of a degree.
+	"temperature": func(d miflora.Data, _ time.Time) uint16 {
+		return uint16(int16(math.Round(d.Sensors.Temperature * 10)))
+	},
+	// The illuminance is limited to the range of a register.
+	"illuminance": func(d miflora.Data, _ time.Time) uint16 {
+		if d.Sensors.Light > math.MaxUint16 {
+			return math.MaxUint16
+		}
+
+		return uint16(d.Sensors.Light)
+	},
+	"conductivity": func(d miflora.Data, _ time.Time) uint16 {
+		return d.Sensors.Conductivity
+	},
+	"battery": func(d miflora.Data, _ time.Time) uint16 {
+		if !d.Firmware.Known() {
+			return unknownBattery
+		}
+		return uint16(d.Firmware.Battery)
+	},
+	// The age of the reading is in seconds, limited to the largest value of a register.
+	"age": func(d miflora.Data, now time.Time) uint16 {
+		age := now.Sub(d.Time).Seconds()
+		if age < 0 {
+			return 0
+		}
+		if age >= noData {
+			return noData - 1
+		}
+		return uint16(age)
+	},
+}
+
+// DefaultRegisters is the layout of the registers of a sensor, which is used if a mapping does not set one.
+var DefaultRegisters = []string{"moisture", "temperature", "illuminance", "conductivity", "battery", "age"}
+
+// Mapping places the registers of a sensor, identified by its name or MAC address, at an address.
+type Mapping struct {
+	Sensor    string   `json:"sensor"`
+	Address   uint16   `json:"address"`
+	Registers []string `json:"registers,omitempty"`
+}
+
+// LoadMappings reads the mappings of the sensors from a JSON file and checks that their registers do not overlap.
+func LoadMappings(fileName string) ([]Mapping, error) {
+	raw, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []Mapping
+	if err := json.Unmarshal(raw, &mappings); err != nil {
+		return nil, fmt.Errorf("can not parse register map %q: %s", fileName, err)
+	}
+
+	used := map[int]string{}
+	for i, m := range mappings {
+		if m.Sensor == "" {
+			return nil, fmt.Errorf("mapping %d needs a sensor", i)
+		}
+
+		if len(m.Registers) == 0 {
+			mappings[i].Registers = DefaultRegisters
+		}
+
+		for offset, name := range mappings[i].Registers {
+			if _, ok := registers[name]; !ok {
+				return nil, fmt.Errorf("unknown register %q of sensor %q", name, m.Sensor)
+			}
+
+			address := int(m.Address) + offset
+			if address > math.MaxUint16 {
+				return nil, fmt.Errorf("registers of sensor %q exceed the address range", m.Sensor)
+			}
+
+			if other, ok := used[address]; ok {
+				return nil, fmt.Errorf("register %d of sensor %q is already used by %q", address, m.Sensor, other)
+			}
+			used[address] = m.Sensor
+		}
+	}
+
+	return mappings, nil
+}
+
+// Server answers requests for reading holding and input registers. Both contain the same values.
+type Server struct {
+	Log      *logging.Logger
+	Mappings []Mapping
+	Sensors  func() []config.Sensor
+	Source   func(macAddress string) (miflora.Data, error)
+
+	listener net.Listener
+}
+
+// Listen opens the listening socket of the server.
+func (s *Server) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	return nil
+}
+
+// Start accepts connections until the context is cancelled.
+func (s *Server) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		<-ctx.Done()
+		if err := s.listener.Close(); err != nil {
+			s.Log.Errorf("Error closing Modbus listener: %s", err)
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := s.listener.Accept()
+			switch {
+			case errors.Is(err, net.ErrClosed):
+				return
+			case err != nil:
+				s.Log.Errorf("Error accepting Modbus connection: %s", err)
+				continue
+			}
+
+			go s.serve(ctx, conn)
+		}
+	}()
+}
+
+func (s *Server) serve(ctx context.Context, conn net.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+	defer conn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	header := make([]byte, 7)
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+
+		// MBAP header: transaction ID, protocol ID, length, unit ID
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.Log.Debugf("Error reading Modbus request from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		length := binary.BigEndian.Uint16(header[4:6])
+		if binary.BigEndian.Uint16(header[2:4]) != 0 || length < 2 || length > 254 {
+			s.Log.Debugf("Invalid Modbus header from %s", conn.RemoteAddr())
+			return
+		}
+
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		response := s.handle(pdu, time.Now())
+
+		result := make([]byte, 7, 7+len(response))
+		copy(result, header)
+		binary.BigEndian.PutUint16(result[4:6], uint16(len(response)+1))
+		if _, err := conn.Write(append(result, response...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(pdu []byte, now time.Time) []byte {
+	function := pdu[0]
+	if function != functionReadHoldingRegisters && function != functionReadInputRegisters {
+		return exception(function, exceptionIllegalFunction)
+	}
+
+	if len(pdu) != 5 {
+		return exception(function, exceptionIllegalDataValue)
+	}
+
+	start := int(binary.BigEndian.Uint16(pdu[1:3]))
+	count := int(binary.BigEndian.Uint16(pdu[3:5]))
+	if count < 1 || count > maxRegisters {
+		return exception(function, exceptionIllegalDataValue)
+	}
+
+	values, ok := s.read(start, count, now)
+	if !ok {
+		return exception(function, exceptionIllegalDataAddress)
+	}
+
+	response := make([]byte, 2, 2+2*count)
+	response[0] = function
+	response[1] = byte(2 * count)
+	for _, v := range values {
+		response = binary.BigEndian.AppendUint16(response, v)
+	}
+
+	return response
+}
+
+// read returns the values of the registers or false if one of them is not mapped.
+func (s *Server) read(start, count int, now time.Time) ([]uint16, bool) {
+	sensors := s.Sensors()
+
+	values := make([]uint16, count)
+	mapped := make([]bool, count)
+	for _, m := range s.Mappings {
+		var data *miflora.Data
+		if sensor, ok := findSensor(sensors, m.Sensor); ok {
+			if d, err := s.Source(sensor.MacAddress); err == nil {
+				data = &d
+			}
+		}
+
+		for offset, name := range m.Registers {
+			i := int(m.Address) + offset - start
+			if i < 0 || i >= count {
+				continue
+			}
+
+			mapped[i] = true
+			switch {
+			case data != nil:
+				values[i] = registers[name](*data, now)
+			case name == "age":
+				values[i] = noData
+			}
+		}
+	}
+
+	for _, ok := range mapped {
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return values, true
+}
+
+func findSensor(sensors []config.Sensor, name string) (config.Sensor, bool) {
+	for _, s := range sensors {
+		if strings.EqualFold(s.MacAddress, name) || (s.Name != "" && s.Name == name) {
+			return s, true
+		}
+	}
+
+	return config.Sensor{}, false
+}
+
+func exception(function, code byte) []byte {
+	return []byte{function | 0x80, code}
+}
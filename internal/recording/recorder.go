@@ -0,0 +1,112 @@
+package recording
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+type reader interface {
+	ReadData(ctx context.Context, macAddress string) (miflora.Data, error)
+}
+
+// Recorder wraps a reader and appends every reading, including the raw payloads, to a recording file.
+// Readings which could not be parsed are recorded with their raw payloads and the error.
+type Recorder struct {
+	log    *logging.Logger
+	reader reader
+	names  map[string]string
+
+	fileLock sync.Mutex
+	file     *os.File
+	encoder  *json.Encoder
+}
+
+// NewRecorder creates a Recorder appending to the named file.
+func NewRecorder(log *logging.Logger, fileName string, reader reader, sensors []config.Sensor) (*Recorder, error) {
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for _, s := range sensors {
+		names[s.MacAddress] = s.Name
+	}
+
+	return &Recorder{
+		log:     log,
+		reader:  reader,
+		names:   names,
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// ReadData implements updater.Reader
+func (r *Recorder) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	data, err := r.reader.ReadData(ctx, macAddress)
+
+	var parseErr *miflora.ParseError
+	switch {
+	case err == nil:
+		r.write(r.newRecord(macAddress, data, data.Raw, nil))
+	case errors.As(err, &parseErr):
+		r.write(r.newRecord(macAddress, miflora.Data{Time: time.Now()}, parseErr.Raw, err))
+	}
+
+	return data, err
+}
+
+// Close closes the recording file and the wrapped reader, if it can be closed.
+func (r *Recorder) Close() error {
+	r.fileLock.Lock()
+	defer r.fileLock.Unlock()
+
+	if closer, ok := r.reader.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			r.log.Errorf("Error closing reader: %s", err)
+		}
+	}
+
+	return r.file.Close()
+}
+
+func (r *Recorder) newRecord(macAddress string, data miflora.Data, raw miflora.RawData, err error) Record {
+	record := Record{
+		Time:            data.Time,
+		MacAddress:      macAddress,
+		Name:            r.names[macAddress],
+		FirmwareVersion: data.Firmware.Version,
+		Battery:         data.Firmware.Battery,
+		Temperature:     data.Sensors.Temperature,
+		Moisture:        data.Sensors.Moisture,
+		Light:           data.Sensors.Light,
+		Conductivity:    data.Sensors.Conductivity,
+		FirmwareRaw:     hex.EncodeToString(raw.Firmware),
+		SensorsRaw:      hex.EncodeToString(raw.Sensors),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	return record
+}
+
+func (r *Recorder) write(record Record) {
+	r.fileLock.Lock()
+	defer r.fileLock.Unlock()
+
+	if err := r.encoder.Encode(record); err != nil {
+		r.log.Errorf("Error writing recording: %s", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package recording contains the file format used for recording sensor readings and a reader which replays them.
+package recording
+
+import (
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// Record contains a single reading of a sensor. Recording files contain one JSON-encoded record per line.
+type Record struct {
+	Time            time.Time `json:"time"`
+	MacAddress      string    `json:"macAddress"`
+	Name            string    `json:"name,omitempty"`
+	FirmwareVersion string    `json:"firmwareVersion"`
+	Battery         byte      `json:"battery"`
+	Temperature     float64   `json:"temperature"`
+	Moisture        byte      `json:"moisture"`
+	Light           uint32    `json:"light"`
+	Conductivity    uint16    `json:"conductivity"`
+	FirmwareRaw     string    `json:"firmwareRaw,omitempty"`
+	SensorsRaw      string    `json:"sensorsRaw,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// Data converts the record back to sensor data.
+func (r Record) Data() miflora.Data {
+	return miflora.Data{
+		Time: r.Time,
+		Firmware: miflora.Firmware{
+			Version: r.FirmwareVersion,
+			Battery: r.Battery,
+		},
+		Sensors: miflora.Sensors{
+			Temperature:  r.Temperature,
+			Moisture:     r.Moisture,
+			Light:        r.Light,
+			Conductivity: r.Conductivity,
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// AdapterName is used in place of a Bluetooth device name when replaying a recording.
+const AdapterName = "replay"
+
+// Replayer provides the readings from a recording file as if they were read from the sensors now.
+// The timeline of the recording starts when the Replayer is created and can be accelerated by a speed factor.
+type Replayer struct {
+	start    time.Time
+	first    time.Time
+	speed    float64
+	sensors  []config.Sensor
+	readings map[string][]Record
+}
+
+// Load reads all records from a recording file.
+func Load(fileName string, speed float64) (*Replayer, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("replay speed needs to be positive: %v", speed)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := &Replayer{
+		start:    time.Now(),
+		speed:    speed,
+		readings: map[string][]Record{},
+	}
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("can not parse line %d: %s", line, err)
+		}
+
+		if record.Error != "" {
+			// Failed readings only contain raw data, which can not be replayed.
+			continue
+		}
+
+		if result.first.IsZero() || record.Time.Before(result.first) {
+			result.first = record.Time
+		}
+
+		if _, ok := result.readings[record.MacAddress]; !ok {
+			result.sensors = append(result.sensors, config.Sensor{
+				Name:       record.Name,
+				MacAddress: record.MacAddress,
+			})
+		}
+		result.readings[record.MacAddress] = append(result.readings[record.MacAddress], record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can not read recording: %s", err)
+	}
+
+	if len(result.readings) == 0 {
+		return nil, errors.New("recording does not contain any readings")
+	}
+
+	for _, records := range result.readings {
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].Time.Before(records[j].Time)
+		})
+	}
+
+	return result, nil
+}
+
+// Sensors returns the sensors contained in the recording.
+func (r *Replayer) Sensors() []config.Sensor {
+	return r.sensors
+}
+
+// ReadData implements updater.Reader
+func (r *Replayer) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	if err := ctx.Err(); err != nil {
+		return miflora.Data{}, err
+	}
+
+	records, ok := r.readings[macAddress]
+	if !ok {
+		return miflora.Data{}, fmt.Errorf("no recorded data for sensor: %s", macAddress)
+	}
+
+	position := r.first.Add(time.Duration(float64(time.Since(r.start)) * r.speed))
+	index := sort.Search(len(records), func(i int) bool {
+		return records[i].Time.After(position)
+	})
+	if index == 0 {
+		return miflora.Data{}, errors.New("no recorded data available yet")
+	}
+
+	data := records[index-1].Data()
+	data.Time = r.replayTime(data.Time)
+	return data, nil
+}
+
+// replayTime converts a timestamp from the recording to the timeline of the replay.
+func (r *Replayer) replayTime(recorded time.Time) time.Time {
+	offset := recorded.Sub(r.first)
+	return r.start.Add(time.Duration(float64(offset) / r.speed))
+}
@@ -0,0 +1,162 @@
+// Package registry keeps a persistent record of the sensors discovered while scanning.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry contains the information stored about one discovered sensor.
+type Entry struct {
+	MacAddress     string    `json:"macAddress"`
+	Name           string    `json:"name,omitempty"`
+	FirstSeen      time.Time `json:"firstSeen"`
+	AutoRegistered bool      `json:"autoRegistered"`
+	// Replaces is set if the sensor is the replacement of the sensor with this MAC address.
+	Replaces   string     `json:"replaces,omitempty"`
+	ReplacedAt *time.Time `json:"replacedAt,omitempty"`
+}
+
+// Registry stores discovered sensors in a state file.
+type Registry struct {
+	fileName string
+
+	lock    sync.RWMutex
+	entries map[string]Entry
+}
+
+// Open loads the registry from the state file. A missing file results in an empty registry.
+func Open(fileName string) (*Registry, error) {
+	r := &Registry{
+		fileName: fileName,
+		entries:  map[string]Entry{},
+	}
+
+	raw, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return r, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("can not parse registry %q: %s", fileName, err)
+	}
+
+	for _, e := range entries {
+		r.entries[e.MacAddress] = e
+	}
+	return r, nil
+}
+
+// Entries returns all entries of the registry ordered by MAC address.
+func (r *Registry) Entries() []Entry {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.sortedEntries()
+}
+
+// Seen records that a sensor has been discovered. Only sensors which are not known yet cause the file to be written.
+func (r *Registry) Seen(macAddress string, now time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.entries[macAddress]; ok {
+		return nil
+	}
+
+	r.entries[macAddress] = Entry{
+		MacAddress: macAddress,
+		FirstSeen:  now,
+	}
+	return r.save()
+}
+
+// Registered records that a sensor has been automatically registered with the given name.
+func (r *Registry) Registered(macAddress, name string, now time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entry, ok := r.entries[macAddress]
+	if !ok {
+		entry = Entry{
+			MacAddress: macAddress,
+			FirstSeen:  now,
+		}
+	}
+
+	entry.Name = name
+	entry.AutoRegistered = true
+	r.entries[macAddress] = entry
+	return r.save()
+}
+
+// Replaced records that a sensor replaces the sensor with the old MAC address. Nothing is written if the replacement
+// has already been recorded.
+func (r *Registry) Replaced(macAddress, old string, now time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entry, ok := r.entries[macAddress]
+	if ok && entry.Replaces == old {
+		return nil
+	}
+
+	if !ok {
+		entry = Entry{
+			MacAddress: macAddress,
+			FirstSeen:  now,
+		}
+	}
+
+	entry.Replaces = old
+	entry.ReplacedAt = &now
+	r.entries[macAddress] = entry
+	return r.save()
+}
+
+func (r *Registry) sortedEntries() []Entry {
+	result := make([]Entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		result = append(result, e)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MacAddress < result[j].MacAddress
+	})
+	return result
+}
+
+// save writes the registry to a temporary file first, so that the state file is never left half-written.
+func (r *Registry) save() error {
+	raw, err := json.MarshalIndent(r.sortedEntries(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.fileName), filepath.Base(r.fileName)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), r.fileName)
+}
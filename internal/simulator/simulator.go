@@ -0,0 +1,116 @@
+// Package simulator provides synthetic sensors, which can be used to run the exporter without Bluetooth hardware.
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	// AdapterName is used in place of a Bluetooth device name when running simulated sensors.
+	AdapterName = "simulator"
+
+	firmwareVersion = "3.2.1-sim"
+	day             = 24 * time.Hour
+	wateringCycle   = 5 * day
+	batteryLifetime = 365 * day
+	historyDuration = 10 * day
+)
+
+// Sensor returns the name and MAC address of the simulated sensor with index i, starting at one.
+func Sensor(i int) (name, macAddress string) {
+	return fmt.Sprintf("simulated-%d", i), fmt.Sprintf("02:00:00:00:%02X:%02X", i>>8&0xFF, i&0xFF)
+}
+
+// Simulator produces plausible, slowly varying data for any sensor it is asked about.
+type Simulator struct {
+	start time.Time
+}
+
+// New creates a new Simulator.
+func New() *Simulator {
+	return &Simulator{
+		start: time.Now(),
+	}
+}
+
+// ReadData implements updater.Reader
+func (s *Simulator) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	if err := ctx.Err(); err != nil {
+		return miflora.Data{}, err
+	}
+
+	now := time.Now()
+	return s.dataAt(seed(macAddress), now), nil
+}
+
+// Blink implements updater.Blinker
+func (s *Simulator) Blink(ctx context.Context, macAddress string) error {
+	return ctx.Err()
+}
+
+// ReadHistory implements updater.HistoryReader
+func (s *Simulator) ReadHistory(ctx context.Context, macAddress string) ([]miflora.HistoryRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	seed := seed(macAddress)
+	now := time.Now().Truncate(time.Hour)
+	result := []miflora.HistoryRecord{}
+	for t := now.Add(-historyDuration); !t.After(now); t = t.Add(time.Hour) {
+		data := s.dataAt(seed, t)
+		result = append(result, miflora.HistoryRecord{
+			Time:         t,
+			Temperature:  data.Sensors.Temperature,
+			Moisture:     data.Sensors.Moisture,
+			Light:        data.Sensors.Light,
+			Conductivity: data.Sensors.Conductivity,
+		})
+	}
+	return result, nil
+}
+
+func (s *Simulator) dataAt(seed float64, now time.Time) miflora.Data {
+	// Offset each sensor in time, so that they do not all show the same values.
+	offset := time.Duration(seed * float64(wateringCycle))
+
+	dayPhase := phase(now.Add(offset), day)
+	daylight := math.Max(0, math.Sin(2*math.Pi*(dayPhase-0.25)))
+	wateringPhase := phase(now.Add(offset), wateringCycle)
+	moisture := 60 - 45*wateringPhase
+
+	age := now.Sub(s.start) + time.Duration(seed*float64(batteryLifetime)/2)
+	battery := 100 - 100*math.Min(1, float64(age)/float64(batteryLifetime))
+
+	return miflora.Data{
+		Time: now,
+		Firmware: miflora.Firmware{
+			Version: firmwareVersion,
+			Battery: byte(math.Round(battery)),
+		},
+		Sensors: miflora.Sensors{
+			Temperature:  math.Round((18+6*daylight+2*seed)*10) / 10,
+			Moisture:     byte(math.Round(moisture)),
+			Light:        uint32(math.Round(20000 * daylight * (0.5 + seed/2))),
+			Conductivity: uint16(math.Round(moisture * 20)),
+		},
+	}
+}
+
+// phase returns the position of t inside a period as a value between zero and one.
+func phase(t time.Time, period time.Duration) float64 {
+	return float64(t.UnixNano()%int64(period)) / float64(period)
+}
+
+// seed derives a stable value between zero and one from the MAC address.
+func seed(macAddress string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(macAddress))
+	return float64(h.Sum32()) / math.MaxUint32
+}
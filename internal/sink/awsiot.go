@@ -0,0 +1,106 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+var thingNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9:_-]+`)
+
+// AWSIoT publishes readings to AWS IoT Core using MQTT with client certificate authentication. Every sensor is
+// represented by a thing named after the sensor. Readings are published as telemetry on dt/flowercare/<thing>/reading
+// and, if enabled, the latest values are reported in the classic device shadow of the thing.
+type AWSIoT struct {
+	conn        *mqttConnection
+	thingPrefix string
+	shadow      bool
+}
+
+// NewAWSIoT connects to the AWS IoT Core endpoint. The auth profile needs to contain the client certificate.
+func NewAWSIoT(ctx context.Context, cfg config.AWSIoTConfig, auth clientauth.Profile) (*AWSIoT, error) {
+	if auth.CertFile == "" {
+		return nil, fmt.Errorf("AWS IoT Core needs a client certificate")
+	}
+
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(fmt.Sprintf("ssl://%s:8883", cfg.Endpoint)).
+		SetClientID(cfg.ClientID).
+		SetTLSConfig(tlsConfig)
+
+	conn, err := newMQTTConnection(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AWSIoT{
+		conn:        conn,
+		thingPrefix: cfg.ThingPrefix,
+		shadow:      cfg.Shadow,
+	}, nil
+}
+
+// Name implements Sink
+func (a *AWSIoT) Name() string {
+	return "awsiot"
+}
+
+// Write implements Sink
+func (a *AWSIoT) Write(ctx context.Context, readings []Reading) error {
+	latest := map[string]int{}
+	for i, r := range readings {
+		latest[r.Sensor.MacAddress] = i
+	}
+
+	for i, r := range readings {
+		thing := a.thingName(r.Sensor)
+		payload, err := json.Marshal(newJSONReading(r))
+		if err != nil {
+			return fmt.Errorf("can not encode reading: %s", err)
+		}
+
+		if err := a.conn.publish(ctx, "dt/flowercare/"+thing+"/reading", payload, false); err != nil {
+			return err
+		}
+
+		if !a.shadow || latest[r.Sensor.MacAddress] != i {
+			continue
+		}
+
+		shadow, err := json.Marshal(map[string]interface{}{
+			"state": map[string]interface{}{
+				"reported": reportedState(r),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("can not encode shadow: %s", err)
+		}
+
+		if err := a.conn.publish(ctx, "$aws/things/"+thing+"/shadow/update", shadow, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *AWSIoT) thingName(sensor config.Sensor) string {
+	return a.thingPrefix + strings.Trim(thingNameInvalidChars.ReplaceAllString(displayName(sensor), "-"), "-")
+}
+
+// Close implements Sink
+func (a *AWSIoT) Close() error {
+	a.conn.close()
+	return nil
+}
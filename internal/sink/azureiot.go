@@ -0,0 +1,190 @@
+package sink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+// azureTwinKeyReplacer replaces the characters, which are not allowed in property names of device twins.
+var azureTwinKeyReplacer = strings.NewReplacer(".", "_", "$", "_", "#", "_", " ", "_")
+
+const (
+	azureAPIVersion = "2021-04-12"
+	azureSASTTL     = 24 * time.Hour
+)
+
+// AzureIoT publishes readings to Azure IoT Hub using MQTT. The exporter is connected as a single device, either using a
+// shared access key from the device connection string or an X.509 certificate. Readings are sent as device-to-cloud
+// messages and the latest values of the sensors are reported as properties of the device twin.
+type AzureIoT struct {
+	conn      *mqttConnection
+	deviceID  string
+	twin      bool
+	requestID uint64
+}
+
+type azureConnectionString struct {
+	HostName        string
+	DeviceID        string
+	SharedAccessKey []byte
+}
+
+func parseAzureConnectionString(value string) (azureConnectionString, error) {
+	var result azureConnectionString
+	for _, part := range strings.Split(strings.TrimSpace(value), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return result, fmt.Errorf("invalid part in connection string: %q", part)
+		}
+
+		switch key {
+		case "HostName":
+			result.HostName = value
+		case "DeviceId":
+			result.DeviceID = value
+		case "SharedAccessKey":
+			sharedKey, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return result, fmt.Errorf("can not decode shared access key: %s", err)
+			}
+			result.SharedAccessKey = sharedKey
+		}
+	}
+
+	if result.HostName == "" || result.DeviceID == "" {
+		return result, errors.New("connection string needs to contain HostName and DeviceId")
+	}
+
+	return result, nil
+}
+
+// sasToken creates a shared access signature for the device, which expires after the duration.
+func (c azureConnectionString) sasToken(now time.Time, ttl time.Duration) string {
+	resource := url.QueryEscape(c.HostName + "/devices/" + c.DeviceID)
+	expiry := strconv.FormatInt(now.Add(ttl).Unix(), 10)
+
+	mac := hmac.New(sha256.New, c.SharedAccessKey)
+	mac.Write([]byte(resource + "\n" + expiry))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s", resource, url.QueryEscape(signature), expiry)
+}
+
+// NewAzureIoT connects to the IoT hub using the device connection string. The auth profile can contain a client
+// certificate, which is used if the connection string contains no shared access key.
+func NewAzureIoT(ctx context.Context, cfg config.AzureIoTConfig, auth clientauth.Profile) (*AzureIoT, error) {
+	raw, err := os.ReadFile(cfg.ConnectionFile)
+	if err != nil {
+		return nil, fmt.Errorf("can not read connection string: %s", err)
+	}
+
+	connection, err := parseAzureConnectionString(string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(connection.SharedAccessKey) == 0 && auth.CertFile == "" {
+		return nil, errors.New("need a shared access key or a client certificate for Azure IoT Hub")
+	}
+
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(fmt.Sprintf("ssl://%s:8883", connection.HostName)).
+		SetClientID(connection.DeviceID).
+		SetTLSConfig(tlsConfig).
+		SetProtocolVersion(4)
+
+	username := fmt.Sprintf("%s/%s/?api-version=%s", connection.HostName, connection.DeviceID, azureAPIVersion)
+	if len(connection.SharedAccessKey) > 0 {
+		// The token is created on every connection attempt, so that reconnects do not use an expired token.
+		opts.SetCredentialsProvider(func() (string, string) {
+			return username, connection.sasToken(time.Now(), azureSASTTL)
+		})
+	} else {
+		opts.SetUsername(username)
+	}
+
+	conn, err := newMQTTConnection(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureIoT{
+		conn:     conn,
+		deviceID: connection.DeviceID,
+		twin:     cfg.Twin,
+	}, nil
+}
+
+// Name implements Sink
+func (a *AzureIoT) Name() string {
+	return "azureiot"
+}
+
+// Write implements Sink
+func (a *AzureIoT) Write(ctx context.Context, readings []Reading) error {
+	reported := map[string]interface{}{}
+	for _, r := range readings {
+		payload, err := json.Marshal(newJSONReading(r))
+		if err != nil {
+			return fmt.Errorf("can not encode reading: %s", err)
+		}
+
+		properties := url.Values{
+			"$.ct":       []string{"application/json"},
+			"$.ce":       []string{"utf-8"},
+			"macAddress": []string{r.Sensor.MacAddress},
+		}
+		if r.Sensor.Name != "" {
+			properties.Set("sensor", r.Sensor.Name)
+		}
+
+		topic := "devices/" + a.deviceID + "/messages/events/" + properties.Encode()
+		if err := a.conn.publish(ctx, topic, payload, false); err != nil {
+			return err
+		}
+
+		reported[azureTwinKeyReplacer.Replace(displayName(r.Sensor))] = reportedState(r)
+	}
+
+	if !a.twin || len(reported) == 0 {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"sensors": reported,
+	})
+	if err != nil {
+		return fmt.Errorf("can not encode twin properties: %s", err)
+	}
+
+	// The response of the hub is not awaited, as this would need a subscription to the response topic.
+	requestID := atomic.AddUint64(&a.requestID, 1)
+	topic := "$iothub/twin/PATCH/properties/reported/?$rid=" + strconv.FormatUint(requestID, 10)
+	return a.conn.publish(ctx, topic, patch, false)
+}
+
+// Close implements Sink
+func (a *AzureIoT) Close() error {
+	a.conn.close()
+	return nil
+}
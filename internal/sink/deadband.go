@@ -0,0 +1,73 @@
+package sink
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// deadband skips the readings of a sensor, whose measurements all changed by no more than their deadband since the
+// last published reading of the sensor. Measurements without a deadband are published on any change.
+type deadband struct {
+	cfg  config.SinkDeadbandConfig
+	lock sync.Mutex
+	last map[string]miflora.Data
+}
+
+func newDeadband(cfg config.SinkDeadbandConfig) (*deadband, error) {
+	for key := range cfg.Deadbands {
+		found := false
+		for _, m := range measurements {
+			if m.Key == key {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown measurement for deadband: %s", key)
+		}
+	}
+
+	return &deadband{
+		cfg:  cfg,
+		last: map[string]miflora.Data{},
+	}, nil
+}
+
+// skip returns true if the reading does not need to be published. Otherwise it is kept as the last published reading.
+func (d *deadband) skip(macAddress string, data miflora.Data) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	last, ok := d.last[macAddress]
+	if ok && d.unchanged(last, data) {
+		return true
+	}
+
+	d.last[macAddress] = data
+	return false
+}
+
+func (d *deadband) unchanged(last, data miflora.Data) bool {
+	if d.cfg.MaxInterval > 0 && data.Time.Sub(last.Time) >= d.cfg.MaxInterval {
+		return false
+	}
+
+	for _, m := range measurements {
+		// Measurements without a deadband have a deadband of zero.
+		band := d.cfg.Deadbands[m.Key]
+		if m.Available(last) != m.Available(data) {
+			return false
+		}
+
+		if m.Available(data) && math.Abs(m.Value(data)-m.Value(last)) > band {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,127 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+func TestDeadbandSkip(t *testing.T) {
+	start := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	reading := func(offset time.Duration, temperature float64, moisture byte) miflora.Data {
+		return miflora.Data{
+			Time:     start.Add(offset),
+			Firmware: miflora.Firmware{Version: "3.2.1", Battery: 80},
+			Sensors: miflora.Sensors{
+				Temperature:  temperature,
+				Moisture:     moisture,
+				Light:        500,
+				Conductivity: 300,
+			},
+		}
+	}
+
+	tests := []struct {
+		desc     string
+		cfg      config.SinkDeadbandConfig
+		readings []miflora.Data
+		want     []bool
+	}{
+		{
+			desc: "first reading is published",
+			cfg: config.SinkDeadbandConfig{
+				Deadbands: config.DeadbandMap{"temperature": 0.5},
+			},
+			readings: []miflora.Data{reading(0, 20, 30)},
+			want:     []bool{false},
+		},
+		{
+			desc: "change within deadband",
+			cfg: config.SinkDeadbandConfig{
+				Deadbands: config.DeadbandMap{"temperature": 0.5, "moisture": 2},
+			},
+			readings: []miflora.Data{
+				reading(0, 20, 30),
+				reading(time.Minute, 20.4, 31),
+				reading(2*time.Minute, 19.6, 28),
+			},
+			want: []bool{false, true, true},
+		},
+		{
+			desc: "change compared to last published reading",
+			cfg: config.SinkDeadbandConfig{
+				Deadbands: config.DeadbandMap{"temperature": 0.5, "moisture": 2},
+			},
+			readings: []miflora.Data{
+				reading(0, 20, 30),
+				reading(time.Minute, 20.3, 30),
+				reading(2*time.Minute, 20.6, 30),
+				reading(3*time.Minute, 20.9, 30),
+			},
+			want: []bool{false, true, false, true},
+		},
+		{
+			desc: "measurement without deadband",
+			cfg: config.SinkDeadbandConfig{
+				Deadbands: config.DeadbandMap{"temperature": 0.5},
+			},
+			readings: []miflora.Data{
+				reading(0, 20, 30),
+				reading(time.Minute, 20, 30),
+				reading(2*time.Minute, 20, 31),
+			},
+			want: []bool{false, true, false},
+		},
+		{
+			desc: "maximum interval",
+			cfg: config.SinkDeadbandConfig{
+				Deadbands:   config.DeadbandMap{"temperature": 0.5, "moisture": 2},
+				MaxInterval: time.Hour,
+			},
+			readings: []miflora.Data{
+				reading(0, 20, 30),
+				reading(30*time.Minute, 20, 30),
+				reading(time.Hour, 20, 30),
+			},
+			want: []bool{false, true, false},
+		},
+		{
+			desc: "battery level becomes known",
+			cfg: config.SinkDeadbandConfig{
+				Deadbands: config.DeadbandMap{"temperature": 0.5, "moisture": 2, "battery": 10},
+			},
+			readings: []miflora.Data{
+				{Time: start, Sensors: miflora.Sensors{Temperature: 20, Moisture: 30, Light: 500, Conductivity: 300}},
+				reading(time.Minute, 20, 30),
+			},
+			want: []bool{false, false},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			d, err := newDeadband(tc.cfg)
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			for i, data := range tc.readings {
+				got := d.skip("C4:7C:8D:00:00:01", data)
+				if got != tc.want[i] {
+					t.Errorf("got skip %v for reading %d, want %v", got, i, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewDeadbandUnknownMeasurement(t *testing.T) {
+	_, err := newDeadband(config.SinkDeadbandConfig{
+		Deadbands: config.DeadbandMap{"humidity": 1},
+	})
+	if err == nil {
+		t.Fatal("got no error for unknown measurement")
+	}
+}
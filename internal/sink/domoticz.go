@@ -0,0 +1,177 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+type domoticzResponse struct {
+	Status string `json:"status"`
+	Title  string `json:"title"`
+	Result []struct {
+		Idx  string `json:"idx"`
+		Name string `json:"Name"`
+	} `json:"result"`
+}
+
+// Domoticz updates devices in Domoticz using its JSON API. The devices need to exist and are named after the sensor
+// and the measurement, for example "Tomatoes Moisture". The battery level is sent along with every measurement.
+type Domoticz struct {
+	url    string
+	client *http.Client
+
+	devicesLock sync.Mutex
+	devices     map[string]string
+}
+
+// NewDomoticz creates a sink for the Domoticz instance and checks that the API can be reached.
+func NewDomoticz(ctx context.Context, cfg config.DomoticzConfig, auth clientauth.Profile) (*Domoticz, error) {
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Domoticz{
+		url:    strings.TrimSuffix(cfg.URL, "/"),
+		client: client,
+	}
+
+	if err := d.loadDevices(ctx); err != nil {
+		return nil, fmt.Errorf("can not connect to Domoticz: %s", err)
+	}
+
+	return d, nil
+}
+
+// Name implements Sink
+func (d *Domoticz) Name() string {
+	return "domoticz"
+}
+
+// Write implements Sink
+func (d *Domoticz) Write(ctx context.Context, readings []Reading) error {
+	reloaded := false
+	for _, r := range readings {
+		if err := d.writeReading(ctx, r, &reloaded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deviceName returns the name of the Domoticz device for a measurement of the sensor.
+func deviceName(sensor config.Sensor, m measurement) string {
+	return displayName(sensor) + " " + m.Label
+}
+
+func (d *Domoticz) writeReading(ctx context.Context, r Reading, reloaded *bool) error {
+	for _, m := range measurements {
+		if m.Key == "battery" {
+			continue
+		}
+
+		name := deviceName(r.Sensor, m)
+		idx, err := d.deviceIdx(ctx, name, reloaded)
+		if err != nil {
+			return err
+		}
+
+		if idx == "" {
+			continue
+		}
+
+		params := url.Values{
+			"type":   {"command"},
+			"param":  {"udevice"},
+			"idx":    {idx},
+			"nvalue": {"0"},
+			"svalue": {formatValue(m.Value(r.Data))},
+		}
+		if r.Data.Firmware.Known() {
+			params.Set("battery", strconv.Itoa(int(r.Data.Firmware.Battery)))
+		}
+
+		if _, err := d.call(ctx, params); err != nil {
+			return fmt.Errorf("can not update device %q: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// deviceIdx returns the index of the named device. If the device is not known, the list of devices is reloaded once
+// per write, so that devices created while the exporter is running are found. An empty index is returned if the
+// device does not exist.
+func (d *Domoticz) deviceIdx(ctx context.Context, name string, reloaded *bool) (string, error) {
+	d.devicesLock.Lock()
+	idx, ok := d.devices[name]
+	d.devicesLock.Unlock()
+	if ok || *reloaded {
+		return idx, nil
+	}
+
+	*reloaded = true
+	if err := d.loadDevices(ctx); err != nil {
+		return "", err
+	}
+
+	d.devicesLock.Lock()
+	defer d.devicesLock.Unlock()
+
+	return d.devices[name], nil
+}
+
+func (d *Domoticz) loadDevices(ctx context.Context) error {
+	res, err := d.call(ctx, url.Values{
+		"type":   {"command"},
+		"param":  {"getdevices"},
+		"filter": {"all"},
+		"used":   {"true"},
+	})
+	if err != nil {
+		return fmt.Errorf("can not list devices: %s", err)
+	}
+
+	devices := map[string]string{}
+	for _, device := range res.Result {
+		devices[device.Name] = device.Idx
+	}
+
+	d.devicesLock.Lock()
+	defer d.devicesLock.Unlock()
+
+	d.devices = devices
+	return nil
+}
+
+func (d *Domoticz) call(ctx context.Context, params url.Values) (domoticzResponse, error) {
+	var result domoticzResponse
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url+"/json.htm?"+params.Encode(), nil)
+	if err != nil {
+		return result, fmt.Errorf("can not create request: %s", err)
+	}
+
+	if err := doJSON(d.client, req, &result); err != nil {
+		return result, err
+	}
+
+	if result.Status != "OK" {
+		return result, fmt.Errorf("API returned status %q: %s", result.Status, result.Title)
+	}
+
+	return result, nil
+}
+
+// Close implements Sink
+func (d *Domoticz) Close() error {
+	return nil
+}
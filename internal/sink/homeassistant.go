@@ -0,0 +1,149 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+var entityInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+type homeAssistantState struct {
+	State      interface{}            `json:"state"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// homeAssistantClasses contains the device class of the measurements, which have one, and the icon of the others.
+var homeAssistantClasses = map[string]struct {
+	DeviceClass string
+	Icon        string
+}{
+	"moisture":     {DeviceClass: "moisture"},
+	"temperature":  {DeviceClass: "temperature"},
+	"illuminance":  {DeviceClass: "illuminance"},
+	"conductivity": {Icon: "mdi:flower"},
+	"battery":      {DeviceClass: "battery"},
+}
+
+// HomeAssistant sets the states of sensor entities in Home Assistant using its REST API. One entity is created per
+// measurement of a sensor.
+type HomeAssistant struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHomeAssistant creates a sink for the Home Assistant instance and checks that the API can be reached.
+func NewHomeAssistant(ctx context.Context, cfg config.HomeAssistantConfig, auth clientauth.Profile) (*HomeAssistant, error) {
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &HomeAssistant{
+		url:    strings.TrimSuffix(cfg.URL, "/"),
+		client: client,
+	}
+
+	token, err := clientauth.ReadSecret(cfg.TokenFile, cfg.TokenEnv, "access token")
+	if err != nil {
+		return nil, err
+	}
+	h.token = token
+
+	if err := h.do(ctx, http.MethodGet, "/api/", nil); err != nil {
+		return nil, fmt.Errorf("can not connect to Home Assistant: %s", err)
+	}
+
+	return h, nil
+}
+
+// Name implements Sink
+func (h *HomeAssistant) Name() string {
+	return "homeassistant"
+}
+
+// Write implements Sink
+func (h *HomeAssistant) Write(ctx context.Context, readings []Reading) error {
+	// Only the latest state of an entity is kept by Home Assistant.
+	latest := map[string]int{}
+	for i, r := range readings {
+		latest[r.Sensor.MacAddress] = i
+	}
+
+	for i, r := range readings {
+		if latest[r.Sensor.MacAddress] != i {
+			continue
+		}
+
+		if err := h.writeReading(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *HomeAssistant) writeReading(ctx context.Context, r Reading) error {
+	name := displayName(r.Sensor)
+	objectID := "flowercare_" + strings.Trim(entityInvalidChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+
+	for _, m := range measurements {
+		if !m.Available(r.Data) {
+			continue
+		}
+
+		attributes := map[string]interface{}{
+			"friendly_name":       fmt.Sprintf("%s %s", name, m.Label),
+			"unit_of_measurement": m.Unit,
+			"state_class":         "measurement",
+			"mac_address":         r.Sensor.MacAddress,
+			"firmware_version":    r.Data.Firmware.Version,
+			"last_reading":        r.Data.Time,
+		}
+		if class := homeAssistantClasses[m.Key]; class.DeviceClass != "" {
+			attributes["device_class"] = class.DeviceClass
+		} else {
+			attributes["icon"] = class.Icon
+		}
+
+		payload, err := json.Marshal(homeAssistantState{
+			State:      m.Value(r.Data),
+			Attributes: attributes,
+		})
+		if err != nil {
+			return fmt.Errorf("can not encode state: %s", err)
+		}
+
+		if err := h.do(ctx, http.MethodPost, "/api/states/sensor."+objectID+"_"+m.Key, payload); err != nil {
+			return fmt.Errorf("can not set state of %s: %s", r.Sensor, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *HomeAssistant) do(ctx context.Context, method, path string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, h.url+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	return doJSON(h.client, req, nil)
+}
+
+// Close implements Sink
+func (h *HomeAssistant) Close() error {
+	return nil
+}
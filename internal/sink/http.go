@@ -0,0 +1,38 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// doJSON sends the request and checks the status of the response. If result is not nil, the response is decoded into it.
+func doJSON(client *http.Client, req *http.Request, result interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return errors.New("credentials not accepted")
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("API returned status %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("can not decode response: %s", err)
+	}
+
+	return nil
+}
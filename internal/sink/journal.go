@@ -0,0 +1,220 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+type journalEntry struct {
+	MacAddress string       `json:"macAddress"`
+	Name       string       `json:"name"`
+	Data       miflora.Data `json:"data"`
+}
+
+// journal stores the readings, which have not been delivered to a sink yet, in a file with one JSON object per line.
+type journal struct {
+	fileName string
+	file     *os.File
+}
+
+// openJournal opens the journal file of the sink in the directory and returns the readings already contained in it.
+func openJournal(log *logging.Logger, dir, sinkName string) (*journal, []Reading, error) {
+	j := &journal{
+		fileName: filepath.Join(dir, sinkName+".journal"),
+	}
+
+	readings, err := j.load(log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := j.open(); err != nil {
+		return nil, nil, err
+	}
+
+	return j, readings, nil
+}
+
+func (j *journal) load(log *logging.Logger) ([]Reading, error) {
+	file, err := os.Open(j.fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	defer file.Close()
+
+	var readings []Reading
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line can be incomplete if the exporter was stopped while writing.
+			log.Warnf("Skipping invalid entry in line %d of journal %q: %s", line, j.fileName, err)
+			continue
+		}
+
+		readings = append(readings, Reading{
+			Sensor: config.Sensor{
+				Name:       entry.Name,
+				MacAddress: entry.MacAddress,
+			},
+			Data: entry.Data,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can not read journal %q: %s", j.fileName, err)
+	}
+
+	return readings, nil
+}
+
+func (j *journal) open() error {
+	file, err := os.OpenFile(j.fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	j.file = file
+	return nil
+}
+
+// Append adds the reading to the end of the journal.
+func (j *journal) Append(r Reading) error {
+	raw, err := json.Marshal(newJournalEntry(r))
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.file.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+
+	return j.file.Sync()
+}
+
+// Replace replaces the contents of the journal with the readings. The new contents are written to a temporary file
+// first, so that the journal is never left half-written.
+func (j *journal) Replace(readings []Reading) error {
+	if len(readings) == 0 {
+		return j.file.Truncate(0)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.fileName), filepath.Base(j.fileName)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, r := range readings {
+		if err := enc.Encode(newJournalEntry(r)); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), j.fileName); err != nil {
+		return err
+	}
+
+	j.file.Close()
+	return j.open()
+}
+
+// Close closes the journal file.
+func (j *journal) Close() error {
+	return j.file.Close()
+}
+
+func newJournalEntry(r Reading) journalEntry {
+	return journalEntry{
+		MacAddress: r.Sensor.MacAddress,
+		Name:       r.Sensor.Name,
+		Data:       r.Data,
+	}
+}
+
+type deadLetterEntry struct {
+	Time  time.Time `json:"time"`
+	Sink  string    `json:"sink"`
+	Error string    `json:"error"`
+	journalEntry
+}
+
+// deadLetter appends readings, which could not be delivered, to a file with one JSON object per line.
+type deadLetter struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+func openDeadLetter(fileName string) (*deadLetter, error) {
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deadLetter{
+		file: file,
+	}, nil
+}
+
+// Append adds the readings, which could not be delivered to the sink, to the file.
+func (d *deadLetter) Append(sinkName string, readings []Reading, cause error, now time.Time) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	w := bufio.NewWriter(d.file)
+	enc := json.NewEncoder(w)
+	for _, r := range readings {
+		if err := enc.Encode(deadLetterEntry{
+			Time:         now,
+			Sink:         sinkName,
+			Error:        cause.Error(),
+			journalEntry: newJournalEntry(r),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return d.file.Sync()
+}
+
+// Close closes the file.
+func (d *deadLetter) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.file.Close()
+}
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/xperimental/flowercare-exporter/internal/loki"
+)
+
+// Loki sends a log line per reading to Loki, so that the readings can be queried without a time-series database. The
+// line contains the values of the reading as JSON.
+type Loki struct {
+	client *loki.Client
+}
+
+// NewLoki creates a sink using the Loki client.
+func NewLoki(client *loki.Client) *Loki {
+	return &Loki{
+		client: client,
+	}
+}
+
+// Name implements Sink
+func (l *Loki) Name() string {
+	return "loki"
+}
+
+// Write implements Sink
+func (l *Loki) Write(ctx context.Context, readings []Reading) error {
+	entries := make([]loki.Entry, 0, len(readings))
+	for _, r := range readings {
+		line := reportedState(r)
+		line["sensor"] = displayName(r.Sensor)
+		line["macAddress"] = r.Sensor.MacAddress
+
+		raw, err := json.Marshal(line)
+		if err != nil {
+			return fmt.Errorf("can not encode reading: %s", err)
+		}
+
+		entries = append(entries, loki.Entry{
+			Time: r.Data.Time,
+			Labels: map[string]string{
+				"type":   "reading",
+				"sensor": displayName(r.Sensor),
+			},
+			Line: string(raw),
+		})
+	}
+
+	return l.client.Push(ctx, entries)
+}
+
+// Close implements Sink
+func (l *Loki) Close() error {
+	return nil
+}
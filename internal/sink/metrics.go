@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricPrefix = "flowercare_"
+
+var sinkLabelNames = []string{
+	"sink",
+}
+
+type dispatchMetrics struct {
+	errors          *prometheus.CounterVec
+	dropped         *prometheus.CounterVec
+	deadLettered    *prometheus.CounterVec
+	deadbandSkipped prometheus.Counter
+	queueLength     *prometheus.Desc
+}
+
+func newDispatchMetrics() dispatchMetrics {
+	return dispatchMetrics{
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricPrefix + "sink_errors_total",
+			Help: "Number of failed attempts to write readings to a sink.",
+		}, sinkLabelNames),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricPrefix + "sink_dropped_total",
+			Help: "Number of readings dropped because the queue of a sink was full.",
+		}, sinkLabelNames),
+		deadLettered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricPrefix + "sink_dead_letter_total",
+			Help: "Number of readings which could not be written within the maximum age of the retry policy.",
+		}, sinkLabelNames),
+		deadbandSkipped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: metricPrefix + "sink_deadband_skipped_total",
+			Help: "Number of readings not published because no measurement changed by more than its deadband.",
+		}),
+		queueLength: prometheus.NewDesc(
+			metricPrefix+"sink_queue_length",
+			"Number of readings waiting to be written to a sink, including readings waiting for a retry.",
+			sinkLabelNames, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (d *Dispatcher) Describe(ch chan<- *prometheus.Desc) {
+	d.metrics.errors.Describe(ch)
+	d.metrics.dropped.Describe(ch)
+	d.metrics.deadLettered.Describe(ch)
+	d.metrics.deadbandSkipped.Describe(ch)
+	ch <- d.metrics.queueLength
+}
+
+// Collect implements prometheus.Collector
+func (d *Dispatcher) Collect(ch chan<- prometheus.Metric) {
+	d.metrics.errors.Collect(ch)
+	d.metrics.dropped.Collect(ch)
+	d.metrics.deadLettered.Collect(ch)
+	d.metrics.deadbandSkipped.Collect(ch)
+
+	for _, w := range d.sinks {
+		length := len(w.queue) + int(atomic.LoadInt64(&w.pendingLen))
+		ch <- prometheus.MustNewConstMetric(d.metrics.queueLength, prometheus.GaugeValue,
+			float64(length), w.sink.Name())
+	}
+}
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const mqttQoS = 1
+
+// mqttConnection is a connection to an MQTT broker. It reconnects automatically, but publishing fails while the
+// connection is down, so that the readings are retried by the dispatcher.
+type mqttConnection struct {
+	client mqtt.Client
+}
+
+func newMQTTConnection(ctx context.Context, opts *mqtt.ClientOptions) (*mqttConnection, error) {
+	opts.SetAutoReconnect(true)
+	opts.SetConnectTimeout(30 * time.Second)
+	opts.SetOrderMatters(false)
+
+	client := mqtt.NewClient(opts)
+	if err := waitToken(ctx, client.Connect()); err != nil {
+		return nil, fmt.Errorf("can not connect to broker: %s", err)
+	}
+
+	return &mqttConnection{
+		client: client,
+	}, nil
+}
+
+func (c *mqttConnection) publish(ctx context.Context, topic string, payload []byte, retained bool) error {
+	if !c.client.IsConnectionOpen() {
+		return errors.New("not connected to broker")
+	}
+
+	if err := waitToken(ctx, c.client.Publish(topic, mqttQoS, retained, payload)); err != nil {
+		return fmt.Errorf("can not publish to %q: %s", topic, err)
+	}
+
+	return nil
+}
+
+func (c *mqttConnection) close() {
+	c.client.Disconnect(250)
+}
+
+func waitToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
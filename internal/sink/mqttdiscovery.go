@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Payloads of the availability topic.
+const (
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+)
+
+// discoveryValues contains the templates used by Home Assistant for extracting the measurements from the readings.
+var discoveryValues = map[string]string{
+	"moisture":     "{{ value_json.data.sensors.moisture.value }}",
+	"temperature":  "{{ value_json.data.sensors.temperature.value }}",
+	"illuminance":  "{{ value_json.data.sensors.light.value }}",
+	"conductivity": "{{ value_json.data.sensors.conductivity.value }}",
+	// The battery level is missing in readings received passively, which sets the state to unknown.
+	"battery": "{{ value_json.data.firmware.battery.value if value_json.data.firmware.battery is defined else None }}",
+}
+
+// announcement contains the information of a sensor used in its published discovery messages.
+type announcement struct {
+	Device discoveryDevice
+	// Battery is set if the battery entity has been announced.
+	Battery bool
+}
+
+type discoveryDevice struct {
+	Identifiers  []string   `json:"identifiers"`
+	Connections  [][]string `json:"connections"`
+	Name         string     `json:"name"`
+	Manufacturer string     `json:"manufacturer"`
+	Model        string     `json:"model"`
+	SWVersion    string     `json:"sw_version,omitempty"`
+}
+
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	ObjectID          string          `json:"object_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template,omitempty"`
+	Unit              string          `json:"unit_of_measurement"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	Icon              string          `json:"icon,omitempty"`
+	StateClass        string          `json:"state_class"`
+	EntityCategory    string          `json:"entity_category,omitempty"`
+	AvailabilityTopic string          `json:"availability_topic,omitempty"`
+	Device            discoveryDevice `json:"device"`
+}
+
+// discoveryID returns the ID of the sensor used in the topics and unique IDs of the entities.
+func discoveryID(r Reading) string {
+	return "flowercare_" + strings.ToLower(strings.ReplaceAll(r.Sensor.MacAddress, ":", ""))
+}
+
+// announce publishes the retained discovery messages of a sensor, unless they have already been published for its
+// current name and firmware version. The battery entity is only announced, once the battery level is known.
+func (m *MQTT) announce(ctx context.Context, r Reading) error {
+	id := discoveryID(r)
+	device := discoveryDevice{
+		Identifiers:  []string{id},
+		Connections:  [][]string{{"mac", strings.ToLower(r.Sensor.MacAddress)}},
+		Name:         displayName(r.Sensor),
+		Manufacturer: "Xiaomi",
+		Model:        "Flower Care",
+		SWVersion:    r.Data.Firmware.Version,
+	}
+
+	announced, ok := m.announced[r.Sensor.MacAddress]
+	if device.SWVersion == "" {
+		// Readings received passively do not contain the firmware version.
+		device.SWVersion = announced.Device.SWVersion
+	}
+	battery := announced.Battery || r.Data.Firmware.Known()
+	if ok && announced.Device.Name == device.Name && announced.Device.SWVersion == device.SWVersion &&
+		announced.Battery == battery {
+		return nil
+	}
+
+	for _, measurement := range measurements {
+		if measurement.Key == "battery" && !battery {
+			continue
+		}
+
+		// Every measurement is published separately as plain value or the reading as JSON.
+		data := mqttData{
+			Name:       displayName(r.Sensor),
+			MacAddress: r.Sensor.MacAddress,
+		}
+		valueTemplate := discoveryValues[measurement.Key]
+		if m.perMeasurement {
+			data.Measurement = measurement.Key
+			valueTemplate = ""
+		}
+
+		stateTopic, err := m.renderTopic(data)
+		if err != nil {
+			return err
+		}
+
+		class := homeAssistantClasses[measurement.Key]
+		cfg := discoveryConfig{
+			Name:              measurement.Label,
+			UniqueID:          id + "_" + measurement.Key,
+			ObjectID:          id + "_" + measurement.Key,
+			StateTopic:        stateTopic,
+			ValueTemplate:     valueTemplate,
+			Unit:              measurement.Unit,
+			DeviceClass:       class.DeviceClass,
+			Icon:              class.Icon,
+			StateClass:        "measurement",
+			AvailabilityTopic: m.availability,
+			Device:            device,
+		}
+		if measurement.Key == "battery" {
+			cfg.EntityCategory = "diagnostic"
+		}
+
+		payload, err := json.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("can not encode discovery message: %s", err)
+		}
+
+		topic := fmt.Sprintf("%s/sensor/%s/%s/config", m.discoveryPrefix, id, measurement.Key)
+		if err := m.conn.publish(ctx, topic, payload, true); err != nil {
+			return err
+		}
+	}
+
+	m.announced[r.Sensor.MacAddress] = announcement{
+		Device:  device,
+		Battery: battery,
+	}
+	return nil
+}
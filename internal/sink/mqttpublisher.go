@@ -0,0 +1,237 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// topicReplacer replaces the characters, which have a special meaning in MQTT topics, in the sensor names.
+var topicReplacer = strings.NewReplacer("/", "_", "+", "_", "#", "_", " ", "_")
+
+// mqttData is passed to the topic and payload templates. The name is sanitized for the topic. Measurement, Value and
+// Unit are only set when publishing every measurement separately.
+type mqttData struct {
+	Name        string
+	MacAddress  string
+	Data        miflora.Data
+	Measurement string
+	Value       float64
+	Unit        string
+}
+
+// mqttMessage is a message published for a reading.
+type mqttMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// MQTT publishes every reading to an MQTT broker. The topic and optionally the payload are rendered from templates,
+// either per reading or per measurement.
+type MQTT struct {
+	conn           *mqttConnection
+	topic          *template.Template
+	payload        *template.Template
+	perMeasurement bool
+	retain         bool
+	availability   string
+	// discoveryPrefix is empty if no discovery messages are published.
+	discoveryPrefix string
+	// announced contains the device information of the sensors, whose discovery messages have been published.
+	announced map[string]announcement
+}
+
+// NewMQTT connects to the MQTT broker. The credentials and TLS settings are taken from the auth profile.
+func NewMQTT(ctx context.Context, cfg config.MQTTConfig, auth clientauth.Profile) (*MQTT, error) {
+	topic, err := template.New("topic").Parse(cfg.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse topic template: %s", err)
+	}
+
+	var payload *template.Template
+	if cfg.Payload != "" {
+		payload, err = template.New("payload").Funcs(webhookFuncs).Parse(cfg.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse payload template: %s", err)
+		}
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID)
+
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	if auth.Username != "" {
+		password, err := auth.Password()
+		if err != nil {
+			return nil, err
+		}
+
+		opts.SetUsername(auth.Username)
+		opts.SetPassword(password)
+	}
+
+	if cfg.Availability != "" {
+		// The broker publishes the will, when the connection is lost.
+		opts.SetWill(cfg.Availability, mqttOffline, mqttQoS, true)
+		opts.SetOnConnectHandler(func(client mqtt.Client) {
+			client.Publish(cfg.Availability, mqttQoS, true, mqttOnline)
+		})
+	}
+
+	conn, err := newMQTTConnection(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MQTT{
+		conn:           conn,
+		topic:          topic,
+		payload:        payload,
+		perMeasurement: cfg.PerMeasurement,
+		retain:         cfg.Retain,
+		availability:   cfg.Availability,
+		announced:      map[string]announcement{},
+	}
+	if cfg.Discovery {
+		m.discoveryPrefix = cfg.DiscoveryPrefix
+	}
+
+	return m, nil
+}
+
+// Name implements Sink
+func (m *MQTT) Name() string {
+	return "mqtt"
+}
+
+// Write implements Sink
+func (m *MQTT) Write(ctx context.Context, readings []Reading) error {
+	for _, r := range readings {
+		if m.discoveryPrefix != "" {
+			if err := m.announce(ctx, r); err != nil {
+				return err
+			}
+		}
+
+		messages, err := m.messages(r)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			if err := m.conn.publish(ctx, msg.Topic, msg.Payload, m.retain); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// messages returns the messages published for a reading.
+func (m *MQTT) messages(r Reading) ([]mqttMessage, error) {
+	data := mqttData{
+		Name:       displayName(r.Sensor),
+		MacAddress: r.Sensor.MacAddress,
+		Data:       r.Data,
+	}
+
+	if !m.perMeasurement {
+		msg, err := m.message(r, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return []mqttMessage{msg}, nil
+	}
+
+	var result []mqttMessage
+	for _, measurement := range measurements {
+		if !measurement.Available(r.Data) {
+			continue
+		}
+
+		data.Measurement = measurement.Key
+		data.Value = measurement.Value(r.Data)
+		data.Unit = measurement.Unit
+		msg, err := m.message(r, data)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, msg)
+	}
+
+	return result, nil
+}
+
+func (m *MQTT) message(r Reading, data mqttData) (mqttMessage, error) {
+	topic, err := m.renderTopic(data)
+	if err != nil {
+		return mqttMessage{}, err
+	}
+
+	var payload []byte
+	switch {
+	case m.payload != nil:
+		buf := &bytes.Buffer{}
+		if err := m.payload.Execute(buf, data); err != nil {
+			return mqttMessage{}, fmt.Errorf("can not render payload: %s", err)
+		}
+		payload = buf.Bytes()
+	case data.Measurement != "":
+		payload = []byte(formatValue(data.Value))
+	default:
+		payload, err = json.Marshal(newJSONReading(r))
+		if err != nil {
+			return mqttMessage{}, fmt.Errorf("can not encode reading: %s", err)
+		}
+	}
+
+	return mqttMessage{
+		Topic:   topic,
+		Payload: payload,
+	}, nil
+}
+
+func (m *MQTT) renderTopic(data mqttData) (string, error) {
+	data.Name = topicReplacer.Replace(data.Name)
+
+	buf := &bytes.Buffer{}
+	if err := m.topic.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("can not render topic: %s", err)
+	}
+
+	return buf.String(), nil
+}
+
+// Close implements Sink
+func (m *MQTT) Close() error {
+	var err error
+	if m.availability != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err = m.conn.publish(ctx, m.availability, []byte(mqttOffline), true)
+	}
+
+	m.conn.close()
+	return err
+}
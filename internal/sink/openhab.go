@@ -0,0 +1,97 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+var itemInvalidChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// OpenHAB updates the states of items in openHAB using its REST API. The items need to exist and are named after the
+// sensor and the measurement, for example Flowercare_Tomatoes_Moisture.
+type OpenHAB struct {
+	url        string
+	itemPrefix string
+	client     *http.Client
+}
+
+// NewOpenHAB creates a sink for the openHAB instance and checks that the API can be reached.
+func NewOpenHAB(ctx context.Context, cfg config.OpenHABConfig, auth clientauth.Profile) (*OpenHAB, error) {
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OpenHAB{
+		url:        strings.TrimSuffix(cfg.URL, "/"),
+		itemPrefix: cfg.ItemPrefix,
+		client:     client,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.url+"/rest/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not create request: %s", err)
+	}
+
+	if err := doJSON(o.client, req, nil); err != nil {
+		return nil, fmt.Errorf("can not connect to openHAB: %s", err)
+	}
+
+	return o, nil
+}
+
+// Name implements Sink
+func (o *OpenHAB) Name() string {
+	return "openhab"
+}
+
+// Write implements Sink
+func (o *OpenHAB) Write(ctx context.Context, readings []Reading) error {
+	for _, r := range readings {
+		if err := o.writeReading(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// itemName returns the name of the openHAB item for a measurement of the sensor.
+func (o *OpenHAB) itemName(sensor config.Sensor, m measurement) string {
+	name := strings.Trim(itemInvalidChars.ReplaceAllString(displayName(sensor), "_"), "_")
+	return o.itemPrefix + name + "_" + m.Label
+}
+
+func (o *OpenHAB) writeReading(ctx context.Context, r Reading) error {
+	for _, m := range measurements {
+		if !m.Available(r.Data) {
+			continue
+		}
+
+		item := o.itemName(r.Sensor, m)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, o.url+"/rest/items/"+url.PathEscape(item)+"/state",
+			strings.NewReader(formatValue(m.Value(r.Data))))
+		if err != nil {
+			return fmt.Errorf("can not create request: %s", err)
+		}
+		req.Header.Set("Content-Type", "text/plain")
+
+		if err := doJSON(o.client, req, nil); err != nil {
+			return fmt.Errorf("can not update item %s: %s", item, err)
+		}
+	}
+
+	return nil
+}
+
+// Close implements Sink
+func (o *OpenHAB) Close() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package sink
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// jsonReading is the JSON representation of a reading used by the sinks publishing messages.
+type jsonReading struct {
+	MacAddress string       `json:"macAddress"`
+	Name       string       `json:"name"`
+	Data       miflora.Data `json:"data"`
+}
+
+func newJSONReading(r Reading) jsonReading {
+	data := r.Data
+	data.Raw = miflora.RawData{}
+
+	return jsonReading{
+		MacAddress: r.Sensor.MacAddress,
+		Name:       r.Sensor.Name,
+		Data:       data,
+	}
+}
+
+// reportedState returns the latest values of a sensor as a flat object, used for device state documents like the
+// AWS IoT device shadow.
+func reportedState(r Reading) map[string]interface{} {
+	state := map[string]interface{}{
+		"time":     r.Data.Time.UTC().Format(time.RFC3339),
+		"firmware": r.Data.Firmware.Version,
+	}
+	for _, m := range measurements {
+		if !m.Available(r.Data) {
+			continue
+		}
+
+		state[m.Key] = m.Value(r.Data)
+	}
+
+	return state
+}
+
+// measurement is a single value of a reading, which is sent to systems using one item or entity per value.
+type measurement struct {
+	Key   string
+	Label string
+	Unit  string
+	Value func(miflora.Data) float64
+	// Known is optional. If set, it returns false for readings, which do not contain the value.
+	Known func(miflora.Data) bool
+}
+
+// Available returns true if the reading contains the value.
+func (m measurement) Available(d miflora.Data) bool {
+	return m.Known == nil || m.Known(d)
+}
+
+var measurements = []measurement{
+	{
+		Key:   "moisture",
+		Label: "Moisture",
+		Unit:  miflora.UnitPercent,
+		Value: func(d miflora.Data) float64 {
+			return float64(d.Sensors.Moisture)
+		},
+	},
+	{
+		Key:   "temperature",
+		Label: "Temperature",
+		Unit:  miflora.UnitCelsius,
+		Value: func(d miflora.Data) float64 {
+			return d.Sensors.Temperature
+		},
+	},
+	{
+		Key:   "illuminance",
+		Label: "Illuminance",
+		Unit:  miflora.UnitLux,
+		Value: func(d miflora.Data) float64 {
+			return float64(d.Sensors.Light)
+		},
+	},
+	{
+		Key:   "conductivity",
+		Label: "Conductivity",
+		Unit:  miflora.UnitConductivity,
+		Value: func(d miflora.Data) float64 {
+			return float64(d.Sensors.Conductivity)
+		},
+	},
+	{
+		Key:   "battery",
+		Label: "Battery",
+		Unit:  miflora.UnitPercent,
+		Value: func(d miflora.Data) float64 {
+			return float64(d.Firmware.Battery)
+		},
+		// Readings received passively only contain the battery level after the sensor broadcast it.
+		Known: func(d miflora.Data) bool {
+			return d.Firmware.Known()
+		},
+	},
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// displayName returns the name of the sensor or its MAC address if it has no name.
+func displayName(s config.Sensor) string {
+	if s.Name == "" {
+		return s.MacAddress
+	}
+
+	return s.Name
+}
@@ -0,0 +1,158 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+var postgresColumns = []string{
+	"time",
+	"macaddress",
+	"name",
+	"firmware_version",
+	"battery_percent",
+	"temperature_celsius",
+	"moisture_percent",
+	"brightness_lux",
+	"conductivity_uscm",
+}
+
+// Postgres inserts readings into a PostgreSQL table, which is optionally converted to a TimescaleDB hypertable.
+type Postgres struct {
+	pool  *pgxpool.Pool
+	table string
+}
+
+// NewPostgres connects to the database and creates the table if it does not exist. The TLS settings and credentials
+// of the auth profile take precedence over the ones in the URL.
+func NewPostgres(ctx context.Context, cfg config.PostgresConfig, auth clientauth.Profile) (*Postgres, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse URL: %s", err)
+	}
+
+	if err := applyPostgresAuth(poolConfig, auth); err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to database: %s", err)
+	}
+
+	p := &Postgres{
+		pool:  pool,
+		table: cfg.Table,
+	}
+	if err := p.migrate(ctx, cfg.Timescale); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func applyPostgresAuth(poolConfig *pgxpool.Config, auth clientauth.Profile) error {
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	connConfig := poolConfig.ConnConfig
+	if tlsConfig != nil {
+		tlsConfig.ServerName = connConfig.Host
+		connConfig.TLSConfig = tlsConfig
+		connConfig.Fallbacks = nil
+	}
+
+	if auth.Username != "" {
+		password, err := auth.Password()
+		if err != nil {
+			return err
+		}
+
+		connConfig.User = auth.Username
+		connConfig.Password = password
+	}
+
+	return nil
+}
+
+func (p *Postgres) migrate(ctx context.Context, timescale bool) error {
+	table := pgx.Identifier{p.table}.Sanitize()
+	_, err := p.pool.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	time timestamptz NOT NULL,
+	macaddress text NOT NULL,
+	name text NOT NULL,
+	firmware_version text NOT NULL,
+	battery_percent smallint,
+	temperature_celsius double precision NOT NULL,
+	moisture_percent smallint NOT NULL,
+	brightness_lux integer NOT NULL,
+	conductivity_uscm integer NOT NULL
+)`, table))
+	if err != nil {
+		return fmt.Errorf("can not create table: %s", err)
+	}
+
+	// The battery level is not known for readings received passively. Tables created by older versions need to be
+	// changed to accept these.
+	_, err = p.pool.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN battery_percent DROP NOT NULL", table))
+	if err != nil {
+		return fmt.Errorf("can not update table: %s", err)
+	}
+
+	if !timescale {
+		return nil
+	}
+
+	_, err = p.pool.Exec(ctx, "SELECT create_hypertable($1, 'time', if_not_exists => TRUE)", p.table)
+	if err != nil {
+		return fmt.Errorf("can not create hypertable: %s", err)
+	}
+
+	return nil
+}
+
+// Name implements Sink
+func (p *Postgres) Name() string {
+	return "postgres"
+}
+
+// Write implements Sink
+func (p *Postgres) Write(ctx context.Context, readings []Reading) error {
+	rows := make([][]interface{}, 0, len(readings))
+	for _, r := range readings {
+		var battery *int16
+		if r.Data.Firmware.Known() {
+			value := int16(r.Data.Firmware.Battery)
+			battery = &value
+		}
+
+		rows = append(rows, []interface{}{
+			r.Data.Time,
+			r.Sensor.MacAddress,
+			r.Sensor.Name,
+			r.Data.Firmware.Version,
+			battery,
+			r.Data.Sensors.Temperature,
+			int16(r.Data.Sensors.Moisture),
+			int32(r.Data.Sensors.Light),
+			int32(r.Data.Sensors.Conductivity),
+		})
+	}
+
+	_, err := p.pool.CopyFrom(ctx, pgx.Identifier{p.table}, postgresColumns, pgx.CopyFromRows(rows))
+	return err
+}
+
+// Close implements Sink
+func (p *Postgres) Close() error {
+	p.pool.Close()
+	return nil
+}
@@ -0,0 +1,94 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+// Redis stores the latest reading of every sensor in a key and/or publishes the readings on a channel.
+type Redis struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+	channel   string
+}
+
+// NewRedis creates a client for the Redis server and checks that it can be reached. The TLS settings and credentials
+// of the auth profile take precedence over the ones in the URL.
+func NewRedis(ctx context.Context, cfg config.RedisConfig, auth clientauth.Profile) (*Redis, error) {
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse URL: %s", err)
+	}
+
+	tlsConfig, err := auth.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		tlsConfig.ServerName, _, _ = strings.Cut(opts.Addr, ":")
+		opts.TLSConfig = tlsConfig
+	}
+
+	if auth.Username != "" {
+		password, err := auth.Password()
+		if err != nil {
+			return nil, err
+		}
+
+		opts.Username = auth.Username
+		opts.Password = password
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("can not connect to server: %s", err)
+	}
+
+	return &Redis{
+		client:    client,
+		keyPrefix: cfg.KeyPrefix,
+		ttl:       cfg.TTL,
+		channel:   cfg.Channel,
+	}, nil
+}
+
+// Name implements Sink
+func (r *Redis) Name() string {
+	return "redis"
+}
+
+// Write implements Sink
+func (r *Redis) Write(ctx context.Context, readings []Reading) error {
+	pipe := r.client.Pipeline()
+	for _, reading := range readings {
+		payload, err := json.Marshal(newJSONReading(reading))
+		if err != nil {
+			return fmt.Errorf("can not encode reading: %s", err)
+		}
+
+		if r.keyPrefix != "" {
+			pipe.Set(ctx, r.keyPrefix+reading.Sensor.MacAddress, payload, r.ttl)
+		}
+
+		if r.channel != "" {
+			pipe.Publish(ctx, r.channel, payload)
+		}
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Close implements Sink
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,240 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// remoteWriteMetric is a series pushed per reading, named like the metric exported for scraping.
+type remoteWriteMetric struct {
+	Name  string
+	Value func(miflora.Data) float64
+	// Optional is set for values, which are not part of every reading.
+	Optional bool
+}
+
+var remoteWriteMetrics = []remoteWriteMetric{
+	{
+		Name:     "flowercare_battery_percent",
+		Value:    func(d miflora.Data) float64 { return float64(d.Firmware.Battery) },
+		Optional: true,
+	},
+	{
+		Name:  "flowercare_conductivity_sm",
+		Value: func(d miflora.Data) float64 { return float64(d.Sensors.Conductivity) * 0.0001 },
+	},
+	{
+		Name:  "flowercare_brightness_lux",
+		Value: func(d miflora.Data) float64 { return float64(d.Sensors.Light) },
+	},
+	{
+		Name:  "flowercare_moisture_percent",
+		Value: func(d miflora.Data) float64 { return float64(d.Sensors.Moisture) },
+	},
+	{
+		Name:  "flowercare_temperature_celsius",
+		Value: func(d miflora.Data) float64 { return d.Sensors.Temperature },
+	},
+}
+
+type remoteWriteLabel struct {
+	Name  string
+	Value string
+}
+
+type remoteWriteSample struct {
+	Value     float64
+	Timestamp int64
+}
+
+type remoteWriteSeries struct {
+	Labels  []remoteWriteLabel
+	Samples []remoteWriteSample
+}
+
+// RemoteWrite pushes readings to a Prometheus remote-write endpoint using their original timestamps. It is meant for
+// the history records read for filling gaps, as the current readings are scraped.
+type RemoteWrite struct {
+	url    string
+	labels []remoteWriteLabel
+	client *http.Client
+}
+
+// NewRemoteWrite creates a remote-write sink. The labels are added to all series, together with the job and instance
+// labels of the configuration.
+func NewRemoteWrite(cfg config.RemoteWriteConfig, labels map[string]string, auth clientauth.Profile) (*RemoteWrite, error) {
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RemoteWrite{
+		url:    cfg.URL,
+		client: client,
+	}
+	for name, value := range labels {
+		r.labels = append(r.labels, remoteWriteLabel{Name: name, Value: value})
+	}
+	if cfg.Job != "" {
+		r.labels = append(r.labels, remoteWriteLabel{Name: "job", Value: cfg.Job})
+	}
+	if cfg.Instance != "" {
+		r.labels = append(r.labels, remoteWriteLabel{Name: "instance", Value: cfg.Instance})
+	}
+
+	return r, nil
+}
+
+// Name implements Sink
+func (r *RemoteWrite) Name() string {
+	return "remote-write"
+}
+
+// Write implements Sink
+func (r *RemoteWrite) Write(ctx context.Context, readings []Reading) error {
+	body := snappyEncode(encodeWriteRequest(r.series(readings)))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if err := doJSON(r.client, req, nil); err != nil {
+		return fmt.Errorf("can not push %d readings: %s", len(readings), err)
+	}
+
+	return nil
+}
+
+// series groups the values of the readings into series, whose samples are ordered by time.
+func (r *RemoteWrite) series(readings []Reading) []remoteWriteSeries {
+	index := map[string]int{}
+	result := []remoteWriteSeries{}
+	for _, reading := range readings {
+		for _, m := range remoteWriteMetrics {
+			if m.Optional && !reading.Data.Firmware.Known() {
+				continue
+			}
+
+			labels := append([]remoteWriteLabel{
+				{Name: "__name__", Value: m.Name},
+				{Name: "macaddress", Value: reading.Sensor.MacAddress},
+				{Name: "name", Value: reading.Sensor.Name},
+			}, r.labels...)
+			sort.Slice(labels, func(i, j int) bool {
+				return labels[i].Name < labels[j].Name
+			})
+
+			key := seriesKey(labels)
+			i, ok := index[key]
+			if !ok {
+				i = len(result)
+				index[key] = i
+				result = append(result, remoteWriteSeries{Labels: labels})
+			}
+
+			result[i].Samples = append(result[i].Samples, remoteWriteSample{
+				Value:     m.Value(reading.Data),
+				Timestamp: reading.Data.Time.UnixMilli(),
+			})
+		}
+	}
+
+	for _, s := range result {
+		sort.Slice(s.Samples, func(i, j int) bool {
+			return s.Samples[i].Timestamp < s.Samples[j].Timestamp
+		})
+	}
+	return result
+}
+
+// Close implements Sink
+func (r *RemoteWrite) Close() error {
+	return nil
+}
+
+func seriesKey(labels []remoteWriteLabel) string {
+	b := &strings.Builder{}
+	for _, l := range labels {
+		b.WriteString(l.Name)
+		b.WriteByte(0)
+		b.WriteString(l.Value)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest protobuf message.
+func encodeWriteRequest(series []remoteWriteSeries) []byte {
+	var result []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.Labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.Name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.Value)
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+
+		for _, sample := range s.Samples {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.Fixed64Type)
+			encoded = protowire.AppendFixed64(encoded, math.Float64bits(sample.Value))
+			encoded = protowire.AppendTag(encoded, 2, protowire.VarintType)
+			encoded = protowire.AppendVarint(encoded, uint64(sample.Timestamp))
+
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, encoded)
+		}
+
+		result = protowire.AppendTag(result, 1, protowire.BytesType)
+		result = protowire.AppendBytes(result, ts)
+	}
+	return result
+}
+
+// snappyMaxLiteral is the maximum length of a literal in the snappy block format.
+const snappyMaxLiteral = 1 << 16
+
+// snappyEncode encodes the data using the snappy block format required by remote-write. The data is stored as
+// literals without compression, which every snappy decoder accepts.
+func snappyEncode(data []byte) []byte {
+	result := binary.AppendUvarint(nil, uint64(len(data)))
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > snappyMaxLiteral {
+			chunk = chunk[:snappyMaxLiteral]
+		}
+		data = data[len(chunk):]
+
+		n := len(chunk) - 1
+		switch {
+		case n < 60:
+			result = append(result, byte(n)<<2)
+		case n < 1<<8:
+			result = append(result, 60<<2, byte(n))
+		default:
+			result = append(result, 61<<2, byte(n), byte(n>>8))
+		}
+		result = append(result, chunk...)
+	}
+	return result
+}
@@ -0,0 +1,349 @@
+// Package sink contains integrations which readings are pushed to.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+const (
+	queueSize    = 1000
+	writeTimeout = 30 * time.Second
+)
+
+// Reading is a successful reading of a sensor.
+type Reading struct {
+	Sensor config.Sensor
+	Data   miflora.Data
+}
+
+// Sink receives batches of readings.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, readings []Reading) error
+	Close() error
+}
+
+// Options contains the settings of the dispatcher.
+type Options struct {
+	// JournalDir is the directory for the journal files. If it is empty, pending readings are only kept in memory.
+	JournalDir string
+	// DeadLetterFile is the file readings are appended to, which could not be delivered within the maximum age of the
+	// retry policy. If it is empty, these readings are dropped.
+	DeadLetterFile string
+	// Deadband skips readings, which did not change much since the last published reading of the sensor. History
+	// records are always published.
+	Deadband config.SinkDeadbandConfig
+}
+
+// Target is a sink together with its batching settings and retry policy.
+type Target struct {
+	Sink  Sink
+	Batch config.SinkBatchConfig
+	Retry config.SinkRetryConfig
+	// HistoryOnly limits the sink to the readings passed to PublishHistory.
+	HistoryOnly bool
+}
+
+// Dispatcher delivers readings to a set of sinks in the background, so that slow sinks do not block the updater.
+type Dispatcher struct {
+	log        *logging.Logger
+	sinks      []*sinkWorker
+	deadLetter *deadLetter
+	// deadband is nil if no deadband is set.
+	deadband *deadband
+	metrics  dispatchMetrics
+}
+
+type sinkWorker struct {
+	log         *logging.Logger
+	sink        Sink
+	batch       config.SinkBatchConfig
+	retry       config.SinkRetryConfig
+	historyOnly bool
+	queue       chan Reading
+	metrics     dispatchMetrics
+
+	// journal keeps the pending readings on disk. If it is nil, the pending readings are only kept in memory.
+	journal     *journal
+	deadLetter  *deadLetter
+	pending     []Reading
+	pendingLen  int64
+	failures    int
+	nextAttempt time.Time
+}
+
+// NewDispatcher creates a dispatcher for the sinks. Readings which can not be written are retried using the retry
+// policy of the sink. If a journal directory is set, the pending readings are stored in a journal file per sink, so
+// that they are not lost when the exporter is restarted.
+func NewDispatcher(log *logging.Logger, opts Options, targets ...Target) (*Dispatcher, error) {
+	d := &Dispatcher{
+		log:     log,
+		metrics: newDispatchMetrics(),
+	}
+
+	if opts.DeadLetterFile != "" {
+		dl, err := openDeadLetter(opts.DeadLetterFile)
+		if err != nil {
+			return nil, fmt.Errorf("can not open dead-letter file: %s", err)
+		}
+		d.deadLetter = dl
+	}
+
+	if opts.Deadband.Enabled() {
+		db, err := newDeadband(opts.Deadband)
+		if err != nil {
+			return nil, err
+		}
+		d.deadband = db
+	}
+
+	for _, t := range targets {
+		w := &sinkWorker{
+			log:         log.With("sink", t.Sink.Name()),
+			sink:        t.Sink,
+			batch:       t.Batch,
+			retry:       t.Retry,
+			historyOnly: t.HistoryOnly,
+			queue:       make(chan Reading, queueSize),
+			metrics:     d.metrics,
+			deadLetter:  d.deadLetter,
+		}
+
+		if opts.JournalDir != "" {
+			j, pending, err := openJournal(w.log, opts.JournalDir, t.Sink.Name())
+			if err != nil {
+				return nil, fmt.Errorf("can not open journal of sink %q: %s", t.Sink.Name(), err)
+			}
+
+			if len(pending) > 0 {
+				w.log.Infof("Found %d undelivered readings in journal.", len(pending))
+			}
+			w.journal = j
+			w.setPending(pending)
+		}
+
+		d.sinks = append(d.sinks, w)
+	}
+
+	return d, nil
+}
+
+// Publish queues a reading for delivery to all sinks, except the ones only receiving history records. Readings are
+// dropped if the queue of a sink is full.
+func (d *Dispatcher) Publish(sensor config.Sensor, data miflora.Data) {
+	d.publish(sensor, data, false)
+}
+
+// PublishHistory queues a history record read from a sensor for delivery to all sinks.
+func (d *Dispatcher) PublishHistory(sensor config.Sensor, data miflora.Data) {
+	d.publish(sensor, data, true)
+}
+
+func (d *Dispatcher) publish(sensor config.Sensor, data miflora.Data, history bool) {
+	if !history && d.deadband != nil && d.deadband.skip(sensor.MacAddress, data) {
+		d.metrics.deadbandSkipped.Inc()
+		return
+	}
+
+	reading := Reading{
+		Sensor: sensor,
+		Data:   data,
+	}
+
+	for _, w := range d.sinks {
+		if w.historyOnly && !history {
+			continue
+		}
+
+		select {
+		case w.queue <- reading:
+		default:
+			w.log.Warnf("Queue full, dropping reading of %q", sensor)
+			w.metrics.dropped.WithLabelValues(w.sink.Name()).Inc()
+		}
+	}
+}
+
+// Start starts delivering readings. Remaining readings are flushed and the sinks closed when the context is done.
+func (d *Dispatcher) Start(ctx context.Context, wg *sync.WaitGroup) {
+	workers := &sync.WaitGroup{}
+	for _, w := range d.sinks {
+		workers.Add(1)
+		go func(w *sinkWorker) {
+			defer workers.Done()
+			w.run(ctx)
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		workers.Wait()
+
+		if d.deadLetter != nil {
+			if err := d.deadLetter.Close(); err != nil {
+				d.log.Errorf("Error closing dead-letter file: %s", err)
+			}
+		}
+	}()
+}
+
+func (w *sinkWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.batch.FlushInterval)
+	defer ticker.Stop()
+
+	w.flush(context.Background(), time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			w.drain()
+			return
+		case r := <-w.queue:
+			w.add(r)
+			if len(w.pending) >= w.batch.Size && w.failures == 0 {
+				w.flush(context.Background(), time.Now())
+			}
+		case now := <-ticker.C:
+			if now.Before(w.nextAttempt) {
+				continue
+			}
+
+			w.flush(context.Background(), now)
+		}
+	}
+}
+
+// add adds the reading to the pending readings and the journal.
+func (w *sinkWorker) add(r Reading) {
+	if w.journal != nil {
+		if err := w.journal.Append(r); err != nil {
+			w.log.Errorf("Error writing reading of %q to journal: %s", r.Sensor, err)
+		}
+	}
+
+	w.setPending(append(w.pending, r))
+}
+
+func (w *sinkWorker) setPending(pending []Reading) {
+	w.pending = pending
+	atomic.StoreInt64(&w.pendingLen, int64(len(pending)))
+}
+
+// drain writes everything still queued and closes the sink. Readings which can not be written are kept in the journal.
+func (w *sinkWorker) drain() {
+	for {
+		select {
+		case r := <-w.queue:
+			w.add(r)
+		default:
+			w.flush(context.Background(), time.Now())
+			if err := w.sink.Close(); err != nil {
+				w.log.Errorf("Error closing sink: %s", err)
+			}
+
+			if w.journal != nil {
+				if err := w.journal.Close(); err != nil {
+					w.log.Errorf("Error closing journal: %s", err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// flush writes the pending readings in batches, oldest first. When a batch can not be written, the remaining readings
+// are kept for the next attempt, which is delayed according to the retry policy. Readings older than the maximum age
+// of the retry policy are moved to the dead-letter file.
+func (w *sinkWorker) flush(ctx context.Context, now time.Time) {
+	if len(w.pending) == 0 {
+		return
+	}
+
+	var err error
+	written := 0
+	for written < len(w.pending) {
+		end := written + w.batch.Size
+		if end > len(w.pending) {
+			end = len(w.pending)
+		}
+
+		err = w.write(ctx, w.pending[written:end])
+		if err != nil {
+			w.log.Errorf("Error writing %d readings: %s", end-written, err)
+			w.metrics.errors.WithLabelValues(w.sink.Name()).Inc()
+			break
+		}
+
+		w.log.Debugf("Wrote %d readings.", end-written)
+		written = end
+	}
+
+	remaining := w.pending[written:]
+	expired := 0
+	if err != nil {
+		w.failures++
+		w.nextAttempt = now.Add(w.retry.Backoff(w.failures))
+
+		remaining, expired = w.expire(remaining, now, err)
+		if len(remaining) > 0 {
+			w.log.Warnf("Retrying %d readings in %s.", len(remaining), w.nextAttempt.Sub(now))
+		}
+	} else {
+		w.failures = 0
+		w.nextAttempt = time.Time{}
+	}
+
+	if w.journal != nil && (written > 0 || expired > 0) {
+		if err := w.journal.Replace(remaining); err != nil {
+			w.log.Errorf("Error updating journal: %s", err)
+		}
+	}
+
+	w.setPending(append(w.pending[:0], remaining...))
+}
+
+// expire removes the readings, which are older than the maximum age, and passes them to the dead-letter file.
+func (w *sinkWorker) expire(readings []Reading, now time.Time, cause error) ([]Reading, int) {
+	remaining := make([]Reading, 0, len(readings))
+	var expired []Reading
+	for _, r := range readings {
+		if now.Sub(r.Data.Time) < w.retry.MaxAge {
+			remaining = append(remaining, r)
+			continue
+		}
+
+		expired = append(expired, r)
+	}
+
+	if len(expired) == 0 {
+		return readings, 0
+	}
+
+	w.metrics.deadLettered.WithLabelValues(w.sink.Name()).Add(float64(len(expired)))
+	if w.deadLetter == nil {
+		w.log.Errorf("Dropping %d readings older than %s.", len(expired), w.retry.MaxAge)
+		return remaining, len(expired)
+	}
+
+	w.log.Errorf("Moving %d readings older than %s to dead-letter file.", len(expired), w.retry.MaxAge)
+	if err := w.deadLetter.Append(w.sink.Name(), expired, cause, now); err != nil {
+		w.log.Errorf("Error writing to dead-letter file: %s", err)
+	}
+
+	return remaining, len(expired)
+}
+
+func (w *sinkWorker) write(ctx context.Context, readings []Reading) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	return w.sink.Write(ctx, readings)
+}
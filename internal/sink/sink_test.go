@@ -0,0 +1,546 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+var errWrite = errors.New("sink unavailable")
+
+// fakeSink records the written batches. The first failures calls of Write return an error, as well as the call with
+// the number failCall.
+type fakeSink struct {
+	lock     sync.Mutex
+	failures int
+	failCall int
+	calls    int
+	batches  [][]Reading
+	closed   bool
+}
+
+func (s *fakeSink) Name() string {
+	return "fake"
+}
+
+func (s *fakeSink) Write(_ context.Context, readings []Reading) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.calls++
+	if s.calls == s.failCall {
+		return errWrite
+	}
+
+	if s.failures > 0 {
+		s.failures--
+		return errWrite
+	}
+
+	s.batches = append(s.batches, append([]Reading(nil), readings...))
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *fakeSink) batchSizes() []int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var result []int
+	for _, b := range s.batches {
+		result = append(result, len(b))
+	}
+	return result
+}
+
+func (s *fakeSink) isClosed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.closed
+}
+
+func (s *fakeSink) written() []Reading {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var result []Reading
+	for _, b := range s.batches {
+		result = append(result, b...)
+	}
+	return result
+}
+
+var (
+	testStart   = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	testSensorA = config.Sensor{Name: "basil", MacAddress: "C4:7C:8D:00:00:01"}
+	testSensorB = config.Sensor{Name: "mint", MacAddress: "C4:7C:8D:00:00:02"}
+)
+
+func testReading(sensor config.Sensor, offset time.Duration, moisture byte) Reading {
+	return Reading{
+		Sensor: sensor,
+		Data: miflora.Data{
+			Time:     testStart.Add(offset),
+			Firmware: miflora.Firmware{Version: "3.2.1", Battery: 80},
+			Sensors: miflora.Sensors{
+				Temperature:  20,
+				Moisture:     moisture,
+				Light:        500,
+				Conductivity: 300,
+			},
+		},
+	}
+}
+
+func testTarget(s Sink) Target {
+	return Target{
+		Sink: s,
+		Batch: config.SinkBatchConfig{
+			Size:          10,
+			FlushInterval: time.Minute,
+		},
+		Retry: config.SinkRetryConfig{
+			MinBackoff: time.Minute,
+			MaxBackoff: 4 * time.Minute,
+			MaxAge:     24 * time.Hour,
+		},
+	}
+}
+
+func newTestWorker(t *testing.T, opts Options, target Target) *sinkWorker {
+	t.Helper()
+
+	d, err := NewDispatcher(logging.Discard(), opts, target)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	return d.sinks[0]
+}
+
+// sameReadings compares the sensors and times of the readings, because the journal does not keep all fields of a
+// sensor and loses the monotonic clock reading of the time.
+func sameReadings(got, want []Reading) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range got {
+		if got[i].Sensor.MacAddress != want[i].Sensor.MacAddress ||
+			!got[i].Data.Time.Equal(want[i].Data.Time) ||
+			got[i].Data.Sensors != want[i].Data.Sensors {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJournalReplay(t *testing.T) {
+	readings := []Reading{
+		testReading(testSensorA, 0, 30),
+		testReading(testSensorB, time.Minute, 40),
+		testReading(testSensorA, 2*time.Minute, 31),
+	}
+
+	tests := []struct {
+		desc        string
+		failures    int
+		wantPending []Reading
+	}{
+		{
+			desc:        "undelivered readings are replayed",
+			failures:    1,
+			wantPending: readings,
+		},
+		{
+			desc:        "delivered readings are removed",
+			failures:    0,
+			wantPending: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := Options{
+				JournalDir: t.TempDir(),
+			}
+
+			w := newTestWorker(t, opts, testTarget(&fakeSink{failures: tc.failures}))
+			for _, r := range readings {
+				w.add(r)
+			}
+			w.flush(context.Background(), testStart.Add(3*time.Minute))
+			w.journal.Close()
+
+			sink := &fakeSink{}
+			w = newTestWorker(t, opts, testTarget(sink))
+			if !sameReadings(w.pending, tc.wantPending) {
+				t.Fatalf("got pending %v, want %v", w.pending, tc.wantPending)
+			}
+
+			w.flush(context.Background(), testStart.Add(4*time.Minute))
+			if got := sink.written(); !sameReadings(got, tc.wantPending) {
+				t.Errorf("got written %v, want %v", got, tc.wantPending)
+			}
+			w.journal.Close()
+
+			w = newTestWorker(t, opts, testTarget(&fakeSink{}))
+			if len(w.pending) != 0 {
+				t.Errorf("got %d pending readings after delivery, want none", len(w.pending))
+			}
+			w.journal.Close()
+		})
+	}
+}
+
+func TestJournalIncompleteEntry(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestWorker(t, Options{JournalDir: dir}, testTarget(&fakeSink{}))
+	w.add(testReading(testSensorA, 0, 30))
+	if _, err := w.journal.file.WriteString(`{"macAddress":"C4:7C`); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	w.journal.Close()
+
+	w = newTestWorker(t, Options{JournalDir: dir}, testTarget(&fakeSink{}))
+	defer w.journal.Close()
+
+	want := []Reading{testReading(testSensorA, 0, 30)}
+	if !sameReadings(w.pending, want) {
+		t.Errorf("got pending %v, want %v", w.pending, want)
+	}
+}
+
+func TestDispatcherSensorOrder(t *testing.T) {
+	sink := &fakeSink{failures: 1}
+	target := testTarget(sink)
+	target.Batch.Size = 3
+	w := newTestWorker(t, Options{}, target)
+	d := &Dispatcher{sinks: []*sinkWorker{w}}
+
+	var want []Reading
+	for i := 0; i < 5; i++ {
+		a := testReading(testSensorA, time.Duration(i)*time.Minute, byte(30+i))
+		b := testReading(testSensorB, time.Duration(i)*time.Minute, byte(40+i))
+		d.Publish(a.Sensor, a.Data)
+		d.Publish(b.Sensor, b.Data)
+		want = append(want, a, b)
+	}
+
+	for len(w.queue) > 0 {
+		w.add(<-w.queue)
+	}
+
+	w.flush(context.Background(), testStart.Add(5*time.Minute))
+	if got := sink.written(); len(got) != 0 {
+		t.Fatalf("got %d written readings, want none", len(got))
+	}
+
+	w.flush(context.Background(), testStart.Add(6*time.Minute))
+	got := sink.written()
+	for _, sensor := range []config.Sensor{testSensorA, testSensorB} {
+		var last time.Time
+		for _, r := range got {
+			if r.Sensor != sensor {
+				continue
+			}
+
+			if r.Data.Time.Before(last) {
+				t.Errorf("got reading of %s at %s after %s", sensor, r.Data.Time, last)
+			}
+			last = r.Data.Time
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got written %v, want %v", got, want)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	type attempt struct {
+		offset          time.Duration
+		wantNextAttempt time.Duration
+		wantPending     int
+		wantDeadLetter  int
+	}
+
+	tests := []struct {
+		desc     string
+		maxAge   time.Duration
+		failures int
+		readings []Reading
+		attempts []attempt
+	}{
+		{
+			desc:     "backoff is doubled up to maximum",
+			maxAge:   time.Hour,
+			failures: 4,
+			readings: []Reading{testReading(testSensorA, 0, 30)},
+			attempts: []attempt{
+				{offset: 0, wantNextAttempt: time.Minute, wantPending: 1},
+				{offset: time.Minute, wantNextAttempt: 3 * time.Minute, wantPending: 1},
+				{offset: 3 * time.Minute, wantNextAttempt: 7 * time.Minute, wantPending: 1},
+				{offset: 7 * time.Minute, wantNextAttempt: 11 * time.Minute, wantPending: 1},
+				{offset: 11 * time.Minute, wantPending: 0},
+			},
+		},
+		{
+			desc:     "success resets backoff",
+			maxAge:   time.Hour,
+			failures: 2,
+			readings: []Reading{testReading(testSensorA, 0, 30)},
+			attempts: []attempt{
+				{offset: 0, wantNextAttempt: time.Minute, wantPending: 1},
+				{offset: time.Minute, wantNextAttempt: 3 * time.Minute, wantPending: 1},
+				{offset: 3 * time.Minute, wantPending: 0},
+			},
+		},
+		{
+			desc:     "readings older than maximum age are dead-lettered",
+			maxAge:   5 * time.Minute,
+			failures: 10,
+			readings: []Reading{
+				testReading(testSensorA, 0, 30),
+				testReading(testSensorB, 2*time.Minute, 40),
+				testReading(testSensorA, 4*time.Minute, 31),
+			},
+			attempts: []attempt{
+				{offset: 4 * time.Minute, wantNextAttempt: 5 * time.Minute, wantPending: 3},
+				{offset: 5 * time.Minute, wantNextAttempt: 7 * time.Minute, wantPending: 2, wantDeadLetter: 1},
+				{offset: 9 * time.Minute, wantNextAttempt: 13 * time.Minute, wantPending: 0, wantDeadLetter: 3},
+			},
+		},
+		{
+			desc:     "failed readings are not retried without maximum age",
+			maxAge:   0,
+			failures: 1,
+			readings: []Reading{
+				testReading(testSensorA, 0, 30),
+				testReading(testSensorB, 0, 40),
+			},
+			attempts: []attempt{
+				{offset: 0, wantNextAttempt: time.Minute, wantPending: 0, wantDeadLetter: 2},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			deadLetterFile := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+			target := testTarget(&fakeSink{failures: tc.failures})
+			target.Retry.MaxAge = tc.maxAge
+
+			d, err := NewDispatcher(logging.Discard(), Options{DeadLetterFile: deadLetterFile}, target)
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			w := d.sinks[0]
+			for _, r := range tc.readings {
+				w.add(r)
+			}
+
+			for i, a := range tc.attempts {
+				now := testStart.Add(a.offset)
+				w.flush(context.Background(), now)
+
+				var wantNextAttempt time.Time
+				if a.wantNextAttempt > 0 {
+					wantNextAttempt = testStart.Add(a.wantNextAttempt)
+				}
+				if !w.nextAttempt.Equal(wantNextAttempt) {
+					t.Errorf("got next attempt %s after attempt %d, want %s", w.nextAttempt, i, wantNextAttempt)
+				}
+
+				if len(w.pending) != a.wantPending {
+					t.Errorf("got %d pending readings after attempt %d, want %d", len(w.pending), i, a.wantPending)
+				}
+
+				deadLettered := int(testutil.ToFloat64(w.metrics.deadLettered.WithLabelValues("fake")))
+				if deadLettered != a.wantDeadLetter {
+					t.Errorf("got %d dead-lettered readings after attempt %d, want %d", deadLettered, i, a.wantDeadLetter)
+				}
+			}
+
+			if err := d.deadLetter.Close(); err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			entries := readDeadLetter(t, deadLetterFile)
+			want := tc.attempts[len(tc.attempts)-1].wantDeadLetter
+			if len(entries) != want {
+				t.Fatalf("got %d dead-letter entries, want %d", len(entries), want)
+			}
+
+			for i, e := range entries {
+				if e.Sink != "fake" || e.Error != errWrite.Error() {
+					t.Errorf("got sink %q and error %q in entry %d, want %q and %q", e.Sink, e.Error, i, "fake", errWrite)
+				}
+
+				if !e.Data.Time.Equal(tc.readings[i].Data.Time) || e.MacAddress != tc.readings[i].Sensor.MacAddress {
+					t.Errorf("got reading of %s at %s in entry %d, want %s at %s", e.MacAddress, e.Data.Time, i,
+						tc.readings[i].Sensor.MacAddress, tc.readings[i].Data.Time)
+				}
+			}
+		})
+	}
+}
+
+func readDeadLetter(t *testing.T, fileName string) []deadLetterEntry {
+	t.Helper()
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	defer file.Close()
+
+	var entries []deadLetterEntry
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var e deadLetterEntry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("got error %q", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestFlushBatches(t *testing.T) {
+	tests := []struct {
+		desc        string
+		size        int
+		readings    int
+		failCall    int
+		wantBatches []int
+		wantPending int
+	}{
+		{
+			desc:        "single batch",
+			size:        10,
+			readings:    3,
+			wantBatches: []int{3},
+		},
+		{
+			desc:        "full batches",
+			size:        2,
+			readings:    4,
+			wantBatches: []int{2, 2},
+		},
+		{
+			desc:        "last batch is smaller",
+			size:        2,
+			readings:    5,
+			wantBatches: []int{2, 2, 1},
+		},
+		{
+			desc:        "failed batch keeps remaining readings",
+			size:        2,
+			readings:    5,
+			failCall:    2,
+			wantBatches: []int{2},
+			wantPending: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := Options{
+				JournalDir: t.TempDir(),
+			}
+
+			sink := &fakeSink{failCall: tc.failCall}
+			target := testTarget(sink)
+			target.Batch.Size = tc.size
+			w := newTestWorker(t, opts, target)
+
+			var readings []Reading
+			for i := 0; i < tc.readings; i++ {
+				r := testReading(testSensorA, time.Duration(i)*time.Minute, byte(30+i))
+				readings = append(readings, r)
+				w.add(r)
+			}
+			w.flush(context.Background(), testStart.Add(time.Hour))
+			w.journal.Close()
+
+			if got := sink.batchSizes(); !reflect.DeepEqual(got, tc.wantBatches) {
+				t.Errorf("got batches %v, want %v", got, tc.wantBatches)
+			}
+
+			wantPending := readings[tc.readings-tc.wantPending:]
+			if !sameReadings(w.pending, wantPending) {
+				t.Errorf("got pending %v, want %v", w.pending, wantPending)
+			}
+
+			w = newTestWorker(t, opts, target)
+			defer w.journal.Close()
+			if !sameReadings(w.pending, wantPending) {
+				t.Errorf("got journal %v, want %v", w.pending, wantPending)
+			}
+		})
+	}
+}
+
+func TestDispatcherFlush(t *testing.T) {
+	sink := &fakeSink{}
+	target := testTarget(sink)
+	target.Batch = config.SinkBatchConfig{
+		Size:          2,
+		FlushInterval: time.Hour,
+	}
+
+	d, err := NewDispatcher(logging.Discard(), Options{}, target)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	d.Start(ctx, wg)
+
+	for i := 0; i < 3; i++ {
+		r := testReading(testSensorA, time.Duration(i)*time.Minute, byte(30+i))
+		d.Publish(r.Sensor, r.Data)
+	}
+
+	// A full batch is written without waiting for the flush interval.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.batchSizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("full batch was not written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	wg.Wait()
+
+	if got, want := sink.batchSizes(), []int{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got batches %v, want %v", got, want)
+	}
+
+	if !sink.isClosed() {
+		t.Error("sink was not closed")
+	}
+}
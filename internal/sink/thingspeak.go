@@ -0,0 +1,147 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+)
+
+// ThingSpeakChannel maps a sensor, identified by its name or MAC address, to a ThingSpeak channel.
+type ThingSpeakChannel struct {
+	Sensor      string `json:"sensor"`
+	Channel     int    `json:"channel"`
+	WriteAPIKey string `json:"writeApiKey"`
+}
+
+type thingSpeakUpdate struct {
+	CreatedAt string `json:"created_at"`
+	Field1    string `json:"field1"`
+	Field2    string `json:"field2"`
+	Field3    string `json:"field3"`
+	Field4    string `json:"field4"`
+	Field5    string `json:"field5"`
+}
+
+type thingSpeakBulkUpdate struct {
+	WriteAPIKey string             `json:"write_api_key"`
+	Updates     []thingSpeakUpdate `json:"updates"`
+}
+
+// ThingSpeak pushes readings to a ThingSpeak channel per sensor using the bulk update API. The measurements are
+// written to the fields 1 to 5 in the order moisture, temperature, illuminance, conductivity and battery.
+type ThingSpeak struct {
+	url      string
+	channels []ThingSpeakChannel
+	client   *http.Client
+}
+
+// NewThingSpeak creates a sink using the channels from the channels file.
+func NewThingSpeak(cfg config.ThingSpeakConfig, auth clientauth.Profile) (*ThingSpeak, error) {
+	raw, err := os.ReadFile(cfg.ChannelsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []ThingSpeakChannel
+	if err := json.Unmarshal(raw, &channels); err != nil {
+		return nil, fmt.Errorf("can not parse channels file %q: %s", cfg.ChannelsFile, err)
+	}
+
+	for i, c := range channels {
+		if c.Sensor == "" || c.Channel == 0 || c.WriteAPIKey == "" {
+			return nil, fmt.Errorf("channel %d needs sensor, channel and write API key", i)
+		}
+	}
+
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ThingSpeak{
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		channels: channels,
+		client:   client,
+	}, nil
+}
+
+// Name implements Sink
+func (t *ThingSpeak) Name() string {
+	return "thingspeak"
+}
+
+// Write implements Sink
+func (t *ThingSpeak) Write(ctx context.Context, readings []Reading) error {
+	updates := map[int][]thingSpeakUpdate{}
+	for _, r := range readings {
+		channel, ok := t.channel(r.Sensor)
+		if !ok {
+			continue
+		}
+
+		update := thingSpeakUpdate{
+			CreatedAt: r.Data.Time.UTC().Format(time.RFC3339),
+		}
+		for i, field := range []*string{&update.Field1, &update.Field2, &update.Field3, &update.Field4, &update.Field5} {
+			if m := measurements[i]; m.Available(r.Data) {
+				*field = formatValue(m.Value(r.Data))
+			}
+		}
+		updates[channel.Channel] = append(updates[channel.Channel], update)
+	}
+
+	for _, c := range t.channels {
+		channelUpdates, ok := updates[c.Channel]
+		if !ok {
+			continue
+		}
+		delete(updates, c.Channel)
+
+		if err := t.bulkUpdate(ctx, c, channelUpdates); err != nil {
+			return fmt.Errorf("can not update channel %d: %s", c.Channel, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *ThingSpeak) channel(sensor config.Sensor) (ThingSpeakChannel, bool) {
+	for _, c := range t.channels {
+		if strings.EqualFold(c.Sensor, sensor.MacAddress) || (sensor.Name != "" && c.Sensor == sensor.Name) {
+			return c, true
+		}
+	}
+
+	return ThingSpeakChannel{}, false
+}
+
+func (t *ThingSpeak) bulkUpdate(ctx context.Context, c ThingSpeakChannel, updates []thingSpeakUpdate) error {
+	payload, err := json.Marshal(thingSpeakBulkUpdate{
+		WriteAPIKey: c.WriteAPIKey,
+		Updates:     updates,
+	})
+	if err != nil {
+		return fmt.Errorf("can not encode updates: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/channels/%d/bulk_update.json", t.url, c.Channel), bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return doJSON(t.client, req, nil)
+}
+
+// Close implements Sink
+func (t *ThingSpeak) Close() error {
+	return nil
+}
@@ -0,0 +1,166 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/template"
+
+	"github.com/xperimental/flowercare-exporter/internal/clientauth"
+	"github.com/xperimental/flowercare-exporter/internal/config"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// WebhookSignatureHeader contains the HMAC-SHA256 signature of the body, if a secret is configured.
+const WebhookSignatureHeader = "X-Flowercare-Signature"
+
+// webhookData is passed to the body template.
+type webhookData struct {
+	Name       string
+	MacAddress string
+	Data       miflora.Data
+}
+
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+}
+
+// Webhook posts readings to a URL, one request per reading. The body is the reading in JSON format or rendered from a
+// template. With a secret, the body is signed using HMAC-SHA256.
+type Webhook struct {
+	url    string
+	body   *template.Template
+	every  int
+	secret []byte
+	client *http.Client
+	counts map[string]int
+}
+
+// NewWebhook creates a webhook sink.
+func NewWebhook(cfg config.WebhookConfig, auth clientauth.Profile) (*Webhook, error) {
+	client, err := auth.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Webhook{
+		url:    cfg.URL,
+		every:  cfg.Every,
+		client: client,
+		counts: map[string]int{},
+	}
+
+	if cfg.BodyFile != "" {
+		raw, err := os.ReadFile(cfg.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can not read body template: %s", err)
+		}
+
+		body, err := template.New("body").Funcs(webhookFuncs).Parse(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("can not parse body template: %s", err)
+		}
+		w.body = body
+	}
+
+	secret, err := clientauth.ReadSecret(cfg.SecretFile, cfg.SecretEnv, "webhook secret")
+	if err != nil {
+		return nil, err
+	}
+	w.secret = []byte(secret)
+
+	return w, nil
+}
+
+// Name implements Sink
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Write implements Sink
+func (w *Webhook) Write(ctx context.Context, readings []Reading) error {
+	// The counts are only updated once all readings have been sent, so that retried readings are selected again.
+	counts := make(map[string]int, len(w.counts))
+	for mac, count := range w.counts {
+		counts[mac] = count
+	}
+
+	for _, r := range readings {
+		count := counts[r.Sensor.MacAddress]
+		counts[r.Sensor.MacAddress] = count + 1
+		if count%w.every != 0 {
+			continue
+		}
+
+		if err := w.post(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	w.counts = counts
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, r Reading) error {
+	payload, err := w.render(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can not create request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(payload)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	if err := doJSON(w.client, req, nil); err != nil {
+		return fmt.Errorf("can not post reading of %s: %s", r.Sensor, err)
+	}
+
+	return nil
+}
+
+func (w *Webhook) render(r Reading) ([]byte, error) {
+	if w.body == nil {
+		payload, err := json.Marshal(newJSONReading(r))
+		if err != nil {
+			return nil, fmt.Errorf("can not encode reading: %s", err)
+		}
+
+		return payload, nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := w.body.Execute(buf, webhookData{
+		Name:       displayName(r.Sensor),
+		MacAddress: r.Sensor.MacAddress,
+		Data:       r.Data,
+	}); err != nil {
+		return nil, fmt.Errorf("can not render body: %s", err)
+	}
+
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("body template does not produce valid JSON: %s", buf)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Close implements Sink
+func (w *Webhook) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package species
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// communityRecord is a species in the format of the plant database used by the Flower Care app,
+// which is also used by Home Assistant's plant integration and OpenPlantbook.
+type communityRecord struct {
+	PID          string   `json:"pid"`
+	DisplayPID   string   `json:"display_pid"`
+	Alias        string   `json:"alias"`
+	MinLightLux  *float64 `json:"min_light_lux"`
+	MaxLightLux  *float64 `json:"max_light_lux"`
+	MinTemp      *float64 `json:"min_temp"`
+	MaxTemp      *float64 `json:"max_temp"`
+	MinSoilMoist *float64 `json:"min_soil_moist"`
+	MaxSoilMoist *float64 `json:"max_soil_moist"`
+	MinSoilEC    *float64 `json:"min_soil_ec"`
+	MaxSoilEC    *float64 `json:"max_soil_ec"`
+}
+
+// LoadFile reads species from a plant database file. CSV files and JSON files containing either a single
+// species or a list of species are supported.
+func LoadFile(fileName string) ([]Species, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []communityRecord
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		records, err = parseCSV(f)
+	case ".json":
+		records, err = parseJSON(f)
+	default:
+		return nil, fmt.Errorf("unknown format of species file: %s", fileName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not parse species file %q: %s", fileName, err)
+	}
+
+	result := make([]Species, 0, len(records))
+	for i, r := range records {
+		s, err := r.species()
+		if err != nil {
+			return nil, fmt.Errorf("species %d in %q: %s", i, fileName, err)
+		}
+
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func parseJSON(r io.Reader) ([]communityRecord, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []communityRecord
+	if err := json.Unmarshal(raw, &records); err == nil {
+		return records, nil
+	}
+
+	var record communityRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, err
+	}
+
+	return []communityRecord{record}, nil
+}
+
+func parseCSV(r io.Reader) ([]communityRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can not read header: %s", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var records []communityRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		number := func(name string) (*float64, error) {
+			value := field(name)
+			if value == "" {
+				return nil, nil
+			}
+
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("can not parse %s: %s", name, err)
+			}
+			return &f, nil
+		}
+
+		record := communityRecord{
+			PID:        field("pid"),
+			DisplayPID: field("display_pid"),
+			Alias:      field("alias"),
+		}
+		for name, target := range map[string]**float64{
+			"min_light_lux":  &record.MinLightLux,
+			"max_light_lux":  &record.MaxLightLux,
+			"min_temp":       &record.MinTemp,
+			"max_temp":       &record.MaxTemp,
+			"min_soil_moist": &record.MinSoilMoist,
+			"max_soil_moist": &record.MaxSoilMoist,
+			"min_soil_ec":    &record.MinSoilEC,
+			"max_soil_ec":    &record.MaxSoilEC,
+		} {
+			if *target, err = number(name); err != nil {
+				return nil, fmt.Errorf("line %d: %s", len(records)+2, err)
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (r communityRecord) species() (Species, error) {
+	if r.PID == "" {
+		return Species{}, errors.New("missing pid")
+	}
+
+	name := r.DisplayPID
+	if r.Alias != "" {
+		name = r.Alias
+	}
+
+	s := Species{
+		ID:   NormalizeID(r.PID),
+		Name: name,
+	}
+	for _, v := range []struct {
+		target   *Range
+		min, max *float64
+		label    string
+	}{
+		{&s.Moisture, r.MinSoilMoist, r.MaxSoilMoist, "soil moisture"},
+		{&s.Temperature, r.MinTemp, r.MaxTemp, "temperature"},
+		{&s.Light, r.MinLightLux, r.MaxLightLux, "light"},
+		{&s.Conductivity, r.MinSoilEC, r.MaxSoilEC, "soil conductivity"},
+	} {
+		if v.min == nil || v.max == nil {
+			return Species{}, fmt.Errorf("%s: missing %s range", r.PID, v.label)
+		}
+
+		v.target.Min = *v.min
+		v.target.Max = *v.max
+	}
+
+	return s, nil
+}
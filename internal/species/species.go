@@ -0,0 +1,72 @@
+// Package species contains recommended growing conditions of plant species, used as default alert thresholds.
+package species
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed species.json
+var builtinSpecies []byte
+
+// Range is the recommended range of a value.
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Species contains the recommended growing conditions of a plant species.
+type Species struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Moisture     Range  `json:"moisture"`
+	Temperature  Range  `json:"temperature"`
+	Light        Range  `json:"light"`
+	Conductivity Range  `json:"conductivity"`
+}
+
+// Database contains species indexed by their normalized ID.
+type Database map[string]Species
+
+// Builtin returns the database of common species shipped with the exporter.
+func Builtin() (Database, error) {
+	var list []Species
+	if err := json.Unmarshal(builtinSpecies, &list); err != nil {
+		return nil, fmt.Errorf("can not parse built-in species: %s", err)
+	}
+
+	db := Database{}
+	db.Add(list...)
+	return db, nil
+}
+
+// Add adds species to the database, replacing existing species with the same ID.
+func (d Database) Add(species ...Species) {
+	for _, s := range species {
+		d[NormalizeID(s.ID)] = s
+	}
+}
+
+// Lookup returns the species identified by the ID.
+func (d Database) Lookup(id string) (Species, bool) {
+	s, ok := d[NormalizeID(id)]
+	return s, ok
+}
+
+// IDs returns the IDs of all species in the database in alphabetical order.
+func (d Database) IDs() []string {
+	result := make([]string, 0, len(d))
+	for id := range d {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// NormalizeID converts a species name like "Ficus lyrata" to the form used as ID, "ficus_lyrata".
+func NormalizeID(id string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(id, "_", " "))), "_")
+}
@@ -0,0 +1,59 @@
+// Package tracing configures the OpenTelemetry trace pipeline used by the exporter.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+const serviceName = "flowercare-exporter"
+
+// Config contains the settings for exporting traces.
+type Config struct {
+	Endpoint string
+	Insecure bool
+}
+
+// Enabled returns true if an endpoint for exporting traces has been configured.
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Setup installs a global tracer provider which exports spans to the configured OTLP endpoint.
+// The returned function flushes outstanding spans and should be called on shutdown.
+func Setup(ctx context.Context, cfg Config, version string) (func(context.Context) error, error) {
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(cfg.Endpoint),
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("can not create OTLP exporter: %s", err)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(version),
+	)
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// AllowList is a list of networks from which clients are allowed to connect.
+type AllowList []netip.Prefix
+
+// ParseAllowList parses a list of CIDR networks or single IP addresses.
+func ParseAllowList(values []string) (AllowList, error) {
+	result := AllowList{}
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("can not parse address %q: %s", v, err)
+			}
+
+			result = append(result, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not parse network %q: %s", v, err)
+		}
+
+		result = append(result, prefix.Masked())
+	}
+
+	return result, nil
+}
+
+// Allowed returns true if the address is contained in one of the networks. An empty list allows all addresses.
+func (l AllowList) Allowed(addr netip.Addr) bool {
+	if len(l) == 0 {
+		return true
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range l {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handler wraps the handler, rejecting requests from clients which are not allowed.
+func (l AllowList) Handler(next http.Handler) http.Handler {
+	if len(l) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !l.Allowed(addr) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+// Package web contains the HTTP server setup of the exporter.
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSConfig contains the settings for serving HTTPS and optionally requiring client certificates.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	AllowedCNs   []string
+}
+
+// Enabled returns true if a server certificate has been configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Validate checks the settings for consistency.
+func (c TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("need to provide both certificate and key for TLS")
+	}
+
+	if c.ClientCAFile != "" && !c.Enabled() {
+		return errors.New("client certificate authentication needs TLS to be enabled")
+	}
+
+	if len(c.AllowedCNs) > 0 && c.ClientCAFile == "" {
+		return errors.New("allowed common names need a client CA to be configured")
+	}
+
+	return nil
+}
+
+func (c TLSConfig) serverConfig() (*tls.Config, error) {
+	result := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.ClientCAFile == "" {
+		return result, nil
+	}
+
+	caPEM, err := os.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("can not read client CA: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %q", c.ClientCAFile)
+	}
+
+	result.ClientCAs = pool
+	result.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(c.AllowedCNs) > 0 {
+		result.VerifyConnection = c.verifyCommonName
+	}
+
+	return result, nil
+}
+
+// verifyCommonName rejects client certificates with a common name which is not on the list of allowed names.
+func (c TLSConfig) verifyCommonName(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no client certificate")
+	}
+
+	cn := state.PeerCertificates[0].Subject.CommonName
+	for _, allowed := range c.AllowedCNs {
+		if cn == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("client certificate common name not allowed: %q", cn)
+}
+
+// ListenAndServe serves the handler on the address, using HTTPS if TLS is enabled.
+func ListenAndServe(addr string, handler http.Handler, tlsConfig TLSConfig) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+
+	if !tlsConfig.Enabled() {
+		return server.ListenAndServe()
+	}
+
+	cfg, err := tlsConfig.serverConfig()
+	if err != nil {
+		return err
+	}
+	server.TLSConfig = cfg
+
+	return server.ListenAndServeTLS(tlsConfig.CertFile, tlsConfig.KeyFile)
+}
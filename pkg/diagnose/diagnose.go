@@ -0,0 +1,117 @@
+// Package diagnose runs connectivity checks against sensors and explains the failures found.
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// StepOpenAdapter is the name of the step opening the Bluetooth adapter.
+const StepOpenAdapter = "open adapter"
+
+// Report contains the result of a connectivity check of one sensor.
+type Report struct {
+	MacAddress string `json:"macAddress"`
+	Adapter    string `json:"adapter"`
+	OK         bool   `json:"ok"`
+	Steps      []Step `json:"steps"`
+}
+
+// Step contains the result of one step of the check.
+type Step struct {
+	Name            string  `json:"name"`
+	OK              bool    `json:"ok"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Detail          string  `json:"detail,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	Hint            string  `json:"hint,omitempty"`
+}
+
+// Run opens the adapter using the open function and checks the connectivity of the sensor step by step. The options
+// are used for parsing the sensor data, see miflora.Diagnose.
+func Run(ctx context.Context, adapter string, open func() (ble.Device, error), macAddress string, scanDuration time.Duration, opts miflora.ReadOptions) Report {
+	report := Report{
+		MacAddress: macAddress,
+		Adapter:    adapter,
+	}
+
+	start := time.Now()
+	device, err := open()
+	report.Steps = append(report.Steps, newStep(miflora.DiagnosticStep{
+		Name:     StepOpenAdapter,
+		Duration: time.Since(start),
+		Detail:   adapter,
+		Err:      err,
+	}))
+	if err != nil {
+		return report
+	}
+
+	steps := miflora.Diagnose(ctx, device, macAddress, scanDuration, opts)
+	for _, s := range steps {
+		report.Steps = append(report.Steps, newStep(s))
+	}
+
+	report.OK = true
+	for _, s := range report.Steps {
+		if !s.OK {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+func newStep(s miflora.DiagnosticStep) Step {
+	step := Step{
+		Name:            s.Name,
+		OK:              s.Err == nil,
+		DurationSeconds: s.Duration.Seconds(),
+		Detail:          s.Detail,
+	}
+	if s.Err != nil {
+		step.Error = s.Err.Error()
+		step.Hint = hint(s.Name, s.Err)
+	}
+
+	return step
+}
+
+func hint(step string, err error) string {
+	timeout := errors.Is(err, context.DeadlineExceeded)
+
+	switch step {
+	case StepOpenAdapter:
+		if errors.Is(err, os.ErrPermission) || strings.Contains(err.Error(), "operation not permitted") {
+			return "Opening the adapter needs root or the capabilities CAP_NET_RAW and CAP_NET_ADMIN, for example using \"setcap cap_net_raw,cap_net_admin+eip\" on the binary."
+		}
+		if strings.Contains(err.Error(), "address family not supported") {
+			return "The kernel does not support Bluetooth. When running in a container, it needs to use the host network."
+		}
+		if strings.Contains(err.Error(), "busy") {
+			return "The adapter is used by another process. Stop bluetoothd or other exporters using the same adapter."
+		}
+		return "Check that the adapter exists and is up, for example using \"hciconfig\" or \"btmgmt info\"."
+	case miflora.StepScan:
+		return "The sensor was not heard. Move it closer to the adapter, check its battery and that the MAC address is correct."
+	case miflora.StepDial:
+		if timeout {
+			return "The connection was not established in time. The sensor might be out of range, connected to another device (like the Flower Care app) or its battery might be low."
+		}
+		return "Connecting failed. Check that no other device is connected to the sensor and that the adapter is not busy with another connection."
+	case miflora.StepFirmware, miflora.StepMode, miflora.StepSensors, miflora.StepDeviceTime:
+		if timeout {
+			return "The connection was established but the sensor stopped responding. This usually means a weak signal, try moving the sensor closer to the adapter."
+		}
+		return "The connection was lost during the exchange. This can be caused by a weak signal or interference from other 2.4 GHz devices."
+	case miflora.StepParse:
+		return "The sensor returned unexpected data. Please report this including the raw values from the previous steps."
+	}
+
+	return ""
+}
@@ -0,0 +1,138 @@
+package miflora
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// BeaconField identifies the value contained in a MiBeacon advertisement.
+type BeaconField string
+
+// Values broadcast by Flower Care devices. The names match the JSON encoding of the data.
+const (
+	BeaconTemperature  BeaconField = "temperature"
+	BeaconMoisture     BeaconField = "moisture"
+	BeaconLight        BeaconField = "light"
+	BeaconConductivity BeaconField = "conductivity"
+	BeaconBattery      BeaconField = "battery"
+)
+
+var beaconObjects = map[uint16]BeaconField{
+	0x1004: BeaconTemperature,
+	0x1007: BeaconLight,
+	0x1008: BeaconMoisture,
+	0x1009: BeaconConductivity,
+	0x100a: BeaconBattery,
+}
+
+const (
+	frameControlEncrypted  = 0x0008
+	frameControlMAC        = 0x0010
+	frameControlCapability = 0x0020
+	frameControlObject     = 0x0040
+
+	capabilityIO = 0x20
+)
+
+// Beacon contains a value broadcast by a sensor in a MiBeacon advertisement. Every advertisement contains only one
+// value, so the values of a sensor arrive one after another.
+type Beacon struct {
+	Field BeaconField
+	Value float64
+}
+
+// ParseBeacon parses the service data of a MiBeacon advertisement. Advertisements without a known value return an
+// error.
+func ParseBeacon(data []byte) (Beacon, error) {
+	if len(data) < 5 {
+		return Beacon{}, fmt.Errorf("data not long enough: %d < 5", len(data))
+	}
+
+	frameControl := binary.LittleEndian.Uint16(data)
+	if frameControl&frameControlEncrypted != 0 {
+		return Beacon{}, errors.New("encrypted beacons are not supported")
+	}
+
+	if frameControl&frameControlObject == 0 {
+		return Beacon{}, errors.New("beacon contains no value")
+	}
+
+	// Frame control, product ID and frame counter
+	offset := 5
+	if frameControl&frameControlMAC != 0 {
+		offset += 6
+	}
+	if frameControl&frameControlCapability != 0 {
+		if len(data) <= offset {
+			return Beacon{}, fmt.Errorf("data not long enough for capabilities: %d", len(data))
+		}
+
+		if data[offset]&capabilityIO != 0 {
+			offset += 2
+		}
+		offset++
+	}
+
+	if len(data) < offset+3 {
+		return Beacon{}, fmt.Errorf("data not long enough for value: %d < %d", len(data), offset+3)
+	}
+
+	objectType := binary.LittleEndian.Uint16(data[offset:])
+	length := int(data[offset+2])
+	value := data[offset+3:]
+	if len(value) < length {
+		return Beacon{}, fmt.Errorf("value not long enough: %d < %d", len(value), length)
+	}
+	value = value[:length]
+
+	field, ok := beaconObjects[objectType]
+	if !ok {
+		return Beacon{}, fmt.Errorf("unknown value type: %#04x", objectType)
+	}
+
+	var minLength int
+	switch field {
+	case BeaconTemperature, BeaconConductivity:
+		minLength = 2
+	case BeaconLight:
+		minLength = 3
+	default:
+		minLength = 1
+	}
+	if length < minLength {
+		return Beacon{}, fmt.Errorf("%s value not long enough: %d < %d", field, length, minLength)
+	}
+
+	result := Beacon{
+		Field: field,
+	}
+	switch field {
+	case BeaconTemperature:
+		result.Value = float64(int16(binary.LittleEndian.Uint16(value))) / 10
+	case BeaconConductivity:
+		result.Value = float64(binary.LittleEndian.Uint16(value))
+	case BeaconLight:
+		result.Value = float64(uint32(value[0]) | uint32(value[1])<<8 | uint32(value[2])<<16)
+	default:
+		result.Value = float64(value[0])
+	}
+	return result, nil
+}
+
+// Apply sets the value of the beacon in the data.
+func (b Beacon) Apply(d *Data) {
+	switch b.Field {
+	case BeaconTemperature:
+		d.Sensors.Temperature = b.Value
+	case BeaconMoisture:
+		d.Sensors.Moisture = byte(clamp(b.Value, math.MaxUint8))
+	case BeaconLight:
+		d.Sensors.Light = uint32(clamp(b.Value, math.MaxUint32))
+	case BeaconConductivity:
+		d.Sensors.Conductivity = uint16(clamp(b.Value, math.MaxUint16))
+	case BeaconBattery:
+		d.Firmware.Battery = byte(clamp(b.Value, 100))
+	}
+}
@@ -0,0 +1,150 @@
+package miflora
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseBeacon(t *testing.T) {
+	tests := []struct {
+		desc    string
+		data    string
+		want    Beacon
+		wantErr bool
+	}{
+		{
+			desc: "temperature",
+			data: "71209800a4368d7c8d7cc40d041002f300",
+			want: Beacon{Field: BeaconTemperature, Value: 24.3},
+		},
+		{
+			desc: "negative temperature",
+			data: "71209800a4368d7c8d7cc40d041002e2ff",
+			want: Beacon{Field: BeaconTemperature, Value: -3},
+		},
+		{
+			desc: "light",
+			data: "712098000a368d7c8d7cc40d071003b20200",
+			want: Beacon{Field: BeaconLight, Value: 690},
+		},
+		{
+			desc: "moisture",
+			data: "71209800a1368d7c8d7cc40d0810012b",
+			want: Beacon{Field: BeaconMoisture, Value: 43},
+		},
+		{
+			desc: "conductivity",
+			data: "71209800a2368d7c8d7cc40d0910021e01",
+			want: Beacon{Field: BeaconConductivity, Value: 286},
+		},
+		{
+			desc: "battery",
+			data: "71209800a3368d7c8d7cc40d0a100164",
+			want: Beacon{Field: BeaconBattery, Value: 100},
+		},
+		{
+			desc: "without MAC address and capabilities",
+			data: "40209800a3041002f300",
+			want: Beacon{Field: BeaconTemperature, Value: 24.3},
+		},
+		{
+			desc: "capabilities with IO",
+			data: "60209800a32d0000041002f300",
+			want: Beacon{Field: BeaconTemperature, Value: 24.3},
+		},
+		{
+			desc:    "too short",
+			data:    "7120",
+			wantErr: true,
+		},
+		{
+			desc:    "encrypted",
+			data:    "5830980012368d7c8d7cc40d041002f300",
+			wantErr: true,
+		},
+		{
+			desc:    "without value",
+			data:    "31209800a4368d7c8d7cc40d",
+			wantErr: true,
+		},
+		{
+			desc:    "unknown value type",
+			data:    "71209800a4368d7c8d7cc40d061002f300",
+			wantErr: true,
+		},
+		{
+			desc:    "value shorter than length",
+			data:    "71209800a4368d7c8d7cc40d041002f3",
+			wantErr: true,
+		},
+		{
+			desc:    "light value too short",
+			data:    "71209800a4368d7c8d7cc40d071002b202",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			data, err := hex.DecodeString(tc.data)
+			if err != nil {
+				t.Fatalf("invalid test data: %s", err)
+			}
+
+			got, err := ParseBeacon(data)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got beacon %#v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got beacon %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBeaconApply(t *testing.T) {
+	tests := []struct {
+		desc   string
+		beacon Beacon
+		want   Data
+	}{
+		{
+			desc:   "temperature",
+			beacon: Beacon{Field: BeaconTemperature, Value: 24.3},
+			want:   Data{Sensors: Sensors{Temperature: 24.3}},
+		},
+		{
+			desc:   "light above 16 bits",
+			beacon: Beacon{Field: BeaconLight, Value: 100000},
+			want:   Data{Sensors: Sensors{Light: 100000}},
+		},
+		{
+			desc:   "moisture is clamped",
+			beacon: Beacon{Field: BeaconMoisture, Value: 300},
+			want:   Data{Sensors: Sensors{Moisture: 255}},
+		},
+		{
+			desc:   "battery is clamped",
+			beacon: Beacon{Field: BeaconBattery, Value: 120},
+			want:   Data{Firmware: Firmware{Battery: 100}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got Data
+			tc.beacon.Apply(&got)
+
+			if got.Sensors != tc.want.Sensors || got.Firmware != tc.want.Firmware {
+				t.Errorf("got data %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package miflora
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ble/ble"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// connect opens a connection to the sensor identified by the MAC address.
+// The connection needs to be closed using closeConnection once it is not needed anymore.
+func connect(ctx context.Context, device ble.Device, macAddress string) (ble.Client, error) {
+	_, span := tracer.Start(ctx, "dial")
+	c, err := dial(ctx, device, ble.NewAddr(macAddress))
+	endSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing: %s", err)
+	}
+
+	return c, nil
+}
+
+// scanAndConnect waits for an advertisement of the sensor and connects using the address as it was advertised.
+// The connection is not attempted if the signal strength of the advertisement is below minRSSI, unless it is zero.
+func scanAndConnect(ctx context.Context, device ble.Device, macAddress string, duration time.Duration, minRSSI int) (ble.Client, error) {
+	_, scanSpan := tracer.Start(ctx, "scan")
+	a, err := findAdvertisement(ctx, device, macAddress, duration)
+	endSpan(scanSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning: %s", err)
+	}
+
+	if minRSSI != 0 && a.RSSI() < minRSSI {
+		return nil, fmt.Errorf("signal too weak: %d dBm < %d dBm", a.RSSI(), minRSSI)
+	}
+
+	_, span := tracer.Start(ctx, "dial")
+	c, err := dial(ctx, device, a.Addr())
+	endSpan(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing: %s", err)
+	}
+
+	return c, nil
+}
+
+// withConnection connects to the sensor and runs the function using the connection.
+func withConnection(ctx context.Context, device ble.Device, macAddress string, fn func(c ble.Client) error) error {
+	c, err := connect(ctx, device, macAddress)
+	if err != nil {
+		return err
+	}
+	defer closeConnection(c)
+
+	return fn(c)
+}
+
+// dial connects to the device, returning early if the context is done before the connection is established.
+// A connection which is established after the context has been cancelled is closed again in the background.
+func dial(ctx context.Context, device ble.Device, addr ble.Addr) (ble.Client, error) {
+	type dialResult struct {
+		client ble.Client
+		err    error
+	}
+
+	resultCh := make(chan dialResult, 1)
+	go func() {
+		c, err := device.Dial(ctx, addr)
+		resultCh <- dialResult{
+			client: c,
+			err:    err,
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		go func() {
+			if r := <-resultCh; r.err == nil {
+				closeConnection(r.client)
+			}
+		}()
+		return nil, ctx.Err()
+	case r := <-resultCh:
+		return r.client, r.err
+	}
+}
+
+func readCharacteristic(ctx context.Context, c ble.Client, characteristic *ble.Characteristic) ([]byte, error) {
+	var value []byte
+	err := withContext(ctx, c, func() error {
+		var err error
+		value, err = c.ReadCharacteristic(characteristic)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func writeCharacteristic(ctx context.Context, c ble.Client, characteristic *ble.Characteristic, value []byte) error {
+	return withContext(ctx, c, func() error {
+		return c.WriteCharacteristic(characteristic, value, false)
+	})
+}
+
+// withContext runs the operation while watching the context. If the context is done before the operation finishes,
+// the connection is closed, which aborts the in-flight operation.
+func withContext(ctx context.Context, c ble.Client, op func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- op()
+	}()
+
+	select {
+	case <-ctx.Done():
+		closeConnection(c)
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// closeConnection closes the underlying connection directly, because the client holds a lock during operations
+// which would block CancelConnection until the in-flight operation returns.
+func closeConnection(c ble.Client) {
+	c.Conn().Close()
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,91 @@
+package miflora
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+var (
+	deviceTimeCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("00001a12-0000-1000-8000-00805f9b34fb"),
+		ValueHandle: 0x41,
+	}
+	deviceNameCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("2a00"),
+		ValueHandle: 0x03,
+	}
+	blinkValue = []byte{0xFD, 0xFF}
+)
+
+// Blink makes the LED of the sensor blink, so that it can be identified.
+func Blink(ctx context.Context, device ble.Device, macAddress string) error {
+	return withConnection(ctx, device, macAddress, func(c ble.Client) error {
+		if err := writeCharacteristic(ctx, c, realtimeReadingCharacteristic, blinkValue); err != nil {
+			return fmt.Errorf("error sending blink command: %s", err)
+		}
+
+		return nil
+	})
+}
+
+// DeviceName reads the Device Name characteristic of the sensor.
+func DeviceName(ctx context.Context, device ble.Device, macAddress string) (string, error) {
+	var name string
+	err := withConnection(ctx, device, macAddress, func(c ble.Client) error {
+		raw, err := readCharacteristic(ctx, c, deviceNameCharacteristic)
+		if err != nil {
+			return fmt.Errorf("error reading device name: %s", err)
+		}
+
+		name = strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+		return nil
+	})
+	return name, err
+}
+
+// DeviceTime reads the internal clock of the device. It returns the value of the clock in seconds and the local time when it was read.
+func DeviceTime(ctx context.Context, device ble.Device, macAddress string) (uint32, time.Time, error) {
+	var (
+		seconds uint32
+		now     time.Time
+	)
+	err := withConnection(ctx, device, macAddress, func(c ble.Client) error {
+		var err error
+		seconds, now, err = readDeviceTime(ctx, c)
+		return err
+	})
+	return seconds, now, err
+}
+
+// SetTime writes the time to the internal clock of the device.
+func SetTime(ctx context.Context, device ble.Device, macAddress string, t time.Time) error {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, uint32(t.Unix()))
+
+	return withConnection(ctx, device, macAddress, func(c ble.Client) error {
+		if err := writeCharacteristic(ctx, c, deviceTimeCharacteristic, value); err != nil {
+			return fmt.Errorf("error writing device time: %s", err)
+		}
+
+		return nil
+	})
+}
+
+func readDeviceTime(ctx context.Context, c ble.Client) (uint32, time.Time, error) {
+	raw, err := readCharacteristic(ctx, c, deviceTimeCharacteristic)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("error reading device time: %s", err)
+	}
+	now := time.Now()
+
+	if len(raw) < 4 {
+		return 0, time.Time{}, fmt.Errorf("device time too short: %d < 4", len(raw))
+	}
+
+	return binary.LittleEndian.Uint32(raw), now, nil
+}
@@ -0,0 +1,111 @@
+package miflora
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+// Names of the steps run by Diagnose.
+const (
+	StepScan       = "scan"
+	StepDial       = "dial"
+	StepFirmware   = "read firmware"
+	StepMode       = "write mode"
+	StepSensors    = "read sensors"
+	StepParse      = "parse"
+	StepDeviceTime = "read device time"
+)
+
+// DiagnosticStep contains the result of a single step of a connectivity check.
+type DiagnosticStep struct {
+	Name     string
+	Duration time.Duration
+	Detail   string
+	Err      error
+}
+
+// Diagnose runs the steps needed for reading data from the sensor one by one and records the result of each step.
+// Scanning is skipped if scanDuration is zero. The check stops at the first step which prevents the next steps from running.
+// Only the parsing options Lenient and Layout are used from opts.
+func Diagnose(ctx context.Context, device ble.Device, macAddress string, scanDuration time.Duration, opts ReadOptions) []DiagnosticStep {
+	var steps []DiagnosticStep
+	run := func(name string, fn func() (string, error)) bool {
+		start := time.Now()
+		detail, err := fn()
+		steps = append(steps, DiagnosticStep{
+			Name:     name,
+			Duration: time.Since(start),
+			Detail:   detail,
+			Err:      err,
+		})
+		return err == nil
+	}
+
+	if scanDuration > 0 {
+		run(StepScan, func() (string, error) {
+			return scanFor(ctx, device, macAddress, scanDuration)
+		})
+	}
+
+	var c ble.Client
+	if !run(StepDial, func() (string, error) {
+		var err error
+		c, err = dial(ctx, device, ble.NewAddr(macAddress))
+		return "", err
+	}) {
+		return steps
+	}
+	defer closeConnection(c)
+
+	var firmwareRaw, sensorsRaw []byte
+	ok := run(StepFirmware, func() (string, error) {
+		var err error
+		firmwareRaw, err = readCharacteristic(ctx, c, firmwareCharacteristic)
+		return fmt.Sprintf("% x", firmwareRaw), err
+	}) && run(StepMode, func() (string, error) {
+		return "", writeCharacteristic(ctx, c, realtimeReadingCharacteristic, realtimeReadingValue)
+	}) && run(StepSensors, func() (string, error) {
+		var err error
+		sensorsRaw, err = readCharacteristic(ctx, c, sensorCharacteristic)
+		return fmt.Sprintf("% x", sensorsRaw), err
+	})
+	if !ok {
+		return steps
+	}
+
+	run(StepParse, func() (string, error) {
+		firmware, sensors, _, err := parseData(firmwareRaw, sensorsRaw, ReadOptions{
+			Lenient: opts.Lenient,
+			Layout:  opts.Layout,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s | %s", sensors, firmware), nil
+	})
+
+	run(StepDeviceTime, func() (string, error) {
+		seconds, _, err := readDeviceTime(ctx, c)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%d seconds", seconds), nil
+	})
+
+	return steps
+}
+
+// scanFor scans until an advertisement of the sensor has been seen or the duration is over.
+func scanFor(ctx context.Context, device ble.Device, macAddress string, duration time.Duration) (string, error) {
+	a, err := findAdvertisement(ctx, device, macAddress, duration)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("name %q, RSSI %d dBm", a.LocalName(), a.RSSI()), nil
+}
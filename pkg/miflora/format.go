@@ -0,0 +1,30 @@
+package miflora
+
+import (
+	"fmt"
+	"time"
+)
+
+// String returns the firmware information in a human-readable form, for example "Firmware 3.2.1 | Battery  99 %".
+func (f Firmware) String() string {
+	return fmt.Sprintf("Firmware %s | Battery %3d %s", f.Version, f.Battery, UnitPercent)
+}
+
+// String returns the sensor values in a human-readable form. The values are padded, so that the output of several
+// readings is aligned when printed on consecutive lines.
+func (s Sensors) String() string {
+	return fmt.Sprintf("Moisture %3d %s | Temp %5.1f %s | Light %6d %s | Conductivity %5d %s",
+		s.Moisture, UnitPercent,
+		s.Temperature, UnitCelsius,
+		s.Light, UnitLux,
+		s.Conductivity, UnitConductivity)
+}
+
+// String returns the data in a human-readable form. The firmware information is left out if it has not been read.
+func (d Data) String() string {
+	if !d.Firmware.Known() {
+		return fmt.Sprintf("%s | %s", d.Time.Format(time.RFC3339), d.Sensors)
+	}
+
+	return fmt.Sprintf("%s | %s | %s", d.Time.Format(time.RFC3339), d.Sensors, d.Firmware)
+}
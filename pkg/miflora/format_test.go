@@ -0,0 +1,51 @@
+package miflora
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDataString(t *testing.T) {
+	sensors := Sensors{
+		Temperature:  21.5,
+		Moisture:     40,
+		Light:        500,
+		Conductivity: 350,
+	}
+
+	tests := []struct {
+		desc     string
+		firmware Firmware
+		want     string
+	}{
+		{
+			desc:     "firmware not read",
+			firmware: Firmware{},
+			want:     "2026-06-01T12:00:00Z | Moisture  40 % | Temp  21.5 °C | Light    500 lx | Conductivity   350 µS/cm",
+		},
+		{
+			desc:     "firmware",
+			firmware: Firmware{Version: "3.2.2", Battery: 80},
+			want:     "2026-06-01T12:00:00Z | Moisture  40 % | Temp  21.5 °C | Light    500 lx | Conductivity   350 µS/cm | Firmware 3.2.2 | Battery  80 %",
+		},
+		{
+			desc:     "battery level without version",
+			firmware: Firmware{Battery: 80},
+			want:     "2026-06-01T12:00:00Z | Moisture  40 % | Temp  21.5 °C | Light    500 lx | Conductivity   350 µS/cm | Firmware  | Battery  80 %",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			d := Data{
+				Time:     time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+				Firmware: tc.firmware,
+				Sensors:  sensors,
+			}
+
+			if got := d.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
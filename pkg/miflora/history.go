@@ -0,0 +1,127 @@
+package miflora
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+var (
+	historyControlCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("00001a10-0000-1000-8000-00805f9b34fb"),
+		ValueHandle: 0x3E,
+	}
+	historyDataCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("00001a11-0000-1000-8000-00805f9b34fb"),
+		ValueHandle: 0x3C,
+	}
+	historyModeValue  = []byte{0xA0, 0x00, 0x00}
+	historyClearValue = []byte{0xA2, 0x00, 0x00}
+)
+
+// HistoryRecord contains one of the hourly measurements stored on the device.
+type HistoryRecord struct {
+	Time         time.Time
+	Temperature  float64
+	Moisture     byte
+	Light        uint32
+	Conductivity uint16
+}
+
+// Data returns the measurements of the record. The firmware information is not part of the history.
+func (r HistoryRecord) Data() Data {
+	return Data{
+		Time: r.Time,
+		Sensors: Sensors{
+			Temperature:  r.Temperature,
+			Moisture:     r.Moisture,
+			Light:        r.Light,
+			Conductivity: r.Conductivity,
+		},
+	}
+}
+
+// deviceRecord is a history record with a timestamp relative to the internal clock of the device.
+type deviceRecord struct {
+	DeviceTime uint32
+	HistoryRecord
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (r *deviceRecord) UnmarshalBinary(data []byte) error {
+	// TT TT TT TT tt tt ?? LL LL LL ?? MM CC CC ?? ??
+	if len(data) != 16 {
+		return fmt.Errorf("invalid history record length: %d != 16", len(data))
+	}
+
+	r.DeviceTime = binary.LittleEndian.Uint32(data[0:4])
+	r.Temperature = float64(int16(binary.LittleEndian.Uint16(data[4:6]))) / 10
+	r.Light = uint32(data[7]) | uint32(data[8])<<8 | uint32(data[9])<<16
+	r.Moisture = data[11]
+	r.Conductivity = binary.LittleEndian.Uint16(data[12:14])
+	return nil
+}
+
+// ReadHistory reads all history records stored on the device.
+func ReadHistory(ctx context.Context, device ble.Device, macAddress string) ([]HistoryRecord, error) {
+	var result []HistoryRecord
+	err := withConnection(ctx, device, macAddress, func(c ble.Client) error {
+		deviceNow, now, err := readDeviceTime(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		if err := writeCharacteristic(ctx, c, historyControlCharacteristic, historyModeValue); err != nil {
+			return fmt.Errorf("can not enable history mode: %s", err)
+		}
+
+		countRaw, err := readCharacteristic(ctx, c, historyDataCharacteristic)
+		if err != nil {
+			return fmt.Errorf("error reading history length: %s", err)
+		}
+
+		if len(countRaw) < 2 {
+			return fmt.Errorf("history length too short: %d < 2", len(countRaw))
+		}
+		count := binary.LittleEndian.Uint16(countRaw)
+
+		result = make([]HistoryRecord, 0, count)
+		for i := uint16(0); i < count; i++ {
+			address := []byte{0xA1, byte(i), byte(i >> 8)}
+			if err := writeCharacteristic(ctx, c, historyControlCharacteristic, address); err != nil {
+				return fmt.Errorf("can not select history record %d: %s", i, err)
+			}
+
+			raw, err := readCharacteristic(ctx, c, historyDataCharacteristic)
+			if err != nil {
+				return fmt.Errorf("error reading history record %d: %s", i, err)
+			}
+
+			var record deviceRecord
+			if err := record.UnmarshalBinary(raw); err != nil {
+				return fmt.Errorf("error parsing history record %d: %s", i, err)
+			}
+
+			age := time.Duration(int64(deviceNow)-int64(record.DeviceTime)) * time.Second
+			record.Time = now.Add(-age)
+			result = append(result, record.HistoryRecord)
+		}
+
+		return nil
+	})
+	return result, err
+}
+
+// ClearHistory deletes all history records stored on the device.
+func ClearHistory(ctx context.Context, device ble.Device, macAddress string) error {
+	return withConnection(ctx, device, macAddress, func(c ble.Client) error {
+		if err := writeCharacteristic(ctx, c, historyControlCharacteristic, historyClearValue); err != nil {
+			return fmt.Errorf("error sending clear history command: %s", err)
+		}
+
+		return nil
+	})
+}
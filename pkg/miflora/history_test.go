@@ -0,0 +1,88 @@
+package miflora
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, value string) []byte {
+	t.Helper()
+
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatalf("invalid test data: %s", err)
+	}
+	return data
+}
+
+func TestDeviceRecordUnmarshalBinary(t *testing.T) {
+	tests := []struct {
+		desc           string
+		data           string
+		wantDeviceTime uint32
+		wantRecord     HistoryRecord
+		wantErr        bool
+	}{
+		{
+			desc:           "record",
+			data:           "d0e31500f20000ee0000002416010000",
+			wantDeviceTime: 1434576,
+			wantRecord:     HistoryRecord{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278},
+		},
+		{
+			desc:           "negative temperature and bright light",
+			data:           "e0f11500e2ff008b8c0100150d010000",
+			wantDeviceTime: 1438176,
+			wantRecord:     HistoryRecord{Temperature: -3, Moisture: 21, Light: 101515, Conductivity: 269},
+		},
+		{
+			desc:    "too short",
+			data:    "d0e31500f20000ee00000024160100",
+			wantErr: true,
+		},
+		{
+			desc:    "too long",
+			data:    "d0e31500f20000ee000000241601000000",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got deviceRecord
+			err := got.UnmarshalBinary(mustDecodeHex(t, tc.data))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got record %#v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got.DeviceTime != tc.wantDeviceTime {
+				t.Errorf("got device time %d, want %d", got.DeviceTime, tc.wantDeviceTime)
+			}
+
+			if got.HistoryRecord != tc.wantRecord {
+				t.Errorf("got record %#v, want %#v", got.HistoryRecord, tc.wantRecord)
+			}
+		})
+	}
+}
+
+func TestHistoryRecordData(t *testing.T) {
+	record := HistoryRecord{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278}
+
+	data := record.Data()
+
+	if data.Sensors != (Sensors{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278}) {
+		t.Errorf("got sensors %#v", data.Sensors)
+	}
+
+	// History records do not contain the battery level, which needs to be skipped instead of reported as zero.
+	if data.Firmware.Known() {
+		t.Errorf("got known firmware %#v for history record", data.Firmware)
+	}
+}
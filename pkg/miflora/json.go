@@ -0,0 +1,117 @@
+package miflora
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Units of the values contained in Data.
+const (
+	UnitPercent      = "%"
+	UnitCelsius      = "°C"
+	UnitLux          = "lx"
+	UnitConductivity = "µS/cm"
+)
+
+// Measurement is a value together with its unit.
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+type jsonData struct {
+	Time     string       `json:"time"`
+	Firmware jsonFirmware `json:"firmware"`
+	Sensors  jsonSensors  `json:"sensors"`
+	Raw      *jsonRaw     `json:"raw,omitempty"`
+}
+
+type jsonFirmware struct {
+	Version string `json:"version"`
+	// Battery is omitted if the firmware values are not known, see Firmware.Known.
+	Battery *Measurement `json:"battery,omitempty"`
+}
+
+type jsonSensors struct {
+	Temperature  Measurement `json:"temperature"`
+	Moisture     Measurement `json:"moisture"`
+	Light        Measurement `json:"light"`
+	Conductivity Measurement `json:"conductivity"`
+}
+
+type jsonRaw struct {
+	Firmware string `json:"firmware,omitempty"`
+	Sensors  string `json:"sensors,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. All values are annotated with their unit and the time is formatted using RFC 3339.
+func (d Data) MarshalJSON() ([]byte, error) {
+	result := jsonData{
+		Time: d.Time.Format(time.RFC3339),
+		Firmware: jsonFirmware{
+			Version: d.Firmware.Version,
+		},
+		Sensors: jsonSensors{
+			Temperature:  Measurement{d.Sensors.Temperature, UnitCelsius},
+			Moisture:     Measurement{float64(d.Sensors.Moisture), UnitPercent},
+			Light:        Measurement{float64(d.Sensors.Light), UnitLux},
+			Conductivity: Measurement{float64(d.Sensors.Conductivity), UnitConductivity},
+		},
+	}
+	if d.Firmware.Known() {
+		result.Firmware.Battery = &Measurement{float64(d.Firmware.Battery), UnitPercent}
+	}
+	if len(d.Raw.Firmware) > 0 || len(d.Raw.Sensors) > 0 {
+		result.Raw = &jsonRaw{
+			Firmware: hex.EncodeToString(d.Raw.Firmware),
+			Sensors:  hex.EncodeToString(d.Raw.Sensors),
+		}
+	}
+
+	return json.Marshal(result)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts the format produced by MarshalJSON.
+func (d *Data) UnmarshalJSON(data []byte) error {
+	var parsed jsonData
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+
+	t, err := time.Parse(time.RFC3339, parsed.Time)
+	if err != nil {
+		return fmt.Errorf("can not parse time: %s", err)
+	}
+
+	result := Data{
+		Time: t,
+		Firmware: Firmware{
+			Version: parsed.Firmware.Version,
+		},
+		Sensors: Sensors{
+			Temperature:  parsed.Sensors.Temperature.Value,
+			Moisture:     byte(parsed.Sensors.Moisture.Value),
+			Light:        uint32(parsed.Sensors.Light.Value),
+			Conductivity: uint16(parsed.Sensors.Conductivity.Value),
+		},
+	}
+
+	if parsed.Firmware.Battery != nil {
+		result.Firmware.Battery = byte(parsed.Firmware.Battery.Value)
+	}
+
+	if parsed.Raw != nil {
+		if result.Raw.Firmware, err = hex.DecodeString(parsed.Raw.Firmware); err != nil {
+			return fmt.Errorf("can not decode raw firmware data: %s", err)
+		}
+
+		if result.Raw.Sensors, err = hex.DecodeString(parsed.Raw.Sensors); err != nil {
+			return fmt.Errorf("can not decode raw sensor data: %s", err)
+		}
+	}
+
+	*d = result
+	return nil
+}
@@ -0,0 +1,123 @@
+package miflora
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Layout describes where the values are found in the sensor data. It can be used for clone devices, which send their
+// data in a different format than the original sensor.
+type Layout struct {
+	Temperature  Field `json:"temperature"`
+	Moisture     Field `json:"moisture"`
+	Light        Field `json:"light"`
+	Conductivity Field `json:"conductivity"`
+}
+
+// Field describes the position and encoding of a value in the sensor data.
+type Field struct {
+	Offset int `json:"offset"`
+	// Size is the number of bytes of the value. It needs to be 1, 2 or 4.
+	Size      int  `json:"size"`
+	BigEndian bool `json:"bigEndian,omitempty"`
+	Signed    bool `json:"signed,omitempty"`
+	// Scale is multiplied with the raw value. Defaults to 1.
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// DefaultLayout is the layout of the sensor data of the original sensor.
+var DefaultLayout = Layout{
+	Temperature:  Field{Offset: 0, Size: 2, Signed: true, Scale: 0.1},
+	Light:        Field{Offset: 3, Size: 4},
+	Moisture:     Field{Offset: 7, Size: 1},
+	Conductivity: Field{Offset: 8, Size: 2},
+}
+
+// MaxOffset is the largest offset of a field. The sensor data of a single GATT characteristic is much shorter.
+const MaxOffset = 255
+
+// Validate checks that the fields of the layout can be parsed.
+func (l Layout) Validate() error {
+	for name, f := range l.fields() {
+		if f.Offset < 0 {
+			return fmt.Errorf("%s: offset can not be negative: %d", name, f.Offset)
+		}
+
+		if f.Offset > MaxOffset {
+			return fmt.Errorf("%s: offset can not be larger than %d: %d", name, MaxOffset, f.Offset)
+		}
+
+		switch f.Size {
+		case 1, 2, 4:
+		default:
+			return fmt.Errorf("%s: size needs to be 1, 2 or 4: %d", name, f.Size)
+		}
+	}
+
+	return nil
+}
+
+// Parse reads the sensor values from the data using the layout. Layouts which are not valid are rejected.
+func (l Layout) Parse(data []byte) (Sensors, error) {
+	if err := l.Validate(); err != nil {
+		return Sensors{}, err
+	}
+
+	for name, f := range l.fields() {
+		if f.Offset+f.Size > len(data) {
+			return Sensors{}, fmt.Errorf("data not long enough for %s: %d < %d", name, len(data), f.Offset+f.Size)
+		}
+	}
+
+	return Sensors{
+		Temperature:  math.Round(l.Temperature.value(data)*10) / 10,
+		Moisture:     byte(clamp(l.Moisture.value(data), math.MaxUint8)),
+		Light:        uint32(clamp(l.Light.value(data), math.MaxUint32)),
+		Conductivity: uint16(clamp(l.Conductivity.value(data), math.MaxUint16)),
+	}, nil
+}
+
+func (l Layout) fields() map[string]Field {
+	return map[string]Field{
+		"temperature":  l.Temperature,
+		"moisture":     l.Moisture,
+		"light":        l.Light,
+		"conductivity": l.Conductivity,
+	}
+}
+
+func (f Field) value(data []byte) float64 {
+	raw := data[f.Offset : f.Offset+f.Size]
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if f.BigEndian {
+		order = binary.BigEndian
+	}
+
+	var value float64
+	switch {
+	case f.Size == 1 && f.Signed:
+		value = float64(int8(raw[0]))
+	case f.Size == 1:
+		value = float64(raw[0])
+	case f.Size == 2 && f.Signed:
+		value = float64(int16(order.Uint16(raw)))
+	case f.Size == 2:
+		value = float64(order.Uint16(raw))
+	case f.Signed:
+		value = float64(int32(order.Uint32(raw)))
+	default:
+		value = float64(order.Uint32(raw))
+	}
+
+	if f.Scale != 0 {
+		value *= f.Scale
+	}
+	return value
+}
+
+// clamp rounds the value and limits it to the range of an unsigned integer with the maximum.
+func clamp(value, max float64) float64 {
+	return math.Max(0, math.Min(max, math.Round(value)))
+}
@@ -0,0 +1,184 @@
+package miflora
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestLayoutValidate(t *testing.T) {
+	tests := []struct {
+		desc    string
+		layout  Layout
+		wantErr bool
+	}{
+		{
+			desc:   "default layout",
+			layout: DefaultLayout,
+		},
+		{
+			desc: "maximum offset",
+			layout: Layout{
+				Temperature:  Field{Offset: MaxOffset, Size: 1},
+				Moisture:     Field{Offset: 0, Size: 1},
+				Light:        Field{Offset: 0, Size: 4},
+				Conductivity: Field{Offset: 0, Size: 2},
+			},
+		},
+		{
+			desc: "negative offset",
+			layout: Layout{
+				Temperature:  Field{Offset: -1, Size: 2},
+				Moisture:     Field{Offset: 0, Size: 1},
+				Light:        Field{Offset: 0, Size: 4},
+				Conductivity: Field{Offset: 0, Size: 2},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "offset too large",
+			layout: Layout{
+				Temperature:  Field{Offset: 0, Size: 2},
+				Moisture:     Field{Offset: MaxOffset + 1, Size: 1},
+				Light:        Field{Offset: 0, Size: 4},
+				Conductivity: Field{Offset: 0, Size: 2},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "invalid size",
+			layout: Layout{
+				Temperature:  Field{Offset: 0, Size: 2},
+				Moisture:     Field{Offset: 0, Size: 1},
+				Light:        Field{Offset: 0, Size: 3},
+				Conductivity: Field{Offset: 0, Size: 2},
+			},
+			wantErr: true,
+		},
+		{
+			desc:    "missing size",
+			layout:  Layout{},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.layout.Validate()
+			if tc.wantErr != (err != nil) {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLayoutParse(t *testing.T) {
+	tests := []struct {
+		desc    string
+		layout  Layout
+		data    string
+		want    Sensors
+		wantErr bool
+	}{
+		{
+			desc:   "default layout",
+			layout: DefaultLayout,
+			data:   "f20000ee000000241601023c00fb349b",
+			want: Sensors{
+				Temperature:  24.2,
+				Moisture:     36,
+				Light:        238,
+				Conductivity: 278,
+			},
+		},
+		{
+			desc:   "default layout with negative temperature",
+			layout: DefaultLayout,
+			data:   "e2ff00000000000a0000000000000000",
+			want: Sensors{
+				Temperature: -3,
+				Moisture:    10,
+			},
+		},
+		{
+			desc: "big endian clone",
+			layout: Layout{
+				Temperature:  Field{Offset: 0, Size: 2, BigEndian: true, Signed: true, Scale: 0.01},
+				Moisture:     Field{Offset: 2, Size: 1},
+				Light:        Field{Offset: 3, Size: 4, BigEndian: true},
+				Conductivity: Field{Offset: 7, Size: 2, BigEndian: true},
+			},
+			data: "097e2800018b8c0116",
+			want: Sensors{
+				Temperature:  24.3,
+				Moisture:     40,
+				Light:        101260,
+				Conductivity: 278,
+			},
+		},
+		{
+			desc: "signed single byte temperature",
+			layout: Layout{
+				Temperature:  Field{Offset: 0, Size: 1, Signed: true},
+				Moisture:     Field{Offset: 1, Size: 1},
+				Light:        Field{Offset: 2, Size: 2},
+				Conductivity: Field{Offset: 4, Size: 2},
+			},
+			data: "fb1e10270000",
+			want: Sensors{
+				Temperature:  -5,
+				Moisture:     30,
+				Light:        10000,
+				Conductivity: 0,
+			},
+		},
+		{
+			desc: "scaled moisture is clamped",
+			layout: Layout{
+				Temperature:  Field{Offset: 0, Size: 1},
+				Moisture:     Field{Offset: 1, Size: 2, Scale: 0.5},
+				Light:        Field{Offset: 0, Size: 1},
+				Conductivity: Field{Offset: 0, Size: 1},
+			},
+			data: "00ffff",
+			want: Sensors{
+				Moisture: 255,
+			},
+		},
+		{
+			desc:    "data too short",
+			layout:  DefaultLayout,
+			data:    "f20000ee000000",
+			wantErr: true,
+		},
+		{
+			desc:    "invalid layout",
+			layout:  Layout{},
+			data:    "f20000ee000000241601023c00fb349b",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			data, err := hex.DecodeString(tc.data)
+			if err != nil {
+				t.Fatalf("invalid test data: %s", err)
+			}
+
+			got, err := tc.layout.Parse(data)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got sensors %#v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got sensors %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
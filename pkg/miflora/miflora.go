@@ -1,4 +1,4 @@
-// Package miflora provides a function to read data from Miflora sensors using Bluetooth LE.
+// Package miflora provides functions to communicate with Miflora sensors using Bluetooth LE.
 package miflora
 
 import (
@@ -6,36 +6,93 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/go-ble/ble"
-	"github.com/sirupsen/logrus"
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/xperimental/flowercare-exporter/pkg/miflora")
+
 var (
 	firmwareCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("00001a02-0000-1000-8000-00805f9b34fb"),
 		ValueHandle: 0x38,
 	}
 	realtimeReadingCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("00001a00-0000-1000-8000-00805f9b34fb"),
 		ValueHandle: 0x33,
 	}
 	realtimeReadingValue = []byte{0xA0, 0x1F}
 	sensorCharacteristic = &ble.Characteristic{
+		UUID:        ble.MustParse("00001a01-0000-1000-8000-00805f9b34fb"),
 		ValueHandle: 0x35,
 	}
 )
 
 // Data contains the data read from the sensor as well as a timestamp.
+// The JSON encoding of Data annotates the values with their units, see MarshalJSON.
 type Data struct {
 	Time     time.Time
 	Firmware Firmware
 	Sensors  Sensors
+	Raw      RawData
+}
+
+// RawData contains the unparsed payloads as they were read from the device.
+type RawData struct {
+	Firmware []byte `json:"firmware,omitempty"`
+	Sensors  []byte `json:"sensors,omitempty"`
+}
+
+// ParseError is returned when the payloads read from the device could not be parsed.
+type ParseError struct {
+	Raw RawData
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Stages of reading data from a device, in which a ReadError can occur.
+const (
+	StageDial = "dial"
+	StageRead = "read"
+)
+
+// ReadError is returned when the communication with the device failed. Stage contains the step, which failed.
+type ReadError struct {
+	Stage string
+	Err   error
+}
+
+func (e *ReadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
 }
 
 // Firmware contains information about the device status.
 type Firmware struct {
-	Version string
-	Battery byte
+	Version string `json:"version"`
+	Battery byte   `json:"battery"`
+}
+
+// Known returns false if the firmware information has not been read. This is the case for sensors, which are only read
+// from their advertisements and do not broadcast their battery level.
+func (f Firmware) Known() bool {
+	return f.Version != "" || f.Battery > 0
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler.
@@ -49,21 +106,51 @@ func (f *Firmware) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
-// Sensors contains the sensor data.
+// Sensors contains the sensor data. Light is a 32-bit value since the sensor reports illuminance above 65535 lx.
 type Sensors struct {
-	Temperature  float64
-	Moisture     byte
-	Light        uint16
-	Conductivity uint16
+	Temperature  float64 `json:"temperature"`
+	Moisture     byte    `json:"moisture"`
+	Light        uint32  `json:"light"`
+	Conductivity uint16  `json:"conductivity"`
 }
 
+const (
+	// sensorsLength is the length of the sensor data sent by original devices.
+	sensorsLength = 16
+	// sensorsKnownLength is the length of the part of the sensor data, which contains the known fields.
+	sensorsKnownLength = 10
+)
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler.
 func (s *Sensors) UnmarshalBinary(data []byte) error {
-	// TT TT ?? LL LL ?? ?? MM CC CC ?? ?? ?? ?? ?? ??
-	if len(data) != 16 {
-		return fmt.Errorf("invalid data length: %d != 10", len(data))
+	if len(data) != sensorsLength {
+		return fmt.Errorf("invalid data length: %d != %d", len(data), sensorsLength)
 	}
 
+	return s.parse(data)
+}
+
+// UnmarshalLenient parses the sensor data like UnmarshalBinary, but accepts data of other lengths as sent by some
+// clone devices, as long as it contains the known fields. It returns the bytes following the length of the original
+// data, whose meaning is unknown.
+func (s *Sensors) UnmarshalLenient(data []byte) ([]byte, error) {
+	if len(data) < sensorsKnownLength {
+		return nil, fmt.Errorf("data not long enough: %d < %d", len(data), sensorsKnownLength)
+	}
+
+	if err := s.parse(data); err != nil {
+		return nil, err
+	}
+
+	if len(data) > sensorsLength {
+		return data[sensorsLength:], nil
+	}
+
+	return nil, nil
+}
+
+func (s *Sensors) parse(data []byte) error {
+	// TT TT ?? LL LL LL LL MM CC CC ?? ?? ?? ?? ?? ??
 	p := bytes.NewBuffer(data)
 	var t int16
 
@@ -76,7 +163,6 @@ func (s *Sensors) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("error reading data: %s", err)
 	}
 
-	p.Next(2)
 	if err := binary.Read(p, binary.LittleEndian, &s.Moisture); err != nil {
 		return fmt.Errorf("error reading data: %s", err)
 	}
@@ -88,43 +174,162 @@ func (s *Sensors) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
-// ReadData uses a Bluetooth LE device to read data from the sensor identified using the MAC address.
-func ReadData(ctx context.Context, log logrus.FieldLogger, device ble.Device, macAddress string) (Data, error) {
-	addr := ble.NewAddr(macAddress)
-	c, err := device.Dial(ctx, addr)
+// ReadOptions controls which steps are done when reading data from a sensor.
+type ReadOptions struct {
+	// SkipFirmware skips reading the firmware version and battery level, which are left empty in the result.
+	SkipFirmware bool
+	// SkipRealtimeMode skips enabling the realtime measurement. This saves battery, but depending on the firmware
+	// the sensor might return the values of the last realtime measurement instead of current ones.
+	SkipRealtimeMode bool
+	// ScanDuration enables scanning for an advertisement of the sensor before connecting. The connection then uses
+	// the address as it was advertised. The read fails if the sensor is not seen within the duration.
+	ScanDuration time.Duration
+	// MinRSSI skips connecting if the advertisement has a lower signal strength. Only used together with
+	// ScanDuration. Disabled if zero.
+	MinRSSI int
+	// Lenient accepts sensor data of unexpected lengths, see Sensors.UnmarshalLenient.
+	Lenient bool
+	// Layout is optional. If set, it is used for parsing the sensor data instead of the layout of the original sensor.
+	Layout *Layout
+}
+
+// ReadData uses a Bluetooth LE device to read data from the sensor identified using the MAC address. Debug messages
+// are written to the logger, which can be nil.
+func ReadData(ctx context.Context, logger *slog.Logger, device ble.Device, macAddress string) (Data, error) {
+	return ReadDataWithOptions(ctx, logger, device, macAddress, ReadOptions{})
+}
+
+// ReadDataWithOptions reads data from the sensor like ReadData, but allows skipping some of the steps.
+func ReadDataWithOptions(ctx context.Context, logger *slog.Logger, device ble.Device, macAddress string, opts ReadOptions) (result Data, err error) {
+	log := logging.New(logger)
+	ctx, span := tracer.Start(ctx, "miflora.ReadData", trace.WithAttributes(
+		attribute.String("macaddress", macAddress),
+	))
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	var c ble.Client
+	if opts.ScanDuration > 0 {
+		c, err = scanAndConnect(ctx, device, macAddress, opts.ScanDuration, opts.MinRSSI)
+	} else {
+		c, err = connect(ctx, device, macAddress)
+	}
 	if err != nil {
-		return Data{}, fmt.Errorf("error dialing: %s", err)
+		return Data{}, &ReadError{
+			Stage: StageDial,
+			Err:   err,
+		}
 	}
+	defer closeConnection(c)
 
-	firmwareRaw, err := c.ReadCharacteristic(firmwareCharacteristic)
-	if err != nil {
-		return Data{}, fmt.Errorf("error reading firmware info: %s", err)
+	var firmwareRaw []byte
+	if !opts.SkipFirmware {
+		_, firmwareSpan := tracer.Start(ctx, "read firmware")
+		firmwareRaw, err = readCharacteristic(ctx, c, firmwareCharacteristic)
+		endSpan(firmwareSpan, err)
+		if err != nil {
+			return Data{}, &ReadError{
+				Stage: StageRead,
+				Err:   fmt.Errorf("error reading firmware info: %s", err),
+			}
+		}
 	}
 
-	var firmware Firmware
-	if err := firmware.UnmarshalBinary(firmwareRaw); err != nil {
-		return Data{}, fmt.Errorf("error parsing firmware info: %s", err)
+	if !opts.SkipRealtimeMode {
+		_, modeSpan := tracer.Start(ctx, "write mode")
+		err = writeCharacteristic(ctx, c, realtimeReadingCharacteristic, realtimeReadingValue)
+		endSpan(modeSpan, err)
+		if err != nil {
+			return Data{}, &ReadError{
+				Stage: StageRead,
+				Err:   fmt.Errorf("can not enable realtime reading: %s", err),
+			}
+		}
+	}
+
+	_, sensorsSpan := tracer.Start(ctx, "read sensors")
+	sensorsRaw, err := readCharacteristic(ctx, c, sensorCharacteristic)
+	endSpan(sensorsSpan, err)
+	if err != nil {
+		return Data{}, &ReadError{
+			Stage: StageRead,
+			Err:   fmt.Errorf("error reading sensor data: %s", err),
+		}
 	}
-	log.Debugf("Firmware of %q: %#v", macAddress, firmware)
 
-	if err := c.WriteCharacteristic(realtimeReadingCharacteristic, realtimeReadingValue, false); err != nil {
-		return Data{}, fmt.Errorf("can not enable realtime reading: %s", err)
+	raw := RawData{
+		Firmware: firmwareRaw,
+		Sensors:  sensorsRaw,
 	}
 
-	sensorsRaw, err := c.ReadCharacteristic(sensorCharacteristic)
+	_, parseSpan := tracer.Start(ctx, "parse")
+	firmware, sensors, unknown, err := parseData(firmwareRaw, sensorsRaw, opts)
+	endSpan(parseSpan, err)
 	if err != nil {
-		return Data{}, fmt.Errorf("error reading sensor data: %s", err)
+		return Data{}, &ParseError{
+			Raw: raw,
+			Err: err,
+		}
 	}
+	if len(unknown) > 0 {
+		log.Debugf("Ignoring %d unknown bytes in sensor data of %q: % x", len(unknown), macAddress, unknown)
+	}
+	log.Debugf("Firmware of %q: %s", macAddress, firmware)
+	log.Debugf("Sensors of %q: %s", macAddress, sensors)
+
+	return Data{
+		Time:     time.Now(),
+		Firmware: firmware,
+		Sensors:  sensors,
+		Raw:      raw,
+	}, nil
+}
 
-	var sensors Sensors
-	if err := sensors.UnmarshalBinary(sensorsRaw); err != nil {
-		return Data{}, fmt.Errorf("error parsing sensor data: %s", err)
+// ParseRawData parses the firmware and sensor data read from a sensor without using ReadData, for example through
+// BlueZ. Only the options used for parsing are applied. Errors are returned as ParseError.
+func ParseRawData(raw RawData, opts ReadOptions) (Data, error) {
+	firmware, sensors, _, err := parseData(raw.Firmware, raw.Sensors, opts)
+	if err != nil {
+		return Data{}, &ParseError{
+			Raw: raw,
+			Err: err,
+		}
 	}
-	log.Debugf("Sensors of %q: %#v", macAddress, sensors)
 
 	return Data{
 		Time:     time.Now(),
 		Firmware: firmware,
 		Sensors:  sensors,
+		Raw:      raw,
 	}, nil
 }
+
+// parseData parses the firmware and sensor data. In lenient mode, the unknown bytes of the sensor data are returned.
+func parseData(firmwareRaw, sensorsRaw []byte, opts ReadOptions) (Firmware, Sensors, []byte, error) {
+	var firmware Firmware
+	if !opts.SkipFirmware {
+		if err := firmware.UnmarshalBinary(firmwareRaw); err != nil {
+			return Firmware{}, Sensors{}, nil, fmt.Errorf("error parsing firmware info: %s", err)
+		}
+	}
+
+	var (
+		sensors Sensors
+		unknown []byte
+		err     error
+	)
+	switch {
+	case opts.Layout != nil:
+		sensors, err = opts.Layout.Parse(sensorsRaw)
+	case opts.Lenient:
+		unknown, err = sensors.UnmarshalLenient(sensorsRaw)
+	default:
+		err = sensors.UnmarshalBinary(sensorsRaw)
+	}
+	if err != nil {
+		return Firmware{}, Sensors{}, nil, fmt.Errorf("error parsing sensor data: %s", err)
+	}
+
+	return firmware, sensors, unknown, nil
+}
@@ -0,0 +1,143 @@
+package miflora
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseData(t *testing.T) {
+	tests := []struct {
+		desc         string
+		firmware     string
+		sensors      string
+		opts         ReadOptions
+		wantFirmware Firmware
+		wantSensors  Sensors
+		wantUnknown  string
+		wantErr      bool
+	}{
+		{
+			desc:         "original sensor",
+			firmware:     "6415332e312e38",
+			sensors:      "f20000ee000000241601023c00fb349b",
+			wantFirmware: Firmware{Battery: 100, Version: "3.1.8"},
+			wantSensors:  Sensors{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278},
+		},
+		{
+			desc:         "light above 16 bits",
+			firmware:     "5a15332e322e31",
+			sensors:      "fd00008b8c0100150d01023c00fb349b",
+			wantFirmware: Firmware{Battery: 90, Version: "3.2.1"},
+			wantSensors:  Sensors{Temperature: 25.3, Moisture: 21, Light: 101515, Conductivity: 269},
+		},
+		{
+			desc:        "skip firmware",
+			sensors:     "f20000ee000000241601023c00fb349b",
+			opts:        ReadOptions{SkipFirmware: true},
+			wantSensors: Sensors{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278},
+		},
+		{
+			desc:     "firmware too short",
+			firmware: "6415",
+			sensors:  "f20000ee000000241601023c00fb349b",
+			wantErr:  true,
+		},
+		{
+			desc:     "short sensor data",
+			firmware: "6415332e312e38",
+			sensors:  "f20000ee0000002416",
+			wantErr:  true,
+		},
+		{
+			desc:     "long sensor data",
+			firmware: "6415332e312e38",
+			sensors:  "f20000ee000000241601023c00fb349b0102",
+			wantErr:  true,
+		},
+		{
+			desc:         "lenient with short sensor data",
+			firmware:     "6415332e312e38",
+			sensors:      "f20000ee00000024160102",
+			opts:         ReadOptions{Lenient: true},
+			wantFirmware: Firmware{Battery: 100, Version: "3.1.8"},
+			wantSensors:  Sensors{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278},
+		},
+		{
+			desc:         "lenient with unknown remainder",
+			firmware:     "6415332e312e38",
+			sensors:      "f20000ee000000241601023c00fb349b01020304",
+			opts:         ReadOptions{Lenient: true},
+			wantFirmware: Firmware{Battery: 100, Version: "3.1.8"},
+			wantSensors:  Sensors{Temperature: 24.2, Moisture: 36, Light: 238, Conductivity: 278},
+			wantUnknown:  "01020304",
+		},
+		{
+			desc:     "lenient with sensor data missing known fields",
+			firmware: "6415332e312e38",
+			sensors:  "f20000ee0000002416",
+			opts:     ReadOptions{Lenient: true},
+			wantErr:  true,
+		},
+		{
+			desc:     "layout",
+			firmware: "6415332e312e38",
+			sensors:  "097e2800018b8c0116",
+			opts: ReadOptions{
+				Layout: &Layout{
+					Temperature:  Field{Offset: 0, Size: 2, BigEndian: true, Signed: true, Scale: 0.01},
+					Moisture:     Field{Offset: 2, Size: 1},
+					Light:        Field{Offset: 3, Size: 4, BigEndian: true},
+					Conductivity: Field{Offset: 7, Size: 2, BigEndian: true},
+				},
+			},
+			wantFirmware: Firmware{Battery: 100, Version: "3.1.8"},
+			wantSensors:  Sensors{Temperature: 24.3, Moisture: 40, Light: 101260, Conductivity: 278},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			firmware, sensors, unknown, err := parseData(mustDecodeHex(t, tc.firmware), mustDecodeHex(t, tc.sensors), tc.opts)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("got sensors %#v, want error", sensors)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if firmware != tc.wantFirmware {
+				t.Errorf("got firmware %#v, want %#v", firmware, tc.wantFirmware)
+			}
+
+			if sensors != tc.wantSensors {
+				t.Errorf("got sensors %#v, want %#v", sensors, tc.wantSensors)
+			}
+
+			if wantUnknown := mustDecodeHex(t, tc.wantUnknown); !bytes.Equal(unknown, wantUnknown) {
+				t.Errorf("got unknown bytes %x, want %x", unknown, wantUnknown)
+			}
+		})
+	}
+}
+
+func TestParseRawDataError(t *testing.T) {
+	raw := RawData{
+		Firmware: mustDecodeHex(t, "6415332e312e38"),
+		Sensors:  mustDecodeHex(t, "f20000ee0000002416"),
+	}
+
+	_, err := ParseRawData(raw, ReadOptions{})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got error %#v, want ParseError", err)
+	}
+
+	if !bytes.Equal(parseErr.Raw.Sensors, raw.Sensors) {
+		t.Errorf("got raw sensor data %x, want %x", parseErr.Raw.Sensors, raw.Sensors)
+	}
+}
@@ -0,0 +1,111 @@
+package miflora
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+const flowerCareProductID = 0x0098
+
+var xiaomiServiceUUID = ble.UUID16(0xfe95)
+
+// Advertisement contains information about a Flower Care device seen while scanning.
+type Advertisement struct {
+	MacAddress string
+	Name       string
+	RSSI       int
+	// Beacon is set if the advertisement contains a value broadcast by the sensor.
+	Beacon *Beacon
+}
+
+// IsFlowerCare returns true if the advertisement was sent by a Flower Care device.
+func IsFlowerCare(a ble.Advertisement) bool {
+	switch strings.ToLower(a.LocalName()) {
+	case "flower care", "flower mate":
+		return true
+	}
+
+	for _, sd := range a.ServiceData() {
+		if !sd.UUID.Equal(xiaomiServiceUUID) || len(sd.Data) < 4 {
+			continue
+		}
+
+		// MiBeacon frames start with two bytes of frame control followed by the product ID.
+		if binary.LittleEndian.Uint16(sd.Data[2:]) == flowerCareProductID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Scan scans for Flower Care devices until the context is done. Every advertisement of a Flower Care device is passed to the handler.
+func Scan(ctx context.Context, device ble.Device, handler func(Advertisement)) error {
+	err := device.Scan(ctx, true, func(a ble.Advertisement) {
+		if !IsFlowerCare(a) {
+			return
+		}
+
+		handler(Advertisement{
+			MacAddress: strings.ToUpper(a.Addr().String()),
+			Name:       a.LocalName(),
+			RSSI:       a.RSSI(),
+			Beacon:     findBeacon(a),
+		})
+	})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	return err
+}
+
+// findBeacon returns the value contained in the MiBeacon service data of the advertisement or nil.
+func findBeacon(a ble.Advertisement) *Beacon {
+	for _, sd := range a.ServiceData() {
+		if !sd.UUID.Equal(xiaomiServiceUUID) {
+			continue
+		}
+
+		if beacon, err := ParseBeacon(sd.Data); err == nil {
+			return &beacon
+		}
+	}
+
+	return nil
+}
+
+// findAdvertisement scans until an advertisement of the sensor has been seen or the duration is over.
+func findAdvertisement(ctx context.Context, device ble.Device, macAddress string, duration time.Duration) (ble.Advertisement, error) {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	foundCh := make(chan ble.Advertisement, 1)
+	err := device.Scan(ctx, true, func(a ble.Advertisement) {
+		if !strings.EqualFold(a.Addr().String(), macAddress) {
+			return
+		}
+
+		select {
+		case foundCh <- a:
+			cancel()
+		default:
+		}
+	})
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+
+	select {
+	case found := <-foundCh:
+		return found, nil
+	default:
+		return nil, fmt.Errorf("no advertisement seen within %s", duration)
+	}
+}
@@ -0,0 +1,98 @@
+package miflora
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ranges of the values which the sensor is able to measure. Values outside of these ranges point to a faulty sensor or
+// corrupted data.
+const (
+	MinTemperature  = -30.0
+	MaxTemperature  = 60.0
+	MaxMoisture     = 100
+	MaxLight        = 100000
+	MaxConductivity = 10000
+	MaxBattery      = 100
+)
+
+// ValidationError describes a value which is outside of the range the sensor can measure.
+type ValidationError struct {
+	Field string
+	Value float64
+	Min   float64
+	Max   float64
+	Unit  string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s of %g %s outside of valid range %g to %g", e.Field, e.Value, e.Unit, e.Min, e.Max)
+}
+
+// ValidationErrors contains all errors found when validating data.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+func (e ValidationErrors) check(field string, value, min, max float64, unit string) ValidationErrors {
+	if value >= min && value <= max {
+		return e
+	}
+
+	return append(e, &ValidationError{
+		Field: field,
+		Value: value,
+		Min:   min,
+		Max:   max,
+		Unit:  unit,
+	})
+}
+
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// Validate checks that the battery level is plausible. Returns ValidationErrors if it is not.
+func (f Firmware) Validate() error {
+	return f.validate(nil).err()
+}
+
+func (f Firmware) validate(errs ValidationErrors) ValidationErrors {
+	return errs.check("battery", float64(f.Battery), 0, MaxBattery, UnitPercent)
+}
+
+// Validate checks that the sensor values are within the ranges the sensor can measure. Returns ValidationErrors
+// listing all implausible values.
+func (s Sensors) Validate() error {
+	return s.validate(nil).err()
+}
+
+func (s Sensors) validate(errs ValidationErrors) ValidationErrors {
+	errs = errs.check("temperature", s.Temperature, MinTemperature, MaxTemperature, UnitCelsius)
+	errs = errs.check("moisture", float64(s.Moisture), 0, MaxMoisture, UnitPercent)
+	errs = errs.check("light", float64(s.Light), 0, MaxLight, UnitLux)
+	errs = errs.check("conductivity", float64(s.Conductivity), 0, MaxConductivity, UnitConductivity)
+	return errs
+}
+
+// Validate checks the firmware information and sensor values of the data. The firmware information is not checked
+// if it has not been read.
+func (d Data) Validate() error {
+	var errs ValidationErrors
+	if d.Firmware.Known() {
+		errs = d.Firmware.validate(errs)
+	}
+
+	return d.Sensors.validate(errs).err()
+}
@@ -0,0 +1,207 @@
+package miflora
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSensorsValidate(t *testing.T) {
+	valid := Sensors{
+		Temperature:  21.5,
+		Moisture:     40,
+		Light:        1200,
+		Conductivity: 350,
+	}
+
+	tests := []struct {
+		desc      string
+		modify    func(s *Sensors)
+		wantField string
+	}{
+		{
+			desc:   "valid",
+			modify: func(s *Sensors) {},
+		},
+		{
+			desc:   "minimum temperature",
+			modify: func(s *Sensors) { s.Temperature = MinTemperature },
+		},
+		{
+			desc:      "temperature too low",
+			modify:    func(s *Sensors) { s.Temperature = MinTemperature - 0.1 },
+			wantField: "temperature",
+		},
+		{
+			desc:   "maximum temperature",
+			modify: func(s *Sensors) { s.Temperature = MaxTemperature },
+		},
+		{
+			desc:      "temperature too high",
+			modify:    func(s *Sensors) { s.Temperature = MaxTemperature + 0.1 },
+			wantField: "temperature",
+		},
+		{
+			desc:   "maximum moisture",
+			modify: func(s *Sensors) { s.Moisture = MaxMoisture },
+		},
+		{
+			desc:      "moisture too high",
+			modify:    func(s *Sensors) { s.Moisture = MaxMoisture + 1 },
+			wantField: "moisture",
+		},
+		{
+			desc:   "maximum light",
+			modify: func(s *Sensors) { s.Light = MaxLight },
+		},
+		{
+			desc:      "light too high",
+			modify:    func(s *Sensors) { s.Light = MaxLight + 1 },
+			wantField: "light",
+		},
+		{
+			desc:   "maximum conductivity",
+			modify: func(s *Sensors) { s.Conductivity = MaxConductivity },
+		},
+		{
+			desc:      "conductivity too high",
+			modify:    func(s *Sensors) { s.Conductivity = MaxConductivity + 1 },
+			wantField: "conductivity",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := valid
+			tc.modify(&s)
+
+			checkValidation(t, s.Validate(), tc.wantField)
+		})
+	}
+}
+
+func TestFirmwareValidate(t *testing.T) {
+	tests := []struct {
+		desc      string
+		battery   byte
+		wantField string
+	}{
+		{
+			desc:    "empty battery",
+			battery: 0,
+		},
+		{
+			desc:    "full battery",
+			battery: MaxBattery,
+		},
+		{
+			desc:      "battery too high",
+			battery:   MaxBattery + 1,
+			wantField: "battery",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			f := Firmware{
+				Version: "3.2.2",
+				Battery: tc.battery,
+			}
+
+			checkValidation(t, f.Validate(), tc.wantField)
+		})
+	}
+}
+
+func TestDataValidate(t *testing.T) {
+	sensors := Sensors{
+		Temperature:  21.5,
+		Moisture:     40,
+		Light:        500,
+		Conductivity: 350,
+	}
+
+	tests := []struct {
+		desc      string
+		firmware  Firmware
+		wantField string
+	}{
+		{
+			desc:     "firmware not read",
+			firmware: Firmware{},
+		},
+		{
+			desc:      "battery too high",
+			firmware:  Firmware{Version: "3.2.2", Battery: MaxBattery + 1},
+			wantField: "battery",
+		},
+		{
+			desc:      "battery too high without version",
+			firmware:  Firmware{Battery: MaxBattery + 1},
+			wantField: "battery",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			d := Data{
+				Firmware: tc.firmware,
+				Sensors:  sensors,
+			}
+
+			checkValidation(t, d.Validate(), tc.wantField)
+		})
+	}
+}
+
+func TestSensorsParseLight(t *testing.T) {
+	// 100000 lux does not fit into two bytes.
+	data := []byte{0xd7, 0x00, 0x00, 0xa0, 0x86, 0x01, 0x00, 0x28, 0x5e, 0x01, 0x02, 0x3c, 0x00, 0x00, 0x00, 0x00}
+
+	var s Sensors
+	if err := s.UnmarshalBinary(data); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	want := Sensors{
+		Temperature:  21.5,
+		Moisture:     40,
+		Light:        100000,
+		Conductivity: 350,
+	}
+	if s != want {
+		t.Errorf("got %#v, want %#v", s, want)
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Errorf("got error %q", err)
+	}
+
+	parsed, err := DefaultLayout.Parse(data)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	if parsed != want {
+		t.Errorf("got %#v using default layout, want %#v", parsed, want)
+	}
+}
+
+func checkValidation(t *testing.T, err error, wantField string) {
+	t.Helper()
+
+	if wantField == "" {
+		if err != nil {
+			t.Errorf("got error %q", err)
+		}
+		return
+	}
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error %v, want ValidationErrors", err)
+	}
+
+	if len(errs) != 1 || errs[0].Field != wantField {
+		t.Errorf("got error %q, want error for %s", err, wantField)
+	}
+}
@@ -0,0 +1,148 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+)
+
+// loadLastReadings loads the times of the last readings from the state file. A missing file results in an empty map.
+func loadLastReadings(log *logging.Logger, fileName string) map[string]time.Time {
+	result := map[string]time.Time{}
+	if fileName == "" {
+		return result
+	}
+
+	raw, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return result
+	case err != nil:
+		log.Errorf("Error reading backfill state: %s", err)
+		return result
+	}
+
+	if err := json.Unmarshal(raw, &result); err != nil {
+		log.Errorf("Can not parse backfill state %q: %s", fileName, err)
+		return map[string]time.Time{}
+	}
+	return result
+}
+
+// saveLastReading records the time of the last reading of the sensor in the state file.
+func (u *Updater) saveLastReading(sensor Sensor, t time.Time) error {
+	if u.backfill.StateFile == "" {
+		return nil
+	}
+	u.lastReadings[sensor.MacAddress] = t
+
+	raw, err := json.MarshalIndent(u.lastReadings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(u.backfill.StateFile), filepath.Base(u.backfill.StateFile)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), u.backfill.StateFile)
+}
+
+// lastReading returns the time of the last successful reading of the sensor. Before the first reading, the time kept
+// in the state file is used. Zero is returned if it is not known.
+func (u *Updater) lastReading(sensor Sensor) time.Time {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	if !ok || d.Data == nil {
+		return u.lastReadings[sensor.MacAddress]
+	}
+	return d.Data.Time
+}
+
+// backfillSince returns the start of the gap before a successful read at now, which needs to be filled using the
+// history. It returns false if there is no gap or the previous reading is not known.
+func (u *Updater) backfillSince(previous, now time.Time) (time.Time, bool) {
+	if u.history == nil || u.backfill.Gap == 0 || previous.IsZero() {
+		return time.Time{}, false
+	}
+
+	if now.Sub(previous) < u.backfill.Gap {
+		return time.Time{}, false
+	}
+
+	if oldest := now.Add(-u.backfill.MaxAge); previous.Before(oldest) {
+		return oldest, true
+	}
+	return previous, true
+}
+
+// fillGap reads the history records of the sensor, which have been missed since the previous reading, and passes
+// them to OnHistory. It needs to be called after a successful read while holding the adapter lock.
+func (u *Updater) fillGap(ctx context.Context, sensor Sensor, previous, now time.Time) {
+	if u.history == nil || u.backfill.Gap == 0 {
+		return
+	}
+
+	if err := u.saveLastReading(sensor, u.lastReading(sensor)); err != nil {
+		u.log.Errorf("Error saving backfill state: %s", err)
+	}
+
+	since, ok := u.backfillSince(previous, now)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.backfill.Timeout)
+	defer cancel()
+
+	u.log.Infof("Reading history of %q for filling the gap since %s", sensor, since.Format(time.RFC3339))
+	records, err := u.history.ReadHistory(ctx, sensor.Address())
+	if err != nil {
+		u.log.Errorf("Error reading history of %q: %s", sensor, err)
+		return
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	count := 0
+	for _, r := range records {
+		if !r.Time.After(since) || !r.Time.Before(now) {
+			continue
+		}
+
+		data := r.Data()
+		if err := data.Validate(); err != nil {
+			u.log.Debugf("Ignoring implausible history record of %q from %s: %s", sensor, r.Time.Format(time.RFC3339), err)
+			continue
+		}
+
+		count++
+		if u.onHistory != nil {
+			u.onHistory(sensor, data)
+		}
+	}
+
+	u.metrics.backfilled.WithLabelValues(sensor.MacAddress, sensor.Name).Add(float64(count))
+	u.log.Infof("Filled gap of %q using %d history records.", sensor, count)
+}
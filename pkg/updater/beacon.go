@@ -0,0 +1,63 @@
+package updater
+
+import (
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// storeBeacon merges a value broadcast by a sensor into its data. Values are only merged once the sensor has been
+// read, so that the values which are not broadcast are known. The values of passive sensors are collected until the
+// sensor is read.
+func (u *Updater) storeBeacon(macAddress string, beacon miflora.Beacon, now time.Time) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	for mac, d := range u.dataMap {
+		if !strings.EqualFold(mac, macAddress) && !strings.EqualFold(d.Info.Address(), macAddress) {
+			continue
+		}
+
+		if !d.Removed.IsZero() {
+			return
+		}
+
+		if u.isPassive(d.Info) {
+			collectBroadcast(d, beacon, now)
+			return
+		}
+
+		if !u.beacons || d.Data == nil {
+			return
+		}
+
+		data := *d.Data
+		beacon.Apply(&data)
+		if err := data.Validate(); err != nil {
+			u.log.Debugf("Ignoring implausible %s value broadcast by %q: %s", beacon.Field, d.Info, err)
+			return
+		}
+
+		// The payloads of the last read do not match the data anymore.
+		data.Raw = miflora.RawData{}
+		data.Time = now
+		d.Data = &data
+		d.Updated[beacon.Field] = now
+		return
+	}
+}
+
+// setUpdated records the time of a read, which updates all values of the sensor. It needs to be called while holding
+// the data lock.
+func setUpdated(d *data, now time.Time) {
+	for _, field := range []miflora.BeaconField{
+		miflora.BeaconTemperature,
+		miflora.BeaconMoisture,
+		miflora.BeaconLight,
+		miflora.BeaconConductivity,
+		miflora.BeaconBattery,
+	} {
+		d.Updated[field] = now
+	}
+}
@@ -0,0 +1,216 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// BenchmarkOptions contains the settings of a benchmark run. The reader of the embedded options provides the data of
+// the sensors, it is wrapped in a fake Bluetooth backend, which fails randomly and replaces the time of the data. The
+// optional components of the embedded options are not used.
+type BenchmarkOptions struct {
+	Options
+	Sensors         []Sensor
+	Duration        time.Duration
+	RefreshDuration time.Duration
+	// ReadDuration is the time a read takes on the virtual clock.
+	ReadDuration time.Duration
+	// FailureRate is the share of reads, which fail.
+	FailureRate float64
+}
+
+// BenchmarkResult contains the statistics collected during a benchmark run.
+type BenchmarkResult struct {
+	Reads    int
+	Failures int
+	// Unread is the number of sensors, which have not been read at all.
+	Unread int
+	// Latency contains percentiles of the delay between the time a read was scheduled for and the time it started.
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+	// MaxQueueLength and AvgQueueLength are sampled at every tick.
+	MaxQueueLength int
+	AvgQueueLength float64
+	// Saturation is the share of ticks at which at least one sensor was overdue.
+	Saturation float64
+	// MaxStale and AvgStale are the number of sensors without data or with stale data sampled at every tick. Only
+	// collected if the stale duration is set.
+	MaxStale int
+	AvgStale float64
+}
+
+// benchmarkReader is a fake Bluetooth backend, which returns the data of another reader and fails randomly.
+type benchmarkReader struct {
+	reader      Reader
+	random      *rand.Rand
+	failureRate float64
+	reads       map[string]int
+	// now is the time on the virtual clock, which is used as the time of the returned data.
+	now time.Time
+
+	// last contains the MAC address of the last read and failed is set if it failed.
+	last   string
+	failed bool
+}
+
+func (r *benchmarkReader) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	r.reads[macAddress]++
+	r.last = macAddress
+	r.failed = r.random.Float64() < r.failureRate
+	if r.failed {
+		return miflora.Data{}, errors.New("simulated read failure")
+	}
+
+	data, err := r.reader.ReadData(ctx, macAddress)
+	data.Time = r.now
+	return data, err
+}
+
+// Benchmark drives an updater with the sensors using a virtual clock, so that a long duration can be benchmarked
+// within seconds. The reader needs to return data for the sensors without delay, for example by simulating them.
+func Benchmark(ctx context.Context, opts BenchmarkOptions) BenchmarkResult {
+	reader := &benchmarkReader{
+		reader:      opts.Reader,
+		random:      rand.New(rand.NewSource(1)),
+		failureRate: opts.FailureRate,
+		reads:       map[string]int{},
+	}
+	updaterOpts := opts.Options
+	updaterOpts.Reader = reader
+	updaterOpts.Scanner = nil
+	updaterOpts.Diagnoser = nil
+	updaterOpts.Namer = nil
+	updaterOpts.SharedLock = nil
+	updaterOpts.OnData = nil
+	updaterOpts.Cooldown = 0
+
+	u := New(nil, updaterOpts)
+	for _, s := range opts.Sensors {
+		u.AddSensor(s)
+	}
+
+	var (
+		result      BenchmarkResult
+		latencies   []time.Duration
+		ticks       int
+		overdue     int
+		queueSum    int
+		staleSum    int
+		now         = time.Now()
+		end         = now.Add(opts.Duration)
+		nextRefresh = now
+	)
+	for now.Before(end) && ctx.Err() == nil {
+		if !now.Before(nextRefresh) {
+			u.UpdateAll(now)
+			nextRefresh = nextRefresh.Add(opts.RefreshDuration)
+		}
+
+		scheduled := u.queueTimes()
+		ticks++
+		queueSum += len(scheduled)
+		if len(scheduled) > result.MaxQueueLength {
+			result.MaxQueueLength = len(scheduled)
+		}
+		for _, at := range scheduled {
+			if at.Before(now) {
+				overdue++
+				break
+			}
+		}
+
+		if opts.StaleDuration > 0 {
+			stale := u.countStale(now)
+			staleSum += stale
+			if stale > result.MaxStale {
+				result.MaxStale = stale
+			}
+		}
+
+		reader.last = ""
+		reader.now = now
+		u.tick(ctx, now)
+		if reader.last == "" {
+			now = now.Add(updaterTickDuration)
+			continue
+		}
+
+		result.Reads++
+		if reader.failed {
+			result.Failures++
+		}
+		latencies = append(latencies, now.Sub(scheduled[reader.last]))
+
+		// The ticker of the updater drops ticks while a read is running.
+		now = now.Add(updaterTickDuration * (1 + opts.ReadDuration/updaterTickDuration))
+	}
+
+	for _, s := range u.Sensors() {
+		if reader.reads[s.MacAddress] == 0 {
+			result.Unread++
+		}
+	}
+
+	if ticks > 0 {
+		result.AvgQueueLength = float64(queueSum) / float64(ticks)
+		result.Saturation = float64(overdue) / float64(ticks)
+		result.AvgStale = float64(staleSum) / float64(ticks)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	result.LatencyP50 = percentile(latencies, 0.5)
+	result.LatencyP95 = percentile(latencies, 0.95)
+	result.LatencyP99 = percentile(latencies, 0.99)
+	result.LatencyMax = percentile(latencies, 1)
+	return result
+}
+
+// queueTimes returns the times the queued sensors are scheduled for by their MAC address.
+func (u *Updater) queueTimes() map[string]time.Time {
+	u.queueLock.RLock()
+	defer u.queueLock.RUnlock()
+
+	result := make(map[string]time.Time, len(u.queue))
+	for mac, item := range u.queue {
+		result[mac] = item.Time
+	}
+	return result
+}
+
+// countStale returns the number of sensors without data or whose data is stale.
+func (u *Updater) countStale(now time.Time) int {
+	deadlines := u.staleDeadlines()
+
+	result := 0
+	for _, s := range u.Sensors() {
+		if !deadlines[s.MacAddress].After(now) {
+			result++
+		}
+	}
+	return result
+}
+
+// percentile returns the value at the percentile p (0 to 1) of the sorted values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(p*float64(len(sorted))+0.5) - 1
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(sorted):
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
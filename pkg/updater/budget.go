@@ -0,0 +1,63 @@
+package updater
+
+import (
+	"sync"
+	"time"
+)
+
+// readBudget tracks the reads done during the current refresh cycle and during the last hour.
+type readBudget struct {
+	config ReadBudgetConfig
+
+	lock       sync.Mutex
+	cycleReads int
+	recent     []time.Time
+}
+
+// newCycle resets the reads counted for the refresh cycle.
+func (b *readBudget) newCycle() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.cycleReads = 0
+}
+
+// available returns true if another read can be done without exceeding the limits.
+func (b *readBudget) available(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.config.PerCycle > 0 && b.cycleReads >= b.config.PerCycle {
+		return false
+	}
+
+	if b.config.PerHour > 0 {
+		b.pruneRecent(now)
+		if len(b.recent) >= b.config.PerHour {
+			return false
+		}
+	}
+
+	return true
+}
+
+// record counts a read against the limits.
+func (b *readBudget) record(now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.cycleReads++
+	if b.config.PerHour > 0 {
+		b.recent = append(b.recent, now)
+	}
+}
+
+func (b *readBudget) pruneRecent(now time.Time) {
+	cutoff := now.Add(-time.Hour)
+
+	i := 0
+	for i < len(b.recent) && !b.recent[i].After(cutoff) {
+		i++
+	}
+	b.recent = b.recent[i:]
+}
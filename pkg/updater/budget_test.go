@@ -0,0 +1,229 @@
+package updater
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+var testStart = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testSensors are read by the test updaters. They are ordered by their MAC address, like the sensors of the updater.
+var testSensors = []Sensor{
+	{Name: "basil", MacAddress: "C4:7C:8D:00:00:01"},
+	{Name: "mint", MacAddress: "C4:7C:8D:00:00:02"},
+	{Name: "thyme", MacAddress: "C4:7C:8D:00:00:03"},
+}
+
+// readerFunc adapts a function to the Reader interface.
+type readerFunc func(ctx context.Context, macAddress string) (miflora.Data, error)
+
+func (f readerFunc) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	return f(ctx, macAddress)
+}
+
+var plausibleReader = readerFunc(func(context.Context, string) (miflora.Data, error) {
+	return miflora.Data{
+		Firmware: miflora.Firmware{Version: "3.2.1", Battery: 80},
+		Sensors: miflora.Sensors{
+			Temperature:  20,
+			Moisture:     30,
+			Light:        500,
+			Conductivity: 300,
+		},
+	}, nil
+})
+
+// newTestUpdater creates an updater for the test sensors, which uses the fake Bluetooth backend of the benchmark with
+// the reader as the source of the data.
+func newTestUpdater(opts Options, source Reader, failureRate float64) (*Updater, *benchmarkReader) {
+	reader := &benchmarkReader{
+		reader:      source,
+		random:      rand.New(rand.NewSource(1)),
+		failureRate: failureRate,
+		reads:       map[string]int{},
+	}
+	opts.Reader = reader
+
+	u := New(nil, opts)
+	for _, s := range testSensors {
+		u.AddSensor(s)
+	}
+	return u, reader
+}
+
+// tickAt runs a tick of the updater at the time on the virtual clock and returns the MAC address of the sensor read.
+func tickAt(ctx context.Context, u *Updater, reader *benchmarkReader, now time.Time) string {
+	reader.last = ""
+	reader.now = now
+	u.tick(ctx, now)
+	return reader.last
+}
+
+func TestReadBudget(t *testing.T) {
+	type action struct {
+		offset time.Duration
+		// newCycle starts a new refresh cycle instead of checking and recording a read.
+		newCycle bool
+		want     bool
+	}
+
+	tests := []struct {
+		desc    string
+		config  ReadBudgetConfig
+		actions []action
+	}{
+		{
+			desc:   "no limits",
+			config: ReadBudgetConfig{},
+			actions: []action{
+				{offset: 0, want: true},
+				{offset: time.Second, want: true},
+				{offset: 2 * time.Second, want: true},
+			},
+		},
+		{
+			desc:   "per cycle",
+			config: ReadBudgetConfig{PerCycle: 2},
+			actions: []action{
+				{offset: 0, want: true},
+				{offset: time.Second, want: true},
+				{offset: 2 * time.Second, want: false},
+				{offset: 2 * time.Hour, want: false},
+				{offset: 2 * time.Hour, newCycle: true},
+				{offset: 2 * time.Hour, want: true},
+			},
+		},
+		{
+			desc:   "per hour",
+			config: ReadBudgetConfig{PerHour: 2},
+			actions: []action{
+				{offset: 0, want: true},
+				{offset: 30 * time.Minute, want: true},
+				{offset: 45 * time.Minute, want: false},
+				{offset: 45 * time.Minute, newCycle: true},
+				{offset: 59 * time.Minute, want: false},
+				{offset: time.Hour, want: true},
+				{offset: 80 * time.Minute, want: false},
+				{offset: 90 * time.Minute, want: true},
+			},
+		},
+		{
+			desc:   "per cycle and per hour",
+			config: ReadBudgetConfig{PerCycle: 1, PerHour: 2},
+			actions: []action{
+				{offset: 0, want: true},
+				{offset: time.Minute, want: false},
+				{offset: 10 * time.Minute, newCycle: true},
+				{offset: 10 * time.Minute, want: true},
+				{offset: 20 * time.Minute, newCycle: true},
+				{offset: 20 * time.Minute, want: false},
+				{offset: 61 * time.Minute, want: true},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			b := &readBudget{config: tc.config}
+			for i, a := range tc.actions {
+				now := testStart.Add(a.offset)
+				if a.newCycle {
+					b.newCycle()
+					continue
+				}
+
+				got := b.available(now)
+				if got != a.want {
+					t.Errorf("got available %v for action %d, want %v", got, i, a.want)
+				}
+
+				if got {
+					b.record(now)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdaterReadBudget(t *testing.T) {
+	tests := []struct {
+		desc   string
+		config ReadBudgetConfig
+		// ticks are the offsets of the ticks after the warm-up. A new refresh cycle is started before the tick at
+		// newCycle, if it is set.
+		ticks    []time.Duration
+		newCycle time.Duration
+		want     []string
+	}{
+		{
+			desc:   "all sensors read",
+			config: ReadBudgetConfig{},
+			ticks:  []time.Duration{0, time.Second, 2 * time.Second},
+			want:   []string{"C4:7C:8D:00:00:01", "C4:7C:8D:00:00:02", "C4:7C:8D:00:00:03"},
+		},
+		{
+			desc:   "per cycle budget exhausted",
+			config: ReadBudgetConfig{PerCycle: 2},
+			ticks:  []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+			want:   []string{"C4:7C:8D:00:00:01", "C4:7C:8D:00:00:02", "", ""},
+		},
+		{
+			desc:     "unread sensor is carried over to next cycle",
+			config:   ReadBudgetConfig{PerCycle: 2},
+			ticks:    []time.Duration{0, time.Second, 2 * time.Second, time.Hour},
+			newCycle: time.Hour,
+			want:     []string{"C4:7C:8D:00:00:01", "C4:7C:8D:00:00:02", "", "C4:7C:8D:00:00:03"},
+		},
+		{
+			desc:   "per hour budget exhausted",
+			config: ReadBudgetConfig{PerHour: 2},
+			ticks:  []time.Duration{0, time.Second, 2 * time.Second, 59 * time.Minute, time.Hour},
+			want:   []string{"C4:7C:8D:00:00:01", "C4:7C:8D:00:00:02", "", "", "C4:7C:8D:00:00:03"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			u, reader := newTestUpdater(Options{ReadBudget: tc.config}, plausibleReader, 0)
+			u.WarmUp(testStart, 3*time.Second)
+
+			for i, offset := range tc.ticks {
+				now := testStart.Add(offset)
+				if tc.newCycle > 0 && offset == tc.newCycle {
+					u.UpdateAll(now)
+				}
+
+				if got := tickAt(context.Background(), u, reader, now); got != tc.want[i] {
+					t.Errorf("got read %q at tick %d, want %q", got, i, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTickCooldownCanceled(t *testing.T) {
+	u, reader := newTestUpdater(Options{
+		ReadBudget: ReadBudgetConfig{PerCycle: 1},
+		Cooldown:   time.Hour,
+	}, plausibleReader, 0)
+	u.UpdateAll(testStart)
+	u.lastAdapterUse = time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := tickAt(ctx, u, reader, testStart); got != "" {
+		t.Fatalf("got read %q while waiting for cooldown", got)
+	}
+
+	if got := len(u.queueTimes()); got != len(testSensors) {
+		t.Errorf("got %d queued sensors, want %d", got, len(testSensors))
+	}
+
+	if !u.budget.available(testStart) {
+		t.Error("got budget used by canceled read")
+	}
+}
@@ -0,0 +1,196 @@
+package updater
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sensor identifies a sensor by its MAC address and contains its name.
+type Sensor struct {
+	Name       string
+	MacAddress string
+	// Device is the MAC address of the device which replaced the original sensor. The sensor keeps its MacAddress
+	// in labels, alerts and history.
+	Device string
+}
+
+// String returns the name and address of the sensor for use in logs.
+func (s Sensor) String() string {
+	name := s.MacAddress
+	if s.Device != "" {
+		name = fmt.Sprintf("%s on %s", s.MacAddress, s.Device)
+	}
+
+	if s.Name == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s (%s)", s.Name, name)
+}
+
+// Address returns the MAC address used for connecting to the device of the sensor.
+func (s Sensor) Address() string {
+	if s.Device != "" {
+		return s.Device
+	}
+
+	return s.MacAddress
+}
+
+// QuietHours is a daily time range during which the sensors are not read. The range is disabled if start and end are equal.
+type QuietHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Type implements pflag.Value.
+func (q *QuietHours) Type() string {
+	return "range"
+}
+
+// String returns the range in the format accepted by Set.
+func (q *QuietHours) String() string {
+	if !q.Enabled() {
+		return ""
+	}
+
+	return fmt.Sprintf("%s-%s", formatTimeOfDay(q.Start), formatTimeOfDay(q.End))
+}
+
+// Set parses a range like 22:00-07:00.
+func (q *QuietHours) Set(value string) error {
+	tokens := strings.SplitN(value, "-", 2)
+	if len(tokens) != 2 {
+		return fmt.Errorf("expected a range like 22:00-07:00: %s", value)
+	}
+
+	start, err := parseTimeOfDay(tokens[0])
+	if err != nil {
+		return err
+	}
+
+	end, err := parseTimeOfDay(tokens[1])
+	if err != nil {
+		return err
+	}
+
+	q.Start = start
+	q.End = end
+	return nil
+}
+
+// Enabled returns true if the range is not empty.
+func (q QuietHours) Enabled() bool {
+	return q.Start != q.End
+}
+
+// Contains returns true if the time of day of t is within the range. Ranges can span midnight.
+func (q QuietHours) Contains(t time.Time) bool {
+	if !q.Enabled() {
+		return false
+	}
+
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if q.Start < q.End {
+		return offset >= q.Start && offset < q.End
+	}
+
+	return offset >= q.Start || offset < q.End
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("can not parse time of day %q: %s", value, err)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// AutoRegisterConfig contains the rules for automatically registering sensors found while scanning.
+type AutoRegisterConfig struct {
+	Enabled bool
+	Allow   []string
+	Deny    []string
+}
+
+// Matches returns true if the MAC address is allowed by the rules. An empty allowlist allows all addresses.
+func (c AutoRegisterConfig) Matches(macAddress string) bool {
+	for _, prefix := range c.Deny {
+		if hasPrefixFold(macAddress, prefix) {
+			return false
+		}
+	}
+
+	if len(c.Allow) == 0 {
+		return true
+	}
+
+	for _, prefix := range c.Allow {
+		if hasPrefixFold(macAddress, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultName returns the name assigned to an auto-registered sensor, derived from the last three bytes of the MAC address.
+func (c AutoRegisterConfig) DefaultName(macAddress string) string {
+	suffix := strings.ReplaceAll(macAddress, ":", "")
+	if len(suffix) > 6 {
+		suffix = suffix[len(suffix)-6:]
+	}
+
+	return "flowercare-" + strings.ToLower(suffix)
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// ReadBudgetConfig limits the number of reads, so that an adapter shared with other applications is not monopolized.
+// Limits which are zero are not enforced.
+type ReadBudgetConfig struct {
+	PerCycle int
+	PerHour  int
+	// OnDemandPerMinute limits the operations requested using the API, like connectivity checks.
+	OnDemandPerMinute int
+}
+
+// BackfillConfig controls reading the history records stored on the sensors for filling gaps in their readings.
+type BackfillConfig struct {
+	// Gap is the minimum time between two successful readings, which is filled using the history. Disabled if zero.
+	Gap time.Duration
+	// MaxAge limits the age of the records used.
+	MaxAge  time.Duration
+	Timeout time.Duration
+	// StateFile is optional. If set, the time of the last reading of every sensor is kept in it, so that gaps caused by
+	// restarts of the exporter are filled as well.
+	StateFile string
+}
+
+// HealthConfig contains the thresholds for changing the state of a sensor.
+type HealthConfig struct {
+	// DegradedAfter is the number of consecutive failed reads after which a sensor is degraded.
+	DegradedAfter int
+	// RecoverAfter is the number of consecutive successful reads needed for a degraded or down sensor to be ok again.
+	RecoverAfter int
+}
+
+// RetryConfig controls the backoff between retries of failed reads.
+type RetryConfig struct {
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Factor      float64
+	// MaxRetries and MaxWindow limit the retries of failed reads. Once a limit is exceeded, the sensor is considered
+	// down and only read every DownInterval. Limits are disabled if zero.
+	MaxRetries   int
+	MaxWindow    time.Duration
+	DownInterval time.Duration
+}
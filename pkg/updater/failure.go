@@ -0,0 +1,69 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// Stages of a read in addition to the ones of miflora.ReadError.
+const (
+	stageParse    = "parse"
+	stageValidate = "validate"
+)
+
+// Reasons of a failed read.
+const (
+	reasonError       = "error"
+	reasonTimeout     = "timeout"
+	reasonWatchdog    = "watchdog"
+	reasonInvalid     = "invalid_data"
+	reasonImplausible = "implausible_data"
+)
+
+// failure is returned for failed reads. It describes in which stage of the read and why it failed.
+type failure struct {
+	Stage  string
+	Reason string
+	Err    error
+}
+
+func (f *failure) Error() string {
+	return f.Err.Error()
+}
+
+// readFailure describes an error returned by the reader. The context is the one used for the read.
+func readFailure(ctx context.Context, err error) *failure {
+	result := &failure{
+		Stage:  miflora.StageRead,
+		Reason: reasonError,
+		Err:    fmt.Errorf("can not read data: %s", err),
+	}
+
+	var readErr *miflora.ReadError
+	var parseErr *miflora.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		result.Stage = stageParse
+		result.Reason = reasonInvalid
+	case errors.As(err, &readErr):
+		result.Stage = readErr.Stage
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Reason = reasonTimeout
+	}
+	return result
+}
+
+// describeFailure returns the stage and reason of an error stored for a sensor.
+func describeFailure(err error) (string, string) {
+	var f *failure
+	if errors.As(err, &f) {
+		return f.Stage, f.Reason
+	}
+
+	return miflora.StageRead, reasonError
+}
@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLocateUnsupported is returned by Locate if the reader can not make the sensors blink.
+var ErrLocateUnsupported = errors.New("locating sensors needs a Bluetooth adapter")
+
+// Locate makes the LED of the sensor blink, so that it can be identified. Like Diagnose, it is rejected if another
+// on-demand operation is queued or the limits are reached.
+func (u *Updater) Locate(ctx context.Context, macAddress string) error {
+	if u.blinker == nil {
+		return ErrLocateUnsupported
+	}
+
+	done, err := u.startOnDemand(time.Now())
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	release, err := u.acquireAdapter(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, u.refreshTimeout)
+	defer cancel()
+
+	u.log.Infof("Blinking LED of sensor %s", macAddress)
+	return u.blinker.Blink(ctx, u.address(macAddress))
+}
+
+// acquireAdapter waits until the adapter can be used outside of the update loop. The returned function needs to be
+// called once the adapter is not used anymore.
+func (u *Updater) acquireAdapter(ctx context.Context) (func(), error) {
+	select {
+	case u.adapterLock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("adapter is busy: %s", ctx.Err())
+	}
+	release := func() {
+		u.lastAdapterUse = time.Now()
+		<-u.adapterLock
+	}
+
+	if !u.waitCooldown(ctx) {
+		release()
+		return nil, fmt.Errorf("adapter is busy: %s", ctx.Err())
+	}
+
+	if !u.lockShared(ctx) {
+		release()
+		return nil, errors.New("adapter is used by another process")
+	}
+
+	return func() {
+		u.unlockShared()
+		release()
+	}, nil
+}
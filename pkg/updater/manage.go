@@ -0,0 +1,59 @@
+package updater
+
+import (
+	"time"
+)
+
+// Register adds a sensor while the updater is running and schedules its first read. A removed sensor, whose data is
+// still kept, is restored. It returns false if a sensor with the MAC address is already registered.
+func (u *Updater) Register(sensor Sensor, now time.Time) bool {
+	if existing, ok := u.registeredSensor(sensor.MacAddress); ok && !u.isRemoved(existing) {
+		return false
+	}
+
+	u.log.Infof("Registering sensor %q", sensor)
+	u.AddSensor(sensor)
+	u.scheduleUpdate(sensor, now)
+	return true
+}
+
+// SetDisabled stops or resumes reading the sensor identified by its MAC address. The last data of a disabled sensor is
+// still exported until it is forgotten. It returns false if the sensor is not registered.
+func (u *Updater) SetDisabled(macAddress string, disabled bool, now time.Time) bool {
+	u.dataLock.Lock()
+	d, ok := u.dataMap[macAddress]
+	if !ok || !d.Removed.IsZero() {
+		u.dataLock.Unlock()
+		return false
+	}
+
+	changed := d.Disabled != disabled
+	d.Disabled = disabled
+	sensor := d.Info
+	u.dataLock.Unlock()
+
+	if !changed {
+		return true
+	}
+
+	if disabled {
+		u.log.Infof("Disabling sensor %q", sensor)
+		u.queueLock.Lock()
+		delete(u.queue, macAddress)
+		u.queueLock.Unlock()
+		return true
+	}
+
+	u.log.Infof("Enabling sensor %q", sensor)
+	u.scheduleUpdate(sensor, now)
+	return true
+}
+
+// isDisabled returns true if reading the sensor has been disabled.
+func (u *Updater) isDisabled(sensor Sensor) bool {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	return ok && d.Disabled
+}
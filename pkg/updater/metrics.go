@@ -0,0 +1,174 @@
+package updater
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const metricPrefix = "flowercare_"
+
+var readLabelNames = []string{
+	"macaddress",
+	"name",
+}
+
+type readMetrics struct {
+	duration       *prometheus.HistogramVec
+	errors         *prometheus.CounterVec
+	advertisements *prometheus.CounterVec
+	backfilled     *prometheus.CounterVec
+	nextUpdate     *prometheus.Desc
+	backoff        *prometheus.Desc
+	down           *prometheus.Desc
+	state          *prometheus.Desc
+	lastError      *prometheus.Desc
+	lastErrorTime  *prometheus.Desc
+}
+
+func newReadMetrics(adapter string) readMetrics {
+	labels := prometheus.Labels{
+		"adapter": adapter,
+	}
+
+	return readMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        metricPrefix + "read_duration_seconds",
+			Help:        "Duration of reading data from a sensor.",
+			ConstLabels: labels,
+			Buckets:     []float64{0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+		}, readLabelNames),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        metricPrefix + "read_errors_total",
+			Help:        "Number of failed reads of a sensor.",
+			ConstLabels: labels,
+		}, readLabelNames),
+		advertisements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        metricPrefix + "advertisements_total",
+			Help:        "Number of advertisements received from a sensor while scanning.",
+			ConstLabels: labels,
+		}, readLabelNames),
+		backfilled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        metricPrefix + "backfilled_records_total",
+			Help:        "Number of history records read from a sensor for filling gaps in its readings.",
+			ConstLabels: labels,
+		}, readLabelNames),
+		nextUpdate: prometheus.NewDesc(
+			metricPrefix+"next_update_timestamp",
+			"Time of the next scheduled read attempt of a sensor as Unix timestamp.",
+			readLabelNames, labels),
+		backoff: prometheus.NewDesc(
+			metricPrefix+"retry_backoff_seconds",
+			"Current wait time between retries of a sensor after failed reads. Zero if the last read was successful.",
+			readLabelNames, labels),
+		down: prometheus.NewDesc(
+			metricPrefix+"sensor_down",
+			"Set to 1 if the sensor exceeded the retry limits and is only probed at a slow interval, 0 otherwise.",
+			readLabelNames, labels),
+		state: prometheus.NewDesc(
+			metricPrefix+"sensor_state",
+			"Health of the sensor based on its recent reads. Set to 1 for the current state, 0 for the others.",
+			append(readLabelNames, "state"), labels),
+		lastError: prometheus.NewDesc(
+			metricPrefix+"last_error_info",
+			"Describes the most recent failed read of a sensor by the stage and the reason of the failure. Value set to 1.",
+			append(readLabelNames, "stage", "reason"), labels),
+		lastErrorTime: prometheus.NewDesc(
+			metricPrefix+"last_error_timestamp",
+			"Time of the most recent failed read of a sensor as Unix timestamp.",
+			readLabelNames, labels),
+	}
+}
+
+// observe records the result of a read. If the read was traced, the trace ID is attached as an exemplar.
+func (m readMetrics) observe(span trace.Span, labels []string, seconds float64, err error) {
+	exemplar := traceExemplar(span)
+
+	observer := m.duration.WithLabelValues(labels...)
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(seconds, exemplar)
+	} else {
+		observer.Observe(seconds)
+	}
+
+	if err == nil {
+		return
+	}
+
+	counter := m.errors.WithLabelValues(labels...)
+	if ea, ok := counter.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		ea.AddWithExemplar(1, exemplar)
+	} else {
+		counter.Inc()
+	}
+}
+
+func traceExemplar(span trace.Span) prometheus.Labels {
+	sc := span.SpanContext()
+	if !sc.IsValid() || !sc.IsSampled() {
+		return nil
+	}
+
+	return prometheus.Labels{
+		"trace_id": sc.TraceID().String(),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (u *Updater) Describe(ch chan<- *prometheus.Desc) {
+	u.metrics.duration.Describe(ch)
+	u.metrics.errors.Describe(ch)
+	u.metrics.advertisements.Describe(ch)
+	u.metrics.backfilled.Describe(ch)
+	ch <- u.metrics.nextUpdate
+	ch <- u.metrics.backoff
+	ch <- u.metrics.down
+	ch <- u.metrics.state
+	ch <- u.metrics.lastError
+	ch <- u.metrics.lastErrorTime
+}
+
+// Collect implements prometheus.Collector
+func (u *Updater) Collect(ch chan<- prometheus.Metric) {
+	u.metrics.duration.Collect(ch)
+	u.metrics.errors.Collect(ch)
+	u.metrics.advertisements.Collect(ch)
+	u.metrics.backfilled.Collect(ch)
+
+	u.dataLock.RLock()
+	for _, d := range u.dataMap {
+		down := 0.0
+		if d.Down {
+			down = 1
+		}
+		ch <- prometheus.MustNewConstMetric(u.metrics.down, prometheus.GaugeValue,
+			down, d.Info.MacAddress, d.Info.Name)
+
+		for _, state := range sensorStates {
+			value := 0.0
+			if d.State == state {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(u.metrics.state, prometheus.GaugeValue,
+				value, d.Info.MacAddress, d.Info.Name, string(state))
+		}
+
+		if d.LastError != nil {
+			stage, reason := describeFailure(d.LastError)
+			ch <- prometheus.MustNewConstMetric(u.metrics.lastError, prometheus.GaugeValue,
+				1, d.Info.MacAddress, d.Info.Name, stage, reason)
+			ch <- prometheus.MustNewConstMetric(u.metrics.lastErrorTime, prometheus.GaugeValue,
+				float64(d.LastErrorTime.Unix()), d.Info.MacAddress, d.Info.Name)
+		}
+	}
+	u.dataLock.RUnlock()
+
+	u.queueLock.RLock()
+	defer u.queueLock.RUnlock()
+
+	for _, item := range u.queue {
+		ch <- prometheus.MustNewConstMetric(u.metrics.nextUpdate, prometheus.GaugeValue,
+			float64(item.Time.Unix()), item.Sensor.MacAddress, item.Sensor.Name)
+		ch <- prometheus.MustNewConstMetric(u.metrics.backoff, prometheus.GaugeValue,
+			item.LastRetry.Seconds(), item.Sensor.MacAddress, item.Sensor.Name)
+	}
+}
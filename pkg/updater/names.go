@@ -0,0 +1,70 @@
+package updater
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^\p{L}\p{N}_.-]+`)
+
+// sanitizeName replaces whitespace and special characters in a device name, so that it can be used as a label.
+func sanitizeName(name string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+// refreshName reads the device name of a sensor without a configured name, if it has not been read within the name
+// interval. The sanitized device name is used as the name of the sensor. It needs to be called while holding the
+// adapter lock.
+func (u *Updater) refreshName(ctx context.Context, sensor Sensor, now time.Time) {
+	if u.namer == nil || u.nameInterval == 0 || !u.nameDue(sensor, now) {
+		return
+	}
+
+	// The adapter has just been used for reading the sensor.
+	u.lastAdapterUse = time.Now()
+	if !u.waitCooldown(ctx) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.refreshTimeout)
+	defer cancel()
+
+	u.log.Debugf("Reading device name of %q", sensor)
+	raw, err := u.namer.DeviceName(ctx, sensor.Address())
+	if err != nil {
+		u.log.Warnf("Error reading device name of %q: %s", sensor, err)
+		return
+	}
+
+	name := sanitizeName(raw)
+	if name == "" {
+		return
+	}
+
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	if !ok || d.Info.Name == name {
+		return
+	}
+
+	u.log.Infof("Using device name %q for sensor %s", name, sensor.MacAddress)
+	d.Info.Name = name
+}
+
+// nameDue checks if the device name of the sensor should be read and records the attempt.
+func (u *Updater) nameDue(sensor Sensor, now time.Time) bool {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	if !ok || !d.Unnamed || now.Sub(d.NameRead) < u.nameInterval {
+		return false
+	}
+
+	d.NameRead = now
+	return true
+}
@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors returned for on-demand operations, like Diagnose and Locate, which are rejected to protect the scheduled reads.
+var (
+	ErrOnDemandBusy        = errors.New("another on-demand operation is already queued")
+	ErrOnDemandLimit       = errors.New("limit of on-demand operations per minute reached")
+	ErrReadBudgetExhausted = errors.New("read budget is exhausted")
+)
+
+// IsRejected returns true if the on-demand operation was rejected, because too many operations were requested.
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrOnDemandBusy) || errors.Is(err, ErrOnDemandLimit) || errors.Is(err, ErrReadBudgetExhausted)
+}
+
+// onDemandLimiter limits the operations using the adapter outside of the update loop. Only one operation can wait for
+// the adapter at a time.
+type onDemandLimiter struct {
+	perMinute int
+	slot      chan struct{}
+
+	lock   sync.Mutex
+	recent []time.Time
+}
+
+func newOnDemandLimiter(perMinute int) *onDemandLimiter {
+	return &onDemandLimiter{
+		perMinute: perMinute,
+		slot:      make(chan struct{}, 1),
+	}
+}
+
+// tryAcquire takes the slot without waiting. The returned function releases the slot.
+func (l *onDemandLimiter) tryAcquire(now time.Time) (func(), error) {
+	select {
+	case l.slot <- struct{}{}:
+	default:
+		return nil, ErrOnDemandBusy
+	}
+	release := func() {
+		<-l.slot
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.perMinute > 0 {
+		cutoff := now.Add(-time.Minute)
+		i := 0
+		for i < len(l.recent) && !l.recent[i].After(cutoff) {
+			i++
+		}
+		l.recent = l.recent[i:]
+
+		if len(l.recent) >= l.perMinute {
+			release()
+			return nil, ErrOnDemandLimit
+		}
+		l.recent = append(l.recent, now)
+	}
+
+	return release, nil
+}
+
+// startOnDemand checks the limits for an on-demand operation and counts it against the read budget. The returned
+// function needs to be called once the operation is done.
+func (u *Updater) startOnDemand(now time.Time) (func(), error) {
+	release, err := u.onDemand.tryAcquire(now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.budget.available(now) {
+		release()
+		return nil, ErrReadBudgetExhausted
+	}
+	u.budget.record(now)
+
+	return release, nil
+}
@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsRejected(t *testing.T) {
+	tests := []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{
+			desc: "no error",
+			err:  nil,
+			want: false,
+		},
+		{
+			desc: "busy",
+			err:  ErrOnDemandBusy,
+			want: true,
+		},
+		{
+			desc: "wrapped limit",
+			err:  fmt.Errorf("can not diagnose sensor: %w", ErrOnDemandLimit),
+			want: true,
+		},
+		{
+			desc: "wrapped budget",
+			err:  fmt.Errorf("can not locate sensor: %w", ErrReadBudgetExhausted),
+			want: true,
+		},
+		{
+			desc: "other error",
+			err:  errors.New("connection failed"),
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := IsRejected(tc.err); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOnDemandLimiter(t *testing.T) {
+	type attempt struct {
+		offset time.Duration
+		// release releases the slot after the operation succeeded.
+		release bool
+		wantErr error
+	}
+
+	tests := []struct {
+		desc      string
+		perMinute int
+		attempts  []attempt
+	}{
+		{
+			desc:      "no limit",
+			perMinute: 0,
+			attempts: []attempt{
+				{offset: 0, release: true},
+				{offset: time.Second, release: true},
+				{offset: 2 * time.Second, release: true},
+			},
+		},
+		{
+			desc:      "only one operation at a time",
+			perMinute: 0,
+			attempts: []attempt{
+				{offset: 0},
+				{offset: time.Second, wantErr: ErrOnDemandBusy},
+			},
+		},
+		{
+			desc:      "limit per minute",
+			perMinute: 2,
+			attempts: []attempt{
+				{offset: 0, release: true},
+				{offset: 10 * time.Second, release: true},
+				{offset: 20 * time.Second, wantErr: ErrOnDemandLimit},
+				{offset: 59 * time.Second, wantErr: ErrOnDemandLimit},
+				{offset: time.Minute, release: true},
+				{offset: 65 * time.Second, wantErr: ErrOnDemandLimit},
+				{offset: 70 * time.Second, release: true},
+			},
+		},
+		{
+			desc:      "rejected operation releases slot",
+			perMinute: 1,
+			attempts: []attempt{
+				{offset: 0, release: true},
+				{offset: time.Second, wantErr: ErrOnDemandLimit},
+				{offset: time.Minute},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			l := newOnDemandLimiter(tc.perMinute)
+			for i, a := range tc.attempts {
+				release, err := l.tryAcquire(testStart.Add(a.offset))
+				if !errors.Is(err, a.wantErr) {
+					t.Fatalf("got error %v for attempt %d, want %v", err, i, a.wantErr)
+				}
+
+				if err == nil && a.release {
+					release()
+				}
+			}
+		})
+	}
+}
+
+func TestStartOnDemand(t *testing.T) {
+	u, _ := newTestUpdater(Options{
+		ReadBudget: ReadBudgetConfig{
+			PerCycle:          1,
+			OnDemandPerMinute: 5,
+		},
+	}, plausibleReader, 0)
+
+	release, err := u.startOnDemand(testStart)
+	if err != nil {
+		t.Fatalf("got error %q", err)
+	}
+	release()
+
+	if u.budget.available(testStart) {
+		t.Error("got on-demand operation not counted against read budget")
+	}
+
+	if _, err := u.startOnDemand(testStart.Add(time.Second)); !errors.Is(err, ErrReadBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, ErrReadBudgetExhausted)
+	}
+
+	// The slot is released when the budget is exhausted.
+	u.budget.newCycle()
+	if _, err := u.startOnDemand(testStart.Add(2 * time.Second)); err != nil {
+		t.Errorf("got error %q", err)
+	}
+}
@@ -0,0 +1,146 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// SourcePassive is the source of data, which has been assembled from the values broadcast by a sensor.
+const SourcePassive = "passive"
+
+// sensorFields are the values, which need to be broadcast before the data of a passive sensor is complete.
+var sensorFields = []miflora.BeaconField{
+	miflora.BeaconTemperature,
+	miflora.BeaconMoisture,
+	miflora.BeaconLight,
+	miflora.BeaconConductivity,
+}
+
+// broadcast collects the values broadcast by a passive sensor until they are read.
+type broadcast struct {
+	Data     miflora.Data
+	Received map[miflora.BeaconField]time.Time
+	// Read is the time of the newest value, which has already been read.
+	Read time.Time
+}
+
+// isPassive returns true if the sensor is only read from the values it broadcasts.
+func (u *Updater) isPassive(sensor Sensor) bool {
+	if passive, ok := u.passiveSensors[strings.ToUpper(sensor.MacAddress)]; ok {
+		return passive
+	}
+	return u.passive
+}
+
+// collectBroadcast stores a value broadcast by a passive sensor. It needs to be called while holding the data lock.
+func collectBroadcast(d *data, beacon miflora.Beacon, now time.Time) {
+	if d.Broadcast == nil {
+		d.Broadcast = &broadcast{
+			Received: map[miflora.BeaconField]time.Time{},
+		}
+	}
+
+	beacon.Apply(&d.Broadcast.Data)
+	d.Broadcast.Received[beacon.Field] = now
+}
+
+// readBroadcast returns the values broadcast by a passive sensor. It returns an error if not all values have been
+// received yet or if nothing has been received since the last read.
+func (u *Updater) readBroadcast(sensor Sensor) (miflora.Data, error) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	if !ok {
+		return miflora.Data{}, fmt.Errorf("sensor has been removed: %s", sensor.MacAddress)
+	}
+
+	b := d.Broadcast
+	if b == nil {
+		return miflora.Data{}, errors.New("no values broadcast yet")
+	}
+
+	for _, field := range sensorFields {
+		if _, ok := b.Received[field]; !ok {
+			return miflora.Data{}, fmt.Errorf("%s has not been broadcast yet", field)
+		}
+	}
+
+	var newest time.Time
+	for _, t := range b.Received {
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	if !newest.After(b.Read) {
+		return miflora.Data{}, fmt.Errorf("no values broadcast since %s", b.Read.Format(time.RFC3339))
+	}
+
+	b.Read = newest
+	data := b.Data
+	data.Time = newest
+	return data, nil
+}
+
+// setBroadcastUpdated records the time each value of a passive sensor has been received. It overwrites the times
+// recorded when storing the data, as the values are not all updated at once.
+func (u *Updater) setBroadcastUpdated(sensor Sensor) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	if !ok || d.Broadcast == nil {
+		return
+	}
+
+	d.Updated = make(map[miflora.BeaconField]time.Time, len(d.Broadcast.Received))
+	for field, t := range d.Broadcast.Received {
+		d.Updated[field] = t
+	}
+}
+
+// readPassive updates a passive sensor using the values it has broadcast. The adapter is not used for this.
+func (u *Updater) readPassive(item queueItem, now time.Time) {
+	u.recordAttempt(item.Sensor, now)
+	if err := u.updatePassive(item.Sensor, item.Retries); err != nil {
+		u.failedRead(item, err, now)
+	}
+}
+
+func (u *Updater) updatePassive(sensor Sensor, retries int) error {
+	data, err := u.readBroadcast(sensor)
+	if err != nil {
+		return &failure{
+			Stage:  miflora.StageRead,
+			Reason: reasonError,
+			Err:    err,
+		}
+	}
+
+	if err := data.Validate(); err != nil {
+		return &failure{
+			Stage:  stageValidate,
+			Reason: reasonImplausible,
+			Err:    fmt.Errorf("implausible data: %s", err),
+		}
+	}
+
+	sensor, err = u.storeData(sensor, data, ReadInfo{
+		Adapter: u.deviceName,
+		Source:  SourcePassive,
+		Retries: retries,
+	})
+	if err != nil {
+		return err
+	}
+	u.setBroadcastUpdated(sensor)
+
+	if u.onData != nil {
+		u.onData(sensor, data)
+	}
+	return nil
+}
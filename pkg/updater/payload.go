@@ -0,0 +1,99 @@
+package updater
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// payload is the last data returned by a sensor, which could be parsed or not.
+type payload struct {
+	Time time.Time
+	Raw  miflora.RawData
+	// Data is nil if the payload could not be parsed.
+	Data *miflora.Data
+	Err  error
+}
+
+// Payload contains the last raw payloads read from a sensor together with the values parsed from them.
+type Payload struct {
+	MacAddress string        `json:"macAddress"`
+	Name       string        `json:"name"`
+	Time       time.Time     `json:"time"`
+	Firmware   string        `json:"firmware,omitempty"`
+	Sensors    string        `json:"sensors,omitempty"`
+	Data       *miflora.Data `json:"data,omitempty"`
+	// Error is set if the payload could not be parsed or the parsed values are implausible.
+	Error string `json:"error,omitempty"`
+}
+
+// Payloads returns the last payloads of all sensors ordered by their MAC address. Sensors which did not return a
+// payload yet are left out. The times are converted to the time zone of the location.
+func (u *Updater) Payloads(location *time.Location) []Payload {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	result := []Payload{}
+	for _, d := range u.dataMap {
+		p := d.Payload
+		if p == nil {
+			continue
+		}
+
+		payload := Payload{
+			MacAddress: d.Info.MacAddress,
+			Name:       d.Info.Name,
+			Time:       p.Time.In(location),
+			Firmware:   hex.EncodeToString(p.Raw.Firmware),
+			Sensors:    hex.EncodeToString(p.Raw.Sensors),
+		}
+		if p.Data != nil {
+			data := *p.Data
+			data.Time = data.Time.In(location)
+			data.Raw = miflora.RawData{}
+			payload.Data = &data
+		}
+		if p.Err != nil {
+			payload.Error = p.Err.Error()
+		}
+		result = append(result, payload)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MacAddress < result[j].MacAddress
+	})
+	return result
+}
+
+// storePayload keeps the payload returned by the sensor. It is called with the data or the error of a read.
+func (u *Updater) storePayload(sensor Sensor, data miflora.Data, err error) {
+	var p *payload
+	var parseErr *miflora.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		p = &payload{
+			Time: time.Now(),
+			Raw:  parseErr.Raw,
+			Err:  parseErr.Err,
+		}
+	case err != nil:
+		return
+	default:
+		p = &payload{
+			Time: data.Time,
+			Raw:  data.Raw,
+			Data: &data,
+			Err:  data.Validate(),
+		}
+	}
+
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	if d, ok := u.dataMap[sensor.MacAddress]; ok {
+		d.Payload = p
+	}
+}
@@ -0,0 +1,187 @@
+package updater
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/xperimental/flowercare-exporter/pkg/diagnose"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// Reader reads the current data of a single sensor.
+type Reader interface {
+	ReadData(ctx context.Context, macAddress string) (miflora.Data, error)
+}
+
+// Scanner scans for sensors in range of the adapter.
+type Scanner interface {
+	Scan(ctx context.Context, duration time.Duration, handler func(miflora.Advertisement)) error
+}
+
+// DiagnoseReport contains the results of a connectivity check.
+type DiagnoseReport = diagnose.Report
+
+// Diagnoser runs connectivity checks of sensors.
+type Diagnoser interface {
+	Diagnose(ctx context.Context, macAddress string, scanDuration time.Duration) diagnose.Report
+}
+
+// Namer reads the name stored on a sensor.
+type Namer interface {
+	DeviceName(ctx context.Context, macAddress string) (string, error)
+}
+
+// Registry records the sensors found while scanning and the ones registered automatically.
+type Registry interface {
+	Seen(macAddress string, now time.Time) error
+	Registered(macAddress, name string, now time.Time) error
+}
+
+// HistoryReader reads the history records stored on a sensor.
+type HistoryReader interface {
+	ReadHistory(ctx context.Context, macAddress string) ([]miflora.HistoryRecord, error)
+}
+
+// Blinker makes the LED of a sensor blink.
+type Blinker interface {
+	Blink(ctx context.Context, macAddress string) error
+}
+
+// SharedLock is an exclusive lock on the adapter, which is shared with other processes.
+type SharedLock interface {
+	Lock(ctx context.Context) error
+	Unlock() error
+}
+
+// DeviceReader reads data from sensors using a Bluetooth device. It can also be used as a Scanner, Diagnoser, Namer,
+// HistoryReader and Blinker. The device is not released by the reader, this is left to the code which opened it.
+type DeviceReader struct {
+	// Log is optional. If set, debug messages about reading the sensors are written to it.
+	Log    *slog.Logger
+	Device ble.Device
+	// FirmwareInterval is the interval for reading the firmware version and battery level of a sensor.
+	// In between, the last values are reused. If zero, they are read every time.
+	FirmwareInterval time.Duration
+	// SkipRealtimeMode disables enabling the realtime measurement before reading the sensor values.
+	SkipRealtimeMode bool
+	// ScanDuration enables scanning for the sensor before connecting to it. MinRSSI is the minimum signal strength
+	// needed for connecting. See miflora.ReadOptions.
+	ScanDuration time.Duration
+	MinRSSI      int
+	// Lenient accepts sensor data of unexpected lengths sent by clone devices.
+	Lenient bool
+	// Layouts contains the layouts of the sensor data of clone devices by their MAC address.
+	Layouts map[string]miflora.Layout
+
+	firmwareLock sync.Mutex
+	firmware     map[string]cachedFirmware
+}
+
+type cachedFirmware struct {
+	Firmware miflora.Firmware
+	Time     time.Time
+}
+
+// ReadData implements Reader
+func (r *DeviceReader) ReadData(ctx context.Context, macAddress string) (miflora.Data, error) {
+	cached, haveFirmware := r.cachedFirmware(macAddress)
+
+	data, err := miflora.ReadDataWithOptions(ctx, r.Log, r.Device, macAddress, miflora.ReadOptions{
+		SkipFirmware:     haveFirmware,
+		SkipRealtimeMode: r.SkipRealtimeMode,
+		ScanDuration:     r.ScanDuration,
+		MinRSSI:          r.MinRSSI,
+		Lenient:          r.Lenient,
+		Layout:           r.layout(macAddress),
+	})
+	if err != nil {
+		return miflora.Data{}, err
+	}
+
+	if haveFirmware {
+		data.Firmware = cached
+	} else {
+		r.storeFirmware(macAddress, data.Firmware, data.Time)
+	}
+
+	return data, nil
+}
+
+func (r *DeviceReader) layout(macAddress string) *miflora.Layout {
+	layout, ok := r.Layouts[strings.ToUpper(macAddress)]
+	if !ok {
+		return nil
+	}
+
+	return &layout
+}
+
+func (r *DeviceReader) cachedFirmware(macAddress string) (miflora.Firmware, bool) {
+	if r.FirmwareInterval == 0 {
+		return miflora.Firmware{}, false
+	}
+
+	r.firmwareLock.Lock()
+	defer r.firmwareLock.Unlock()
+
+	c, ok := r.firmware[macAddress]
+	if !ok || time.Since(c.Time) >= r.FirmwareInterval {
+		return miflora.Firmware{}, false
+	}
+
+	return c.Firmware, true
+}
+
+func (r *DeviceReader) storeFirmware(macAddress string, firmware miflora.Firmware, now time.Time) {
+	if r.FirmwareInterval == 0 {
+		return
+	}
+
+	r.firmwareLock.Lock()
+	defer r.firmwareLock.Unlock()
+
+	if r.firmware == nil {
+		r.firmware = map[string]cachedFirmware{}
+	}
+	r.firmware[macAddress] = cachedFirmware{
+		Firmware: firmware,
+		Time:     now,
+	}
+}
+
+// DeviceName implements Namer
+func (r *DeviceReader) DeviceName(ctx context.Context, macAddress string) (string, error) {
+	return miflora.DeviceName(ctx, r.Device, macAddress)
+}
+
+// Blink implements Blinker
+func (r *DeviceReader) Blink(ctx context.Context, macAddress string) error {
+	return miflora.Blink(ctx, r.Device, macAddress)
+}
+
+// ReadHistory implements HistoryReader
+func (r *DeviceReader) ReadHistory(ctx context.Context, macAddress string) ([]miflora.HistoryRecord, error) {
+	return miflora.ReadHistory(ctx, r.Device, macAddress)
+}
+
+// Scan implements Scanner
+func (r *DeviceReader) Scan(ctx context.Context, duration time.Duration, handler func(miflora.Advertisement)) error {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	return miflora.Scan(ctx, r.Device, handler)
+}
+
+// Diagnose implements Diagnoser
+func (r *DeviceReader) Diagnose(ctx context.Context, macAddress string, scanDuration time.Duration) diagnose.Report {
+	return diagnose.Run(ctx, "", func() (ble.Device, error) {
+		return r.Device, nil
+	}, macAddress, scanDuration, miflora.ReadOptions{
+		Lenient: r.Lenient,
+		Layout:  r.layout(macAddress),
+	})
+}
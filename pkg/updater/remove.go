@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"sort"
+	"time"
+)
+
+// RemoveSensor stops reading the sensor identified by its MAC address. Its last data is kept for the removal grace
+// period, so that it can still be exported flagged as removed. It returns false if the sensor is not registered.
+func (u *Updater) RemoveSensor(macAddress string, now time.Time) bool {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	d, ok := u.dataMap[macAddress]
+	if !ok || !d.Removed.IsZero() {
+		return false
+	}
+
+	u.queueLock.Lock()
+	delete(u.queue, macAddress)
+	u.queueLock.Unlock()
+
+	if u.removalGrace == 0 {
+		u.log.Infof("Removing sensor %q", d.Info)
+		delete(u.dataMap, macAddress)
+		return true
+	}
+
+	u.log.Infof("Removing sensor %q, keeping its data for %s", d.Info, u.removalGrace)
+	d.Removed = now
+	return true
+}
+
+// Removed returns the sensors which have been removed, but are still within the removal grace period, ordered by their
+// MAC address.
+func (u *Updater) Removed() []Sensor {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	result := []Sensor{}
+	for _, d := range u.dataMap {
+		if !d.Removed.IsZero() {
+			result = append(result, d.Info)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MacAddress < result[j].MacAddress
+	})
+	return result
+}
+
+// isRemoved returns true if the sensor is not registered or has been removed.
+func (u *Updater) isRemoved(sensor Sensor) bool {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	d, ok := u.dataMap[sensor.MacAddress]
+	return !ok || !d.Removed.IsZero()
+}
+
+// forgetRemoved drops the data of removed sensors once the removal grace period has passed.
+func (u *Updater) forgetRemoved(now time.Time) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	for mac, d := range u.dataMap {
+		if d.Removed.IsZero() || now.Sub(d.Removed) < u.removalGrace {
+			continue
+		}
+
+		u.log.Debugf("Forgetting removed sensor %q", d.Info)
+		delete(u.dataMap, mac)
+	}
+}
@@ -0,0 +1,145 @@
+package updater
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// Sighting contains information about a sensor seen while scanning.
+type Sighting struct {
+	MacAddress string
+	Name       string
+	RSSI       int
+	LastSeen   time.Time
+}
+
+// Unconfigured returns the sensors which have been seen while scanning but are not registered.
+func (u *Updater) Unconfigured() []Sighting {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+	u.seenLock.RLock()
+	defer u.seenLock.RUnlock()
+
+	configured := map[string]bool{}
+	for mac, d := range u.dataMap {
+		configured[strings.ToUpper(mac)] = true
+		configured[strings.ToUpper(d.Info.Address())] = true
+	}
+
+	result := []Sighting{}
+	for mac, s := range u.seen {
+		if configured[mac] {
+			continue
+		}
+
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MacAddress < result[j].MacAddress
+	})
+	return result
+}
+
+func (u *Updater) scanDue(now time.Time) bool {
+	if u.scanner == nil || u.scanInterval == 0 {
+		return false
+	}
+
+	return !now.Before(u.nextScan)
+}
+
+func (u *Updater) scan(ctx context.Context, now time.Time) {
+	u.nextScan = now.Add(u.scanInterval)
+
+	u.log.Debugf("Scanning for sensors on %q for %s", u.deviceName, u.scanDuration)
+	err := u.scanner.Scan(ctx, u.scanDuration, func(a miflora.Advertisement) {
+		u.recordSighting(a)
+
+		if a.Beacon != nil {
+			u.storeBeacon(a.MacAddress, *a.Beacon, time.Now())
+		}
+
+		if u.autoRegister.Enabled {
+			u.autoRegisterSensor(a.MacAddress)
+		}
+	})
+	if err != nil {
+		u.log.Errorf("Error scanning for sensors on %q: %s", u.deviceName, err)
+	}
+}
+
+func (u *Updater) recordSighting(a miflora.Advertisement) {
+	now := time.Now()
+
+	name := a.Name
+	if sensor, ok := u.registeredSensor(a.MacAddress); ok {
+		name = sensor.Name
+	}
+	u.metrics.advertisements.WithLabelValues(a.MacAddress, name).Inc()
+
+	u.seenLock.Lock()
+	_, known := u.seen[a.MacAddress]
+	u.seen[a.MacAddress] = Sighting{
+		MacAddress: a.MacAddress,
+		Name:       a.Name,
+		RSSI:       a.RSSI,
+		LastSeen:   now,
+	}
+	u.seenLock.Unlock()
+
+	if known {
+		return
+	}
+	u.log.Debugf("Found sensor %s (RSSI %d)", a.MacAddress, a.RSSI)
+
+	if u.registry != nil {
+		if err := u.registry.Seen(a.MacAddress, now); err != nil {
+			u.log.Errorf("Error saving registry: %s", err)
+		}
+	}
+}
+
+// autoRegisterSensor registers a newly discovered sensor, if it is allowed by the auto-registration rules.
+func (u *Updater) autoRegisterSensor(macAddress string) {
+	if u.isRegistered(macAddress) || !u.autoRegister.Matches(macAddress) {
+		return
+	}
+
+	sensor := Sensor{
+		Name:       u.autoRegister.DefaultName(macAddress),
+		MacAddress: macAddress,
+	}
+	u.log.Infof("Auto-registering sensor %q", sensor)
+	u.AddSensor(sensor)
+	u.scheduleUpdate(sensor, time.Now())
+
+	if u.registry != nil {
+		if err := u.registry.Registered(sensor.MacAddress, sensor.Name, time.Now()); err != nil {
+			u.log.Errorf("Error saving registry: %s", err)
+		}
+	}
+}
+
+func (u *Updater) isRegistered(macAddress string) bool {
+	_, ok := u.registeredSensor(macAddress)
+	return ok
+}
+
+// registeredSensor returns the sensor registered with the MAC address or using it as device address.
+func (u *Updater) registeredSensor(macAddress string) (Sensor, bool) {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	for mac, d := range u.dataMap {
+		if strings.EqualFold(mac, macAddress) || strings.EqualFold(d.Info.Address(), macAddress) {
+			return d.Info, true
+		}
+	}
+
+	return Sensor{}, false
+}
@@ -0,0 +1,54 @@
+package updater
+
+// SensorState describes the health of a sensor based on its recent reads.
+type SensorState string
+
+// States of a sensor. A sensor is degraded after a number of failed reads and down once it exceeded the retry limits.
+// After a successful read, a degraded or down sensor is recovering until it has been read successfully enough times.
+const (
+	StateOK         SensorState = "ok"
+	StateDegraded   SensorState = "degraded"
+	StateDown       SensorState = "down"
+	StateRecovering SensorState = "recovering"
+)
+
+// sensorStates contains all states in the order they are exported.
+var sensorStates = []SensorState{
+	StateOK,
+	StateDegraded,
+	StateDown,
+	StateRecovering,
+}
+
+// stateMachine advances the state of sensors after reads.
+type stateMachine struct {
+	config HealthConfig
+}
+
+// failed returns the state after a failed read.
+func (m stateMachine) failed(current SensorState, failures int, down bool) SensorState {
+	switch {
+	case down:
+		return StateDown
+	case failures >= m.config.DegradedAfter:
+		return StateDegraded
+	case current == StateRecovering:
+		return StateDegraded
+	default:
+		return current
+	}
+}
+
+// succeeded returns the state after a successful read. successes is the number of consecutive successful reads.
+func (m stateMachine) succeeded(current SensorState, successes int) SensorState {
+	switch current {
+	case StateDegraded, StateDown, StateRecovering:
+		if successes >= m.config.RecoverAfter {
+			return StateOK
+		}
+
+		return StateRecovering
+	default:
+		return StateOK
+	}
+}
@@ -0,0 +1,154 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+func TestStateMachine(t *testing.T) {
+	m := stateMachine{
+		config: HealthConfig{
+			DegradedAfter: 2,
+			RecoverAfter:  3,
+		},
+	}
+
+	tests := []struct {
+		desc    string
+		current SensorState
+		// failures is the number of consecutive failed reads if the read failed, otherwise successes is the number of
+		// consecutive successful reads.
+		failed    bool
+		failures  int
+		down      bool
+		successes int
+		want      SensorState
+	}{
+		{
+			desc:     "first failure",
+			current:  StateOK,
+			failed:   true,
+			failures: 1,
+			want:     StateOK,
+		},
+		{
+			desc:     "degraded after failures",
+			current:  StateOK,
+			failed:   true,
+			failures: 2,
+			want:     StateDegraded,
+		},
+		{
+			desc:     "down",
+			current:  StateDegraded,
+			failed:   true,
+			failures: 5,
+			down:     true,
+			want:     StateDown,
+		},
+		{
+			desc:     "failure while recovering",
+			current:  StateRecovering,
+			failed:   true,
+			failures: 1,
+			want:     StateDegraded,
+		},
+		{
+			desc:      "success",
+			current:   StateOK,
+			successes: 1,
+			want:      StateOK,
+		},
+		{
+			desc:      "degraded starts recovering",
+			current:   StateDegraded,
+			successes: 1,
+			want:      StateRecovering,
+		},
+		{
+			desc:      "down starts recovering",
+			current:   StateDown,
+			successes: 1,
+			want:      StateRecovering,
+		},
+		{
+			desc:      "still recovering",
+			current:   StateRecovering,
+			successes: 2,
+			want:      StateRecovering,
+		},
+		{
+			desc:      "recovered",
+			current:   StateRecovering,
+			successes: 3,
+			want:      StateOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got SensorState
+			if tc.failed {
+				got = m.failed(tc.current, tc.failures, tc.down)
+			} else {
+				got = m.succeeded(tc.current, tc.successes)
+			}
+
+			if got != tc.want {
+				t.Errorf("got state %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdaterSensorState(t *testing.T) {
+	fail := false
+	source := readerFunc(func(ctx context.Context, macAddress string) (miflora.Data, error) {
+		if fail {
+			return miflora.Data{}, errors.New("connection failed")
+		}
+
+		return plausibleReader(ctx, macAddress)
+	})
+
+	u, _ := newTestUpdater(Options{
+		Retry: RetryConfig{
+			MaxRetries: 3,
+		},
+		Health: HealthConfig{
+			DegradedAfter: 2,
+			RecoverAfter:  2,
+		},
+	}, source, 0)
+	sensor := testSensors[0]
+
+	steps := []struct {
+		fail bool
+		want SensorState
+	}{
+		{fail: true, want: StateOK},
+		{fail: true, want: StateDegraded},
+		{fail: true, want: StateDegraded},
+		{fail: true, want: StateDown},
+		{fail: false, want: StateRecovering},
+		{fail: true, want: StateDegraded},
+		{fail: false, want: StateRecovering},
+		{fail: false, want: StateOK},
+	}
+
+	for i, s := range steps {
+		now := testStart.Add(time.Duration(i) * time.Minute)
+		fail = s.fail
+		if err := u.updateWithWatchdog(context.Background(), sensor, 0); err != nil {
+			u.recordError(sensor, err, now)
+		}
+
+		if got := u.dataMap[sensor.MacAddress].State; got != s.want {
+			t.Errorf("got state %q after step %d, want %q", got, i, s.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package updater
+
+import (
+	"sort"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+// Status contains the internal state of the updater.
+type Status struct {
+	Adapter     AdapterStatus  `json:"adapter"`
+	QueueLength int            `json:"queueLength"`
+	Sensors     []SensorStatus `json:"sensors"`
+}
+
+// AdapterStatus contains the state of the adapter used for reading data.
+type AdapterStatus struct {
+	Name    string `json:"name"`
+	Suspect bool   `json:"suspect"`
+}
+
+// SensorStatus contains the state of a single sensor.
+type SensorStatus struct {
+	MacAddress      string      `json:"macAddress"`
+	Name            string      `json:"name"`
+	Device          string      `json:"device,omitempty"`
+	LastAttempt     *time.Time  `json:"lastAttempt,omitempty"`
+	LastSuccess     *time.Time  `json:"lastSuccess,omitempty"`
+	LastError       string      `json:"lastError,omitempty"`
+	LastErrorStage  string      `json:"lastErrorStage,omitempty"`
+	LastErrorReason string      `json:"lastErrorReason,omitempty"`
+	LastErrorTime   *time.Time  `json:"lastErrorTime,omitempty"`
+	NextUpdate      *time.Time  `json:"nextUpdate,omitempty"`
+	BackoffSeconds  float64     `json:"backoffSeconds"`
+	Battery         *int        `json:"battery,omitempty"`
+	Down            bool        `json:"down"`
+	State           SensorState `json:"state"`
+	Disabled        bool        `json:"disabled"`
+	Removed         *time.Time  `json:"removed,omitempty"`
+	// Updated contains the time each value has last been updated.
+	Updated map[miflora.BeaconField]time.Time `json:"updated,omitempty"`
+}
+
+// Status returns a snapshot of the internal state of the updater.
+func (u *Updater) Status() Status {
+	result := Status{
+		Adapter: AdapterStatus{
+			Name:    u.deviceName,
+			Suspect: u.AdapterSuspect(),
+		},
+		Sensors: []SensorStatus{},
+	}
+
+	u.queueLock.RLock()
+	result.QueueLength = len(u.queue)
+	queue := make(map[string]queueItem, len(u.queue))
+	for mac, item := range u.queue {
+		queue[mac] = item
+	}
+	u.queueLock.RUnlock()
+
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	for _, d := range u.dataMap {
+		sensor := d.Info
+		status := SensorStatus{
+			MacAddress:  sensor.MacAddress,
+			Name:        sensor.Name,
+			Device:      sensor.Device,
+			LastAttempt: optionalTime(d.LastAttempt),
+			Down:        d.Down,
+			Disabled:    d.Disabled,
+			State:       d.State,
+			Removed:     optionalTime(d.Removed),
+		}
+		if d.Data != nil {
+			status.LastSuccess = optionalTime(d.Data.Time)
+			if d.Data.Firmware.Known() {
+				battery := int(d.Data.Firmware.Battery)
+				status.Battery = &battery
+			}
+			status.Updated = make(map[miflora.BeaconField]time.Time, len(d.Updated))
+			for field, t := range d.Updated {
+				status.Updated[field] = t
+			}
+		}
+		if d.LastError != nil {
+			status.LastError = d.LastError.Error()
+			status.LastErrorStage, status.LastErrorReason = describeFailure(d.LastError)
+			status.LastErrorTime = optionalTime(d.LastErrorTime)
+		}
+		if item, ok := queue[sensor.MacAddress]; ok {
+			status.NextUpdate = optionalTime(item.Time)
+			status.BackoffSeconds = item.LastRetry.Seconds()
+		}
+
+		result.Sensors = append(result.Sensors, status)
+	}
+
+	sort.Slice(result.Sensors, func(i, j int) bool {
+		return result.Sensors[i].MacAddress < result.Sensors[j].MacAddress
+	})
+	return result
+}
+
+// In returns a copy of the status with all times in the time zone.
+func (s Status) In(location *time.Location) Status {
+	sensors := make([]SensorStatus, len(s.Sensors))
+	for i, sensor := range s.Sensors {
+		for _, t := range []**time.Time{&sensor.LastAttempt, &sensor.LastSuccess, &sensor.LastErrorTime, &sensor.NextUpdate, &sensor.Removed} {
+			if *t != nil {
+				*t = optionalTime((*t).In(location))
+			}
+		}
+		if sensor.Updated != nil {
+			updated := make(map[miflora.BeaconField]time.Time, len(sensor.Updated))
+			for field, t := range sensor.Updated {
+				updated[field] = t.In(location)
+			}
+			sensor.Updated = updated
+		}
+		sensors[i] = sensor
+	}
+	s.Sensors = sensors
+
+	return s
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,926 @@
+// Package updater reads the data of Flower Care sensors in the background and caches it. It contains the scheduling of
+// the reads, including retries with backoff, read budgets and health states of the sensors, so that it can be reused
+// by other exporters for Bluetooth LE sensors. The data is obtained using a Reader, for example a DeviceReader using a
+// Bluetooth adapter.
+//
+// The exported API of this package follows semantic versioning: breaking changes are only made with a new major
+// version of the module.
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/internal/logging"
+	"github.com/xperimental/flowercare-exporter/pkg/diagnose"
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	updaterTickDuration = 10 * time.Second
+
+	tracer = otel.Tracer("github.com/xperimental/flowercare-exporter/pkg/updater")
+)
+
+type data struct {
+	Info          Sensor
+	Data          *miflora.Data
+	ReadInfo      ReadInfo
+	LastAttempt   time.Time
+	LastError     error
+	LastErrorTime time.Time
+	// Failures counts the failed reads since the last successful one.
+	Failures     int
+	FirstFailure time.Time
+	// Down is set once the sensor exceeded the retry limits. It is then only probed at a slow interval.
+	Down bool
+	// Successes counts the successful reads since the last failed one.
+	Successes int
+	State     SensorState
+	// Removed is set when the sensor has been removed. Its data is kept until the removal grace period has passed.
+	Removed time.Time
+	// Unnamed is set if no name has been configured for the sensor. Its name is then read from the device.
+	Unnamed  bool
+	NameRead time.Time
+	// Payload is the last payload returned by the sensor, even if it could not be parsed.
+	Payload *payload
+	// Updated contains the time each value has last been updated by a read or a broadcast of the sensor.
+	Updated map[miflora.BeaconField]time.Time
+	// Disabled is set if the sensor should not be read.
+	Disabled bool
+	// Broadcast contains the values broadcast by a passive sensor.
+	Broadcast *broadcast
+}
+
+type queueItem struct {
+	Sensor    Sensor
+	Time      time.Time
+	LastRetry time.Duration
+	Retries   int
+	Down      bool
+}
+
+// ReadInfo contains metadata about how the current data of a sensor was obtained.
+type ReadInfo struct {
+	Adapter string
+	Source  string
+	Retries int
+}
+
+// Defaults used by New for options which are not set.
+const (
+	DefaultRefreshTimeout   = time.Minute
+	DefaultScanDuration     = 10 * time.Second
+	DefaultRetryMinDuration = 30 * time.Second
+	DefaultRetryMaxDuration = 30 * time.Minute
+	DefaultRetryFactor      = 2
+	DefaultDownInterval     = time.Hour
+	DefaultDegradedAfter    = 1
+	DefaultRecoverAfter     = 2
+)
+
+// Options contains the settings of an Updater. Only Reader is required, durations, retry and health settings which
+// are zero are replaced by their defaults.
+type Options struct {
+	// AdapterName identifies the adapter used by the reader in logs and metrics.
+	AdapterName string
+	// Source describes how the reader obtains data, for example "active" for connecting to the sensors.
+	Source string
+	Reader Reader
+	// RefreshTimeout limits a single read. Defaults to DefaultRefreshTimeout.
+	RefreshTimeout time.Duration
+	// WatchdogTimeout is the hard limit for a read, after which it is abandoned. It needs to be longer than
+	// RefreshTimeout and defaults to twice the refresh timeout.
+	WatchdogTimeout time.Duration
+	// Retry defaults to retrying after DefaultRetryMinDuration, backing off by DefaultRetryFactor up to
+	// DefaultRetryMaxDuration.
+	Retry      RetryConfig
+	ReadBudget ReadBudgetConfig
+	// Health defaults to DefaultDegradedAfter and DefaultRecoverAfter.
+	Health HealthConfig
+	// Cooldown is the minimum time between two consecutive uses of the adapter.
+	Cooldown time.Duration
+	// QuietHours is a daily time range during which the adapter is not used.
+	QuietHours QuietHours
+	// SharedLock is optional. If set, it is held while using the adapter to coordinate with other processes.
+	SharedLock SharedLock
+
+	// Scanner is optional. If set, the adapter is periodically scanned for sensors.
+	Scanner      Scanner
+	ScanInterval time.Duration
+	ScanDuration time.Duration
+	// Beacons enables merging the values broadcast by the sensors, which are received while scanning.
+	Beacons bool
+	// Passive reads all sensors only from the values they broadcast, without connecting to them.
+	Passive bool
+	// PassiveSensors overrides Passive for single sensors identified by their uppercase address.
+	PassiveSensors map[string]bool
+	AutoRegister   AutoRegisterConfig
+	// Registry is optional. If set, discovered and auto-registered sensors are recorded in it.
+	Registry Registry
+	// Diagnoser is optional. If set, connectivity checks can be run using Diagnose.
+	Diagnoser Diagnoser
+	// Blinker is optional. If set, sensors can be identified using Locate.
+	Blinker Blinker
+	// Namer is optional. If set, the device names of sensors without a configured name are read every NameInterval.
+	Namer        Namer
+	NameInterval time.Duration
+	// OnData is optional. If set, it is called with every successful reading.
+	OnData func(sensor Sensor, data miflora.Data)
+	// History is optional. If set, gaps between the readings of a sensor are filled using the history records stored
+	// on the sensor, which are passed to OnHistory.
+	History   HistoryReader
+	Backfill  BackfillConfig
+	OnHistory func(sensor Sensor, data miflora.Data)
+	// RemovalGrace is the duration the data of removed sensors is kept.
+	RemovalGrace time.Duration
+	// StaleDuration is optional. If set, due sensors whose data goes stale first are read first.
+	StaleDuration time.Duration
+}
+
+// Updater can be used to get data from a set of Miflora sensors and cache that data temporarily.
+type Updater struct {
+	log             *logging.Logger
+	refreshTimeout  time.Duration
+	watchdogTimeout time.Duration
+	retryConfig     RetryConfig
+	budget          readBudget
+	onDemand        *onDemandLimiter
+	health          stateMachine
+
+	deviceName     string
+	source         string
+	reader         Reader
+	diagnoser      Diagnoser
+	blinker        Blinker
+	namer          Namer
+	nameInterval   time.Duration
+	adapterLock    chan struct{}
+	cooldown       time.Duration
+	quietHours     QuietHours
+	sharedLock     SharedLock
+	lastAdapterUse time.Time
+	adapterSuspect atomic.Bool
+	metrics        readMetrics
+
+	scanner        Scanner
+	scanInterval   time.Duration
+	scanDuration   time.Duration
+	nextScan       time.Time
+	beacons        bool
+	passive        bool
+	passiveSensors map[string]bool
+	autoRegister   AutoRegisterConfig
+	registry       Registry
+	onData         func(sensor Sensor, data miflora.Data)
+	history        HistoryReader
+	backfill       BackfillConfig
+	onHistory      func(sensor Sensor, data miflora.Data)
+	// lastReadings contains the times of the last readings loaded from the backfill state file. It is only used by the
+	// update loop.
+	lastReadings  map[string]time.Time
+	removalGrace  time.Duration
+	staleDuration time.Duration
+
+	queueLock sync.RWMutex
+	queue     map[string]queueItem
+
+	dataLock sync.RWMutex
+	dataMap  map[string]*data
+
+	seenLock sync.RWMutex
+	seen     map[string]Sighting
+
+	// reads tracks the running reads, including the ones abandoned by the watchdog.
+	reads sync.WaitGroup
+}
+
+// New creates a new Updater using the specified options. Options which are not set are replaced by their defaults.
+// Messages are written to the logger, which can be nil.
+func New(logger *slog.Logger, opts Options) *Updater {
+	opts = opts.withDefaults()
+	log := logging.New(logger)
+
+	return &Updater{
+		log:             log,
+		refreshTimeout:  opts.RefreshTimeout,
+		watchdogTimeout: opts.WatchdogTimeout,
+		retryConfig:     opts.Retry,
+		deviceName:      opts.AdapterName,
+		source:          opts.Source,
+		reader:          opts.Reader,
+		diagnoser:       opts.Diagnoser,
+		blinker:         opts.Blinker,
+		namer:           opts.Namer,
+		nameInterval:    opts.NameInterval,
+		adapterLock:     make(chan struct{}, 1),
+		cooldown:        opts.Cooldown,
+		quietHours:      opts.QuietHours,
+		sharedLock:      opts.SharedLock,
+		metrics:         newReadMetrics(opts.AdapterName),
+		scanner:         opts.Scanner,
+		scanInterval:    opts.ScanInterval,
+		scanDuration:    opts.ScanDuration,
+		beacons:         opts.Beacons,
+		passive:         opts.Passive,
+		passiveSensors:  opts.PassiveSensors,
+		autoRegister:    opts.AutoRegister,
+		registry:        opts.Registry,
+		onData:          opts.OnData,
+		history:         opts.History,
+		backfill:        opts.Backfill,
+		onHistory:       opts.OnHistory,
+		lastReadings:    loadLastReadings(log, opts.Backfill.StateFile),
+		removalGrace:    opts.RemovalGrace,
+		staleDuration:   opts.StaleDuration,
+		queue:           map[string]queueItem{},
+		dataMap:         map[string]*data{},
+		seen:            map[string]Sighting{},
+		budget: readBudget{
+			config: opts.ReadBudget,
+		},
+		onDemand: newOnDemandLimiter(opts.ReadBudget.OnDemandPerMinute),
+		health: stateMachine{
+			config: opts.Health,
+		},
+	}
+}
+
+// withDefaults returns the options with defaults for the settings which are not set or invalid.
+func (o Options) withDefaults() Options {
+	if o.RefreshTimeout <= 0 {
+		o.RefreshTimeout = DefaultRefreshTimeout
+	}
+
+	if o.WatchdogTimeout <= o.RefreshTimeout {
+		o.WatchdogTimeout = 2 * o.RefreshTimeout
+	}
+
+	if o.ScanDuration <= 0 {
+		o.ScanDuration = DefaultScanDuration
+	}
+
+	if o.Retry.MinDuration <= 0 {
+		o.Retry.MinDuration = DefaultRetryMinDuration
+	}
+
+	if o.Retry.MaxDuration <= 0 {
+		o.Retry.MaxDuration = DefaultRetryMaxDuration
+	}
+
+	if o.Retry.MaxDuration < o.Retry.MinDuration {
+		o.Retry.MaxDuration = o.Retry.MinDuration
+	}
+
+	if o.Retry.Factor < 1 {
+		o.Retry.Factor = DefaultRetryFactor
+	}
+
+	if o.Retry.DownInterval <= 0 {
+		o.Retry.DownInterval = DefaultDownInterval
+	}
+
+	if o.Health.DegradedAfter <= 0 {
+		o.Health.DegradedAfter = DefaultDegradedAfter
+	}
+
+	if o.Health.RecoverAfter <= 0 {
+		o.Health.RecoverAfter = DefaultRecoverAfter
+	}
+
+	return o
+}
+
+// AddSensor adds a sensor to the updater. A sensor which has been removed, but whose data is still kept, is restored.
+func (u *Updater) AddSensor(sensor Sensor) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	if d, ok := u.dataMap[sensor.MacAddress]; ok && !d.Removed.IsZero() {
+		u.log.Debugf("Restoring removed sensor %q", sensor)
+		d.Info = sensor
+		d.Removed = time.Time{}
+		d.Disabled = false
+		d.Unnamed = sensor.Name == ""
+		d.NameRead = time.Time{}
+		return
+	}
+
+	u.log.Debugf("Adding sensor %q", sensor)
+	u.dataMap[sensor.MacAddress] = &data{
+		Info:    sensor,
+		State:   StateOK,
+		Unnamed: sensor.Name == "",
+		Updated: map[miflora.BeaconField]time.Time{},
+	}
+}
+
+// GetReadInfo returns metadata about the latest data available for the sensor identified by its MAC address.
+func (u *Updater) GetReadInfo(macAddress string) (ReadInfo, bool) {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	d, ok := u.dataMap[macAddress]
+	if !ok || d.Data == nil {
+		return ReadInfo{}, false
+	}
+
+	return d.ReadInfo, true
+}
+
+// GetData returns the latest data available for the sensor identified by its MAC address.
+func (u *Updater) GetData(macAddress string) (miflora.Data, error) {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	d, ok := u.dataMap[macAddress]
+	if !ok {
+		return miflora.Data{}, fmt.Errorf("no sensor with MAC address registered: %s", macAddress)
+	}
+
+	if d.Data == nil {
+		return miflora.Data{}, errors.New("no data available")
+	}
+
+	return *d.Data, nil
+}
+
+// Start starts the updater queue. It will periodically check if it needs to update data of one or more sensors.
+func (u *Updater) Start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(updaterTickDuration)
+		for {
+			select {
+			case <-ctx.Done():
+				u.log.Debug("Shutting down updater.")
+				return
+			case now := <-ticker.C:
+				u.adapterLock <- struct{}{}
+				if u.tick(ctx, now) {
+					u.lastAdapterUse = time.Now()
+				}
+				<-u.adapterLock
+			}
+		}
+	}()
+}
+
+// tick runs a scan or reads the next sensor which is due. It returns true if the adapter has been used.
+func (u *Updater) tick(ctx context.Context, now time.Time) bool {
+	u.forgetRemoved(now)
+
+	if u.quietHours.Contains(now) {
+		u.log.Debug("Quiet hours, not using adapter.")
+		return false
+	}
+
+	if u.scanDue(now) {
+		if !u.waitCooldown(ctx) || !u.lockShared(ctx) {
+			return false
+		}
+		defer u.unlockShared()
+
+		u.scan(ctx, now)
+		return true
+	}
+
+	next, ok := u.getNextQueueItem(now)
+	if !ok || u.isRemoved(next.Sensor) || u.isDisabled(next.Sensor) {
+		return false
+	}
+	u.log.Debugf("Queue item: %#v", next)
+
+	if u.isPassive(next.Sensor) {
+		u.readPassive(next, now)
+		return false
+	}
+
+	if !u.budget.available(now) {
+		u.log.Debug("Read budget exhausted, waiting.")
+		u.requeueItem(next)
+		return false
+	}
+
+	if !u.lockShared(ctx) {
+		u.requeueItem(next)
+		return false
+	}
+	defer u.unlockShared()
+
+	if !u.waitCooldown(ctx) {
+		u.requeueItem(next)
+		return false
+	}
+	u.budget.record(now)
+
+	previous := u.lastReading(next.Sensor)
+	u.recordAttempt(next.Sensor, now)
+	err := u.updateWithWatchdog(ctx, next.Sensor, next.Retries)
+	if err != nil {
+		u.failedRead(next, err, now)
+		return true
+	}
+
+	u.refreshName(ctx, next.Sensor, now)
+	u.fillGap(ctx, next.Sensor, previous, now)
+	return true
+}
+
+// failedRead records the error of a read and schedules the next attempt.
+func (u *Updater) failedRead(item queueItem, err error, now time.Time) {
+	down := u.recordError(item.Sensor, err, now)
+	u.log.Errorf("Error updating sensor %s: %s", item.Sensor, err)
+	if down {
+		u.probeItem(item, now)
+	} else {
+		u.retryItem(item, now)
+	}
+}
+
+// Close waits until the adapter is not used anymore and closes the reader, if it implements io.Closer. Reads abandoned
+// by the watchdog are waited for until the context is done. The updater can not use the adapter afterwards, so Close
+// should be called after the context passed to Start is done.
+func (u *Updater) Close(ctx context.Context) error {
+	select {
+	case u.adapterLock <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("adapter is busy: %s", ctx.Err())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		u.reads.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		u.log.Warnf("Closing adapter %q while abandoned reads are still running.", u.deviceName)
+	}
+
+	closer, ok := u.reader.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	u.log.Debugf("Closing adapter %q", u.deviceName)
+	return closer.Close()
+}
+
+// waitCooldown waits until the cooldown after the last use of the adapter has passed.
+// It needs to be called while holding the adapter lock. It returns false if the context is done while waiting.
+func (u *Updater) waitCooldown(ctx context.Context) bool {
+	wait := u.cooldown - time.Since(u.lastAdapterUse)
+	if wait <= 0 {
+		return true
+	}
+
+	u.log.Debugf("Waiting %s for adapter cooldown.", wait)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// lockShared acquires the lock shared with other processes. It returns false if the lock is not acquired within the
+// refresh timeout.
+func (u *Updater) lockShared(ctx context.Context) bool {
+	if u.sharedLock == nil {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.refreshTimeout)
+	defer cancel()
+
+	if err := u.sharedLock.Lock(ctx); err != nil {
+		u.log.Warnf("Can not use adapter: %s", err)
+		return false
+	}
+
+	return true
+}
+
+func (u *Updater) unlockShared() {
+	if u.sharedLock == nil {
+		return
+	}
+
+	if err := u.sharedLock.Unlock(); err != nil {
+		u.log.Errorf("Error releasing shared adapter lock: %s", err)
+	}
+}
+
+// ErrDiagnoseUnsupported is returned by Diagnose if the reader can not run connectivity checks.
+var ErrDiagnoseUnsupported = errors.New("connectivity checks need a Bluetooth adapter")
+
+// Diagnose runs a connectivity check of the sensor. The check waits until the adapter is not used by the updater. It
+// is rejected if another on-demand operation is queued or the limits are reached, see IsRejected.
+func (u *Updater) Diagnose(ctx context.Context, macAddress string, scanDuration time.Duration) (diagnose.Report, error) {
+	if u.diagnoser == nil {
+		return diagnose.Report{}, ErrDiagnoseUnsupported
+	}
+
+	done, err := u.startOnDemand(time.Now())
+	if err != nil {
+		return diagnose.Report{}, err
+	}
+	defer done()
+
+	release, err := u.acquireAdapter(ctx)
+	if err != nil {
+		return diagnose.Report{}, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, u.refreshTimeout+scanDuration)
+	defer cancel()
+
+	report := u.diagnoser.Diagnose(ctx, u.address(macAddress), scanDuration)
+	report.Adapter = u.deviceName
+	return report, nil
+}
+
+// address returns the address of the device of a registered sensor. Other MAC addresses are returned unchanged.
+func (u *Updater) address(macAddress string) string {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	for mac, d := range u.dataMap {
+		if strings.EqualFold(mac, macAddress) {
+			return d.Info.Address()
+		}
+	}
+
+	return macAddress
+}
+
+// AdapterSuspect returns true if the last read on the adapter had to be abandoned by the watchdog.
+func (u *Updater) AdapterSuspect() bool {
+	return u.adapterSuspect.Load()
+}
+
+// UpdateAll schedules an update for all registered sensors and starts a new refresh cycle.
+func (u *Updater) UpdateAll(now time.Time) {
+	u.budget.newCycle()
+	sensors := u.enabledSensors()
+
+	for _, s := range sensors {
+		u.scheduleUpdate(s, now)
+	}
+}
+
+// WarmUp starts the first refresh cycle with the reads of the sensors spread evenly over the warm-up window.
+func (u *Updater) WarmUp(now time.Time, window time.Duration) {
+	u.budget.newCycle()
+	sensors := u.enabledSensors()
+
+	for i, s := range sensors {
+		offset := window * time.Duration(i) / time.Duration(len(sensors))
+		u.scheduleUpdate(s, now.Add(offset))
+	}
+}
+
+// Sensors returns all registered sensors, which have not been removed, ordered by their MAC address.
+func (u *Updater) Sensors() []Sensor {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	result := []Sensor{}
+	for _, d := range u.dataMap {
+		if d.Removed.IsZero() {
+			result = append(result, d.Info)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].MacAddress < result[j].MacAddress
+	})
+	return result
+}
+
+// enabledSensors returns the registered sensors, which have not been removed or disabled.
+func (u *Updater) enabledSensors() []Sensor {
+	result := []Sensor{}
+	for _, s := range u.Sensors() {
+		if !u.isDisabled(s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (u *Updater) getNextQueueItem(now time.Time) (queueItem, bool) {
+	deadlines := u.staleDeadlines()
+
+	u.queueLock.Lock()
+	defer u.queueLock.Unlock()
+
+	if len(u.queue) == 0 {
+		return queueItem{}, false
+	}
+	u.log.Debugf("Queue length: %d", len(u.queue))
+
+	items := []queueItem{}
+	for _, i := range u.queue {
+		items = append(items, i)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Time.Before(items[j].Time)
+	})
+
+	next := items[0]
+	diff := next.Time.Sub(now)
+	if diff > 0 {
+		u.log.Debugf("Sensor %q is still waiting %s", next.Sensor, diff)
+		return queueItem{}, false
+	}
+
+	if u.staleDuration > 0 {
+		next = mostUrgent(items, deadlines, now)
+	}
+
+	delete(u.queue, next.Sensor.MacAddress)
+	return next, true
+}
+
+// mostUrgent returns the due item of the sorted items, whose data goes stale first. Sensors which have not been read
+// yet come first.
+func mostUrgent(items []queueItem, deadlines map[string]time.Time, now time.Time) queueItem {
+	result := items[0]
+	for _, item := range items[1:] {
+		if item.Time.After(now) {
+			break
+		}
+
+		if deadlines[item.Sensor.MacAddress].Before(deadlines[result.Sensor.MacAddress]) {
+			result = item
+		}
+	}
+
+	return result
+}
+
+// staleDeadlines returns the times the data of the sensors goes stale by their MAC address. Sensors without data are
+// left out.
+func (u *Updater) staleDeadlines() map[string]time.Time {
+	u.dataLock.RLock()
+	defer u.dataLock.RUnlock()
+
+	result := make(map[string]time.Time, len(u.dataMap))
+	for mac, d := range u.dataMap {
+		if d.Data != nil {
+			result[mac] = d.Data.Time.Add(u.staleDuration)
+		}
+	}
+	return result
+}
+
+func (u *Updater) scheduleUpdate(sensor Sensor, at time.Time) {
+	u.queueLock.Lock()
+	defer u.queueLock.Unlock()
+
+	if existing, ok := u.queue[sensor.MacAddress]; ok && (existing.LastRetry == 0 || existing.Down) {
+		// Keep the position of sensors which have not been read in the last cycle and of sensors which are down.
+		return
+	}
+
+	u.queue[sensor.MacAddress] = queueItem{
+		Sensor:    sensor,
+		Time:      at,
+		LastRetry: 0,
+	}
+}
+
+// updateWithWatchdog updates the sensor, but stops waiting for the read once the watchdog timeout is exceeded.
+// This keeps a wedged connection from stalling the queue for all other sensors.
+func (u *Updater) updateWithWatchdog(ctx context.Context, sensor Sensor, retries int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	u.reads.Add(1)
+	go func() {
+		defer u.reads.Done()
+		errCh <- u.updateSensor(ctx, sensor, retries)
+	}()
+
+	watchdog := time.NewTimer(u.watchdogTimeout)
+	defer watchdog.Stop()
+
+	select {
+	case err := <-errCh:
+		if err == nil && u.adapterSuspect.Swap(false) {
+			u.log.Infof("Adapter %q is working again.", u.deviceName)
+		}
+		return err
+	case <-watchdog.C:
+		u.log.Warnf("Read of %q on %q exceeded watchdog timeout of %s, marking adapter as suspect.", sensor, u.deviceName, u.watchdogTimeout)
+		u.adapterSuspect.Store(true)
+		return &failure{
+			Stage:  miflora.StageRead,
+			Reason: reasonWatchdog,
+			Err:    fmt.Errorf("read abandoned by watchdog after %s", u.watchdogTimeout),
+		}
+	}
+}
+
+func (u *Updater) updateSensor(ctx context.Context, sensor Sensor, retries int) (err error) {
+	ctx, span := tracer.Start(ctx, "updater.updateSensor", trace.WithAttributes(
+		attribute.String("macaddress", sensor.MacAddress),
+		attribute.String("name", sensor.Name),
+		attribute.String("adapter", u.deviceName),
+	))
+	defer func(start time.Time) {
+		elapsed := time.Since(start)
+		u.log.Debugf("Updating %q took %s.", sensor, elapsed)
+		u.metrics.observe(span, []string{sensor.MacAddress, sensor.Name}, elapsed.Seconds(), err)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}(time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, u.refreshTimeout)
+	defer cancel()
+
+	u.log.Debugf("Reading data for %q on %q", sensor.Address(), u.deviceName)
+	data, err := u.reader.ReadData(ctx, sensor.Address())
+	u.storePayload(sensor, data, err)
+	if err != nil {
+		return readFailure(ctx, err)
+	}
+
+	if err := data.Validate(); err != nil {
+		return &failure{
+			Stage:  stageValidate,
+			Reason: reasonImplausible,
+			Err:    fmt.Errorf("implausible data: %s", err),
+		}
+	}
+
+	sensor, err = u.storeData(sensor, data, ReadInfo{
+		Adapter: u.deviceName,
+		Source:  u.source,
+		Retries: retries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if u.onData != nil {
+		u.onData(sensor, data)
+	}
+	return nil
+}
+
+// storeData stores the data of the sensor and returns the current information about the sensor.
+func (u *Updater) storeData(sensor Sensor, data miflora.Data, info ReadInfo) (Sensor, error) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	mapItem, ok := u.dataMap[sensor.MacAddress]
+	if !ok {
+		return sensor, fmt.Errorf("sensor has been removed: %s", sensor.MacAddress)
+	}
+	mapItem.Data = &data
+	mapItem.ReadInfo = info
+	setUpdated(mapItem, data.Time)
+	mapItem.Failures = 0
+	mapItem.FirstFailure = time.Time{}
+	mapItem.Successes++
+	if mapItem.Down {
+		u.log.Infof("Sensor %q is reachable again.", sensor)
+		mapItem.Down = false
+	}
+	u.setState(sensor, mapItem, u.health.succeeded(mapItem.State, mapItem.Successes))
+	return mapItem.Info, nil
+}
+
+func (u *Updater) recordAttempt(sensor Sensor, now time.Time) {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	if mapItem, ok := u.dataMap[sensor.MacAddress]; ok {
+		mapItem.LastAttempt = now
+	}
+}
+
+// recordError stores the error of a failed read. It returns true if the sensor is considered down, because it exceeded
+// the maximum number of retries or the maximum retry window.
+func (u *Updater) recordError(sensor Sensor, err error, now time.Time) bool {
+	u.dataLock.Lock()
+	defer u.dataLock.Unlock()
+
+	mapItem, ok := u.dataMap[sensor.MacAddress]
+	if !ok {
+		return false
+	}
+	mapItem.LastError = err
+	mapItem.LastErrorTime = time.Now()
+	mapItem.Failures++
+	if mapItem.FirstFailure.IsZero() {
+		mapItem.FirstFailure = now
+	}
+
+	mapItem.Successes = 0
+
+	if !mapItem.Down && u.givesUp(mapItem.Failures, now.Sub(mapItem.FirstFailure)) {
+		u.log.Warnf("Giving up on sensor %q after %d failed reads, probing every %s.", sensor, mapItem.Failures, u.retryConfig.DownInterval)
+		mapItem.Down = true
+	}
+	u.setState(sensor, mapItem, u.health.failed(mapItem.State, mapItem.Failures, mapItem.Down))
+
+	return mapItem.Down
+}
+
+// setState changes the state of the sensor. It needs to be called while holding the data lock.
+func (u *Updater) setState(sensor Sensor, d *data, state SensorState) {
+	if d.State == state {
+		return
+	}
+
+	u.log.Debugf("Sensor %q changed state: %s -> %s", sensor, d.State, state)
+	d.State = state
+}
+
+func (u *Updater) givesUp(failures int, window time.Duration) bool {
+	if u.retryConfig.MaxRetries > 0 && failures > u.retryConfig.MaxRetries {
+		return true
+	}
+
+	return u.retryConfig.MaxWindow > 0 && window >= u.retryConfig.MaxWindow
+}
+
+// probeItem schedules the next read of a sensor which is down.
+func (u *Updater) probeItem(item queueItem, now time.Time) {
+	u.queueLock.Lock()
+	defer u.queueLock.Unlock()
+
+	u.queue[item.Sensor.MacAddress] = queueItem{
+		Sensor:    item.Sensor,
+		Time:      now.Add(u.retryConfig.DownInterval),
+		LastRetry: u.retryConfig.DownInterval,
+		Retries:   item.Retries + 1,
+		Down:      true,
+	}
+}
+
+// requeueItem puts an item back into the queue, unless the sensor has been scheduled again in the meantime.
+func (u *Updater) requeueItem(item queueItem) {
+	u.queueLock.Lock()
+	defer u.queueLock.Unlock()
+
+	if _, ok := u.queue[item.Sensor.MacAddress]; ok {
+		return
+	}
+	u.queue[item.Sensor.MacAddress] = item
+}
+
+func (u *Updater) retryItem(item queueItem, now time.Time) {
+	retryAfter := item.LastRetry
+	if retryAfter < u.retryConfig.MinDuration {
+		retryAfter = u.retryConfig.MinDuration
+	} else {
+		retryAfter = time.Duration(float64(retryAfter) * u.retryConfig.Factor)
+
+		if retryAfter > u.retryConfig.MaxDuration {
+			retryAfter = u.retryConfig.MaxDuration
+		}
+	}
+
+	u.queueLock.Lock()
+	defer u.queueLock.Unlock()
+
+	u.log.Debugf("Retrying %q after %s", item.Sensor, retryAfter)
+	u.queue[item.Sensor.MacAddress] = queueItem{
+		Sensor:    item.Sensor,
+		Time:      now.Add(retryAfter),
+		LastRetry: retryAfter,
+		Retries:   item.Retries + 1,
+	}
+}
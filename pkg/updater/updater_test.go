@@ -0,0 +1,219 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xperimental/flowercare-exporter/pkg/miflora"
+)
+
+func TestOptionsWithDefaults(t *testing.T) {
+	tests := []struct {
+		desc string
+		opts Options
+		want Options
+	}{
+		{
+			desc: "zero value",
+			opts: Options{},
+			want: Options{
+				RefreshTimeout:  DefaultRefreshTimeout,
+				WatchdogTimeout: 2 * DefaultRefreshTimeout,
+				ScanDuration:    DefaultScanDuration,
+				Retry: RetryConfig{
+					MinDuration:  DefaultRetryMinDuration,
+					MaxDuration:  DefaultRetryMaxDuration,
+					Factor:       DefaultRetryFactor,
+					DownInterval: DefaultDownInterval,
+				},
+				Health: HealthConfig{
+					DegradedAfter: DefaultDegradedAfter,
+					RecoverAfter:  DefaultRecoverAfter,
+				},
+			},
+		},
+		{
+			desc: "keep set values",
+			opts: Options{
+				RefreshTimeout:  10 * time.Second,
+				WatchdogTimeout: 15 * time.Second,
+				ScanDuration:    time.Second,
+				Retry: RetryConfig{
+					MinDuration:  time.Minute,
+					MaxDuration:  time.Hour,
+					Factor:       1.5,
+					MaxRetries:   3,
+					DownInterval: 2 * time.Hour,
+				},
+				Health: HealthConfig{
+					DegradedAfter: 3,
+					RecoverAfter:  1,
+				},
+			},
+			want: Options{
+				RefreshTimeout:  10 * time.Second,
+				WatchdogTimeout: 15 * time.Second,
+				ScanDuration:    time.Second,
+				Retry: RetryConfig{
+					MinDuration:  time.Minute,
+					MaxDuration:  time.Hour,
+					Factor:       1.5,
+					MaxRetries:   3,
+					DownInterval: 2 * time.Hour,
+				},
+				Health: HealthConfig{
+					DegradedAfter: 3,
+					RecoverAfter:  1,
+				},
+			},
+		},
+		{
+			desc: "watchdog derived from refresh timeout",
+			opts: Options{
+				RefreshTimeout:  10 * time.Second,
+				WatchdogTimeout: 5 * time.Second,
+			},
+			want: Options{
+				RefreshTimeout:  10 * time.Second,
+				WatchdogTimeout: 20 * time.Second,
+				ScanDuration:    DefaultScanDuration,
+				Retry: RetryConfig{
+					MinDuration:  DefaultRetryMinDuration,
+					MaxDuration:  DefaultRetryMaxDuration,
+					Factor:       DefaultRetryFactor,
+					DownInterval: DefaultDownInterval,
+				},
+				Health: HealthConfig{
+					DegradedAfter: DefaultDegradedAfter,
+					RecoverAfter:  DefaultRecoverAfter,
+				},
+			},
+		},
+		{
+			desc: "maximum retry below minimum",
+			opts: Options{
+				Retry: RetryConfig{
+					MinDuration: 2 * time.Hour,
+					Factor:      0.5,
+				},
+			},
+			want: Options{
+				RefreshTimeout:  DefaultRefreshTimeout,
+				WatchdogTimeout: 2 * DefaultRefreshTimeout,
+				ScanDuration:    DefaultScanDuration,
+				Retry: RetryConfig{
+					MinDuration:  2 * time.Hour,
+					MaxDuration:  2 * time.Hour,
+					Factor:       DefaultRetryFactor,
+					DownInterval: DefaultDownInterval,
+				},
+				Health: HealthConfig{
+					DegradedAfter: DefaultDegradedAfter,
+					RecoverAfter:  DefaultRecoverAfter,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.opts.withDefaults()
+
+			if got.RefreshTimeout != tc.want.RefreshTimeout {
+				t.Errorf("got refresh timeout %s, want %s", got.RefreshTimeout, tc.want.RefreshTimeout)
+			}
+
+			if got.WatchdogTimeout != tc.want.WatchdogTimeout {
+				t.Errorf("got watchdog timeout %s, want %s", got.WatchdogTimeout, tc.want.WatchdogTimeout)
+			}
+
+			if got.ScanDuration != tc.want.ScanDuration {
+				t.Errorf("got scan duration %s, want %s", got.ScanDuration, tc.want.ScanDuration)
+			}
+
+			if got.Retry != tc.want.Retry {
+				t.Errorf("got retry %#v, want %#v", got.Retry, tc.want.Retry)
+			}
+
+			if got.Health != tc.want.Health {
+				t.Errorf("got health %#v, want %#v", got.Health, tc.want.Health)
+			}
+		})
+	}
+}
+
+func TestUpdateWithWatchdog(t *testing.T) {
+	wedged := make(chan struct{})
+	source := readerFunc(func(ctx context.Context, macAddress string) (miflora.Data, error) {
+		if macAddress == testSensors[1].MacAddress {
+			// The read ignores the context, like a read stuck in the Bluetooth stack.
+			<-wedged
+			return miflora.Data{}, errors.New("connection reset")
+		}
+
+		return plausibleReader(ctx, macAddress)
+	})
+
+	// The fake Bluetooth backend of the benchmark is not used, because it does not support concurrent reads.
+	u := New(nil, Options{
+		RefreshTimeout:  10 * time.Millisecond,
+		WatchdogTimeout: 50 * time.Millisecond,
+		Reader:          source,
+	})
+	for _, s := range testSensors {
+		u.AddSensor(s)
+	}
+
+	tests := []struct {
+		desc        string
+		sensor      Sensor
+		wantReason  string
+		wantSuspect bool
+	}{
+		{
+			desc:   "read in time",
+			sensor: testSensors[0],
+		},
+		{
+			desc:        "read abandoned by watchdog",
+			sensor:      testSensors[1],
+			wantReason:  reasonWatchdog,
+			wantSuspect: true,
+		},
+		{
+			desc:   "successful read clears suspect adapter",
+			sensor: testSensors[2],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := u.updateWithWatchdog(context.Background(), tc.sensor, 0)
+			var reason string
+			var f *failure
+			if errors.As(err, &f) {
+				reason = f.Reason
+			}
+
+			if reason != tc.wantReason {
+				t.Errorf("got error %v with reason %q, want reason %q", err, reason, tc.wantReason)
+			}
+
+			if got := u.AdapterSuspect(); got != tc.wantSuspect {
+				t.Errorf("got suspect adapter %v, want %v", got, tc.wantSuspect)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := u.Close(ctx); err != nil {
+		t.Fatalf("got error %q", err)
+	}
+
+	// Close waits for the abandoned read until the context is done.
+	close(wedged)
+	u.reads.Wait()
+}